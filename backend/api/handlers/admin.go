@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/keeper"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAdminListLength and maxAdminListLength bound admin listings
+	defaultAdminListLength = 50
+	maxAdminListLength     = 200
+)
+
+// failedTransactionStatuses are the final statuses of transactions that did not go through
+var failedTransactionStatuses = []models.TransactionStatus{
+	models.TransactionStatusReverted,
+	models.TransactionStatusDropped,
+	models.TransactionStatusCancelled,
+}
+
+type AdminHandler struct {
+	db         *gorm.DB
+	blockchain service.BlockchainService
+	sessions   *session.Manager
+	keeper     *keeper.Keeper
+}
+
+func NewAdminHandler(db *gorm.DB, blockchain service.BlockchainService, sessions *session.Manager, keeper *keeper.Keeper) *AdminHandler {
+	return &AdminHandler{
+		db:         db,
+		blockchain: blockchain,
+		sessions:   sessions,
+		keeper:     keeper,
+	}
+}
+
+type UpdateUserRolesRequest struct {
+	Roles []string `json:"roles"` // admin, support or auditor; empty removes all roles
+}
+
+type CancelNonceResponse struct {
+	Nonce         uint64     `json:"nonce"`
+	TxHash        string     `json:"tx_hash"`                  // Cancellation transaction
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty"` // Tracked transaction being cancelled
+	Message       string     `json:"message"`
+}
+
+// CancelNonce godoc
+// @Summary Cancel a pending server signer nonce
+// @Description Replace the server signer's pending transaction at a nonce with a zero-value self-transfer at higher fees
+// @Tags admin
+// @Produce json
+// @Param nonce path int true "Nonce"
+// @Success 200 {object} CancelNonceResponse
+// @Router /admin/nonces/{nonce}/cancel [post]
+// @Security BearerAuth
+func (h *AdminHandler) CancelNonce(c fiber.Ctx) error {
+	// Parse nonce
+	nonce, err := strconv.ParseUint(c.Params("nonce"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid nonce",
+		})
+	}
+
+	tx, err := h.blockchain.ReplaceTransaction(c.Context(), nonce, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNonceNotPending):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No pending transaction for nonce",
+			})
+		case errors.Is(err, service.ErrFeeCeiling):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to cancel nonce",
+		})
+	}
+
+	response := CancelNonceResponse{
+		Nonce:   nonce,
+		TxHash:  tx.Hash().Hex(),
+		Message: "Cancellation broadcast. The nonce is cancelled once it is mined.",
+	}
+
+	// Link the cancellation so the tracker marks the transaction cancelled
+	var record models.Transaction
+	err = h.db.Where("LOWER(from_address) = LOWER(?) AND nonce = ? AND status = ?",
+		h.blockchain.RelayerAddress().Hex(), nonce, models.TransactionStatusPending).
+		First(&record).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query transaction",
+		})
+	}
+	if err == nil {
+		if err := tracker.RecordReplacement(h.db, &record, tx, true); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to record cancellation",
+			})
+		}
+		response.TransactionID = &record.ID
+	}
+
+	return c.JSON(response)
+}
+
+// ListVaults godoc
+// @Summary List all vaults
+// @Description List every user's vaults, newest first, with their owners and heirs
+// @Tags admin
+// @Produce json
+// @Param status query string false "locked, unlocked or claimed"
+// @Param owner query string false "Owner wallet address"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Vaults to skip"
+// @Success 200 {array} models.Vault
+// @Router /admin/vaults [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListVaults(c fiber.Ctx) error {
+	limit, offset, ferr := parsePage(c, defaultAdminListLength, maxAdminListLength)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	query := h.db.Model(&models.Vault{})
+	if status := c.Query("status"); status != "" {
+		switch models.VaultStatus(status) {
+		case models.VaultStatusLocked, models.VaultStatusUnlocked, models.VaultStatusClaimed:
+			query = query.Where("status = ?", status)
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid status",
+			})
+		}
+	}
+	if owner := c.Query("owner"); owner != "" {
+		if !common.IsHexAddress(owner) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid owner address",
+			})
+		}
+		query = query.Where("owner_id IN (?)",
+			h.db.Model(&models.User{}).Select("id").Where("LOWER(address) = LOWER(?)", owner))
+	}
+
+	var vaults []models.Vault
+	if err := query.Preload("Owner").
+		Preload("Heirs").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&vaults).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch vaults",
+		})
+	}
+
+	return c.JSON(vaults)
+}
+
+// GetVault godoc
+// @Summary Get any vault
+// @Description Get a vault of any user with its owner and heirs
+// @Tags admin
+// @Produce json
+// @Param id path string true "Vault UUID"
+// @Success 200 {object} models.Vault
+// @Router /admin/vaults/{id} [get]
+// @Security BearerAuth
+func (h *AdminHandler) GetVault(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid vault ID",
+		})
+	}
+
+	var vault models.Vault
+	if err := h.db.Preload("Owner").Preload("Heirs").First(&vault, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Vault not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch vault",
+		})
+	}
+
+	return c.JSON(vault)
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List users, newest first
+// @Tags admin
+// @Produce json
+// @Param role query string false "Only users with this role"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Users to skip"
+// @Success 200 {array} models.User
+// @Router /admin/users [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListUsers(c fiber.Ctx) error {
+	limit, offset, ferr := parsePage(c, defaultAdminListLength, maxAdminListLength)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	query := h.db.Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		if !models.ValidRole(role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid role",
+			})
+		}
+		query = query.Where("',' || roles || ',' LIKE ?", "%,"+role+",%")
+	}
+
+	var users []models.User
+	if err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch users",
+		})
+	}
+
+	return c.JSON(users)
+}
+
+// UpdateUserRoles godoc
+// @Summary Set a user's roles
+// @Description Replace a user's roles. They take effect on the user's next token refresh; removing a role logs the user out everywhere so it takes effect at once.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User UUID"
+// @Param request body UpdateUserRolesRequest true "Roles"
+// @Success 200 {object} models.User
+// @Router /admin/users/{id}/roles [put]
+// @Security BearerAuth
+func (h *AdminHandler) UpdateUserRoles(c fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req UpdateUserRolesRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var roles models.Roles
+	for _, role := range req.Roles {
+		if !models.ValidRole(role) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid role: " + role,
+			})
+		}
+		if !roles.Has(role) {
+			roles = append(roles, role)
+		}
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch user",
+		})
+	}
+
+	removed := false
+	for _, role := range user.Roles {
+		if !roles.Has(role) {
+			removed = true
+		}
+	}
+
+	if err := h.db.Model(&user).Update("roles", roles).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update roles",
+		})
+	}
+	user.Roles = roles
+
+	// Access tokens carry roles, so end the sessions still holding a removed one
+	if removed {
+		if _, err := h.sessions.RevokeAll(c.Context(), user.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Roles updated, but failed to log out the user's sessions",
+			})
+		}
+	}
+
+	return c.JSON(user)
+}
+
+// ListFailedTransactions godoc
+// @Summary List failed transactions
+// @Description List reverted, dropped and cancelled transactions of all users, newest first
+// @Tags admin
+// @Produce json
+// @Param status query string false "reverted, dropped or cancelled"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Transactions to skip"
+// @Success 200 {array} models.Transaction
+// @Router /admin/transactions/failed [get]
+// @Security BearerAuth
+func (h *AdminHandler) ListFailedTransactions(c fiber.Ctx) error {
+	limit, offset, ferr := parsePage(c, defaultAdminListLength, maxAdminListLength)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	statuses := failedTransactionStatuses
+	if status := c.Query("status"); status != "" {
+		statuses = nil
+		for _, failed := range failedTransactionStatuses {
+			if models.TransactionStatus(status) == failed {
+				statuses = []models.TransactionStatus{failed}
+			}
+		}
+		if statuses == nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid status",
+			})
+		}
+	}
+
+	var transactions []models.Transaction
+	if err := h.db.Where("status IN ?", statuses).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&transactions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch transactions",
+		})
+	}
+
+	return c.JSON(transactions)
+}
+
+// RunKeeper godoc
+// @Summary Run a keeper round
+// @Description Submit checkAndUnlock for every vault whose heartbeat deadline has passed now, instead of waiting for the next KEEPER_POLL_INTERVAL
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /admin/keeper/run [post]
+// @Security BearerAuth
+func (h *AdminHandler) RunKeeper(c fiber.Ctx) error {
+	if err := h.keeper.Poll(c.Context()); err != nil {
+		if errors.Is(err, keeper.ErrBusy) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A keeper round is already running",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Keeper round failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Keeper round completed",
+	})
+}
+
+// parsePage reads the limit and offset query parameters. The limit defaults to
+// defaultLimit and is capped at maxLimit.
+func parsePage(c fiber.Ctx, defaultLimit, maxLimit int) (int, int, *fiber.Error) {
+	limit := defaultLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid limit")
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid offset")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
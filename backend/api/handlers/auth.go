@@ -2,83 +2,146 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/mail"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/gofiber/fiber/v3"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/session"
 	"github.com/haneumLee/legacychain/backend/models"
 	"github.com/haneumLee/legacychain/backend/pkg/crypto"
 	"github.com/redis/go-redis/v9"
-	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
-	db           *gorm.DB
 	cfg          *config.Config
 	nonceManager *crypto.NonceManager
+	sessions     *session.Manager
+	keys         *session.KeySet
+	blockchain   service.BlockchainService
+	users        repository.UserRepository
 }
 
-func NewAuthHandler(db *gorm.DB, cfg *config.Config, redisClient *redis.Client) *AuthHandler {
+func NewAuthHandler(cfg *config.Config, redisClient *redis.Client, keys *session.KeySet, sessions *session.Manager, blockchain service.BlockchainService, repos *repository.Repositories) *AuthHandler {
 	return &AuthHandler{
-		db:           db,
 		cfg:          cfg,
 		nonceManager: crypto.NewNonceManager(redisClient),
+		sessions:     sessions,
+		keys:         keys,
+		blockchain:   blockchain,
+		users:        repos.Users,
 	}
 }
 
 type NonceResponse struct {
 	Nonce     string `json:"nonce"`
-	Message   string `json:"message"`
+	Message   string `json:"message,omitempty"` // SIWE message if address was given, else the legacy message if enabled
 	Timestamp int64  `json:"timestamp"`
+	Domain    string `json:"domain"` // SIWE parameters, for clients that build the message themselves
+	URI       string `json:"uri"`
+	ChainID   int64  `json:"chain_id"`
 }
 
+// LoginRequest is a signed SIWE (EIP-4361) message, or the legacy login
+// message with its nonce and timestamp
 type LoginRequest struct {
-	Address   string `json:"address" validate:"required,eth_addr"`
+	Address   string `json:"address" validate:"omitempty,eth_addr"` // Required for legacy messages
 	Signature string `json:"signature" validate:"required"`
 	Message   string `json:"message" validate:"required"`
-	Nonce     string `json:"nonce" validate:"required"`
-	Timestamp int64  `json:"timestamp" validate:"required"`
+	Nonce     string `json:"nonce"`     // Legacy messages only
+	Timestamp int64  `json:"timestamp"` // Legacy messages only
+}
+
+type UpdateMeRequest struct {
+	Email    *string `json:"email"` // Heartbeat reminders are sent here; empty to stop them
+	Nickname *string `json:"nickname"`
 }
 
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token        string       `json:"token"`         // Access token
+	RefreshToken string       `json:"refresh_token"` // Single use, exchanged at /auth/refresh
+	ExpiresIn    int64        `json:"expires_in"`    // Seconds until the access token expires
+	User         *models.User `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type RefreshResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"` // Replaces the one sent, which no longer works
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 // GetNonce godoc
 // @Summary Get nonce for signature
-// @Description Generate a nonce for signing the login message
+// @Description Generate a nonce for signing the login message. With address, message is a ready-to-sign SIWE (EIP-4361) message; otherwise clients build one from nonce, domain, uri and chain_id.
 // @Tags auth
 // @Produce json
+// @Param address query string false "Wallet address to build the SIWE message for"
 // @Success 200 {object} NonceResponse
 // @Router /auth/nonce [get]
 func (h *AuthHandler) GetNonce(c fiber.Ctx) error {
 	ctx := context.Background()
 
-	// Generate nonce
-	nonce, timestamp, err := h.nonceManager.GenerateNonce(ctx)
+	address := c.Query("address")
+	if address != "" && !common.IsHexAddress(address) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid address",
+		})
+	}
+
+	// Generate nonce (alphanumeric, so it is valid in either message format)
+	nonce, timestamp, err := h.nonceManager.GenerateSIWENonce(ctx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate nonce",
 		})
 	}
 
-	// Create login message
-	message := crypto.FormatLoginMessage(nonce, timestamp)
-
-	return c.JSON(NonceResponse{
+	response := NonceResponse{
 		Nonce:     nonce,
-		Message:   message,
 		Timestamp: timestamp,
-	})
+		Domain:    h.cfg.SIWE.Domain,
+		URI:       h.cfg.SIWE.URI,
+		ChainID:   h.cfg.Blockchain.ChainID,
+	}
+
+	// Create login message
+	switch {
+	case address != "":
+		issuedAt := time.Unix(timestamp, 0).UTC()
+		expiresAt := issuedAt.Add(crypto.NonceExpiration)
+		message := crypto.SIWEMessage{
+			Domain:         h.cfg.SIWE.Domain,
+			Address:        common.HexToAddress(address),
+			Statement:      h.cfg.SIWE.Statement,
+			URI:            h.cfg.SIWE.URI,
+			Version:        crypto.SIWEVersion,
+			ChainID:        h.cfg.Blockchain.ChainID,
+			Nonce:          nonce,
+			IssuedAt:       issuedAt,
+			ExpirationTime: &expiresAt,
+		}
+		response.Message = message.String()
+	case h.cfg.SIWE.LegacyLogin:
+		response.Message = crypto.FormatLoginMessage(nonce, timestamp)
+	}
+
+	return c.JSON(response)
 }
 
 // Login godoc
 // @Summary User login
-// @Description Authenticate user with Ethereum address and signature
+// @Description Authenticate user with a signed SIWE (EIP-4361) message. The legacy "Login to LegacyChain" message is accepted while LEGACY_LOGIN_ENABLED is true.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -95,82 +158,248 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 		})
 	}
 
-	// 1. Validate nonce (check if it exists and hasn't been used)
-	valid, err := h.nonceManager.ValidateNonce(ctx, req.Nonce)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to validate nonce",
+	// 1-4. Verify the signed message and find out who signed it
+	var address string
+	var ferr *fiber.Error
+	if crypto.IsSIWEMessage(req.Message) {
+		address, ferr = h.verifySIWELogin(ctx, &req)
+	} else {
+		address, ferr = h.verifyLegacyLogin(ctx, &req)
+	}
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
 		})
 	}
-	if !valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid or expired nonce",
+
+	// 5. Find or create user
+	user, err := h.users.FindOrCreate(ctx, address)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to find or create user",
 		})
 	}
 
-	// 2. Validate timestamp (not too old, not in the future)
-	valid, err = crypto.ValidateTimestamp(req.Timestamp)
+	// 6. Start a session: short-lived access token plus refresh token
+	tokens, err := h.sessions.Start(ctx, user)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": err.Error(),
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
 		})
 	}
 
-	// 3. Reconstruct the message that should have been signed
-	expectedMessage := crypto.FormatLoginMessage(req.Nonce, req.Timestamp)
-	if strings.TrimSpace(req.Message) != strings.TrimSpace(expectedMessage) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Message mismatch",
+	return c.JSON(LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+		User:         user,
+	})
+}
+
+// Refresh godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token works once; presenting one that was already exchanged revokes its session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} RefreshResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.Bind().Body(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
 		})
 	}
 
-	// 4. Verify signature using EIP-191 (Ethereum Personal Sign)
-	valid, err = crypto.VerifySignature(req.Address, req.Message, req.Signature)
-	if err != nil {
+	tokens, err := h.sessions.Refresh(c.Context(), req.RefreshToken)
+	if errors.Is(err, session.ErrInvalidRefreshToken) || errors.Is(err, session.ErrRefreshTokenReused) {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": fmt.Sprintf("Signature verification failed: %v", err),
+			"error": err.Error(),
 		})
 	}
-	if !valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid signature: address mismatch",
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh token",
 		})
 	}
 
-	// 5. Find or create user
-	var user models.User
-	result := h.db.Where("address = ?", req.Address).First(&user)
-	if result.Error == gorm.ErrRecordNotFound {
-		user = models.User{
-			Address: req.Address,
-		}
-		if err := h.db.Create(&user).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create user",
-			})
-		}
+	return c.JSON(RefreshResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	})
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description End the current session. Its refresh token and every access token issued for it stop working.
+// @Tags auth
+// @Success 204
+// @Router /auth/logout [post]
+// @Security BearerAuth
+func (h *AuthHandler) Logout(c fiber.Ctx) error {
+	claims := c.Locals("claims").(*session.Claims)
+
+	if err := h.sessions.Revoke(c.Context(), claims); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
 	}
 
-	// 6. Generate JWT token
-	claims := jwt.MapClaims{
-		"address": user.Address,
-		"exp":     time.Now().Add(h.cfg.JWT.ExpiresIn).Unix(),
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Log out all sessions
+// @Description End every session of the current user, on all devices, including this one
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Router /auth/logout-all [post]
+// @Security BearerAuth
+func (h *AuthHandler) LogoutAll(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	user, err := h.users.FindByAddress(c.Context(), address)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(h.cfg.JWT.Secret))
+	revoked, err := h.sessions.RevokeAll(c.Context(), user.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to generate token",
+			"error": "Failed to log out sessions",
 		})
 	}
 
-	return c.JSON(LoginResponse{
-		Token: tokenString,
-		User:  &user,
+	return c.JSON(fiber.Map{
+		"revoked": revoked,
 	})
 }
 
+// GetJWKS godoc
+// @Summary Get token verification keys
+// @Description Public keys access tokens are signed with, as a JSON Web Key Set (RFC 7517). A token's kid header names its key; during a key rotation the set holds both the new and the old keys.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} session.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) GetJWKS(c fiber.Ctx) error {
+	// Short enough that a key added for rotation is picked up before it signs
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(h.keys.JWKS())
+}
+
+// verifySIWELogin checks a SIWE message is bound to this site and chain,
+// consumes its nonce and verifies the signature. It returns the signer.
+func (h *AuthHandler) verifySIWELogin(ctx context.Context, req *LoginRequest) (string, *fiber.Error) {
+	// 1. Parse the message and check what it is bound to
+	message, err := crypto.ParseSIWEMessage(req.Message)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if req.Address != "" && !strings.EqualFold(req.Address, message.Address.Hex()) {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Address does not match the signed message")
+	}
+	if err := message.Validate(crypto.SIWEExpectations{
+		Domain:  h.cfg.SIWE.Domain,
+		URI:     h.cfg.SIWE.URI,
+		ChainID: h.cfg.Blockchain.ChainID,
+		MaxAge:  crypto.SignatureMaxAge,
+		Now:     time.Now(),
+	}); err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	// 2. Validate nonce (check if it exists and hasn't been used)
+	valid, err := h.nonceManager.ValidateNonce(ctx, message.Nonce)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to validate nonce")
+	}
+	if !valid {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired nonce")
+	}
+
+	// 3. Verify signature using EIP-191 (Ethereum Personal Sign)
+	address := message.Address.Hex()
+	if ferr := h.verifyLoginSignature(ctx, address, req.Message, req.Signature); ferr != nil {
+		return "", ferr
+	}
+
+	return address, nil
+}
+
+// verifyLegacyLogin checks the pre-SIWE login message. It returns the signer.
+func (h *AuthHandler) verifyLegacyLogin(ctx context.Context, req *LoginRequest) (string, *fiber.Error) {
+	if !h.cfg.SIWE.LegacyLogin {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Legacy login messages are disabled, sign a SIWE (EIP-4361) message")
+	}
+	if !common.IsHexAddress(req.Address) || req.Nonce == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Address, nonce and timestamp are required")
+	}
+
+	// 1. Validate nonce (check if it exists and hasn't been used)
+	valid, err := h.nonceManager.ValidateNonce(ctx, req.Nonce)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Failed to validate nonce")
+	}
+	if !valid {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired nonce")
+	}
+
+	// 2. Validate timestamp (not too old, not in the future)
+	if _, err := crypto.ValidateTimestamp(req.Timestamp); err != nil {
+		return "", fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	// 3. Reconstruct the message that should have been signed
+	expectedMessage := crypto.FormatLoginMessage(req.Nonce, req.Timestamp)
+	if strings.TrimSpace(req.Message) != strings.TrimSpace(expectedMessage) {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "Message mismatch")
+	}
+
+	// 4. Verify signature using EIP-191 (Ethereum Personal Sign)
+	if ferr := h.verifyLoginSignature(ctx, req.Address, req.Message, req.Signature); ferr != nil {
+		return "", ferr
+	}
+
+	return req.Address, nil
+}
+
+// verifyLoginSignature checks that address signed message: by ECDSA recovery
+// for wallets with a private key, or else by asking the contract at address
+// (EIP-1271), so smart-contract wallets such as Safe can log in as themselves
+func (h *AuthHandler) verifyLoginSignature(ctx context.Context, address, message, signature string) *fiber.Error {
+	valid, ecdsaErr := crypto.VerifySignature(address, message, signature)
+	if ecdsaErr == nil && valid {
+		return nil
+	}
+
+	sigBytes, err := hexutil.Decode(signature)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, fmt.Sprintf("Signature verification failed: %v", err))
+	}
+	hash := common.HexToHash(crypto.HashMessage(message))
+	valid, err = h.blockchain.IsValidSignature(ctx, common.HexToAddress(address), hash, sigBytes)
+	switch {
+	case errors.Is(err, crypto.ErrNotContract):
+		// Not a contract wallet: report why ECDSA verification failed
+		if ecdsaErr != nil {
+			return fiber.NewError(fiber.StatusUnauthorized, fmt.Sprintf("Signature verification failed: %v", ecdsaErr))
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid signature: address mismatch")
+	case err != nil:
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Failed to verify contract wallet signature")
+	case !valid:
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid signature: rejected by contract wallet")
+	}
+	return nil
+}
+
 // GetMe godoc
 // @Summary Get current user
 // @Description Get current authenticated user
@@ -182,8 +411,8 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 func (h *AuthHandler) GetMe(c fiber.Ctx) error {
 	address := c.Locals("address").(string)
 
-	var user models.User
-	if err := h.db.Where("address = ?", address).First(&user).Error; err != nil {
+	user, err := h.users.FindByAddress(c.Context(), address)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
@@ -191,3 +420,69 @@ func (h *AuthHandler) GetMe(c fiber.Ctx) error {
 
 	return c.JSON(user)
 }
+
+// UpdateMe godoc
+// @Summary Update current user
+// @Description Update the email address heartbeat reminders are sent to, and the nickname. Omitted fields are left unchanged.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body UpdateMeRequest true "Profile fields"
+// @Success 200 {object} models.User
+// @Router /auth/me [patch]
+// @Security BearerAuth
+func (h *AuthHandler) UpdateMe(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var req UpdateMeRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if email != "" {
+			parsed, err := mail.ParseAddress(email)
+			if err != nil || parsed.Address != email || len(email) > 255 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid email address",
+				})
+			}
+		}
+		req.Email = &email
+	}
+	if req.Nickname != nil {
+		nickname := strings.TrimSpace(*req.Nickname)
+		if len(nickname) > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Nickname must be at most 100 characters",
+			})
+		}
+		req.Nickname = &nickname
+	}
+
+	user, err := h.users.FindByAddress(c.Context(), address)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if req.Email != nil || req.Nickname != nil {
+		if req.Email != nil {
+			user.Email = *req.Email
+		}
+		if req.Nickname != nil {
+			user.Nickname = *req.Nickname
+		}
+		if err := h.users.UpdateProfile(c.Context(), user); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update user",
+			})
+		}
+	}
+
+	return c.JSON(user)
+}
@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMe(t *testing.T) {
+	ta := newTestApp(t)
+
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, "/api/v1/auth/me", ownerAddress, nil, nil))
+
+	created := ta.user(t, ownerAddress)
+	var user models.User
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, "/api/v1/auth/me", strings.ToLower(ownerAddress), nil, &user))
+	assert.Equal(t, created.ID, user.ID)
+	assert.Equal(t, ownerAddress, user.Address)
+
+	assert.Equal(t, http.StatusUnauthorized, ta.request(t, http.MethodGet, "/api/v1/auth/me", "", nil, nil))
+}
+
+func TestUpdateMe(t *testing.T) {
+	ta := newTestApp(t)
+	ta.user(t, ownerAddress)
+
+	var user models.User
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodPatch, "/api/v1/auth/me", ownerAddress,
+		map[string]string{"email": " owner@example.com ", "nickname": "owner"}, &user))
+	assert.Equal(t, "owner@example.com", user.Email)
+	assert.Equal(t, "owner", user.Nickname)
+
+	// Omitted fields are left unchanged, empty ones are cleared
+	var cleared models.User
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodPatch, "/api/v1/auth/me", ownerAddress,
+		map[string]string{"email": ""}, &cleared))
+	assert.Empty(t, cleared.Email)
+	assert.Equal(t, "owner", cleared.Nickname)
+
+	stored, err := ta.repos.Users.FindByAddress(context.Background(), ownerAddress)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Email)
+	assert.Equal(t, "owner", stored.Nickname)
+
+	var body errorBody
+	assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPatch, "/api/v1/auth/me", ownerAddress,
+		map[string]string{"email": "not an email"}, &body))
+	assert.Equal(t, "Invalid email address", body.Error)
+
+	assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPatch, "/api/v1/auth/me", ownerAddress,
+		map[string]string{"nickname": strings.Repeat("n", 101)}, nil))
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/authz"
+)
+
+// authorizeVault loads a vault for the caller and checks they relate to it in
+// one of the allowed ways; with none given, any caller may load it. Callers
+// who are neither owner nor heir get 404, so vault IDs cannot be probed.
+func authorizeVault(c fiber.Ctx, authorizer *authz.Authorizer, vaultID uuid.UUID, allowed []authz.Relation, preload ...string) (*authz.Access, *fiber.Error) {
+	address, _ := c.Locals("address").(string)
+
+	access, err := authorizer.Vault(c.Context(), address, vaultID, preload...)
+	if err != nil {
+		if errors.Is(err, authz.ErrVaultNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Vault not found or you don't have permission")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to query vault")
+	}
+
+	if len(allowed) == 0 {
+		return access, nil
+	}
+	for _, relation := range allowed {
+		if access.Relation == relation {
+			return access, nil
+		}
+	}
+
+	switch {
+	case access.Relation == authz.RelationNone:
+		return nil, fiber.NewError(fiber.StatusNotFound, "Vault not found or you don't have permission")
+	case len(allowed) == 1 && allowed[0] == authz.RelationOwner:
+		return nil, fiber.NewError(fiber.StatusForbidden, "You are not the owner of this vault")
+	default:
+		return nil, fiber.NewError(fiber.StatusForbidden, "You are not an heir of this vault")
+	}
+}
+
+var (
+	// ownerOnly, heirOnly and ownerOrHeir are the allowed relations of authorizeVault
+	ownerOnly   = []authz.Relation{authz.RelationOwner}
+	heirOnly    = []authz.Relation{authz.RelationHeir}
+	ownerOrHeir = []authz.Relation{authz.RelationOwner, authz.RelationHeir}
+)
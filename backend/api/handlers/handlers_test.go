@@ -0,0 +1,334 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/api/routes"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/keeper"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerAddress    = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	heirAddress     = "0x00000000000000000000000000000000000000A1"
+	coHeirAddress   = "0x00000000000000000000000000000000000000A2"
+	strangerAddress = "0x00000000000000000000000000000000000000B1"
+	relayerAddress  = "0x00000000000000000000000000000000000000F1"
+	factoryAddress  = "0x00000000000000000000000000000000000000FA"
+)
+
+// fakeChainID is the chain ID user transactions are signed for
+var fakeChainID = big.NewInt(31337)
+
+// fakeChain answers the reads the tested endpoints make. Calling any other
+// BlockchainService method panics on the nil embedded interface.
+type fakeChain struct {
+	service.BlockchainService
+
+	vaults    map[common.Address]*service.VaultConfig
+	vaultIDs  map[common.Address]int64 // From the VaultCreated events
+	approved  map[common.Address]bool
+	sent      map[common.Hash]*types.Transaction // Broadcast user transactions and server transactions
+	created   map[common.Hash]*service.CreatedVault
+	revealErr error
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		vaults:   make(map[common.Address]*service.VaultConfig),
+		vaultIDs: make(map[common.Address]int64),
+		approved: make(map[common.Address]bool),
+		sent:     make(map[common.Hash]*types.Transaction),
+		created:  make(map[common.Hash]*service.CreatedVault),
+	}
+}
+
+// serverTx sends a transaction to the address from the server account
+func (f *fakeChain) serverTx(to common.Address) string {
+	tx := types.NewTx(&types.LegacyTx{Nonce: uint64(len(f.sent)), To: &to, Gas: 100000, GasPrice: big.NewInt(1)})
+	f.sent[tx.Hash()] = tx
+	return tx.Hash().Hex()
+}
+
+func (f *fakeChain) vault(address common.Address) (*service.VaultConfig, error) {
+	vaultConfig, ok := f.vaults[address]
+	if !ok {
+		return nil, errors.New("execution reverted")
+	}
+	return vaultConfig, nil
+}
+
+func (f *fakeChain) IsFactoryVault(ctx context.Context, owner, vaultAddress common.Address) (bool, error) {
+	vaultConfig, ok := f.vaults[vaultAddress]
+	return ok && vaultConfig.Owner == owner, nil
+}
+
+func (f *fakeChain) FindVaultCreated(ctx context.Context, owner, vaultAddress common.Address, fromBlock uint64) (*service.CreatedVault, error) {
+	vaultConfig, ok := f.vaults[vaultAddress]
+	if !ok || vaultConfig.Owner != owner {
+		return nil, service.ErrVaultNotCreated
+	}
+	return &service.CreatedVault{Address: vaultAddress, Owner: owner, VaultID: f.vaultIDs[vaultAddress]}, nil
+}
+
+func (f *fakeChain) GetVaultConfig(ctx context.Context, vaultAddress common.Address) (*service.VaultConfig, error) {
+	return f.vault(vaultAddress)
+}
+
+func (f *fakeChain) GetHeirCount(ctx context.Context, vaultAddress common.Address) (*big.Int, error) {
+	vaultConfig, err := f.vault(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	return big.NewInt(int64(len(vaultConfig.Heirs))), nil
+}
+
+func (f *fakeChain) GetHeirShare(ctx context.Context, vaultAddress common.Address, index *big.Int) (*big.Int, error) {
+	vaultConfig, err := f.vault(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	return vaultConfig.HeirShares[index.Int64()], nil
+}
+
+func (f *fakeChain) IsPaused(ctx context.Context, vaultAddress common.Address) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeChain) GetLastHeartbeat(ctx context.Context, vaultAddr common.Address) (*big.Int, error) {
+	vaultConfig, err := f.vault(vaultAddr)
+	if err != nil {
+		return nil, err
+	}
+	return vaultConfig.LastHeartbeat, nil
+}
+
+func (f *fakeChain) RevealHeartbeat(ctx context.Context, vaultAddr common.Address, nonce [32]byte) (string, error) {
+	if f.revealErr != nil {
+		return "", f.revealErr
+	}
+	return f.serverTx(vaultAddr), nil
+}
+
+func (f *fakeChain) ApproveInheritance(ctx context.Context, vaultAddr common.Address) (string, error) {
+	return f.serverTx(vaultAddr), nil
+}
+
+// CreateVault deploys the vault at the next free address, as the factory
+// would, with the next vault ID
+func (f *fakeChain) CreateVault(ctx context.Context, owner common.Address, heirs []common.Address, shares []*big.Int, heartbeatInterval, gracePeriod, requiredApprovals *big.Int) (string, error) {
+	vaultID := int64(len(f.vaults) + 1)
+	contract := common.BigToAddress(big.NewInt(0xDE00 + vaultID))
+	f.vaults[contract] = &service.VaultConfig{
+		Owner:             owner,
+		Heirs:             heirs,
+		HeirShares:        shares,
+		HeartbeatInterval: heartbeatInterval,
+		GracePeriod:       gracePeriod,
+		RequiredApprovals: requiredApprovals,
+		LastHeartbeat:     big.NewInt(0),
+		ApprovalCount:     big.NewInt(0),
+	}
+	f.vaultIDs[contract] = vaultID
+
+	txHash := f.serverTx(common.HexToAddress(factoryAddress))
+	f.created[common.HexToHash(txHash)] = &service.CreatedVault{
+		Address:   contract,
+		Owner:     owner,
+		VaultID:   vaultID,
+		TxHash:    common.HexToHash(txHash),
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+	return txHash, nil
+}
+
+func (f *fakeChain) WaitForVaultCreated(ctx context.Context, txHash string) (*service.CreatedVault, error) {
+	created, ok := f.created[common.HexToHash(txHash)]
+	if !ok {
+		return nil, errors.New("transaction not found")
+	}
+	return created, nil
+}
+
+func (f *fakeChain) DecodeRawTransaction(rawTx string) (*types.Transaction, common.Address, error) {
+	raw, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, common.Address{}, err
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(fakeChainID), tx)
+	return tx, sender, err
+}
+
+func (f *fakeChain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	f.sent[tx.Hash()] = tx
+	return nil
+}
+
+func (f *fakeChain) GetTransactionByHash(ctx context.Context, txHash string) (*types.Transaction, bool, error) {
+	tx, ok := f.sent[common.HexToHash(txHash)]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	return tx, true, nil
+}
+
+func (f *fakeChain) RelayerAddress() common.Address {
+	return common.HexToAddress(relayerAddress)
+}
+
+func (f *fakeChain) GetHeirApprovalStatus(ctx context.Context, vaultAddr common.Address, heirAddr common.Address) (bool, error) {
+	return f.approved[heirAddr], nil
+}
+
+// testApp is the full API on in-memory repositories, Redis and chain
+type testApp struct {
+	app   *fiber.App
+	cfg   *config.Config
+	repos *repository.Repositories
+	chain *fakeChain
+	keys  *session.KeySet
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(signingKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	cfg := &config.Config{
+		Server:    config.ServerConfig{Env: "test"},
+		JWT:       config.JWTConfig{SigningKeyFile: keyFile},
+		RateLimit: config.RateLimitConfig{Max: 1000, Window: time.Minute},
+		Vaults:    config.VaultCreationConfig{Mode: config.VaultCreationModeVerify},
+	}
+	keys, err := session.LoadKeySet(cfg)
+	require.NoError(t, err)
+
+	ta := &testApp{
+		app:   fiber.New(),
+		cfg:   cfg,
+		repos: repository.NewMemory(),
+		chain: newFakeChain(),
+		keys:  keys,
+	}
+	routes.Setup(ta.app, nil, ta.repos, redisClient, cfg, ta.chain, keys, stream.NewHub(redisClient), keeper.New(nil, redisClient, ta.chain, cfg))
+	return ta
+}
+
+// request sends a request as address, or without a token if address is
+// empty, and decodes the JSON response into out unless it is nil
+func (ta *testApp) request(t *testing.T, method, path, address string, body, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(raw)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if address != "" {
+		token, _, err := session.SignAccessToken(ta.keys, time.Minute, address, nil, uuid.New(), time.Now())
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := ta.app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out), "%s %s", method, path)
+	}
+	return resp.StatusCode
+}
+
+// user creates the user with the wallet address, as logging in does
+func (ta *testApp) user(t *testing.T, address string) *models.User {
+	t.Helper()
+	user, err := ta.repos.Users.FindOrCreate(context.Background(), address)
+	require.NoError(t, err)
+	return user
+}
+
+// vault records a vault of owner with the given heirs, equal shares, and
+// deploys it on the fake chain
+func (ta *testApp) vault(t *testing.T, owner string, vaultID int64, heirs ...string) *models.Vault {
+	t.Helper()
+	contract := common.BigToAddress(big.NewInt(0xABC0 + vaultID))
+
+	vaultConfig := &service.VaultConfig{
+		Owner:         common.HexToAddress(owner),
+		LastHeartbeat: big.NewInt(0),
+		ApprovalCount: big.NewInt(0),
+	}
+	records := make([]models.Heir, len(heirs))
+	for i, heir := range heirs {
+		share := 10000 / len(heirs)
+		records[i] = models.Heir{Address: heir, ShareBPS: share}
+		vaultConfig.Heirs = append(vaultConfig.Heirs, common.HexToAddress(heir))
+		vaultConfig.HeirShares = append(vaultConfig.HeirShares, big.NewInt(int64(share)))
+	}
+	ta.chain.vaults[contract] = vaultConfig
+	ta.chain.vaultIDs[contract] = vaultID
+
+	vault, err := ta.repos.Vaults.CreateWithHeirs(context.Background(), &models.Vault{
+		VaultID:           vaultID,
+		ContractAddress:   contract.Hex(),
+		OwnerID:           ta.user(t, owner).ID,
+		HeartbeatInterval: 30 * 24 * 60 * 60,
+		GracePeriod:       90 * 24 * 60 * 60,
+		RequiredApprovals: 1,
+		Status:            models.VaultStatusLocked,
+	}, records, "")
+	require.NoError(t, err)
+	return vault
+}
+
+// errorBody is the JSON of a failed request
+type errorBody struct {
+	Error string `json:"error"`
+}
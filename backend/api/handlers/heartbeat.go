@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -10,20 +11,26 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/authz"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
 	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
 	"github.com/haneumLee/legacychain/backend/models"
-	"gorm.io/gorm"
 )
 
 type HeartbeatHandler struct {
-	db         *gorm.DB
-	blockchain service.BlockchainService
+	blockchain   service.BlockchainService
+	authz        *authz.Authorizer
+	heartbeats   repository.HeartbeatRepository
+	transactions repository.TransactionRepository
 }
 
-func NewHeartbeatHandler(db *gorm.DB, blockchain service.BlockchainService) *HeartbeatHandler {
+func NewHeartbeatHandler(blockchain service.BlockchainService, repos *repository.Repositories) *HeartbeatHandler {
 	return &HeartbeatHandler{
-		db:         db,
-		blockchain: blockchain,
+		blockchain:   blockchain,
+		authz:        authz.New(repos.Vaults, repos.Heirs),
+		heartbeats:   repos.Heartbeats,
+		transactions: repos.Transactions,
 	}
 }
 
@@ -83,33 +90,18 @@ func (h *HeartbeatHandler) CommitHeartbeat(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ? AND owner_address = ?", vaultID, address).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found or you don't have permission",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
+	// Find vault owned by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOnly)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	vault := access.Vault
 
 	// Generate commit hash: keccak256(abi.encodePacked(msg.sender, nonce))
-	// In Go, we need to match Solidity's behavior
-	nonceBytes, err := hex.DecodeString(req.Nonce)
-	if err != nil {
-		// If not hex, treat as string
-		nonceBytes = []byte(req.Nonce)
-	}
-	
-	senderAddr := common.HexToAddress(address)
-	
-	// Concatenate: address (20 bytes) + nonce
-	data := append(senderAddr.Bytes(), nonceBytes...)
-	commitHash := crypto.Keccak256Hash(data)
-	
+	commitHash := heartbeatCommitHash(common.HexToAddress(address), heartbeatNonce(req.Nonce))
+
 	// Convert to [32]byte for smart contract
 	var commitHashArray [32]byte
 	copy(commitHashArray[:], commitHash.Bytes())
@@ -132,12 +124,22 @@ func (h *HeartbeatHandler) CommitHeartbeat(c fiber.Ctx) error {
 		CommittedAt:  time.Now(),
 	}
 
-	if err := h.db.Create(&heartbeat).Error; err != nil {
+	if err := h.heartbeats.Create(c.Context(), &heartbeat); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save heartbeat record",
 		})
 	}
 
+	// Track the commit so a revert marks the heartbeat as failed
+	if err := trackTransaction(c.Context(), h.transactions, h.blockchain, txHash, "commitHeartbeat", tracker.Link{
+		VaultID:     &vault.ID,
+		HeartbeatID: &heartbeat.ID,
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save transaction record",
+		})
+	}
+
 	return c.JSON(CommitHeartbeatResponse{
 		TxHash:     txHash,
 		CommitHash: commitHash.Hex(),
@@ -156,8 +158,6 @@ func (h *HeartbeatHandler) CommitHeartbeat(c fiber.Ctx) error {
 // @Router /heartbeat/reveal [post]
 // @Security BearerAuth
 func (h *HeartbeatHandler) RevealHeartbeat(c fiber.Ctx) error {
-	address := c.Locals("address").(string)
-
 	var req RevealHeartbeatRequest
 	if err := c.Bind().Body(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -173,25 +173,19 @@ func (h *HeartbeatHandler) RevealHeartbeat(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ? AND owner_address = ?", vaultID, address).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found or you don't have permission",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
+	// Find vault owned by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOnly)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	vault := access.Vault
 
 	// Find the latest committed heartbeat
-	var heartbeat models.Heartbeat
-	if err := h.db.Where("vault_id = ? AND nonce = ? AND status = ?", vaultID, req.Nonce, models.HeartbeatStatusCommitted).
-		Order("committed_at DESC").
-		First(&heartbeat).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	heartbeat, err := h.heartbeats.FindCommitted(c.Context(), vaultID, req.Nonce)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "No matching committed heartbeat found with this nonce",
 			})
@@ -202,53 +196,38 @@ func (h *HeartbeatHandler) RevealHeartbeat(c fiber.Ctx) error {
 	}
 
 	// Convert nonce to [32]byte
-	var nonceArray [32]byte
-	nonceBytes, err := hex.DecodeString(req.Nonce)
-	if err != nil {
-		// If not hex, convert string to bytes
-		nonceBytes = []byte(req.Nonce)
-	}
-	
-	// Pad or truncate to 32 bytes
-	if len(nonceBytes) > 32 {
-		copy(nonceArray[:], nonceBytes[:32])
-	} else {
-		copy(nonceArray[:], nonceBytes)
-	}
+	nonceArray := heartbeatNonce(req.Nonce)
 
 	// Send reveal transaction
 	txHash, err := h.blockchain.RevealHeartbeat(c.Context(), common.HexToAddress(vault.ContractAddress), nonceArray)
 	if err != nil {
 		// Mark as failed
-		heartbeat.Status = models.HeartbeatStatusFailed
-		h.db.Save(&heartbeat)
+		h.heartbeats.UpdateStatus(c.Context(), heartbeat, models.HeartbeatStatusFailed)
 		
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to reveal heartbeat: %v", err),
 		})
 	}
 
-	// Update heartbeat record
-	now := time.Now()
-	heartbeat.RevealTxHash = txHash
-	heartbeat.Status = models.HeartbeatStatusRevealed
-	heartbeat.RevealedAt = &now
-
-	if err := h.db.Save(&heartbeat).Error; err != nil {
+	// The heartbeat is marked revealed by the tracker once the reveal is mined
+	if err := trackTransaction(c.Context(), h.transactions, h.blockchain, txHash, "revealHeartbeat", tracker.Link{
+		VaultID:     &vault.ID,
+		HeartbeatID: &heartbeat.ID,
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update heartbeat record",
+			"error": "Failed to save transaction record",
 		})
 	}
 
 	return c.JSON(RevealHeartbeatResponse{
 		TxHash:  txHash,
-		Message: "Heartbeat revealed successfully",
+		Message: "Heartbeat reveal submitted. Check GET /tx/{hash} for confirmation.",
 	})
 }
 
 // GetHeartbeatStatus godoc
 // @Summary Get heartbeat status
-// @Description Get the current heartbeat status for a vault
+// @Description Get the current heartbeat status for a vault. Visible to the owner and heirs; heirs do not see nonces.
 // @Tags heartbeat
 // @Produce json
 // @Param vault_id path string true "Vault ID (UUID)"
@@ -256,7 +235,6 @@ func (h *HeartbeatHandler) RevealHeartbeat(c fiber.Ctx) error {
 // @Router /heartbeat/status/{vault_id} [get]
 // @Security BearerAuth
 func (h *HeartbeatHandler) GetHeartbeatStatus(c fiber.Ctx) error {
-	address := c.Locals("address").(string)
 	vaultIDStr := c.Params("vault_id")
 
 	// Parse vault ID
@@ -267,28 +245,26 @@ func (h *HeartbeatHandler) GetHeartbeatStatus(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ? AND owner_address = ?", vaultID, address).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found or you don't have permission",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	vault := access.Vault
 
 	// Get latest heartbeat from database
-	var latestHeartbeat models.Heartbeat
-	err = h.db.Where("vault_id = ?", vaultID).
-		Order("committed_at DESC").
-		First(&latestHeartbeat).Error
-	
+	latestHeartbeat, err := h.heartbeats.Latest(c.Context(), vaultID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query heartbeat",
+		})
+	}
+
 	var latestHeartbeatPtr *models.Heartbeat
-	if err != gorm.ErrRecordNotFound {
-		latestHeartbeatPtr = &latestHeartbeat
+	if latestHeartbeat != nil {
+		latestHeartbeatPtr = &access.RedactHeartbeats([]models.Heartbeat{*latestHeartbeat})[0]
 	}
 
 	// Get on-chain last heartbeat timestamp
@@ -314,7 +290,7 @@ func (h *HeartbeatHandler) GetHeartbeatStatus(c fiber.Ctx) error {
 
 // ListHeartbeats godoc
 // @Summary List heartbeats for a vault
-// @Description Get all heartbeat records for a vault
+// @Description Get all heartbeat records for a vault. Visible to the owner and heirs; heirs do not see nonces.
 // @Tags heartbeat
 // @Produce json
 // @Param vault_id path string true "Vault ID (UUID)"
@@ -322,7 +298,6 @@ func (h *HeartbeatHandler) GetHeartbeatStatus(c fiber.Ctx) error {
 // @Router /heartbeat/list/{vault_id} [get]
 // @Security BearerAuth
 func (h *HeartbeatHandler) ListHeartbeats(c fiber.Ctx) error {
-	address := c.Locals("address").(string)
 	vaultIDStr := c.Params("vault_id")
 
 	// Parse vault ID
@@ -333,28 +308,42 @@ func (h *HeartbeatHandler) ListHeartbeats(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ? AND owner_address = ?", vaultID, address).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found or you don't have permission",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
 
 	// Get all heartbeats
-	var heartbeats []models.Heartbeat
-	if err := h.db.Where("vault_id = ?", vaultID).
-		Order("committed_at DESC").
-		Find(&heartbeats).Error; err != nil {
+	heartbeats, err := h.heartbeats.ListByVault(c.Context(), vaultID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to query heartbeats",
 		})
 	}
 
-	return c.JSON(heartbeats)
+	return c.JSON(access.RedactHeartbeats(heartbeats))
+}
+
+// heartbeatNonce converts a client nonce to the bytes32 passed to revealHeartbeat.
+// Hex nonces are decoded, anything else is used as raw bytes; the result is
+// right-padded or truncated to 32 bytes.
+func heartbeatNonce(nonce string) [32]byte {
+	nonceBytes, err := hex.DecodeString(nonce)
+	if err != nil {
+		// If not hex, convert string to bytes
+		nonceBytes = []byte(nonce)
+	}
+
+	var nonceArray [32]byte
+	copy(nonceArray[:], nonceBytes)
+	return nonceArray
+}
+
+// heartbeatCommitHash computes keccak256(abi.encodePacked(owner, nonce)), the
+// commitment revealHeartbeat checks against
+func heartbeatCommitHash(owner common.Address, nonce [32]byte) common.Hash {
+	return crypto.Keccak256Hash(owner.Bytes(), nonce[:])
 }
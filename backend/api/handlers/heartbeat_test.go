@@ -0,0 +1,124 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/haneumLee/legacychain/backend/api/handlers"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeatStatusAndList(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress)
+	ta.chain.vaults[common.HexToAddress(vault.ContractAddress)].LastHeartbeat = big.NewInt(1700000000)
+
+	statusPath := "/api/v1/heartbeat/status/" + vault.ID.String()
+	listPath := "/api/v1/heartbeat/list/" + vault.ID.String()
+
+	var status handlers.HeartbeatStatusResponse
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, statusPath, ownerAddress, nil, &status))
+	assert.Nil(t, status.LatestCommit)
+	assert.EqualValues(t, 1700000000, status.LastHeartbeat.Int64())
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, ta.repos.Heartbeats.Create(ctx, &models.Heartbeat{VaultID: vault.ID, Nonce: "older", CommittedAt: now.Add(-time.Hour)}))
+	require.NoError(t, ta.repos.Heartbeats.Create(ctx, &models.Heartbeat{VaultID: vault.ID, Nonce: "newer", CommittedAt: now}))
+
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, statusPath, ownerAddress, nil, &status))
+	require.NotNil(t, status.LatestCommit)
+	assert.Equal(t, "newer", status.LatestCommit.Nonce)
+
+	var heartbeats []models.Heartbeat
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, listPath, ownerAddress, nil, &heartbeats))
+	require.Len(t, heartbeats, 2)
+	assert.Equal(t, "newer", heartbeats[0].Nonce)
+	assert.Equal(t, "older", heartbeats[1].Nonce)
+
+	// Heirs see the heartbeats, but not the owner's nonces
+	status = handlers.HeartbeatStatusResponse{}
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, statusPath, heirAddress, nil, &status))
+	require.NotNil(t, status.LatestCommit)
+	assert.Empty(t, status.LatestCommit.Nonce)
+
+	var redacted []models.Heartbeat
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, listPath, heirAddress, nil, &redacted))
+	require.Len(t, redacted, 2)
+	assert.Empty(t, redacted[0].Nonce)
+	assert.Empty(t, redacted[1].Nonce)
+
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, statusPath, strangerAddress, nil, nil))
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, listPath, strangerAddress, nil, nil))
+}
+
+func TestCommitHeartbeatOwnerOnly(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress)
+	req := handlers.CommitHeartbeatRequest{VaultID: vault.ID.String(), Nonce: "nonce"}
+
+	var body errorBody
+	assert.Equal(t, http.StatusForbidden, ta.request(t, http.MethodPost, "/api/v1/heartbeat/commit", heirAddress, req, &body))
+	assert.Equal(t, "You are not the owner of this vault", body.Error)
+
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodPost, "/api/v1/heartbeat/commit", strangerAddress, req, nil))
+}
+
+func TestRevealHeartbeat(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress)
+
+	ctx := context.Background()
+	committed := &models.Heartbeat{VaultID: vault.ID, Nonce: "nonce", CommittedAt: time.Now()}
+	require.NoError(t, ta.repos.Heartbeats.Create(ctx, committed))
+
+	t.Run("unknown nonce", func(t *testing.T) {
+		req := handlers.RevealHeartbeatRequest{VaultID: vault.ID.String(), Nonce: "other"}
+		assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodPost, "/api/v1/heartbeat/reveal", ownerAddress, req, nil))
+	})
+
+	t.Run("heir", func(t *testing.T) {
+		req := handlers.RevealHeartbeatRequest{VaultID: vault.ID.String(), Nonce: "nonce"}
+		assert.Equal(t, http.StatusForbidden, ta.request(t, http.MethodPost, "/api/v1/heartbeat/reveal", heirAddress, req, nil))
+	})
+
+	t.Run("revealed", func(t *testing.T) {
+		req := handlers.RevealHeartbeatRequest{VaultID: vault.ID.String(), Nonce: "nonce"}
+		var resp handlers.RevealHeartbeatResponse
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodPost, "/api/v1/heartbeat/reveal", ownerAddress, req, &resp))
+
+		// Tracked from the server account, linked to the heartbeat it reveals
+		record, err := ta.repos.Transactions.FindByHash(ctx, resp.TxHash)
+		require.NoError(t, err)
+		assert.Equal(t, "revealHeartbeat", record.Method)
+		assert.Equal(t, common.HexToAddress(relayerAddress).Hex(), record.FromAddress)
+		assert.Equal(t, &committed.ID, record.HeartbeatID)
+
+		// It stays committed until the reveal is mined
+		latest, err := ta.repos.Heartbeats.Latest(ctx, vault.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.HeartbeatStatusCommitted, latest.Status)
+		assert.Equal(t, resp.TxHash, latest.RevealTxHash)
+	})
+
+	t.Run("reveal fails", func(t *testing.T) {
+		ta.chain.revealErr = errors.New("execution reverted")
+		req := handlers.RevealHeartbeatRequest{VaultID: vault.ID.String(), Nonce: "nonce"}
+		assert.Equal(t, http.StatusInternalServerError, ta.request(t, http.MethodPost, "/api/v1/heartbeat/reveal", ownerAddress, req, nil))
+
+		latest, err := ta.repos.Heartbeats.Latest(ctx, vault.ID)
+		require.NoError(t, err)
+		assert.Equal(t, committed.ID, latest.ID)
+		assert.Equal(t, models.HeartbeatStatusFailed, latest.Status)
+
+		// A failed commit cannot be revealed again
+		assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodPost, "/api/v1/heartbeat/reveal", ownerAddress, req, nil))
+	})
+}
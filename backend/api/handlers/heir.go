@@ -6,20 +6,25 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/authz"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
 	"github.com/haneumLee/legacychain/backend/internal/service"
-	"github.com/haneumLee/legacychain/backend/models"
-	"gorm.io/gorm"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
 )
 
 type HeirHandler struct {
-	db         *gorm.DB
-	blockchain service.BlockchainService
+	blockchain   service.BlockchainService
+	authz        *authz.Authorizer
+	heirs        repository.HeirRepository
+	transactions repository.TransactionRepository
 }
 
-func NewHeirHandler(db *gorm.DB, blockchain service.BlockchainService) *HeirHandler {
+func NewHeirHandler(blockchain service.BlockchainService, repos *repository.Repositories) *HeirHandler {
 	return &HeirHandler{
-		db:         db,
-		blockchain: blockchain,
+		blockchain:   blockchain,
+		authz:        authz.New(repos.Vaults, repos.Heirs),
+		heirs:        repos.Heirs,
+		transactions: repos.Transactions,
 	}
 }
 
@@ -60,8 +65,6 @@ type HeirApprovalStatus struct {
 // @Router /heir/approve [post]
 // @Security BearerAuth
 func (h *HeirHandler) ApproveHeir(c fiber.Ctx) error {
-	address := c.Locals("address").(string)
-
 	var req ApproveHeirRequest
 	if err := c.Bind().Body(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -77,31 +80,14 @@ func (h *HeirHandler) ApproveHeir(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ?", vaultID).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
-		})
-	}
-
-	// Check if the caller is an heir of this vault
-	var heir models.Heir
-	if err := h.db.Where("vault_id = ? AND address = ?", vaultID, address).First(&heir).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You are not an heir of this vault",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to verify heir status",
+	// Find vault and check that the caller is an heir of it
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, heirOnly)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	vault, heir := access.Vault, access.Heir
 
 	// Send approval transaction
 	txHash, err := h.blockchain.ApproveInheritance(
@@ -114,9 +100,19 @@ func (h *HeirHandler) ApproveHeir(c fiber.Ctx) error {
 		})
 	}
 
+	// The heir is marked approved by the tracker once the transaction is mined
+	if err := trackTransaction(c.Context(), h.transactions, h.blockchain, txHash, "approveInheritance", tracker.Link{
+		VaultID: &vault.ID,
+		HeirID:  &heir.ID,
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save transaction record",
+		})
+	}
+
 	return c.JSON(ApproveHeirResponse{
 		TxHash:  txHash,
-		Message: "Inheritance approved successfully",
+		Message: "Inheritance approval submitted. Check GET /tx/{hash} for confirmation.",
 	})
 }
 
@@ -148,31 +144,14 @@ func (h *HeirHandler) ClaimInheritance(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ?", vaultID).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
-		})
-	}
-
-	// Check if the caller is an heir
-	var heir models.Heir
-	if err := h.db.Where("vault_id = ? AND address = ?", vaultID, address).First(&heir).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You are not an heir of this vault",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to verify heir status",
+	// Find vault and check that the caller is an heir of it
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, heirOnly)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	vault, heir := access.Vault, access.Heir
 
 	// Check approval status from blockchain (using vault config approval count)
 	vaultConfig, err := h.blockchain.GetVaultConfig(c.Context(), common.HexToAddress(vault.ContractAddress))
@@ -183,8 +162,8 @@ func (h *HeirHandler) ClaimInheritance(c fiber.Ctx) error {
 	}
 
 	// Get total number of heirs
-	var heirCount int64
-	if err := h.db.Model(&models.Heir{}).Where("vault_id = ?", vaultID).Count(&heirCount).Error; err != nil {
+	heirCount, err := h.heirs.CountByVault(c.Context(), vaultID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to count heirs",
 		})
@@ -220,16 +199,19 @@ func (h *HeirHandler) ClaimInheritance(c fiber.Ctx) error {
 		})
 	}
 
-	// Update vault status
-	vault.Status = "claimed"
-	if err := h.db.Save(&vault).Error; err != nil {
-		// Log error but don't fail the response since blockchain transaction succeeded
-		fmt.Printf("Warning: Failed to update vault status: %v\n", err)
+	// The heir and vault are marked claimed by the tracker once the transaction is mined
+	if err := trackTransaction(c.Context(), h.transactions, h.blockchain, txHash, "claimInheritance", tracker.Link{
+		VaultID: &vault.ID,
+		HeirID:  &heir.ID,
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save transaction record",
+		})
 	}
 
 	return c.JSON(ClaimInheritanceResponse{
 		TxHash:  txHash,
-		Message: "Inheritance claimed successfully",
+		Message: "Inheritance claim submitted. Check GET /tx/{hash} for confirmation.",
 	})
 }
 
@@ -254,31 +236,14 @@ func (h *HeirHandler) GetApprovalStatus(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ?", vaultID).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
-		})
-	}
-
-	// Check if the caller is an heir
-	var heir models.Heir
-	if err := h.db.Where("vault_id = ? AND address = ?", vaultID, address).First(&heir).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "You are not an heir of this vault",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to verify heir status",
+	// Find vault and check that the caller is an heir of it
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, heirOnly)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	vault := access.Vault
 
 	// Get vault config from blockchain
 	vaultConfig, err := h.blockchain.GetVaultConfig(c.Context(), common.HexToAddress(vault.ContractAddress))
@@ -299,8 +264,8 @@ func (h *HeirHandler) GetApprovalStatus(c fiber.Ctx) error {
 	approvalCount := vaultConfig.ApprovalCount
 
 	// Get total number of heirs
-	var heirCount int64
-	if err := h.db.Model(&models.Heir{}).Where("vault_id = ?", vaultID).Count(&heirCount).Error; err != nil {
+	heirCount, err := h.heirs.CountByVault(c.Context(), vaultID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to count heirs",
 		})
@@ -321,7 +286,7 @@ func (h *HeirHandler) GetApprovalStatus(c fiber.Ctx) error {
 
 // ListHeirs godoc
 // @Summary List all heirs for a vault
-// @Description Get all heirs and their shares for a specific vault
+// @Description Get all heirs and their shares for a specific vault. Visible to the owner and heirs; heirs see only their own entry.
 // @Tags heir
 // @Produce json
 // @Param vault_id path string true "Vault ID (UUID)"
@@ -339,26 +304,21 @@ func (h *HeirHandler) ListHeirs(c fiber.Ctx) error {
 		})
 	}
 
-	// Find vault
-	var vault models.Vault
-	if err := h.db.Where("id = ?", vaultID).First(&vault).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Vault not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to query vault",
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
 
 	// Get all heirs
-	var heirs []models.Heir
-	if err := h.db.Where("vault_id = ?", vaultID).Find(&heirs).Error; err != nil {
+	heirs, err := h.heirs.ListByVault(c.Context(), vaultID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to query heirs",
 		})
 	}
 
-	return c.JSON(heirs)
+	return c.JSON(access.RedactHeirs(heirs))
 }
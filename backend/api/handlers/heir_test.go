@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/haneumLee/legacychain/backend/api/handlers"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListHeirs(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress, coHeirAddress)
+	path := "/api/v1/heir/list/" + vault.ID.String()
+
+	var heirs []models.Heir
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, ownerAddress, nil, &heirs))
+	assert.Len(t, heirs, 2)
+
+	// Heirs only see their own entry
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, strings.ToLower(heirAddress), nil, &heirs))
+	require.Len(t, heirs, 1)
+	assert.Equal(t, heirAddress, heirs[0].Address)
+	assert.Equal(t, 5000, heirs[0].ShareBPS)
+
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, path, strangerAddress, nil, nil))
+}
+
+func TestGetApprovalStatus(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress, coHeirAddress)
+	path := "/api/v1/heir/status/" + vault.ID.String()
+
+	ta.chain.vaults[common.HexToAddress(vault.ContractAddress)].ApprovalCount = big.NewInt(1)
+	ta.chain.approved[common.HexToAddress(heirAddress)] = true
+
+	var status handlers.HeirApprovalStatus
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, heirAddress, nil, &status))
+	assert.Equal(t, "1 (You have approved)", status.ApprovalCount)
+	assert.Equal(t, 2, status.RequiredCount, "a majority of the two heirs")
+	assert.False(t, status.CanClaim)
+
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, coHeirAddress, nil, &status))
+	assert.Equal(t, "1 (You have not approved)", status.ApprovalCount)
+
+	var body errorBody
+	assert.Equal(t, http.StatusForbidden, ta.request(t, http.MethodGet, path, ownerAddress, nil, &body))
+	assert.Equal(t, "You are not an heir of this vault", body.Error)
+}
+
+func TestApproveHeir(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress, coHeirAddress)
+	req := handlers.ApproveHeirRequest{VaultID: vault.ID.String()}
+
+	var body errorBody
+	assert.Equal(t, http.StatusForbidden, ta.request(t, http.MethodPost, "/api/v1/heir/approve", ownerAddress, req, &body))
+
+	var resp handlers.ApproveHeirResponse
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodPost, "/api/v1/heir/approve", coHeirAddress, req, &resp))
+
+	// Tracked from the server account, linked to the approving heir
+	heir, err := ta.repos.Heirs.FindByVaultAndAddress(context.Background(), vault.ID, coHeirAddress)
+	require.NoError(t, err)
+	record, err := ta.repos.Transactions.FindByHash(context.Background(), resp.TxHash)
+	require.NoError(t, err)
+	assert.Equal(t, "approveInheritance", record.Method)
+	assert.Equal(t, &vault.ID, record.VaultID)
+	assert.Equal(t, &heir.ID, record.HeirID)
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/authz"
+	"github.com/haneumLee/legacychain/backend/internal/relayer"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/pkg/crypto"
+)
+
+type MetaTxHandler struct {
+	relayer *relayer.Relayer
+	authz   *authz.Authorizer
+}
+
+func NewMetaTxHandler(relayer *relayer.Relayer, repos *repository.Repositories) *MetaTxHandler {
+	return &MetaTxHandler{
+		relayer: relayer,
+		authz:   authz.New(repos.Vaults, repos.Heirs),
+	}
+}
+
+// ForwardRequestBody is an ERC-2771 forward request in JSON form
+type ForwardRequestBody struct {
+	From     string `json:"from" validate:"required,eth_addr"`
+	To       string `json:"to" validate:"required,eth_addr"`
+	Value    string `json:"value"`
+	Gas      string `json:"gas" validate:"required"`
+	Nonce    string `json:"nonce" validate:"required"`
+	Deadline uint64 `json:"deadline" validate:"required"`
+	Data     string `json:"data" validate:"required"` // 0x-prefixed calldata
+}
+
+type GasBudgetResponse struct {
+	Limit    uint64    `json:"limit"` // 0 = unlimited
+	Used     uint64    `json:"used"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+type MetaTxNonceResponse struct {
+	Domain    apitypes.TypedDataDomain `json:"domain"`
+	Nonce     string                   `json:"nonce"`
+	GasBudget GasBudgetResponse        `json:"gas_budget"`
+}
+
+type BuildMetaTxRequest struct {
+	VaultID string `json:"vault_id" validate:"required,uuid"`
+	Action  string `json:"action" validate:"required"` // reveal_heartbeat, approve_inheritance, claim_inheritance, pause_vault or unpause_vault
+	Nonce   string `json:"nonce"`                      // Heartbeat nonce for reveal_heartbeat
+}
+
+type BuildMetaTxResponse struct {
+	TypedData apitypes.TypedData `json:"typed_data"` // Pass to eth_signTypedData_v4
+	Request   ForwardRequestBody `json:"request"`
+}
+
+type RelayMetaTxRequest struct {
+	Request   ForwardRequestBody `json:"request"`
+	Signature string             `json:"signature" validate:"required"` // eth_signTypedData_v4 signature
+	Reason    string             `json:"reason"`                        // Why the vault is paused or unpaused, kept in its audit log
+}
+
+type RelayMetaTxResponse struct {
+	ID      uuid.UUID `json:"id"`
+	TxHash  string    `json:"tx_hash"`
+	Message string    `json:"message"`
+}
+
+// GetNonce godoc
+// @Summary Get meta-transaction signing parameters
+// @Description Get the forwarder EIP-712 domain, the caller's forwarder nonce and remaining gas budget
+// @Tags metatx
+// @Produce json
+// @Success 200 {object} MetaTxNonceResponse
+// @Router /metatx/nonce [get]
+// @Security BearerAuth
+func (h *MetaTxHandler) GetNonce(c fiber.Ctx) error {
+	address := common.HexToAddress(c.Locals("address").(string))
+
+	domain, err := h.relayer.Domain(c.Context())
+	if err != nil {
+		return relayError(c, err)
+	}
+
+	nonce, err := h.relayer.Nonce(c.Context(), address)
+	if err != nil {
+		return relayError(c, err)
+	}
+
+	budget, err := h.relayer.Budget(c.Context(), address)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query gas budget",
+		})
+	}
+
+	return c.JSON(MetaTxNonceResponse{
+		Domain: crypto.ForwardRequestTypedData(*domain, crypto.ForwardRequest{}).Domain,
+		Nonce:  nonce.String(),
+		GasBudget: GasBudgetResponse{
+			Limit:    h.relayer.BudgetLimit(),
+			Used:     budget.GasUsed,
+			ResetsAt: budget.WindowStart.Add(h.relayer.BudgetWindow()),
+		},
+	})
+}
+
+// BuildMetaTx godoc
+// @Summary Build a meta-transaction for signing
+// @Description Build the EIP-712 forward request for a vault action, to be signed with eth_signTypedData_v4
+// @Tags metatx
+// @Accept json
+// @Produce json
+// @Param request body BuildMetaTxRequest true "Build request"
+// @Success 200 {object} BuildMetaTxResponse
+// @Router /metatx/build [post]
+// @Security BearerAuth
+func (h *MetaTxHandler) BuildMetaTx(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var req BuildMetaTxRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Action == ActionCommitHeartbeat {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "commit_heartbeat cannot be relayed",
+		})
+	}
+
+	// Parse vault ID
+	vaultID, err := uuid.Parse(req.VaultID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid vault ID format",
+		})
+	}
+
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	vault := access.Vault
+
+	// Check the caller's role and encode the call
+	call, fiberErr := resolveVaultAction(access, address, req.Action, req.Nonce)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	forwardReq, domain, err := h.relayer.BuildRequest(
+		c.Context(),
+		common.HexToAddress(address),
+		common.HexToAddress(vault.ContractAddress),
+		call.Method,
+		call.Args...,
+	)
+	if err != nil {
+		return relayError(c, err)
+	}
+
+	return c.JSON(BuildMetaTxResponse{
+		TypedData: crypto.ForwardRequestTypedData(*domain, *forwardReq),
+		Request: ForwardRequestBody{
+			From:     forwardReq.From.Hex(),
+			To:       forwardReq.To.Hex(),
+			Value:    forwardReq.Value.String(),
+			Gas:      forwardReq.Gas.String(),
+			Nonce:    forwardReq.Nonce.String(),
+			Deadline: forwardReq.Deadline,
+			Data:     hexutil.Encode(forwardReq.Data),
+		},
+	})
+}
+
+// RelayMetaTx godoc
+// @Summary Relay a signed meta-transaction
+// @Description Verify an EIP-712 signed forward request and submit it through the forwarder, paid by the relayer
+// @Tags metatx
+// @Accept json
+// @Produce json
+// @Param request body RelayMetaTxRequest true "Signed forward request"
+// @Success 200 {object} RelayMetaTxResponse
+// @Router /metatx/relay [post]
+// @Security BearerAuth
+func (h *MetaTxHandler) RelayMetaTx(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var req RelayMetaTxRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.Reason) > maxAuditReasonLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Reason must be at most %d characters", maxAuditReasonLength),
+		})
+	}
+
+	forwardReq, err := req.Request.toForwardRequest()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Requests are relayed for the authenticated user only
+	if forwardReq.From != common.HexToAddress(address) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Request signer does not match authenticated address",
+		})
+	}
+
+	metaTx, err := h.relayer.Relay(c.Context(), *forwardReq, req.Signature, req.Reason)
+	if err != nil {
+		return relayError(c, err)
+	}
+
+	return c.JSON(RelayMetaTxResponse{
+		ID:      metaTx.ID,
+		TxHash:  metaTx.TxHash,
+		Message: "Meta-transaction relayed successfully",
+	})
+}
+
+// toForwardRequest parses the JSON request into its typed form
+func (b ForwardRequestBody) toForwardRequest() (*crypto.ForwardRequest, error) {
+	if !common.IsHexAddress(b.From) || !common.IsHexAddress(b.To) {
+		return nil, fmt.Errorf("Invalid from or to address")
+	}
+
+	value := big.NewInt(0)
+	if b.Value != "" {
+		if _, ok := value.SetString(b.Value, 10); !ok {
+			return nil, fmt.Errorf("Invalid value")
+		}
+	}
+
+	gas, ok := new(big.Int).SetString(b.Gas, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid gas")
+	}
+
+	nonce, ok := new(big.Int).SetString(b.Nonce, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid nonce")
+	}
+
+	data, err := hexutil.Decode(b.Data)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid data: %v", err)
+	}
+
+	return &crypto.ForwardRequest{
+		From:     common.HexToAddress(b.From),
+		To:       common.HexToAddress(b.To),
+		Value:    value,
+		Gas:      gas,
+		Nonce:    nonce,
+		Deadline: b.Deadline,
+		Data:     data,
+	}, nil
+}
+
+// relayError maps relayer errors to HTTP responses
+func relayError(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, relayer.ErrDisabled):
+		status = fiber.StatusServiceUnavailable
+	case errors.Is(err, relayer.ErrInvalidSignature):
+		status = fiber.StatusUnauthorized
+	case errors.Is(err, relayer.ErrReplayed), errors.Is(err, relayer.ErrNonceMismatch):
+		status = fiber.StatusConflict
+	case errors.Is(err, relayer.ErrGasBudgetExceeded):
+		status = fiber.StatusTooManyRequests
+	case errors.Is(err, relayer.ErrUnknownVault),
+		errors.Is(err, relayer.ErrUnsupportedCall),
+		errors.Is(err, relayer.ErrExpired),
+		errors.Is(err, relayer.ErrDeadlineTooFar),
+		errors.Is(err, relayer.ErrWouldRevert):
+		status = fiber.StatusBadRequest
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/redis/go-redis/v9"
+)
+
+// streamRetry is the reconnect delay, in milliseconds, suggested to clients
+const streamRetry = 3000
+
+type StreamHandler struct {
+	redis  *redis.Client
+	hub    *stream.Hub
+	cfg    *config.Config
+	vaults repository.VaultRepository
+}
+
+func NewStreamHandler(repos *repository.Repositories, redisClient *redis.Client, hub *stream.Hub, cfg *config.Config) *StreamHandler {
+	return &StreamHandler{
+		redis:  redisClient,
+		hub:    hub,
+		cfg:    cfg,
+		vaults: repos.Vaults,
+	}
+}
+
+// StreamTicketResponse is a single-use credential for GET /stream
+type StreamTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"` // Seconds
+}
+
+// CreateStreamTicket godoc
+// @Summary Issue a stream ticket
+// @Description Issue a single-use ticket that authenticates one GET /stream request through the ticket query parameter, for clients such as EventSource that cannot send an Authorization header
+// @Tags stream
+// @Produce json
+// @Success 200 {object} StreamTicketResponse
+// @Router /stream/ticket [post]
+func (h *StreamHandler) CreateStreamTicket(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	ticket, err := stream.IssueTicket(c.Context(), h.redis, address)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to issue stream ticket",
+		})
+	}
+
+	return c.JSON(StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresIn: int(stream.TicketTTL.Seconds()),
+	})
+}
+
+// Stream godoc
+// @Summary Stream vault updates
+// @Description Server-Sent Events stream of updates to the caller's vaults: indexed contract events and tracked transaction status changes. Subscribes to every vault the caller owns or is an heir of, or to the comma-separated vault_id list. Sends a subscribed event first, then an update event per change. A resync event means updates were dropped and the client should refetch state before reconnecting.
+// @Tags stream
+// @Produce text/event-stream
+// @Param vault_id query string false "Comma-separated vault UUIDs"
+// @Param ticket query string false "Ticket from POST /stream/ticket, instead of the Authorization header"
+// @Success 200 {string} string "event stream"
+// @Router /stream [get]
+func (h *StreamHandler) Stream(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	vaultIDs, ferr := h.streamVaults(c.Context(), address, c.Query("vault_id"))
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable nginx response buffering
+
+	sub := h.hub.Subscribe(vaultIDs)
+	keepalive := h.cfg.Stream.KeepaliveInterval
+	maxDuration := h.cfg.Stream.MaxDuration
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+		if writeEvent(w, "subscribed", fiber.Map{"vault_ids": vaultIDs}) != nil {
+			return
+		}
+
+		ticker := time.NewTicker(keepalive)
+		defer ticker.Stop()
+		deadline := time.NewTimer(maxDuration)
+		defer deadline.Stop()
+
+		for {
+			select {
+			case update, ok := <-sub.Updates():
+				if !ok {
+					if sub.Lagged() {
+						writeEvent(w, "resync", fiber.Map{"reason": "stream fell behind"})
+					}
+					return
+				}
+				if writeEvent(w, "update", update) != nil {
+					return
+				}
+			case <-ticker.C:
+				// A failed flush means the client went away
+				fmt.Fprint(w, ": keepalive\n\n")
+				if w.Flush() != nil {
+					return
+				}
+			case <-deadline.C:
+				// Clients reconnect, which re-checks which vaults they can see
+				return
+			}
+		}
+	})
+}
+
+// streamVaults resolves the vaults a stream subscribes to: the requested ones,
+// which must all be visible to the address, or else every vault it owns or is
+// an heir of
+func (h *StreamHandler) streamVaults(ctx context.Context, address, requested string) ([]uuid.UUID, *fiber.Error) {
+	var requestedIDs []uuid.UUID
+	for _, raw := range strings.Split(requested, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid vault ID")
+		}
+		requestedIDs = append(requestedIDs, id)
+	}
+
+	vaultIDs, err := h.vaults.VisibleIDs(ctx, address, requestedIDs)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to query vaults")
+	}
+
+	if len(requestedIDs) > 0 {
+		visible := make(map[uuid.UUID]bool, len(vaultIDs))
+		for _, id := range vaultIDs {
+			visible[id] = true
+		}
+		for _, id := range requestedIDs {
+			if !visible[id] {
+				return nil, fiber.NewError(fiber.StatusNotFound, "Vault not found or you don't have permission")
+			}
+		}
+	}
+
+	return vaultIDs, nil
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload and flushes it
+func writeEvent(w *bufio.Writer, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return w.Flush()
+}
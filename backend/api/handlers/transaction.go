@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/authz"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+)
+
+// Vault actions that can be built for the user's wallet to sign
+const (
+	ActionCommitHeartbeat    = "commit_heartbeat"
+	ActionRevealHeartbeat    = "reveal_heartbeat"
+	ActionApproveInheritance = "approve_inheritance"
+	ActionClaimInheritance   = "claim_inheritance"
+	ActionPauseVault         = "pause_vault"
+	ActionUnpauseVault       = "unpause_vault"
+)
+
+// maxAuditReasonLength limits the reason given for pausing or unpausing a vault
+const maxAuditReasonLength = 500
+
+type TransactionHandler struct {
+	blockchain   service.BlockchainService
+	authz        *authz.Authorizer
+	vaults       repository.VaultRepository
+	transactions repository.TransactionRepository
+}
+
+func NewTransactionHandler(blockchain service.BlockchainService, repos *repository.Repositories) *TransactionHandler {
+	return &TransactionHandler{
+		blockchain:   blockchain,
+		authz:        authz.New(repos.Vaults, repos.Heirs),
+		vaults:       repos.Vaults,
+		transactions: repos.Transactions,
+	}
+}
+
+type BuildTransactionRequest struct {
+	VaultID string `json:"vault_id" validate:"required,uuid"`
+	Action  string `json:"action" validate:"required"`
+	Nonce   string `json:"nonce"` // Heartbeat nonce for commit_heartbeat and reveal_heartbeat
+}
+
+type BuildTransactionResponse struct {
+	Transaction *service.UnsignedTx `json:"transaction"`
+	CommitHash  string              `json:"commit_hash,omitempty"`
+}
+
+type SendTransactionRequest struct {
+	RawTx  string `json:"raw_tx" validate:"required"` // 0x-prefixed signed transaction
+	Reason string `json:"reason"`                     // Why the vault is paused or unpaused, kept in its audit log
+}
+
+type SendTransactionResponse struct {
+	TxHash  string `json:"tx_hash"`
+	Message string `json:"message"`
+}
+
+// BuildTransaction godoc
+// @Summary Build an unsigned vault transaction
+// @Description Build the transaction for a vault action so the caller's wallet can sign it
+// @Tags tx
+// @Accept json
+// @Produce json
+// @Param request body BuildTransactionRequest true "Build request"
+// @Success 200 {object} BuildTransactionResponse
+// @Router /tx/build [post]
+// @Security BearerAuth
+func (h *TransactionHandler) BuildTransaction(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var req BuildTransactionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Parse vault ID
+	vaultID, err := uuid.Parse(req.VaultID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid vault ID format",
+		})
+	}
+
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, vaultID, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	vault := access.Vault
+
+	// Check the caller's role and encode the call
+	call, fiberErr := resolveVaultAction(access, address, req.Action, req.Nonce)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	unsignedTx, err := h.blockchain.BuildVaultTransaction(
+		c.Context(),
+		common.HexToAddress(address),
+		common.HexToAddress(vault.ContractAddress),
+		call.Method,
+		call.Args...,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to build transaction: %v", err),
+		})
+	}
+
+	return c.JSON(BuildTransactionResponse{
+		Transaction: unsignedTx,
+		CommitHash:  call.CommitHash,
+	})
+}
+
+// SendTransaction godoc
+// @Summary Broadcast a user-signed vault transaction
+// @Description Verify that a signed transaction comes from the caller and targets a known vault, then broadcast and track it
+// @Tags tx
+// @Accept json
+// @Produce json
+// @Param request body SendTransactionRequest true "Signed transaction"
+// @Success 200 {object} SendTransactionResponse
+// @Router /tx/send [post]
+// @Security BearerAuth
+func (h *TransactionHandler) SendTransaction(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var req SendTransactionRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.Reason) > maxAuditReasonLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Reason must be at most %d characters", maxAuditReasonLength),
+		})
+	}
+
+	// Decode and recover the signer
+	tx, sender, err := h.blockchain.DecodeRawTransaction(req.RawTx)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if sender != common.HexToAddress(address) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Transaction sender does not match authenticated address",
+		})
+	}
+
+	// Only calls to vaults we know about are relayed
+	if tx.To() == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Contract creation is not allowed",
+		})
+	}
+
+	vault, err := h.vaults.FindByContractAddress(c.Context(), tx.To().Hex())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Transaction target is not a known vault",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query vault",
+		})
+	}
+
+	// Plain transfers are deposits to the vault's receive function
+	method := "deposit"
+	var args []interface{}
+	if len(tx.Data()) > 0 {
+		method, args, err = service.DecodeVaultCall(tx.Data())
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid vault call: %v", err),
+			})
+		}
+	}
+
+	// Broadcast
+	if err := h.blockchain.SendTransaction(c.Context(), tx); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to broadcast transaction: %v", err),
+		})
+	}
+
+	txHash := tx.Hash().Hex()
+
+	// Track the transaction until it is mined. Commits are only known to the
+	// backend through the relay, reveals are linked to the committed heartbeat
+	// they open.
+	call := &repository.VaultCall{
+		Tx:     tx,
+		From:   sender,
+		Sender: sender,
+		Vault:  vault,
+		Method: method,
+		Args:   args,
+		Reason: req.Reason,
+	}
+	if method == "commitHeartbeat" {
+		commitment := args[0].([32]byte)
+		call.Heartbeat = &models.Heartbeat{
+			VaultID:      vault.ID,
+			CommitHash:   common.Hash(commitment).Hex(),
+			CommitTxHash: txHash,
+			Status:       models.HeartbeatStatusCommitted,
+			CommittedAt:  time.Now(),
+		}
+	}
+
+	if _, err := h.transactions.RecordVaultCall(c.Context(), call); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save transaction record",
+		})
+	}
+
+	return c.JSON(SendTransactionResponse{
+		TxHash:  txHash,
+		Message: "Transaction broadcast successfully",
+	})
+}
+
+// GetTransaction godoc
+// @Summary Get transaction status
+// @Description Get the tracked status of a transaction (pending, mined, reverted, dropped or cancelled). Hashes replaced with higher fees resolve to the same transaction.
+// @Tags tx
+// @Produce json
+// @Param hash path string true "Transaction hash"
+// @Success 200 {object} models.Transaction
+// @Router /tx/{hash} [get]
+// @Security BearerAuth
+func (h *TransactionHandler) GetTransaction(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+	hash := c.Params("hash")
+
+	// Find transaction, also by a hash that was replaced with higher fees
+	record, err := h.transactions.FindByHash(c.Context(), hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Transaction not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query transaction",
+		})
+	}
+
+	// Visible to the sender and to the owner and heirs of the vault it touches
+	allowed, err := h.canViewTransaction(c.Context(), address, record)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify permission",
+		})
+	}
+	if !allowed {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Transaction not found",
+		})
+	}
+
+	return c.JSON(record)
+}
+
+// canViewTransaction reports whether address sent the transaction, or owns or
+// is an heir of the vault it touches
+func (h *TransactionHandler) canViewTransaction(ctx context.Context, address string, record *models.Transaction) (bool, error) {
+	if strings.EqualFold(record.FromAddress, address) {
+		return true, nil
+	}
+	if record.VaultID == nil {
+		return false, nil
+	}
+
+	access, err := h.authz.Vault(ctx, address, *record.VaultID)
+	if errors.Is(err, authz.ErrVaultNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return access.Relation != authz.RelationNone, nil
+}
+
+// vaultCall is an IndividualVault call resolved from an API action
+type vaultCall struct {
+	Method     string
+	Args       []interface{}
+	CommitHash string // Set for commit_heartbeat
+}
+
+// resolveVaultAction checks that the caller may perform the action on the vault
+// (owner for heartbeats and pausing, heir for inheritance) and encodes the contract call
+func resolveVaultAction(access *authz.Access, address, action, nonce string) (*vaultCall, *fiber.Error) {
+	switch action {
+	case ActionCommitHeartbeat, ActionRevealHeartbeat:
+		if !access.IsOwner() {
+			return nil, fiber.NewError(fiber.StatusForbidden, "You are not the owner of this vault")
+		}
+		if nonce == "" {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Nonce is required for heartbeat actions")
+		}
+
+		heartbeat := heartbeatNonce(nonce)
+		if action == ActionCommitHeartbeat {
+			hash := heartbeatCommitHash(common.HexToAddress(address), heartbeat)
+			return &vaultCall{Method: "commitHeartbeat", Args: []interface{}{[32]byte(hash)}, CommitHash: hash.Hex()}, nil
+		}
+		return &vaultCall{Method: "revealHeartbeat", Args: []interface{}{heartbeat}}, nil
+
+	case ActionApproveInheritance, ActionClaimInheritance:
+		if !access.IsHeir() {
+			return nil, fiber.NewError(fiber.StatusForbidden, "You are not an heir of this vault")
+		}
+
+		if action == ActionApproveInheritance {
+			return &vaultCall{Method: "approveInheritance"}, nil
+		}
+		return &vaultCall{Method: "claimInheritance"}, nil
+
+	case ActionPauseVault, ActionUnpauseVault:
+		if !access.IsOwner() {
+			return nil, fiber.NewError(fiber.StatusForbidden, "You are not the owner of this vault")
+		}
+
+		if action == ActionPauseVault {
+			return &vaultCall{Method: "pause"}, nil
+		}
+		return &vaultCall{Method: "unpause"}, nil
+
+	default:
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unknown action: %s", action))
+	}
+}
+
+// trackTransaction hands a transaction sent from the server account to the tracker
+func trackTransaction(ctx context.Context, transactions repository.TransactionRepository, blockchain service.BlockchainService, txHash, method string, link tracker.Link) error {
+	tx, _, err := blockchain.GetTransactionByHash(ctx, txHash)
+	if err != nil {
+		return err
+	}
+
+	_, err = transactions.Record(ctx, tx, blockchain.RelayerAddress(), method, link)
+	return err
+}
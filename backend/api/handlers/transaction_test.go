@@ -0,0 +1,138 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/api/handlers"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedTx signs a transaction to the address with key and returns it raw
+func signedTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, to common.Address, value *big.Int, data []byte) string {
+	t.Helper()
+	tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   fakeChainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       100000,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	}), types.LatestSignerForChainID(fakeChainID), key)
+	require.NoError(t, err)
+
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return hexutil.Encode(raw)
+}
+
+// vaultCalldata encodes a call to an IndividualVault method
+func vaultCalldata(t *testing.T, method string, args ...interface{}) []byte {
+	t.Helper()
+	vaultABI, err := bindings.IndividualVaultMetaData.GetAbi()
+	require.NoError(t, err)
+	data, err := vaultABI.Pack(method, args...)
+	require.NoError(t, err)
+	return data
+}
+
+func TestSendTransaction(t *testing.T) {
+	ta := newTestApp(t)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	vault := ta.vault(t, owner, 1, heirAddress)
+	contract := common.HexToAddress(vault.ContractAddress)
+
+	t.Run("signed by someone else", func(t *testing.T) {
+		ta.user(t, ownerAddress)
+		req := handlers.SendTransactionRequest{RawTx: signedTx(t, key, 0, contract, big.NewInt(1), nil)}
+
+		var body errorBody
+		assert.Equal(t, http.StatusForbidden, ta.request(t, http.MethodPost, "/api/v1/tx/send", ownerAddress, req, &body))
+		assert.Equal(t, "Transaction sender does not match authenticated address", body.Error)
+	})
+
+	t.Run("unknown target", func(t *testing.T) {
+		req := handlers.SendTransactionRequest{RawTx: signedTx(t, key, 0, common.HexToAddress(strangerAddress), big.NewInt(1), nil)}
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPost, "/api/v1/tx/send", owner, req, nil))
+	})
+
+	t.Run("deposit", func(t *testing.T) {
+		req := handlers.SendTransactionRequest{RawTx: signedTx(t, key, 0, contract, big.NewInt(1e18), nil)}
+
+		var resp handlers.SendTransactionResponse
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodPost, "/api/v1/tx/send", owner, req, &resp))
+		assert.Contains(t, ta.chain.sent, common.HexToHash(resp.TxHash))
+
+		record, err := ta.repos.Transactions.FindByHash(context.Background(), resp.TxHash)
+		require.NoError(t, err)
+		assert.Equal(t, "deposit", record.Method)
+		assert.Equal(t, owner, record.FromAddress)
+		assert.Equal(t, &vault.ID, record.VaultID)
+		assert.Equal(t, models.TransactionStatusPending, record.Status)
+	})
+
+	t.Run("pause with reason", func(t *testing.T) {
+		req := handlers.SendTransactionRequest{
+			RawTx:  signedTx(t, key, 1, contract, nil, vaultCalldata(t, "pause")),
+			Reason: "lost my phone",
+		}
+
+		var resp handlers.SendTransactionResponse
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodPost, "/api/v1/tx/send", owner, req, &resp))
+
+		var audit []models.VaultAuditLog
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, "/api/v1/vaults/"+vault.ID.String()+"/audit", owner, nil, &audit))
+		require.Len(t, audit, 1)
+		assert.Equal(t, models.VaultAuditPause, audit[0].Action)
+		assert.Equal(t, "lost my phone", audit[0].Reason)
+		assert.Equal(t, resp.TxHash, audit[0].TxHash)
+		assert.Nil(t, audit[0].ConfirmedAt)
+	})
+
+	t.Run("not a vault call", func(t *testing.T) {
+		req := handlers.SendTransactionRequest{RawTx: signedTx(t, key, 2, contract, nil, []byte{0xde, 0xad, 0xbe, 0xef})}
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPost, "/api/v1/tx/send", owner, req, nil))
+	})
+}
+
+func TestGetTransaction(t *testing.T) {
+	ta := newTestApp(t)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	ta.user(t, sender)
+	ta.user(t, strangerAddress)
+
+	// The heir deposits into the owner's vault
+	vault := ta.vault(t, ownerAddress, 1, sender, heirAddress)
+	req := handlers.SendTransactionRequest{RawTx: signedTx(t, key, 0, common.HexToAddress(vault.ContractAddress), big.NewInt(1), nil)}
+	var sent handlers.SendTransactionResponse
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodPost, "/api/v1/tx/send", sender, req, &sent))
+	path := "/api/v1/tx/" + sent.TxHash
+
+	for _, address := range []string{sender, ownerAddress, heirAddress} {
+		var record models.Transaction
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, address, nil, &record), address)
+		assert.Equal(t, sent.TxHash, record.Hash)
+	}
+
+	// Others cannot tell it exists
+	var body errorBody
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, path, strangerAddress, nil, &body))
+	assert.Equal(t, "Transaction not found", body.Error)
+	assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, "/api/v1/tx/"+common.Hash{1}.Hex(), sender, nil, nil))
+}
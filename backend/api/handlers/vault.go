@@ -1,23 +1,72 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/authz"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
 	"github.com/haneumLee/legacychain/backend/models"
-	"gorm.io/gorm"
 )
 
+// Limits enforced by VaultFactory.createVault
+const (
+	maxHeirs             = 10
+	totalShareBPS        = 10000
+	minHeartbeatInterval = 7 * 24 * 60 * 60
+	maxHeartbeatInterval = 90 * 24 * 60 * 60
+	minGracePeriod       = 30 * 24 * 60 * 60
+	maxGracePeriod       = 365 * 24 * 60 * 60
+)
+
+const (
+	// defaultLedgerLength and maxLedgerLength bound a page of the ledger
+	defaultLedgerLength = 50
+	maxLedgerLength     = 200
+)
+
+// errVaultMismatch is returned when a client-deployed vault differs from the request
+var errVaultMismatch = errors.New("vault does not match the on-chain contract")
+
 type VaultHandler struct {
-	db *gorm.DB
+	cfg          *config.Config
+	blockchain   service.BlockchainService
+	authz        *authz.Authorizer
+	users        repository.UserRepository
+	vaults       repository.VaultRepository
+	transactions repository.TransactionRepository
+	ledger       repository.LedgerRepository
+	audit        repository.AuditRepository
 }
 
-func NewVaultHandler(db *gorm.DB) *VaultHandler {
-	return &VaultHandler{db: db}
+func NewVaultHandler(cfg *config.Config, blockchain service.BlockchainService, repos *repository.Repositories) *VaultHandler {
+	return &VaultHandler{
+		cfg:          cfg,
+		blockchain:   blockchain,
+		authz:        authz.New(repos.Vaults, repos.Heirs),
+		users:        repos.Users,
+		vaults:       repos.Vaults,
+		transactions: repos.Transactions,
+		ledger:       repos.Ledger,
+		audit:        repos.Audit,
+	}
 }
 
+// CreateVaultRequest describes a vault. In deploy mode the server deploys it and
+// vault_id and contract_address must be omitted; in verify mode the client has
+// already deployed it and both are required.
 type CreateVaultRequest struct {
-	VaultID           int64    `json:"vault_id" validate:"required"`
-	ContractAddress   string   `json:"contract_address" validate:"required,eth_addr"`
+	VaultID           int64    `json:"vault_id,omitempty"`
+	ContractAddress   string   `json:"contract_address,omitempty" validate:"omitempty,eth_addr"`
 	HeartbeatInterval int64    `json:"heartbeat_interval" validate:"required,min=1"`
 	GracePeriod       int64    `json:"grace_period" validate:"required,min=1"`
 	RequiredApprovals int      `json:"required_approvals" validate:"required,min=1"`
@@ -25,14 +74,22 @@ type CreateVaultRequest struct {
 	HeirShares        []int    `json:"heir_shares" validate:"required,min=1"`
 }
 
+// PendingVaultResponse is returned when a deployment is not mined within VAULT_DEPLOY_TIMEOUT
+type PendingVaultResponse struct {
+	TxHash  string `json:"tx_hash"`
+	Message string `json:"message"`
+}
+
 // CreateVault godoc
 // @Summary Create new vault
-// @Description Create a new vault after on-chain deployment
+// @Description Deploy a vault through the factory (deploy mode) or record a
+// @Description client-deployed vault after checking it on-chain (verify mode)
 // @Tags vaults
 // @Accept json
 // @Produce json
 // @Param request body CreateVaultRequest true "Vault data"
 // @Success 201 {object} models.Vault
+// @Success 202 {object} PendingVaultResponse
 // @Router /vaults [post]
 // @Security BearerAuth
 func (h *VaultHandler) CreateVault(c fiber.Ctx) error {
@@ -45,68 +102,269 @@ func (h *VaultHandler) CreateVault(c fiber.Ctx) error {
 		})
 	}
 
-	// Validate heir arrays length
-	if len(req.HeirAddresses) != len(req.HeirShares) {
+	// Validate vault parameters
+	if err := validateVaultParams(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Heir addresses and shares must have same length",
+			"error": err.Error(),
 		})
 	}
 
 	// Find user
-	var user models.User
-	if err := h.db.Where("address = ?", address).First(&user).Error; err != nil {
+	user, err := h.users.FindByAddress(c.Context(), address)
+	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "User not found",
 		})
 	}
 
-	// Create vault
+	if h.cfg.Vaults.Mode == config.VaultCreationModeDeploy {
+		return h.deployVault(c, user, common.HexToAddress(address), &req)
+	}
+	return h.verifyVault(c, user, common.HexToAddress(address), &req)
+}
+
+// deployVault creates the vault through the factory on the owner's behalf and
+// records it from the VaultCreated event
+func (h *VaultHandler) deployVault(c fiber.Ctx, user *models.User, owner common.Address, req *CreateVaultRequest) error {
+	if req.ContractAddress != "" || req.VaultID != 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "vault_id and contract_address are assigned by the factory",
+		})
+	}
+
+	heirs := make([]common.Address, len(req.HeirAddresses))
+	shares := make([]*big.Int, len(req.HeirShares))
+	for i := range req.HeirAddresses {
+		heirs[i] = common.HexToAddress(req.HeirAddresses[i])
+		shares[i] = big.NewInt(int64(req.HeirShares[i]))
+	}
+
+	// Send the deployment
+	txHash, err := h.blockchain.CreateVault(
+		c.Context(),
+		owner,
+		heirs,
+		shares,
+		big.NewInt(req.HeartbeatInterval),
+		big.NewInt(req.GracePeriod),
+		big.NewInt(int64(req.RequiredApprovals)),
+	)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to deploy vault",
+		})
+	}
+
+	// Track the deployment so a stuck transaction is re-priced
+	if err := trackTransaction(c.Context(), h.transactions, h.blockchain, txHash, "createVaultFor", tracker.Link{}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save transaction record",
+		})
+	}
+
+	// Wait for the VaultCreated event
+	ctx, cancel := context.WithTimeout(c.Context(), h.cfg.Vaults.DeployTimeout)
+	defer cancel()
+
+	created, err := h.blockchain.WaitForVaultCreated(ctx, txHash)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		// The indexer records the vault once the deployment is mined
+		return c.Status(fiber.StatusAccepted).JSON(PendingVaultResponse{
+			TxHash:  txHash,
+			Message: "Vault deployment is pending. It will be listed once mined.",
+		})
+	case errors.Is(err, service.ErrDeploymentReverted):
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to confirm vault deployment",
+		})
+	}
+
+	// Save the vault and link the deployment to it
+	vault, err := h.saveVault(c.Context(), user, created.Address, created.VaultID, req, created.CreatedAt, txHash)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create vault",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(vault)
+}
+
+// verifyVault records a client-deployed vault once it matches the chain
+func (h *VaultHandler) verifyVault(c fiber.Ctx, user *models.User, owner common.Address, req *CreateVaultRequest) error {
+	if !common.IsHexAddress(req.ContractAddress) || req.VaultID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "vault_id and contract_address of the deployed vault are required",
+		})
+	}
+	vaultAddr := common.HexToAddress(req.ContractAddress)
+
+	// Cross-check the request against the chain
+	lastHeartbeat, err := h.verifyOnChain(c.Context(), owner, vaultAddr, req)
+	if errors.Is(err, errVaultMismatch) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read vault from blockchain",
+		})
+	}
+
+	vault, err := h.saveVault(c.Context(), user, vaultAddr, req.VaultID, req, lastHeartbeat, "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create vault",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(vault)
+}
+
+// verifyOnChain checks that vaultAddr was created by the factory for owner
+// under the requested vault ID and holds exactly the requested heirs, shares
+// and timing. It returns the vault's last heartbeat.
+func (h *VaultHandler) verifyOnChain(ctx context.Context, owner, vaultAddr common.Address, req *CreateVaultRequest) (time.Time, error) {
+	isFactoryVault, err := h.blockchain.IsFactoryVault(ctx, owner, vaultAddr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !isFactoryVault {
+		return time.Time{}, fmt.Errorf("%w: not a factory vault owned by the caller", errVaultMismatch)
+	}
+
+	// The vault ID is the factory-wide sequence number the indexer assigns
+	created, err := h.blockchain.FindVaultCreated(ctx, owner, vaultAddr, h.cfg.Indexer.StartBlock)
+	if errors.Is(err, service.ErrVaultNotCreated) {
+		return time.Time{}, fmt.Errorf("%w: not a factory vault owned by the caller", errVaultMismatch)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if created.VaultID != req.VaultID {
+		return time.Time{}, fmt.Errorf("%w: vault_id", errVaultMismatch)
+	}
+
+	vaultConfig, err := h.blockchain.GetVaultConfig(ctx, vaultAddr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case vaultConfig.Owner != owner:
+		return time.Time{}, fmt.Errorf("%w: owner", errVaultMismatch)
+	case vaultConfig.HeartbeatInterval.Cmp(big.NewInt(req.HeartbeatInterval)) != 0:
+		return time.Time{}, fmt.Errorf("%w: heartbeat_interval", errVaultMismatch)
+	case vaultConfig.GracePeriod.Cmp(big.NewInt(req.GracePeriod)) != 0:
+		return time.Time{}, fmt.Errorf("%w: grace_period", errVaultMismatch)
+	case vaultConfig.RequiredApprovals.Cmp(big.NewInt(int64(req.RequiredApprovals))) != 0:
+		return time.Time{}, fmt.Errorf("%w: required_approvals", errVaultMismatch)
+	}
+
+	heirCount, err := h.blockchain.GetHeirCount(ctx, vaultAddr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if heirCount.Cmp(big.NewInt(int64(len(req.HeirAddresses)))) != 0 || len(vaultConfig.Heirs) != len(req.HeirAddresses) {
+		return time.Time{}, fmt.Errorf("%w: heir count", errVaultMismatch)
+	}
+
+	for i, heirAddr := range req.HeirAddresses {
+		if vaultConfig.Heirs[i] != common.HexToAddress(heirAddr) {
+			return time.Time{}, fmt.Errorf("%w: heir %d", errVaultMismatch, i)
+		}
+
+		share, err := h.blockchain.GetHeirShare(ctx, vaultAddr, big.NewInt(int64(i)))
+		if err != nil {
+			return time.Time{}, err
+		}
+		if share.Cmp(big.NewInt(int64(req.HeirShares[i]))) != 0 {
+			return time.Time{}, fmt.Errorf("%w: share of heir %d", errVaultMismatch, i)
+		}
+	}
+
+	return time.Unix(vaultConfig.LastHeartbeat.Int64(), 0), nil
+}
+
+// saveVault stores a vault confirmed on-chain together with its heirs and links
+// its deployment transaction, if any. If the indexer already recorded the
+// contract, that row is returned instead.
+func (h *VaultHandler) saveVault(ctx context.Context, user *models.User, contractAddress common.Address, vaultID int64, req *CreateVaultRequest, lastHeartbeat time.Time, deployTxHash string) (*models.Vault, error) {
 	vault := models.Vault{
-		VaultID:           req.VaultID,
-		ContractAddress:   req.ContractAddress,
+		VaultID:           vaultID,
+		ContractAddress:   contractAddress.Hex(),
 		OwnerID:           user.ID,
 		HeartbeatInterval: req.HeartbeatInterval,
 		GracePeriod:       req.GracePeriod,
 		RequiredApprovals: req.RequiredApprovals,
 		Status:            models.VaultStatusLocked,
+		LastHeartbeat:     &lastHeartbeat,
 	}
 
-	// Start transaction
-	tx := h.db.Begin()
+	heirs := make([]models.Heir, len(req.HeirAddresses))
+	for i, heirAddr := range req.HeirAddresses {
+		heirs[i] = models.Heir{
+			Address:  common.HexToAddress(heirAddr).Hex(),
+			ShareBPS: req.HeirShares[i],
+		}
+	}
 
-	if err := tx.Create(&vault).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create vault",
-		})
+	return h.vaults.CreateWithHeirs(ctx, &vault, heirs, deployTxHash)
+}
+
+// validateVaultParams applies the factory's limits so invalid vaults are
+// rejected before any gas is spent
+func validateVaultParams(req *CreateVaultRequest) error {
+	if len(req.HeirAddresses) == 0 || len(req.HeirAddresses) > maxHeirs {
+		return fmt.Errorf("a vault needs between 1 and %d heirs", maxHeirs)
+	}
+	if len(req.HeirAddresses) != len(req.HeirShares) {
+		return errors.New("Heir addresses and shares must have same length")
 	}
 
-	// Create heirs
+	seen := make(map[common.Address]bool, len(req.HeirAddresses))
+	totalShare := 0
 	for i, heirAddr := range req.HeirAddresses {
-		heir := models.Heir{
-			VaultID:  vault.ID,
-			Address:  heirAddr,
-			ShareBPS: req.HeirShares[i],
+		if !common.IsHexAddress(heirAddr) {
+			return fmt.Errorf("invalid heir address %q", heirAddr)
 		}
-		if err := tx.Create(&heir).Error; err != nil {
-			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create heirs",
-			})
+		heir := common.HexToAddress(heirAddr)
+		if heir == (common.Address{}) || seen[heir] {
+			return fmt.Errorf("heir address %s is zero or duplicated", heir.Hex())
 		}
-	}
+		seen[heir] = true
 
-	tx.Commit()
+		if req.HeirShares[i] <= 0 {
+			return errors.New("heir shares must be positive")
+		}
+		totalShare += req.HeirShares[i]
+	}
+	if totalShare != totalShareBPS {
+		return fmt.Errorf("heir shares must sum to %d basis points", totalShareBPS)
+	}
 
-	// Load relationships
-	h.db.Preload("Owner").Preload("Heirs").First(&vault, vault.ID)
+	if req.HeartbeatInterval < minHeartbeatInterval || req.HeartbeatInterval > maxHeartbeatInterval {
+		return fmt.Errorf("heartbeat_interval must be between %d and %d seconds", minHeartbeatInterval, maxHeartbeatInterval)
+	}
+	if req.GracePeriod < minGracePeriod || req.GracePeriod > maxGracePeriod {
+		return fmt.Errorf("grace_period must be between %d and %d seconds", minGracePeriod, maxGracePeriod)
+	}
+	if req.RequiredApprovals < 1 || req.RequiredApprovals > len(req.HeirAddresses) {
+		return errors.New("required_approvals must be between 1 and the number of heirs")
+	}
 
-	return c.Status(fiber.StatusCreated).JSON(vault)
+	return nil
 }
 
 // GetVault godoc
 // @Summary Get vault by ID
-// @Description Get vault details with heirs and heartbeats
+// @Description Get vault details with heirs and heartbeats. Visible to the owner and heirs; heirs see only their own heir entry, and no owner email or heartbeat nonces.
 // @Tags vaults
 // @Produce json
 // @Param id path string true "Vault UUID"
@@ -123,13 +381,20 @@ func (h *VaultHandler) GetVault(c fiber.Ctx) error {
 		})
 	}
 
-	var vault models.Vault
-	if err := h.db.Preload("Owner").Preload("Heirs").Preload("Heartbeats").
-		First(&vault, uid).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Vault not found",
+	access, fiberErr := authorizeVault(c, h.authz, uid, ownerOrHeir, "Heirs", "Heartbeats")
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
 		})
 	}
+	access.RedactVault()
+	vault := access.Vault
+
+	// The indexer may lag behind an emergency pause, so read it from the
+	// contract and fall back to the indexed flag if the node is unavailable
+	if paused, err := h.blockchain.IsPaused(c.Context(), common.HexToAddress(vault.ContractAddress)); err == nil {
+		vault.Paused = paused
+	}
 
 	return c.JSON(vault)
 }
@@ -145,21 +410,282 @@ func (h *VaultHandler) GetVault(c fiber.Ctx) error {
 func (h *VaultHandler) ListVaults(c fiber.Ctx) error {
 	address := c.Locals("address").(string)
 
-	var user models.User
-	if err := h.db.Where("address = ?", address).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "User not found",
+	vaults, err := h.vaults.ListByOwnerAddress(c.Context(), address, "Heirs")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch vaults",
+		})
+	}
+
+	return c.JSON(vaults)
+}
+
+// FundVaultRequest is an amount in wei, as a decimal string
+type FundVaultRequest struct {
+	Amount string `json:"amount" validate:"required"`
+}
+
+// VaultLedgerResponse is a vault's indexed balance with the movements behind it
+type VaultLedgerResponse struct {
+	Balance string                    `json:"balance"`
+	Entries []models.VaultLedgerEntry `json:"entries"`
+}
+
+// DepositVault godoc
+// @Summary Build a deposit transaction
+// @Description Build a transfer of amount wei to the vault for the caller's wallet to sign and send through /tx/send
+// @Tags vaults
+// @Accept json
+// @Produce json
+// @Param id path string true "Vault UUID"
+// @Param request body FundVaultRequest true "Amount in wei"
+// @Success 200 {object} BuildTransactionResponse
+// @Router /vaults/{id}/deposit [post]
+// @Security BearerAuth
+func (h *VaultHandler) DepositVault(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	// Anyone may fund a vault
+	vault, amount, fiberErr := h.parseFundRequest(c, nil)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	unsignedTx, err := h.blockchain.BuildDepositTransaction(
+		c.Context(),
+		common.HexToAddress(address),
+		common.HexToAddress(vault.ContractAddress),
+		amount,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to build transaction: %v", err),
+		})
+	}
+
+	return c.JSON(BuildTransactionResponse{Transaction: unsignedTx})
+}
+
+// WithdrawVault godoc
+// @Summary Build a withdraw transaction
+// @Description Build the owner's withdraw call for amount wei, to be signed and sent through /tx/send. Only possible while the vault is locked.
+// @Tags vaults
+// @Accept json
+// @Produce json
+// @Param id path string true "Vault UUID"
+// @Param request body FundVaultRequest true "Amount in wei"
+// @Success 200 {object} BuildTransactionResponse
+// @Router /vaults/{id}/withdraw [post]
+// @Security BearerAuth
+func (h *VaultHandler) WithdrawVault(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	// Only the owner can withdraw
+	vault, amount, fiberErr := h.parseFundRequest(c, ownerOnly)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	// Gas estimation reverts with the contract's reason if the amount exceeds
+	// the balance or the vault is unlocked or paused
+	unsignedTx, err := h.blockchain.BuildVaultTransaction(
+		c.Context(),
+		common.HexToAddress(address),
+		common.HexToAddress(vault.ContractAddress),
+		"withdraw",
+		amount,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to build transaction: %v", err),
 		})
 	}
 
-	var vaults []models.Vault
-	if err := h.db.Where("owner_id = ?", user.ID).
-		Preload("Heirs").
-		Find(&vaults).Error; err != nil {
+	return c.JSON(BuildTransactionResponse{Transaction: unsignedTx})
+}
+
+// PauseVault godoc
+// @Summary Build a pause transaction
+// @Description Build the owner's emergency pause call, which freezes heartbeats, approvals, claims and withdrawals. Sign it and send it through /tx/send, or as a meta-transaction through /metatx, with a reason for the audit log.
+// @Tags vaults
+// @Produce json
+// @Param id path string true "Vault UUID"
+// @Success 200 {object} BuildTransactionResponse
+// @Router /vaults/{id}/pause [post]
+// @Security BearerAuth
+func (h *VaultHandler) PauseVault(c fiber.Ctx) error {
+	return h.buildOwnerAction(c, ActionPauseVault)
+}
+
+// UnpauseVault godoc
+// @Summary Build an unpause transaction
+// @Description Build the owner's unpause call, to be signed and sent through /tx/send or /metatx with a reason for the audit log
+// @Tags vaults
+// @Produce json
+// @Param id path string true "Vault UUID"
+// @Success 200 {object} BuildTransactionResponse
+// @Router /vaults/{id}/unpause [post]
+// @Security BearerAuth
+func (h *VaultHandler) UnpauseVault(c fiber.Ctx) error {
+	return h.buildOwnerAction(c, ActionUnpauseVault)
+}
+
+// buildOwnerAction builds an owner-only vault action without arguments
+func (h *VaultHandler) buildOwnerAction(c fiber.Ctx, action string) error {
+	address := c.Locals("address").(string)
+
+	// Parse vault ID
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid vault ID",
+		})
+	}
+
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, uid, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	vault := access.Vault
+
+	call, fiberErr := resolveVaultAction(access, address, action, "")
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	// Gas estimation reverts if the vault is already in the requested state
+	unsignedTx, err := h.blockchain.BuildVaultTransaction(
+		c.Context(),
+		common.HexToAddress(address),
+		common.HexToAddress(vault.ContractAddress),
+		call.Method,
+	)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to build transaction: %v", err),
+		})
+	}
+
+	return c.JSON(BuildTransactionResponse{Transaction: unsignedTx})
+}
+
+// GetVaultLedger godoc
+// @Summary Get vault balance and ledger
+// @Description Get the indexed balance and a page of the deposits, withdrawals and claims of a vault, newest first. Visible to the owner and heirs.
+// @Tags vaults
+// @Produce json
+// @Param id path string true "Vault UUID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param offset query int false "Entries to skip"
+// @Success 200 {object} VaultLedgerResponse
+// @Router /vaults/{id}/ledger [get]
+// @Security BearerAuth
+func (h *VaultHandler) GetVaultLedger(c fiber.Ctx) error {
+	// Parse vault ID
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid vault ID",
+		})
+	}
+
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, uid, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	vault := access.Vault
+
+	limit, offset, fiberErr := parsePage(c, defaultLedgerLength, maxLedgerLength)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	entries, err := h.ledger.ListByVault(c.Context(), vault.ID, limit, offset)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch vaults",
+			"error": "Failed to query ledger",
 		})
 	}
 
-	return c.JSON(vaults)
+	return c.JSON(VaultLedgerResponse{
+		Balance: vault.Balance,
+		Entries: entries,
+	})
+}
+
+// GetVaultAudit godoc
+// @Summary Get a vault's pause history
+// @Description Get who paused or unpaused the vault, when and why, newest first. Entries without confirmed_at are not mined yet. Visible to the owner and heirs.
+// @Tags vaults
+// @Produce json
+// @Param id path string true "Vault ID"
+// @Success 200 {array} models.VaultAuditLog
+// @Router /vaults/{id}/audit [get]
+// @Security BearerAuth
+func (h *VaultHandler) GetVaultAudit(c fiber.Ctx) error {
+	// Parse vault ID
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid vault ID",
+		})
+	}
+
+	// Find vault owned or inherited by the caller
+	access, fiberErr := authorizeVault(c, h.authz, uid, ownerOrHeir)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	vault := access.Vault
+
+	entries, err := h.audit.ListByVault(c.Context(), vault.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query audit log",
+		})
+	}
+
+	return c.JSON(entries)
+}
+
+// parseFundRequest loads the vault in the path, if the caller relates to it in
+// one of the allowed ways, and parses a positive wei amount
+func (h *VaultHandler) parseFundRequest(c fiber.Ctx, allowed []authz.Relation) (*models.Vault, *big.Int, *fiber.Error) {
+	uid, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid vault ID")
+	}
+
+	var req FundVaultRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Amount must be a positive integer in wei")
+	}
+
+	access, fiberErr := authorizeVault(c, h.authz, uid, allowed)
+	if fiberErr != nil {
+		return nil, nil, fiberErr
+	}
+
+	return access.Vault, amount, nil
 }
@@ -0,0 +1,299 @@
+package handlers_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/api/handlers"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateVault(t *testing.T) {
+	ta := newTestApp(t)
+	ta.user(t, ownerAddress)
+
+	contract := common.HexToAddress("0x00000000000000000000000000000000000C0DE1")
+	ta.chain.vaults[contract] = &service.VaultConfig{
+		Owner:             common.HexToAddress(ownerAddress),
+		Heirs:             []common.Address{common.HexToAddress(heirAddress), common.HexToAddress(coHeirAddress)},
+		HeirShares:        []*big.Int{big.NewInt(7000), big.NewInt(3000)},
+		HeartbeatInterval: big.NewInt(30 * 24 * 60 * 60),
+		GracePeriod:       big.NewInt(90 * 24 * 60 * 60),
+		RequiredApprovals: big.NewInt(2),
+		LastHeartbeat:     big.NewInt(1700000000),
+	}
+	ta.chain.vaultIDs[contract] = 7
+	req := handlers.CreateVaultRequest{
+		VaultID:           7,
+		ContractAddress:   contract.Hex(),
+		HeartbeatInterval: 30 * 24 * 60 * 60,
+		GracePeriod:       90 * 24 * 60 * 60,
+		RequiredApprovals: 2,
+		HeirAddresses:     []string{heirAddress, coHeirAddress},
+		HeirShares:        []int{7000, 3000},
+	}
+
+	t.Run("does not match the chain", func(t *testing.T) {
+		mismatch := req
+		mismatch.HeirShares = []int{5000, 5000}
+
+		var body errorBody
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPost, "/api/v1/vaults", ownerAddress, mismatch, &body))
+		assert.Contains(t, body.Error, "share of heir 0")
+	})
+
+	t.Run("wrong vault ID", func(t *testing.T) {
+		mismatch := req
+		mismatch.VaultID = 8
+
+		var body errorBody
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPost, "/api/v1/vaults", ownerAddress, mismatch, &body))
+		assert.Contains(t, body.Error, "vault_id")
+	})
+
+	t.Run("someone else's vault", func(t *testing.T) {
+		ta.user(t, strangerAddress)
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPost, "/api/v1/vaults", strangerAddress, req, nil))
+	})
+
+	var created models.Vault
+	require.Equal(t, http.StatusCreated, ta.request(t, http.MethodPost, "/api/v1/vaults", ownerAddress, req, &created))
+	assert.Equal(t, contract.Hex(), created.ContractAddress)
+	assert.Equal(t, ownerAddress, created.Owner.Address)
+	require.Len(t, created.Heirs, 2)
+	assert.Equal(t, 7000, created.Heirs[0].ShareBPS)
+	require.NotNil(t, created.LastHeartbeat)
+	assert.EqualValues(t, 1700000000, created.LastHeartbeat.Unix())
+
+	// Submitting it again returns the recorded vault
+	var again models.Vault
+	require.Equal(t, http.StatusCreated, ta.request(t, http.MethodPost, "/api/v1/vaults", ownerAddress, req, &again))
+	assert.Equal(t, created.ID, again.ID)
+}
+
+func TestCreateVaultDeploy(t *testing.T) {
+	ta := newTestApp(t)
+	ta.cfg.Vaults = config.VaultCreationConfig{Mode: config.VaultCreationModeDeploy, DeployTimeout: time.Second}
+	ta.user(t, ownerAddress)
+	req := handlers.CreateVaultRequest{
+		HeartbeatInterval: 30 * 24 * 60 * 60,
+		GracePeriod:       90 * 24 * 60 * 60,
+		RequiredApprovals: 1,
+		HeirAddresses:     []string{heirAddress},
+		HeirShares:        []int{10000},
+	}
+
+	t.Run("client-assigned vault ID", func(t *testing.T) {
+		assigned := req
+		assigned.VaultID = 1
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodPost, "/api/v1/vaults", ownerAddress, assigned, nil))
+	})
+
+	var created models.Vault
+	require.Equal(t, http.StatusCreated, ta.request(t, http.MethodPost, "/api/v1/vaults", ownerAddress, req, &created))
+	assert.EqualValues(t, 1, created.VaultID)
+	require.Len(t, created.Heirs, 1)
+
+	// The deployment is tracked and linked to the vault it created
+	require.Len(t, ta.chain.created, 1)
+	for txHash := range ta.chain.created {
+		record, err := ta.repos.Transactions.FindByHash(context.Background(), txHash.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, "createVaultFor", record.Method)
+		assert.Equal(t, &created.ID, record.VaultID)
+	}
+}
+
+func TestListVaults(t *testing.T) {
+	ta := newTestApp(t)
+	owned := ta.vault(t, ownerAddress, 1, heirAddress)
+	ta.vault(t, strangerAddress, 2, ownerAddress)
+
+	// Listed by the owner's address in any casing, heirs included
+	var vaults []models.Vault
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, "/api/v1/vaults", strings.ToLower(ownerAddress), nil, &vaults))
+	require.Len(t, vaults, 1)
+	assert.Equal(t, owned.ID, vaults[0].ID)
+	assert.Len(t, vaults[0].Heirs, 1)
+
+	require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, "/api/v1/vaults", heirAddress, nil, &vaults))
+	assert.Empty(t, vaults)
+
+	assert.Equal(t, http.StatusUnauthorized, ta.request(t, http.MethodGet, "/api/v1/vaults", "", nil, nil))
+}
+
+func TestGetVault(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress, coHeirAddress)
+	path := "/api/v1/vaults/" + vault.ID.String()
+
+	owner := ta.user(t, ownerAddress)
+	owner.Email = "owner@example.com"
+	require.NoError(t, ta.repos.Users.UpdateProfile(context.Background(), owner))
+	require.NoError(t, ta.repos.Heartbeats.Create(context.Background(), &models.Heartbeat{VaultID: vault.ID, Nonce: "secret"}))
+
+	t.Run("owner", func(t *testing.T) {
+		var got models.Vault
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, ownerAddress, nil, &got))
+		assert.Equal(t, "owner@example.com", got.Owner.Email)
+		assert.Len(t, got.Heirs, 2)
+		require.Len(t, got.Heartbeats, 1)
+		assert.Equal(t, "secret", got.Heartbeats[0].Nonce)
+	})
+
+	t.Run("heir", func(t *testing.T) {
+		var got models.Vault
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, strings.ToLower(coHeirAddress), nil, &got))
+		assert.Empty(t, got.Owner.Email)
+		require.Len(t, got.Heirs, 1)
+		assert.Equal(t, coHeirAddress, got.Heirs[0].Address)
+		require.Len(t, got.Heartbeats, 1)
+		assert.Empty(t, got.Heartbeats[0].Nonce)
+	})
+
+	t.Run("stranger", func(t *testing.T) {
+		var body errorBody
+		assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, path, strangerAddress, nil, &body))
+		assert.Equal(t, "Vault not found or you don't have permission", body.Error)
+	})
+
+	t.Run("invalid ID", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodGet, "/api/v1/vaults/not-a-uuid", ownerAddress, nil, nil))
+	})
+}
+
+func TestWithdrawVaultOwnerOnly(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress)
+
+	var body errorBody
+	status := ta.request(t, http.MethodPost, "/api/v1/vaults/"+vault.ID.String()+"/withdraw", heirAddress,
+		handlers.FundVaultRequest{Amount: "1000"}, &body)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "You are not the owner of this vault", body.Error)
+}
+
+func TestGetVaultLedger(t *testing.T) {
+	ta := newTestApp(t)
+	vault := ta.vault(t, ownerAddress, 1, heirAddress)
+	other := ta.vault(t, ownerAddress, 2, heirAddress)
+	ta.user(t, strangerAddress)
+	path := "/api/v1/vaults/" + vault.ID.String() + "/ledger"
+
+	// Indexed out of chain order, with an entry of another vault
+	logs := []struct {
+		vault    *models.Vault
+		block    uint64
+		logIndex uint
+	}{{vault, 2, 0}, {vault, 1, 3}, {other, 5, 0}, {vault, 3, 0}, {vault, 2, 1}}
+	for i, log := range logs {
+		require.NoError(t, ta.repos.Ledger.Create(context.Background(), &models.VaultLedgerEntry{
+			VaultID:     log.vault.ID,
+			Kind:        models.LedgerEntryDeposit,
+			Address:     ownerAddress,
+			Amount:      "100",
+			TxHash:      common.BigToHash(big.NewInt(int64(i))).Hex(),
+			LogIndex:    log.logIndex,
+			BlockNumber: log.block,
+			Timestamp:   time.Now(),
+		}))
+	}
+
+	chainOrder := func(entries []models.VaultLedgerEntry) [][2]uint64 {
+		order := [][2]uint64{}
+		for _, entry := range entries {
+			order = append(order, [2]uint64{entry.BlockNumber, uint64(entry.LogIndex)})
+		}
+		return order
+	}
+
+	t.Run("newest first", func(t *testing.T) {
+		for _, address := range []string{ownerAddress, heirAddress} {
+			var ledger handlers.VaultLedgerResponse
+			require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, address, nil, &ledger), address)
+			assert.Equal(t, vault.Balance, ledger.Balance)
+			assert.Equal(t, [][2]uint64{{3, 0}, {2, 1}, {2, 0}, {1, 3}}, chainOrder(ledger.Entries))
+		}
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var ledger handlers.VaultLedgerResponse
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path+"?limit=2&offset=1", ownerAddress, nil, &ledger))
+		assert.Equal(t, [][2]uint64{{2, 1}, {2, 0}}, chainOrder(ledger.Entries))
+
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path+"?limit=2&offset=4", ownerAddress, nil, &ledger))
+		assert.NotNil(t, ledger.Entries)
+		assert.Empty(t, ledger.Entries)
+
+		var body errorBody
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodGet, path+"?limit=0", ownerAddress, nil, &body))
+		assert.Equal(t, "Invalid limit", body.Error)
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodGet, path+"?offset=-1", ownerAddress, nil, &body))
+		assert.Equal(t, "Invalid offset", body.Error)
+	})
+
+	t.Run("stranger", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, path, strangerAddress, nil, nil))
+	})
+}
+
+func TestGetVaultAudit(t *testing.T) {
+	ta := newTestApp(t)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	owner := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	vault := ta.vault(t, owner, 1, heirAddress)
+	other := ta.vault(t, owner, 2, coHeirAddress)
+	ta.user(t, strangerAddress)
+	path := "/api/v1/vaults/" + vault.ID.String() + "/audit"
+
+	// The owner pauses and unpauses the vault, and pauses another one
+	calls := []struct {
+		vault  *models.Vault
+		method string
+		reason string
+	}{{vault, "pause", "lost my phone"}, {other, "pause", "other vault"}, {vault, "unpause", "found it"}}
+	for i, call := range calls {
+		req := handlers.SendTransactionRequest{
+			RawTx:  signedTx(t, key, uint64(i), common.HexToAddress(call.vault.ContractAddress), nil, vaultCalldata(t, call.method)),
+			Reason: call.reason,
+		}
+		require.Equal(t, http.StatusOK, ta.request(t, http.MethodPost, "/api/v1/tx/send", owner, req, nil))
+	}
+
+	t.Run("newest first", func(t *testing.T) {
+		for _, address := range []string{owner, heirAddress} {
+			var audit []models.VaultAuditLog
+			require.Equal(t, http.StatusOK, ta.request(t, http.MethodGet, path, address, nil, &audit), address)
+			require.Len(t, audit, 2)
+			assert.Equal(t, models.VaultAuditUnpause, audit[0].Action)
+			assert.Equal(t, "found it", audit[0].Reason)
+			assert.Equal(t, models.VaultAuditPause, audit[1].Action)
+			assert.Equal(t, "lost my phone", audit[1].Reason)
+			assert.Equal(t, owner, audit[1].Actor)
+		}
+	})
+
+	t.Run("not the owner or an heir", func(t *testing.T) {
+		// Heirs of another vault of the same owner are denied too
+		for _, address := range []string{strangerAddress, coHeirAddress} {
+			var body errorBody
+			assert.Equal(t, http.StatusNotFound, ta.request(t, http.MethodGet, path, address, nil, &body), address)
+			assert.Equal(t, "Vault not found or you don't have permission", body.Error)
+		}
+	})
+
+	t.Run("invalid vault ID", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, ta.request(t, http.MethodGet, "/api/v1/vaults/not-a-uuid/audit", owner, nil, nil))
+	})
+}
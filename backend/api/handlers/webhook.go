@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/webhook"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxWebhooksPerUser bounds the endpoints one user can register
+	maxWebhooksPerUser = 10
+
+	// maxWebhookListLength bounds delivery and dead letter listings
+	maxWebhookListLength = 100
+)
+
+type WebhookHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewWebhookHandler(db *gorm.DB, cfg *config.Config) *WebhookHandler {
+	return &WebhookHandler{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required"`
+	EventTypes []string `json:"event_types" validate:"required"`
+	AllVaults  bool     `json:"all_vaults"` // Receive events of every vault (admins only)
+}
+
+type UpdateWebhookRequest struct {
+	URL        *string  `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	models.Webhook
+	EventTypes []models.WebhookEventType `json:"event_types"`
+	Secret     string                    `json:"secret,omitempty"` // Only returned when the webhook is created
+}
+
+type WebhookDeliveryResponse struct {
+	models.WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+type WebhookDeadLetterResponse struct {
+	models.WebhookDeadLetter
+	Payload json.RawMessage `json:"payload"`
+}
+
+type ReplayWebhookResponse struct {
+	Replayed int64 `json:"replayed"`
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Register an endpoint for vault lifecycle events of the caller's vaults (as owner or heir). Deliveries are POSTed as JSON and signed with HMAC-SHA256 in the X-LegacyChain-Signature header. The signing secret is only returned in this response.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body CreateWebhookRequest true "Webhook"
+// @Success 201 {object} WebhookResponse
+// @Router /webhooks [post]
+// @Security BearerAuth
+func (h *WebhookHandler) CreateWebhook(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var req CreateWebhookRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := webhook.ValidateURL(req.URL, h.cfg.Webhooks.AllowInsecure); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook URL: " + err.Error(),
+		})
+	}
+
+	eventTypes, fiberErr := parseWebhookEventTypes(req.EventTypes)
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	if req.AllVaults && !h.cfg.Admin.IsAdmin(address) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only admins can subscribe to all vaults",
+		})
+	}
+
+	var user models.User
+	if err := h.db.Where("LOWER(address) = LOWER(?)", address).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	var count int64
+	if err := h.db.Model(&models.Webhook{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count webhooks",
+		})
+	}
+	if count >= maxWebhooksPerUser {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Webhook limit reached",
+		})
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate webhook secret",
+		})
+	}
+
+	hook := models.Webhook{
+		UserID:     user.ID,
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: joinWebhookEventTypes(eventTypes),
+		AllVaults:  req.AllVaults,
+		Active:     true,
+	}
+	if err := h.db.Create(&hook).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create webhook",
+		})
+	}
+
+	response := webhookResponse(&hook)
+	response.Secret = secret
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description Get the webhooks registered by the authenticated user
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} WebhookResponse
+// @Router /webhooks [get]
+// @Security BearerAuth
+func (h *WebhookHandler) ListWebhooks(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	var webhooks []models.Webhook
+	if err := h.db.Joins("JOIN users ON users.id = webhooks.user_id").
+		Where("LOWER(users.address) = LOWER(?)", address).
+		Order("webhooks.created_at").
+		Find(&webhooks).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch webhooks",
+		})
+	}
+
+	response := make([]WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		response[i] = webhookResponse(&webhooks[i])
+	}
+
+	return c.JSON(response)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Change a webhook's URL or event types, or pause and resume it with active. Deliveries queued while it is inactive are sent once it is active again.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param request body UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} WebhookResponse
+// @Router /webhooks/{id} [patch]
+// @Security BearerAuth
+func (h *WebhookHandler) UpdateWebhook(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	hook, fiberErr := h.findWebhook(address, c.Params("id"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.Bind().Body(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if err := webhook.ValidateURL(*req.URL, h.cfg.Webhooks.AllowInsecure); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid webhook URL: " + err.Error(),
+			})
+		}
+		updates["url"] = *req.URL
+	}
+	if req.EventTypes != nil {
+		eventTypes, fiberErr := parseWebhookEventTypes(req.EventTypes)
+		if fiberErr != nil {
+			return c.Status(fiberErr.Code).JSON(fiber.Map{
+				"error": fiberErr.Message,
+			})
+		}
+		updates["event_types"] = joinWebhookEventTypes(eventTypes)
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(hook).Updates(updates).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update webhook",
+			})
+		}
+		if err := h.db.First(hook, hook.ID).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to reload webhook",
+			})
+		}
+	}
+
+	return c.JSON(webhookResponse(hook))
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook and drop its pending deliveries
+// @Tags webhooks
+// @Param id path string true "Webhook ID"
+// @Success 204
+// @Router /webhooks/{id} [delete]
+// @Security BearerAuth
+func (h *WebhookHandler) DeleteWebhook(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	hook, fiberErr := h.findWebhook(address, c.Params("id"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ? AND status = ?", hook.ID, models.WebhookDeliveryPending).
+			Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(hook).Error
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary List webhook deliveries
+// @Description Get a webhook's most recent deliveries, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "pending, delivered or dead"
+// @Success 200 {array} WebhookDeliveryResponse
+// @Router /webhooks/{id}/deliveries [get]
+// @Security BearerAuth
+func (h *WebhookHandler) ListWebhookDeliveries(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	hook, fiberErr := h.findWebhook(address, c.Params("id"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	query := h.db.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		switch models.WebhookDeliveryStatus(status) {
+		case models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+			query = query.Where("status = ?", status)
+		default:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid status",
+			})
+		}
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").Limit(maxWebhookListLength).Find(&deliveries).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch deliveries",
+		})
+	}
+
+	response := make([]WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		response[i] = WebhookDeliveryResponse{WebhookDelivery: delivery, Payload: json.RawMessage(delivery.Payload)}
+	}
+
+	return c.JSON(response)
+}
+
+// ListWebhookDeadLetters godoc
+// @Summary List a webhook's dead letters
+// @Description Get the deliveries to a webhook that failed every attempt, newest first. Replayed ones are only included with all=true.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param all query bool false "Include replayed dead letters"
+// @Success 200 {array} WebhookDeadLetterResponse
+// @Router /webhooks/{id}/dead-letters [get]
+// @Security BearerAuth
+func (h *WebhookHandler) ListWebhookDeadLetters(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	hook, fiberErr := h.findWebhook(address, c.Params("id"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	return h.listDeadLetters(c, h.db.Where("webhook_id = ?", hook.ID))
+}
+
+// ListAllWebhookDeadLetters godoc
+// @Summary List all dead letters
+// @Description Get the dead letters of every webhook, newest first. Replayed ones are only included with all=true.
+// @Tags admin
+// @Produce json
+// @Param all query bool false "Include replayed dead letters"
+// @Success 200 {array} WebhookDeadLetterResponse
+// @Router /admin/webhooks/dead-letters [get]
+// @Security BearerAuth
+func (h *WebhookHandler) ListAllWebhookDeadLetters(c fiber.Ctx) error {
+	return h.listDeadLetters(c, h.db)
+}
+
+// ReplayWebhookDeadLetter godoc
+// @Summary Replay a dead letter
+// @Description Queue a dead-lettered delivery again with its original payload and delivery ID. Admins can replay any webhook's dead letters.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Success 200 {object} ReplayWebhookResponse
+// @Router /webhooks/dead-letters/{id}/replay [post]
+// @Security BearerAuth
+func (h *WebhookHandler) ReplayWebhookDeadLetter(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	// Parse dead letter ID
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dead letter ID",
+		})
+	}
+
+	var letter models.WebhookDeadLetter
+	if err := h.db.First(&letter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Dead letter not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to query dead letter",
+		})
+	}
+
+	// Only the webhook's owner or an admin may replay it
+	if _, fiberErr := h.findWebhook(address, letter.WebhookID.String()); fiberErr != nil {
+		if fiberErr.Code == fiber.StatusNotFound {
+			fiberErr.Message = "Dead letter not found"
+		}
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	replayed, err := h.replayDeadLetters("id = ?", letter.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to replay dead letter",
+		})
+	}
+	if replayed == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Dead letter was already replayed",
+		})
+	}
+
+	return c.JSON(ReplayWebhookResponse{Replayed: replayed})
+}
+
+// ReplayWebhookDeadLetters godoc
+// @Summary Replay all of a webhook's dead letters
+// @Description Queue every dead letter of the webhook that has not been replayed yet
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} ReplayWebhookResponse
+// @Router /webhooks/{id}/dead-letters/replay [post]
+// @Security BearerAuth
+func (h *WebhookHandler) ReplayWebhookDeadLetters(c fiber.Ctx) error {
+	address := c.Locals("address").(string)
+
+	hook, fiberErr := h.findWebhook(address, c.Params("id"))
+	if fiberErr != nil {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+
+	replayed, err := h.replayDeadLetters("webhook_id = ?", hook.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to replay dead letters",
+		})
+	}
+
+	return c.JSON(ReplayWebhookResponse{Replayed: replayed})
+}
+
+// findWebhook loads a webhook registered by the address, or any webhook for admins
+func (h *WebhookHandler) findWebhook(address, id string) (*models.Webhook, *fiber.Error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid webhook ID")
+	}
+
+	query := h.db.Where("webhooks.id = ?", uid)
+	if !h.cfg.Admin.IsAdmin(address) {
+		query = query.Joins("JOIN users ON users.id = webhooks.user_id").
+			Where("LOWER(users.address) = LOWER(?)", address)
+	}
+
+	var hook models.Webhook
+	if err := query.First(&hook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to query webhook")
+	}
+
+	return &hook, nil
+}
+
+// listDeadLetters responds with the newest dead letters matching query
+func (h *WebhookHandler) listDeadLetters(c fiber.Ctx, query *gorm.DB) error {
+	if c.Query("all") != "true" {
+		query = query.Where("replayed_at IS NULL")
+	}
+
+	var letters []models.WebhookDeadLetter
+	if err := query.Preload("Delivery").
+		Order("created_at DESC").
+		Limit(maxWebhookListLength).
+		Find(&letters).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch dead letters",
+		})
+	}
+
+	response := make([]WebhookDeadLetterResponse, len(letters))
+	for i, letter := range letters {
+		response[i] = WebhookDeadLetterResponse{WebhookDeadLetter: letter, Payload: json.RawMessage(letter.Delivery.Payload)}
+	}
+
+	return c.JSON(response)
+}
+
+// replayDeadLetters marks the unreplayed dead letters matching query as
+// replayed and queues their deliveries again from the first attempt
+func (h *WebhookHandler) replayDeadLetters(query string, args ...interface{}) (int64, error) {
+	var replayed int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var letters []models.WebhookDeadLetter
+		if err := tx.Where(query, args...).Where("replayed_at IS NULL").Find(&letters).Error; err != nil {
+			return err
+		}
+		if len(letters) == 0 {
+			return nil
+		}
+
+		letterIDs := make([]uuid.UUID, len(letters))
+		deliveryIDs := make([]uuid.UUID, len(letters))
+		for i, letter := range letters {
+			letterIDs[i] = letter.ID
+			deliveryIDs[i] = letter.DeliveryID
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.WebhookDeadLetter{}).
+			Where("id IN ? AND replayed_at IS NULL", letterIDs).
+			Update("replayed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		replayed = result.RowsAffected
+
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ? AND status = ?", deliveryIDs, models.WebhookDeliveryDead).
+			Updates(map[string]interface{}{
+				"status":          models.WebhookDeliveryPending,
+				"attempts":        0,
+				"next_attempt_at": now,
+				"response_status": nil,
+				"last_error":      "",
+			}).Error
+	})
+	return replayed, err
+}
+
+// parseWebhookEventTypes validates and deduplicates subscribed event types
+func parseWebhookEventTypes(values []string) ([]models.WebhookEventType, *fiber.Error) {
+	if len(values) == 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "At least one event type is required")
+	}
+
+	var eventTypes []models.WebhookEventType
+	for _, value := range values {
+		eventType := models.WebhookEventType(strings.TrimSpace(value))
+
+		known := false
+		for _, t := range models.WebhookEventTypes {
+			known = known || t == eventType
+		}
+		if !known {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown event type: "+value)
+		}
+
+		duplicate := false
+		for _, t := range eventTypes {
+			duplicate = duplicate || t == eventType
+		}
+		if !duplicate {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	return eventTypes, nil
+}
+
+func joinWebhookEventTypes(eventTypes []models.WebhookEventType) string {
+	values := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		values[i] = string(eventType)
+	}
+	return strings.Join(values, ",")
+}
+
+func webhookResponse(hook *models.Webhook) WebhookResponse {
+	return WebhookResponse{Webhook: *hook, EventTypes: hook.Events()}
+}
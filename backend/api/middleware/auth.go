@@ -4,16 +4,13 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v3"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+	"github.com/redis/go-redis/v9"
 )
 
-type Claims struct {
-	Address string `json:"address"`
-	jwt.RegisteredClaims
-}
-
-func JWTAuth(cfg *config.Config) fiber.Handler {
+// JWTAuth accepts a valid access token that has not been revoked, and stores
+// its address and claims in the "address" and "claims" locals
+func JWTAuth(keys *session.KeySet, redisClient *redis.Client) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -30,21 +27,29 @@ func JWTAuth(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		tokenString := parts[1]
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWT.Secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := session.ParseAccessToken(keys, parts[1])
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid or expired token",
 			})
 		}
 
+		// Reject tokens revoked by logout, logout-all or refresh token reuse
+		denied, err := session.IsDenied(c.Context(), redisClient, claims)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check token revocation",
+			})
+		}
+		if denied {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token has been revoked",
+			})
+		}
+
 		// Store user address in context
 		c.Locals("address", claims.Address)
+		c.Locals("claims", claims)
 		return c.Next()
 	}
 }
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v3"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+)
+
+// RequireRole allows only users with any of roles. Roles come from the access
+// token, so it must run after JWTAuth.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		claims, _ := c.Locals("claims").(*session.Claims)
+
+		if claims != nil && claims.HasRole(roles...) {
+			return c.Next()
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Insufficient role",
+		})
+	}
+}
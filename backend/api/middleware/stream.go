@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/redis/go-redis/v9"
+)
+
+// StreamAuth authenticates a stream request with a single-use ticket in the
+// ticket query parameter, for EventSource clients that cannot send headers,
+// and falls back to JWTAuth otherwise.
+func StreamAuth(keys *session.KeySet, redisClient *redis.Client) fiber.Handler {
+	jwtAuth := JWTAuth(keys, redisClient)
+
+	return func(c fiber.Ctx) error {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			return jwtAuth(c)
+		}
+
+		address, err := stream.RedeemTicket(c.Context(), redisClient, ticket)
+		if errors.Is(err, stream.ErrInvalidTicket) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired stream ticket",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to redeem stream ticket",
+			})
+		}
+
+		c.Locals("address", address)
+		return c.Next()
+	}
+}
@@ -5,12 +5,18 @@ import (
 	"github.com/haneumLee/legacychain/backend/api/handlers"
 	"github.com/haneumLee/legacychain/backend/api/middleware"
 	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/keeper"
+	"github.com/haneumLee/legacychain/backend/internal/relayer"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
 	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/haneumLee/legacychain/backend/models"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
-func Setup(app *fiber.App, db *gorm.DB, redisClient *redis.Client, cfg *config.Config, blockchain service.BlockchainService) {
+func Setup(app *fiber.App, db *gorm.DB, repos *repository.Repositories, redisClient *redis.Client, cfg *config.Config, blockchain service.BlockchainService, keys *session.KeySet, hub *stream.Hub, unlockKeeper *keeper.Keeper) {
 	// Health check
 	app.Get("/health", func(c fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -19,6 +25,12 @@ func Setup(app *fiber.App, db *gorm.DB, redisClient *redis.Client, cfg *config.C
 		})
 	})
 
+	sessions := session.NewManager(db, redisClient, keys, cfg)
+	authHandler := handlers.NewAuthHandler(cfg, redisClient, keys, sessions, blockchain, repos)
+
+	// Public keys access tokens can be verified with
+	app.Get("/.well-known/jwks.json", authHandler.GetJWKS)
+
 	// API v1 group
 	api := app.Group("/api/v1")
 
@@ -26,29 +38,43 @@ func Setup(app *fiber.App, db *gorm.DB, redisClient *redis.Client, cfg *config.C
 	api.Use(middleware.RateLimiter(cfg, redisClient))
 
 	// Auth routes (no JWT required)
-	authHandler := handlers.NewAuthHandler(db, cfg, redisClient)
 	auth := api.Group("/auth")
 	{
 		auth.Get("/nonce", authHandler.GetNonce)
 		auth.Post("/login", authHandler.Login)
-		auth.Get("/me", middleware.JWTAuth(cfg), authHandler.GetMe)
+		auth.Post("/refresh", authHandler.Refresh)
+		auth.Post("/logout", middleware.JWTAuth(keys, redisClient), authHandler.Logout)
+		auth.Post("/logout-all", middleware.JWTAuth(keys, redisClient), authHandler.LogoutAll)
+		auth.Get("/me", middleware.JWTAuth(keys, redisClient), authHandler.GetMe)
+		auth.Patch("/me", middleware.JWTAuth(keys, redisClient), authHandler.UpdateMe)
 	}
 
+	// Stream routes (JWT or a single-use ticket, for EventSource clients)
+	streamHandler := handlers.NewStreamHandler(repos, redisClient, hub, cfg)
+	api.Post("/stream/ticket", middleware.JWTAuth(keys, redisClient), streamHandler.CreateStreamTicket)
+	api.Get("/stream", middleware.StreamAuth(keys, redisClient), streamHandler.Stream)
+
 	// Protected routes
 	protected := api.Group("")
-	protected.Use(middleware.JWTAuth(cfg))
+	protected.Use(middleware.JWTAuth(keys, redisClient))
 
 	// Vault routes
-	vaultHandler := handlers.NewVaultHandler(db)
+	vaultHandler := handlers.NewVaultHandler(cfg, blockchain, repos)
 	vaults := protected.Group("/vaults")
 	{
 		vaults.Post("", vaultHandler.CreateVault)
 		vaults.Get("", vaultHandler.ListVaults)
 		vaults.Get("/:id", vaultHandler.GetVault)
+		vaults.Get("/:id/ledger", vaultHandler.GetVaultLedger)
+		vaults.Get("/:id/audit", vaultHandler.GetVaultAudit)
+		vaults.Post("/:id/deposit", vaultHandler.DepositVault)
+		vaults.Post("/:id/withdraw", vaultHandler.WithdrawVault)
+		vaults.Post("/:id/pause", vaultHandler.PauseVault)
+		vaults.Post("/:id/unpause", vaultHandler.UnpauseVault)
 	}
 
 	// Heartbeat routes
-	heartbeatHandler := handlers.NewHeartbeatHandler(db, blockchain)
+	heartbeatHandler := handlers.NewHeartbeatHandler(blockchain, repos)
 	heartbeat := protected.Group("/heartbeat")
 	{
 		heartbeat.Post("/commit", heartbeatHandler.CommitHeartbeat)
@@ -58,7 +84,7 @@ func Setup(app *fiber.App, db *gorm.DB, redisClient *redis.Client, cfg *config.C
 	}
 
 	// Heir routes
-	heirHandler := handlers.NewHeirHandler(db, blockchain)
+	heirHandler := handlers.NewHeirHandler(blockchain, repos)
 	heir := protected.Group("/heir")
 	{
 		heir.Post("/approve", heirHandler.ApproveHeir)
@@ -66,4 +92,50 @@ func Setup(app *fiber.App, db *gorm.DB, redisClient *redis.Client, cfg *config.C
 		heir.Get("/status/:vault_id", heirHandler.GetApprovalStatus)
 		heir.Get("/list/:vault_id", heirHandler.ListHeirs)
 	}
+
+	// Transaction relay routes (user-signed transactions)
+	txHandler := handlers.NewTransactionHandler(blockchain, repos)
+	tx := protected.Group("/tx")
+	{
+		tx.Post("/build", txHandler.BuildTransaction)
+		tx.Post("/send", txHandler.SendTransaction)
+		tx.Get("/:hash", txHandler.GetTransaction)
+	}
+
+	// Meta-transaction routes (EIP-712 signed, gas paid by the relayer)
+	metaTxHandler := handlers.NewMetaTxHandler(relayer.New(repos, blockchain, cfg), repos)
+	metatx := protected.Group("/metatx")
+	{
+		metatx.Get("/nonce", metaTxHandler.GetNonce)
+		metatx.Post("/build", metaTxHandler.BuildMetaTx)
+		metatx.Post("/relay", metaTxHandler.RelayMetaTx)
+	}
+
+	// Webhook routes
+	webhookHandler := handlers.NewWebhookHandler(db, cfg)
+	webhooks := protected.Group("/webhooks")
+	{
+		webhooks.Post("", webhookHandler.CreateWebhook)
+		webhooks.Get("", webhookHandler.ListWebhooks)
+		webhooks.Post("/dead-letters/:id/replay", webhookHandler.ReplayWebhookDeadLetter)
+		webhooks.Patch("/:id", webhookHandler.UpdateWebhook)
+		webhooks.Delete("/:id", webhookHandler.DeleteWebhook)
+		webhooks.Get("/:id/deliveries", webhookHandler.ListWebhookDeliveries)
+		webhooks.Get("/:id/dead-letters", webhookHandler.ListWebhookDeadLetters)
+		webhooks.Post("/:id/dead-letters/replay", webhookHandler.ReplayWebhookDeadLetters)
+	}
+
+	// Admin routes: auditors read, support also runs the keeper, admins do everything
+	adminHandler := handlers.NewAdminHandler(db, blockchain, sessions, unlockKeeper)
+	admin := protected.Group("/admin", middleware.RequireRole(models.RoleAdmin, models.RoleSupport, models.RoleAuditor))
+	{
+		admin.Get("/vaults", adminHandler.ListVaults)
+		admin.Get("/vaults/:id", adminHandler.GetVault)
+		admin.Get("/users", adminHandler.ListUsers)
+		admin.Get("/transactions/failed", adminHandler.ListFailedTransactions)
+		admin.Get("/webhooks/dead-letters", webhookHandler.ListAllWebhookDeadLetters)
+		admin.Post("/keeper/run", middleware.RequireRole(models.RoleAdmin, models.RoleSupport), adminHandler.RunKeeper)
+		admin.Put("/users/:id/roles", middleware.RequireRole(models.RoleAdmin), adminHandler.UpdateUserRoles)
+		admin.Post("/nonces/:nonce/cancel", middleware.RequireRole(models.RoleAdmin), adminHandler.CancelNonce)
+	}
 }
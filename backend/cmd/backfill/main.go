@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/indexer"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/utils"
+)
+
+func main() {
+	from := flag.Uint64("from", 0, "first block to scan (VaultFactory deployment block)")
+	to := flag.Uint64("to", 0, "last block to scan (default: indexer cursor, or latest confirmed block)")
+	chunk := flag.Uint64("chunk", 0, "blocks per filter request (default: INDEXER_BATCH_SIZE)")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Initialize database
+	db, err := utils.InitDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	// Initialize Blockchain Service (read-only, so signer nonces are not shared)
+	blockchain, err := service.NewBlockchainService(cfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to initialize blockchain service: %v", err)
+	}
+	defer blockchain.Close()
+
+	ctx := context.Background()
+
+	backfiller, err := indexer.NewBackfiller(db, blockchain, cfg, *chunk)
+	if err != nil {
+		log.Fatalf("Failed to initialize backfill: %v", err)
+	}
+
+	if *to == 0 {
+		_, defaultTo, err := backfiller.DefaultRange(ctx)
+		if err != nil {
+			log.Fatalf("Failed to determine block range: %v", err)
+		}
+		*to = defaultTo
+	}
+
+	log.Printf("📦 Backfilling blocks %d-%d", *from, *to)
+
+	err = backfiller.Run(ctx, *from, *to, func(p indexer.BackfillProgress) {
+		done := float64(p.Current-p.From+1) / float64(p.To-p.From+1) * 100
+		log.Printf("📦 Block %d/%d (%.1f%%): %d vaults, %d heartbeats, %d ledger entries created, %d vaults reconciled",
+			p.Current, p.To, done, p.VaultsCreated, p.HeartbeatsCreated, p.LedgerEntries, p.VaultsReconciled)
+	})
+	if err != nil {
+		log.Fatalf("Backfill failed: %v", err)
+	}
+
+	log.Println("✅ Backfill completed")
+}
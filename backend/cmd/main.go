@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/gofiber/fiber/v3"
@@ -9,7 +10,15 @@ import (
 	"github.com/gofiber/fiber/v3/middleware/recover"
 	"github.com/haneumLee/legacychain/backend/api/routes"
 	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/indexer"
+	"github.com/haneumLee/legacychain/backend/internal/keeper"
+	"github.com/haneumLee/legacychain/backend/internal/notify"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
 	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/session"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/internal/webhook"
 	"github.com/haneumLee/legacychain/backend/utils"
 )
 
@@ -17,6 +26,13 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Load the keys access tokens are signed and verified with
+	keys, err := session.LoadKeySet(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	log.Printf("🔑 Signing access tokens with key %s", keys.SigningKeyID())
+
 	// Initialize database
 	db, err := utils.InitDatabase(cfg)
 	if err != nil {
@@ -30,7 +46,7 @@ func main() {
 	}
 
 	// Initialize Blockchain Service
-	blockchain, err := service.NewBlockchainService(cfg)
+	blockchain, err := service.NewBlockchainService(cfg, redisClient)
 	if err != nil {
 		log.Fatalf("Failed to initialize blockchain service: %v", err)
 	}
@@ -38,6 +54,56 @@ func main() {
 
 	log.Printf("✅ Blockchain service connected to %s (Chain ID: %d)", cfg.Blockchain.RpcURL, cfg.Blockchain.ChainID)
 
+	// Background workers stop when main returns
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Vault updates are fanned out to every API instance's streams through Redis
+	publisher := stream.NewRedisPublisher(redisClient)
+	hub := stream.NewHub(redisClient)
+	go hub.Run(ctx)
+
+	// Start event indexer
+	if cfg.Indexer.Enabled {
+		vaultIndexer, err := indexer.New(db, blockchain, publisher, cfg)
+		if err != nil {
+			log.Fatalf("Failed to initialize indexer: %v", err)
+		}
+		go vaultIndexer.Run(ctx)
+
+		log.Println("✅ Event indexer started")
+	}
+
+	// Start transaction tracker
+	if cfg.Tracker.Enabled {
+		go tracker.New(db, blockchain, publisher, cfg).Run(ctx)
+
+		log.Println("✅ Transaction tracker started")
+	}
+
+	// Start unlock keeper; admins can run a round even when it is disabled
+	unlockKeeper := keeper.New(db, redisClient, blockchain, cfg)
+	if cfg.Keeper.Enabled {
+		go unlockKeeper.Run(ctx)
+
+		log.Println("✅ Unlock keeper started")
+	}
+
+	// Start heartbeat reminders
+	if cfg.Notifications.Enabled {
+		notifier := notify.NewSMTPNotifier(cfg.Notifications.SMTP)
+		go notify.NewReminderScheduler(db, notifier, cfg).Run(ctx)
+
+		log.Println("✅ Heartbeat reminders started")
+	}
+
+	// Start webhook dispatcher
+	if cfg.Webhooks.Enabled {
+		go webhook.NewDispatcher(db, cfg).Run(ctx)
+
+		log.Println("✅ Webhook dispatcher started")
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "LegacyChain API v1.0",
@@ -50,11 +116,11 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"*"},
 		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 	}))
 
 	// Setup routes
-	routes.Setup(app, db, redisClient, cfg, blockchain)
+	routes.Setup(app, db, repository.NewGorm(db), redisClient, cfg, blockchain, keys, hub, unlockKeeper)
 
 	// Start server
 	log.Printf("🚀 Server starting on port %s", cfg.Server.Port)
@@ -2,20 +2,33 @@ package config
 
 import (
 	"log"
+	"math/big"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server     ServerConfig
-	Database   DatabaseConfig
-	Redis      RedisConfig
-	Blockchain BlockchainConfig
-	JWT        JWTConfig
-	RateLimit  RateLimitConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Redis         RedisConfig
+	Blockchain    BlockchainConfig
+	Fees          FeeConfig
+	Vaults        VaultCreationConfig
+	JWT           JWTConfig
+	SIWE          SIWEConfig
+	RateLimit     RateLimitConfig
+	Admin         AdminConfig
+	Indexer       IndexerConfig
+	Relayer       RelayerConfig
+	Tracker       TrackerConfig
+	Keeper        KeeperConfig
+	Notifications NotificationConfig
+	Webhooks      WebhookConfig
+	Stream        StreamConfig
 }
 
 type ServerConfig struct {
@@ -45,11 +58,43 @@ type BlockchainConfig struct {
 	ChainID             int64
 	VaultFactoryAddress string
 	PrivateKey          string
+	NonceResyncInterval time.Duration // How often the signer nonce allocator is reconciled with the chain (0 = never)
+	NonceStuckTimeout   time.Duration // How long an allocated nonce may go unsent before it is cancelled
+}
+
+type FeeConfig struct {
+	GasLimitMultiplier   float64  // Safety margin applied to gas estimates
+	MaxGasLimit          uint64   // Highest gas limit the server signer sends with (0 = unlimited)
+	MaxFeePerGas         *big.Int // Ceiling for the fee cap, or the gas price on legacy chains, in wei (nil = unlimited)
+	MaxPriorityFeePerGas *big.Int // Ceiling for the priority fee in wei (nil = unlimited)
+	ForceLegacy          bool     // Use legacy gas pricing even if the chain supports EIP-1559
+	BumpPercent          uint64   // Fee increase for same-nonce replacements (at least 10)
+}
+
+// Vault creation modes for POST /vaults
+const (
+	VaultCreationModeDeploy = "deploy" // The server deploys the vault through the factory
+	VaultCreationModeVerify = "verify" // The client deploys it and the server checks it on-chain
+)
+
+type VaultCreationConfig struct {
+	Mode          string
+	DeployTimeout time.Duration // How long POST /vaults waits for the deployment to be mined
 }
 
 type JWTConfig struct {
-	Secret    string
-	ExpiresIn time.Duration
+	SigningKeyFile       string        // PEM private key (ECDSA P-256 for ES256, or Ed25519 for EdDSA) access tokens are signed with
+	VerificationKeyFiles []string      // PEM keys of previous signing keys, still accepted during rotation
+	ExpiresIn            time.Duration // Lifetime of access tokens
+	RefreshExpiresIn     time.Duration // Lifetime of refresh tokens, extended on every refresh
+}
+
+// SIWEConfig is what Sign-In with Ethereum (EIP-4361) messages must be bound to
+type SIWEConfig struct {
+	Domain      string // Host (and port) of the frontend that requests signatures
+	URI         string // Origin of the frontend, e.g. https://app.legacychain.io
+	Statement   string // Shown to the user by the wallet
+	LegacyLogin bool   // Also accept the pre-SIWE "Login to LegacyChain" message, while clients migrate
 }
 
 type RateLimitConfig struct {
@@ -57,6 +102,83 @@ type RateLimitConfig struct {
 	Window time.Duration
 }
 
+type AdminConfig struct {
+	Addresses []string // Wallet addresses allowed to use the admin API
+}
+
+// IsAdmin reports whether address is one of the admin addresses
+func (a AdminConfig) IsAdmin(address string) bool {
+	for _, admin := range a.Addresses {
+		if address != "" && strings.EqualFold(admin, address) {
+			return true
+		}
+	}
+	return false
+}
+
+type IndexerConfig struct {
+	Enabled       bool
+	StartBlock    uint64
+	BatchSize     uint64
+	PollInterval  time.Duration
+	Confirmations uint64
+	ReorgWindow   uint64
+}
+
+type TrackerConfig struct {
+	Enabled       bool
+	PollInterval  time.Duration
+	Confirmations uint64
+	DropTimeout   time.Duration // How long an unknown transaction may stay unmined before it is dropped
+	StuckTimeout  time.Duration // How long a server-signed transaction may stay unmined before its fees are bumped (0 = never)
+}
+
+type KeeperConfig struct {
+	Enabled      bool
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int           // Failed checkAndUnlock transactions per expiry before the keeper gives up (0 = unlimited)
+	RetryBackoff time.Duration // Wait after a failed attempt, doubled after each further failure
+}
+
+type NotificationConfig struct {
+	Enabled         bool
+	PollInterval    time.Duration
+	ReminderOffsets []time.Duration // How long before the heartbeat deadline owners are reminded
+	SMTP            SMTPConfig
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string // Empty = no authentication
+	Password string
+	From     string
+}
+
+type WebhookConfig struct {
+	Enabled       bool
+	PollInterval  time.Duration
+	BatchSize     int
+	Timeout       time.Duration // Per delivery attempt
+	MaxAttempts   int           // Attempts before a delivery is dead-lettered
+	RetryBackoff  time.Duration // Wait after the first failed attempt, doubled after each further failure
+	AllowInsecure bool          // Allow http:// URLs and private or loopback targets (local development only)
+}
+
+type StreamConfig struct {
+	KeepaliveInterval time.Duration // How often idle streams send a comment, so proxies keep them open
+	MaxDuration       time.Duration // Streams are closed after this long and clients reconnect, re-checking access
+}
+
+type RelayerConfig struct {
+	Enabled          bool
+	ForwarderAddress string
+	GasBudget        uint64 // Gas the relayer pays per user per window (0 = unlimited)
+	BudgetWindow     time.Duration
+	MaxDeadline      time.Duration // Furthest a signed request's deadline may be in the future
+}
+
 func Load() *Config {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
@@ -67,7 +189,47 @@ func Load() *Config {
 	chainID, _ := strconv.ParseInt(getEnv("CHAIN_ID", "1337"), 10, 64)
 	rateLimitMax, _ := strconv.Atoi(getEnv("RATE_LIMIT_MAX", "100"))
 	rateLimitWindow, _ := time.ParseDuration(getEnv("RATE_LIMIT_WINDOW", "1m"))
-	jwtExpiresIn, _ := time.ParseDuration(getEnv("JWT_EXPIRES_IN", "24h"))
+	nonceResyncInterval, _ := time.ParseDuration(getEnv("NONCE_RESYNC_INTERVAL", "30s"))
+	nonceStuckTimeout, _ := time.ParseDuration(getEnv("NONCE_STUCK_TIMEOUT", "2m"))
+	gasLimitMultiplier, _ := strconv.ParseFloat(getEnv("GAS_LIMIT_MULTIPLIER", "1.2"), 64)
+	maxGasLimit, _ := strconv.ParseUint(getEnv("MAX_GAS_LIMIT", "8000000"), 10, 64)
+	feeForceLegacy, _ := strconv.ParseBool(getEnv("FEE_FORCE_LEGACY", "false"))
+	feeBumpPercent, _ := strconv.ParseUint(getEnv("FEE_BUMP_PERCENT", "15"), 10, 64)
+	vaultDeployTimeout, _ := time.ParseDuration(getEnv("VAULT_DEPLOY_TIMEOUT", "60s"))
+	jwtExpiresIn, _ := time.ParseDuration(getEnv("JWT_EXPIRES_IN", "15m"))
+	jwtRefreshExpiresIn, _ := time.ParseDuration(getEnv("JWT_REFRESH_EXPIRES_IN", "720h"))
+	legacyLoginEnabled, _ := strconv.ParseBool(getEnv("LEGACY_LOGIN_ENABLED", "true"))
+	indexerEnabled, _ := strconv.ParseBool(getEnv("INDEXER_ENABLED", "true"))
+	indexerStartBlock, _ := strconv.ParseUint(getEnv("INDEXER_START_BLOCK", "0"), 10, 64)
+	indexerBatchSize, _ := strconv.ParseUint(getEnv("INDEXER_BATCH_SIZE", "1000"), 10, 64)
+	indexerPollInterval, _ := time.ParseDuration(getEnv("INDEXER_POLL_INTERVAL", "5s"))
+	indexerConfirmations, _ := strconv.ParseUint(getEnv("INDEXER_CONFIRMATIONS", "2"), 10, 64)
+	indexerReorgWindow, _ := strconv.ParseUint(getEnv("INDEXER_REORG_WINDOW", "128"), 10, 64)
+	trackerEnabled, _ := strconv.ParseBool(getEnv("TRACKER_ENABLED", "true"))
+	trackerPollInterval, _ := time.ParseDuration(getEnv("TRACKER_POLL_INTERVAL", "5s"))
+	trackerConfirmations, _ := strconv.ParseUint(getEnv("TRACKER_CONFIRMATIONS", "2"), 10, 64)
+	trackerDropTimeout, _ := time.ParseDuration(getEnv("TRACKER_DROP_TIMEOUT", "10m"))
+	trackerStuckTimeout, _ := time.ParseDuration(getEnv("TRACKER_STUCK_TIMEOUT", "3m"))
+	keeperEnabled, _ := strconv.ParseBool(getEnv("KEEPER_ENABLED", "false"))
+	keeperPollInterval, _ := time.ParseDuration(getEnv("KEEPER_POLL_INTERVAL", "30s"))
+	keeperBatchSize, _ := strconv.Atoi(getEnv("KEEPER_BATCH_SIZE", "50"))
+	keeperMaxAttempts, _ := strconv.Atoi(getEnv("KEEPER_MAX_ATTEMPTS", "5"))
+	keeperRetryBackoff, _ := time.ParseDuration(getEnv("KEEPER_RETRY_BACKOFF", "1m"))
+	notifyEnabled, _ := strconv.ParseBool(getEnv("NOTIFICATIONS_ENABLED", "false"))
+	notifyPollInterval, _ := time.ParseDuration(getEnv("NOTIFICATION_POLL_INTERVAL", "1m"))
+	webhooksEnabled, _ := strconv.ParseBool(getEnv("WEBHOOKS_ENABLED", "false"))
+	webhookPollInterval, _ := time.ParseDuration(getEnv("WEBHOOK_POLL_INTERVAL", "5s"))
+	webhookBatchSize, _ := strconv.Atoi(getEnv("WEBHOOK_BATCH_SIZE", "50"))
+	webhookTimeout, _ := time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	webhookMaxAttempts, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	webhookRetryBackoff, _ := time.ParseDuration(getEnv("WEBHOOK_RETRY_BACKOFF", "30s"))
+	webhookAllowInsecure, _ := strconv.ParseBool(getEnv("WEBHOOK_ALLOW_INSECURE", "false"))
+	streamKeepaliveInterval, _ := time.ParseDuration(getEnv("STREAM_KEEPALIVE_INTERVAL", "15s"))
+	streamMaxDuration, _ := time.ParseDuration(getEnv("STREAM_MAX_DURATION", "1h"))
+	relayerEnabled, _ := strconv.ParseBool(getEnv("RELAYER_ENABLED", "false"))
+	relayerGasBudget, _ := strconv.ParseUint(getEnv("RELAYER_GAS_BUDGET", "1000000"), 10, 64)
+	relayerBudgetWindow, _ := time.ParseDuration(getEnv("RELAYER_BUDGET_WINDOW", "24h"))
+	relayerMaxDeadline, _ := time.ParseDuration(getEnv("RELAYER_MAX_DEADLINE", "1h"))
 
 	return &Config{
 		Server: ServerConfig{
@@ -94,15 +256,94 @@ func Load() *Config {
 			ChainID:             chainID,
 			VaultFactoryAddress: getEnv("VAULT_FACTORY_ADDRESS", ""),
 			PrivateKey:          getEnv("BLOCKCHAIN_PRIVATE_KEY", ""),
+			NonceResyncInterval: nonceResyncInterval,
+			NonceStuckTimeout:   nonceStuckTimeout,
+		},
+		Fees: FeeConfig{
+			GasLimitMultiplier:   gasLimitMultiplier,
+			MaxGasLimit:          maxGasLimit,
+			MaxFeePerGas:         getEnvWei("MAX_FEE_PER_GAS"),
+			MaxPriorityFeePerGas: getEnvWei("MAX_PRIORITY_FEE_PER_GAS"),
+			ForceLegacy:          feeForceLegacy,
+			BumpPercent:          feeBumpPercent,
+		},
+		Vaults: VaultCreationConfig{
+			Mode:          getEnv("VAULT_CREATION_MODE", VaultCreationModeVerify),
+			DeployTimeout: vaultDeployTimeout,
 		},
 		JWT: JWTConfig{
-			Secret:    getEnv("JWT_SECRET", "change-me-in-production"),
-			ExpiresIn: jwtExpiresIn,
+			SigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+			VerificationKeyFiles: getEnvList("JWT_VERIFICATION_KEY_FILES"),
+			ExpiresIn:            jwtExpiresIn,
+			RefreshExpiresIn:     jwtRefreshExpiresIn,
+		},
+		SIWE: SIWEConfig{
+			Domain:      getEnv("SIWE_DOMAIN", "localhost:3000"),
+			URI:         getEnv("SIWE_URI", "http://localhost:3000"),
+			Statement:   getEnv("SIWE_STATEMENT", "Sign in to LegacyChain."),
+			LegacyLogin: legacyLoginEnabled,
 		},
 		RateLimit: RateLimitConfig{
 			Max:    rateLimitMax,
 			Window: rateLimitWindow,
 		},
+		Admin: AdminConfig{
+			Addresses: getEnvList("ADMIN_ADDRESSES"),
+		},
+		Indexer: IndexerConfig{
+			Enabled:       indexerEnabled,
+			StartBlock:    indexerStartBlock,
+			BatchSize:     indexerBatchSize,
+			PollInterval:  indexerPollInterval,
+			Confirmations: indexerConfirmations,
+			ReorgWindow:   indexerReorgWindow,
+		},
+		Tracker: TrackerConfig{
+			Enabled:       trackerEnabled,
+			PollInterval:  trackerPollInterval,
+			Confirmations: trackerConfirmations,
+			DropTimeout:   trackerDropTimeout,
+			StuckTimeout:  trackerStuckTimeout,
+		},
+		Keeper: KeeperConfig{
+			Enabled:      keeperEnabled,
+			PollInterval: keeperPollInterval,
+			BatchSize:    keeperBatchSize,
+			MaxAttempts:  keeperMaxAttempts,
+			RetryBackoff: keeperRetryBackoff,
+		},
+		Notifications: NotificationConfig{
+			Enabled:         notifyEnabled,
+			PollInterval:    notifyPollInterval,
+			ReminderOffsets: getEnvDurations("HEARTBEAT_REMINDER_OFFSETS", "168h,24h,1h"),
+			SMTP: SMTPConfig{
+				Host:     getEnv("SMTP_HOST", "localhost"),
+				Port:     getEnv("SMTP_PORT", "587"),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+				From:     getEnv("SMTP_FROM", "noreply@legacychain.local"),
+			},
+		},
+		Webhooks: WebhookConfig{
+			Enabled:       webhooksEnabled,
+			PollInterval:  webhookPollInterval,
+			BatchSize:     webhookBatchSize,
+			Timeout:       webhookTimeout,
+			MaxAttempts:   webhookMaxAttempts,
+			RetryBackoff:  webhookRetryBackoff,
+			AllowInsecure: webhookAllowInsecure,
+		},
+		Stream: StreamConfig{
+			KeepaliveInterval: streamKeepaliveInterval,
+			MaxDuration:       streamMaxDuration,
+		},
+		Relayer: RelayerConfig{
+			Enabled:          relayerEnabled,
+			ForwarderAddress: getEnv("FORWARDER_ADDRESS", ""),
+			GasBudget:        relayerGasBudget,
+			BudgetWindow:     relayerBudgetWindow,
+			MaxDeadline:      relayerMaxDeadline,
+		},
 	}
 }
 
@@ -112,3 +353,34 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated value, skipping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvDurations parses a comma-separated list of durations, skipping invalid ones
+func getEnvDurations(key, defaultValue string) []time.Duration {
+	var durations []time.Duration
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if d, err := time.ParseDuration(strings.TrimSpace(value)); err == nil && d > 0 {
+			durations = append(durations, d)
+		}
+	}
+	return durations
+}
+
+// getEnvWei parses a wei amount, returning nil if it is unset or invalid
+func getEnvWei(key string) *big.Int {
+	value, ok := new(big.Int).SetString(getEnv(key, ""), 10)
+	if !ok || value.Sign() <= 0 {
+		return nil
+	}
+	return value
+}
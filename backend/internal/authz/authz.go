@@ -0,0 +1,131 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/models"
+)
+
+// Relation is how a caller relates to a vault
+type Relation string
+
+const (
+	RelationNone  Relation = ""      // Neither owner nor heir
+	RelationOwner Relation = "owner" // Owns the vault
+	RelationHeir  Relation = "heir"  // Is one of the vault's heirs
+)
+
+// ErrVaultNotFound is returned for vault IDs that do not exist
+var ErrVaultNotFound = errors.New("vault not found")
+
+// Access is a vault together with the caller's relation to it
+type Access struct {
+	Vault    *models.Vault
+	Relation Relation
+	Heir     *models.Heir // The caller's heir entry, for heirs
+}
+
+// IsOwner reports whether the caller owns the vault
+func (a *Access) IsOwner() bool {
+	return a.Relation == RelationOwner
+}
+
+// IsHeir reports whether the caller is an heir of the vault
+func (a *Access) IsHeir() bool {
+	return a.Relation == RelationHeir
+}
+
+// Authorizer decides how callers relate to vaults, so every handler answers
+// "may this wallet see or act on this vault" the same way
+type Authorizer struct {
+	vaults repository.VaultRepository
+	heirs  repository.HeirRepository
+}
+
+// New creates a new Authorizer
+func New(vaults repository.VaultRepository, heirs repository.HeirRepository) *Authorizer {
+	return &Authorizer{
+		vaults: vaults,
+		heirs:  heirs,
+	}
+}
+
+// Vault loads a vault with its owner, and the given relationships, and
+// determines address's relation to it. Addresses compare case-insensitively.
+// An owner who is also listed as an heir counts as the owner.
+func (a *Authorizer) Vault(ctx context.Context, address string, vaultID uuid.UUID, preload ...string) (*Access, error) {
+	vault, err := a.vaults.FindByID(ctx, vaultID, preload...)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrVaultNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	access := &Access{Vault: vault}
+	if address != "" && strings.EqualFold(vault.Owner.Address, address) {
+		access.Relation = RelationOwner
+		return access, nil
+	}
+
+	heir, err := a.heirs.FindByVaultAndAddress(ctx, vault.ID, address)
+	if err == nil {
+		access.Relation = RelationHeir
+		access.Heir = heir
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	return access, nil
+}
+
+// RedactVault removes from the vault, and the relationships loaded with it,
+// what the caller may not see. Owners see everything. Heirs do not see the
+// owner's contact details, the other heirs, or heartbeat nonces.
+func (a *Access) RedactVault() {
+	if a.IsOwner() {
+		return
+	}
+
+	vault := a.Vault
+	vault.Owner.Email = ""
+	vault.Owner.Roles = nil
+	vault.Heirs = a.RedactHeirs(vault.Heirs)
+	vault.Heartbeats = a.RedactHeartbeats(vault.Heartbeats)
+}
+
+// RedactHeirs returns the heirs the caller may see: all of them for the
+// owner, only their own entry for an heir, whose share is between them and
+// the owner
+func (a *Access) RedactHeirs(heirs []models.Heir) []models.Heir {
+	if a.IsOwner() {
+		return heirs
+	}
+
+	visible := []models.Heir{}
+	for _, heir := range heirs {
+		if a.Heir != nil && heir.ID == a.Heir.ID {
+			visible = append(visible, heir)
+		}
+	}
+	return visible
+}
+
+// RedactHeartbeats returns heartbeats without their nonces, unless the caller
+// is the owner. Nonces are the owner's commit-reveal secrets.
+func (a *Access) RedactHeartbeats(heartbeats []models.Heartbeat) []models.Heartbeat {
+	if a.IsOwner() || heartbeats == nil {
+		return heartbeats
+	}
+
+	redacted := make([]models.Heartbeat, len(heartbeats))
+	for i, heartbeat := range heartbeats {
+		heartbeat.Nonce = ""
+		redacted[i] = heartbeat
+	}
+	return redacted
+}
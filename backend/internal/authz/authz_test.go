@@ -0,0 +1,78 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testVault() *models.Vault {
+	return &models.Vault{
+		ID: uuid.New(),
+		Owner: models.User{
+			Address: "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+			Email:   "owner@example.com",
+			Roles:   models.Roles{models.RoleSupport},
+		},
+		Heirs: []models.Heir{
+			{ID: uuid.New(), Address: "0x0000000000000000000000000000000000000001", ShareBPS: 6000},
+			{ID: uuid.New(), Address: "0x0000000000000000000000000000000000000002", ShareBPS: 4000},
+		},
+		Heartbeats: []models.Heartbeat{
+			{ID: uuid.New(), Nonce: "secret-1"},
+			{ID: uuid.New(), Nonce: "secret-2"},
+		},
+	}
+}
+
+func TestRedactVaultForOwner(t *testing.T) {
+	vault := testVault()
+	access := &Access{Vault: vault, Relation: RelationOwner}
+
+	access.RedactVault()
+	assert.Equal(t, "owner@example.com", vault.Owner.Email)
+	assert.Len(t, vault.Heirs, 2)
+	assert.Equal(t, "secret-1", vault.Heartbeats[0].Nonce)
+}
+
+func TestRedactVaultForHeir(t *testing.T) {
+	vault := testVault()
+	heir := vault.Heirs[1]
+	access := &Access{Vault: vault, Relation: RelationHeir, Heir: &heir}
+
+	access.RedactVault()
+	assert.Empty(t, vault.Owner.Email)
+	assert.Empty(t, vault.Owner.Roles)
+	assert.Equal(t, "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", vault.Owner.Address)
+
+	// Only the heir's own entry, and so only their own share
+	require.Len(t, vault.Heirs, 1)
+	assert.Equal(t, heir.ID, vault.Heirs[0].ID)
+	assert.Equal(t, 4000, vault.Heirs[0].ShareBPS)
+
+	require.Len(t, vault.Heartbeats, 2)
+	for _, heartbeat := range vault.Heartbeats {
+		assert.Empty(t, heartbeat.Nonce)
+		assert.NotEqual(t, uuid.Nil, heartbeat.ID)
+	}
+}
+
+func TestRedactHeartbeatsLeavesInputAlone(t *testing.T) {
+	heartbeats := testVault().Heartbeats
+	access := &Access{Relation: RelationHeir}
+
+	redacted := access.RedactHeartbeats(heartbeats)
+	assert.Empty(t, redacted[0].Nonce)
+	assert.Equal(t, "secret-1", heartbeats[0].Nonce)
+}
+
+func TestRedactHeirsWithoutHeirEntry(t *testing.T) {
+	access := &Access{Relation: RelationNone}
+
+	assert.Empty(t, access.RedactHeirs(testVault().Heirs))
+	assert.False(t, access.IsOwner())
+	assert.False(t, access.IsHeir())
+}
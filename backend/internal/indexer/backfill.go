@@ -0,0 +1,439 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"gorm.io/gorm"
+)
+
+// BackfillProgress reports how far a backfill has walked
+type BackfillProgress struct {
+	From              uint64
+	To                uint64
+	Current           uint64
+	VaultsCreated     int
+	HeartbeatsCreated int
+	LedgerEntries     int
+	VaultsReconciled  int
+}
+
+// Backfiller imports vaults created before the indexer started. It walks the
+// factory and vault event iterators over a block range, creates the missing
+// vault, heir, heartbeat and ledger rows, then reconciles every vault's derived state
+// with the chain at the end of the range. Every step is idempotent, so a
+// backfill can be re-run over the same range.
+type Backfiller struct {
+	ix         *Indexer
+	db         *gorm.DB
+	blockchain service.BlockchainService
+	factory    *bindings.VaultFactoryFilterer
+	chunkSize  uint64
+}
+
+// NewBackfiller creates a new Backfiller walking chunkSize blocks at a time
+func NewBackfiller(db *gorm.DB, blockchain service.BlockchainService, cfg *config.Config, chunkSize uint64) (*Backfiller, error) {
+	// Historical events are not pushed to live streams
+	ix, err := New(db, blockchain, nil, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	factory, err := bindings.NewVaultFactoryFilterer(blockchain.VaultFactoryAddress(), blockchain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind VaultFactory filterer: %w", err)
+	}
+
+	if chunkSize == 0 {
+		chunkSize = cfg.Indexer.BatchSize
+	}
+
+	return &Backfiller{
+		ix:         ix,
+		db:         db,
+		blockchain: blockchain,
+		factory:    factory,
+		chunkSize:  chunkSize,
+	}, nil
+}
+
+// DefaultRange returns the range a backfill should cover when none is given:
+// up to the indexer cursor if the indexer has run, otherwise up to the latest
+// confirmed block
+func (b *Backfiller) DefaultRange(ctx context.Context) (from, to uint64, err error) {
+	var cursor models.IndexerCursor
+	err = b.db.Where("name = ?", CursorName).First(&cursor).Error
+	if err == nil {
+		return 0, cursor.BlockNumber, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, 0, fmt.Errorf("failed to load indexer cursor: %w", err)
+	}
+
+	head, err := b.blockchain.BlockNumber(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	if head < b.ix.cfg.Confirmations {
+		return 0, 0, nil
+	}
+
+	return 0, head - b.ix.cfg.Confirmations, nil
+}
+
+// Run backfills blocks [from, to], calling progress after every chunk
+func (b *Backfiller) Run(ctx context.Context, from, to uint64, progress func(BackfillProgress)) error {
+	if from > to {
+		return fmt.Errorf("invalid block range: %d > %d", from, to)
+	}
+
+	p := BackfillProgress{From: from, To: to}
+
+	for start := from; start <= to; start += b.chunkSize {
+		end := start + b.chunkSize - 1
+		if end > to || end < start {
+			end = to
+		}
+
+		created, err := b.importVaults(ctx, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to import vaults in blocks %d-%d: %w", start, end, err)
+		}
+		p.VaultsCreated += created
+
+		heartbeats, err := b.importHeartbeats(ctx, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to import heartbeats in blocks %d-%d: %w", start, end, err)
+		}
+		p.HeartbeatsCreated += heartbeats
+
+		entries, err := b.importLedger(ctx, start, end)
+		if err != nil {
+			return fmt.Errorf("failed to import ledger in blocks %d-%d: %w", start, end, err)
+		}
+		p.LedgerEntries += entries
+
+		p.Current = end
+		if progress != nil {
+			progress(p)
+		}
+
+		if end == to {
+			break
+		}
+	}
+
+	reconciled, err := b.reconcile(ctx, to)
+	if err != nil {
+		return err
+	}
+	p.VaultsReconciled = reconciled
+	if progress != nil {
+		progress(p)
+	}
+
+	// Let the live indexer continue from here if it has never run
+	var cursors int64
+	if err := b.db.Model(&models.IndexerCursor{}).Where("name = ?", CursorName).Count(&cursors).Error; err != nil {
+		return fmt.Errorf("failed to load indexer cursor: %w", err)
+	}
+	if cursors == 0 {
+		cursor := models.IndexerCursor{Name: CursorName, BlockNumber: to}
+		if err := b.db.Create(&cursor).Error; err != nil {
+			return fmt.Errorf("failed to save indexer cursor: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// importVaults creates the vaults deployed by the factory in [start, end]
+func (b *Backfiller) importVaults(ctx context.Context, start, end uint64) (int, error) {
+	it, err := b.factory.FilterVaultCreated(&bind.FilterOpts{Start: start, End: &end, Context: ctx}, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	var logs []types.Log
+	for it.Next() {
+		logs = append(logs, it.Event.Raw)
+	}
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+
+	vaultIDs, err := b.ix.assignVaultIDs(ctx, logs)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		for _, vLog := range logs {
+			_, isNew, err := b.ix.handleVaultCreated(ctx, tx, vLog, vaultIDs[keyOf(vLog)])
+			if err != nil {
+				return err
+			}
+			if isNew {
+				created++
+			}
+		}
+		return nil
+	})
+
+	return created, err
+}
+
+// importHeartbeats records the revealed heartbeats of every known vault in [start, end]
+func (b *Backfiller) importHeartbeats(ctx context.Context, start, end uint64) (int, error) {
+	var vaults []models.Vault
+	if err := b.db.Find(&vaults).Error; err != nil {
+		return 0, fmt.Errorf("failed to load vaults: %w", err)
+	}
+
+	created := 0
+	for _, vault := range vaults {
+		filterer, err := bindings.NewIndividualVaultFilterer(common.HexToAddress(vault.ContractAddress), b.blockchain)
+		if err != nil {
+			return created, fmt.Errorf("failed to bind vault filterer: %w", err)
+		}
+
+		it, err := filterer.FilterHeartbeat(&bind.FilterOpts{Start: start, End: &end, Context: ctx})
+		if err != nil {
+			return created, err
+		}
+
+		for it.Next() {
+			isNew, err := b.importHeartbeat(&vault, it.Event)
+			if err != nil {
+				it.Close()
+				return created, err
+			}
+			if isNew {
+				created++
+			}
+		}
+		err = it.Error()
+		it.Close()
+		if err != nil {
+			return created, err
+		}
+	}
+
+	return created, nil
+}
+
+// importHeartbeat creates or completes the heartbeat row for a Heartbeat event
+func (b *Backfiller) importHeartbeat(vault *models.Vault, event *bindings.IndividualVaultHeartbeat) (bool, error) {
+	timestamp := time.Unix(event.Timestamp.Int64(), 0)
+	commitHash := common.Hash(event.Commitment).Hex()
+
+	var heartbeat models.Heartbeat
+	err := b.db.Where("vault_id = ? AND commit_hash = ?", vault.ID, commitHash).First(&heartbeat).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		heartbeat = models.Heartbeat{
+			VaultID:      vault.ID,
+			CommitHash:   commitHash,
+			RevealTxHash: event.Raw.TxHash.Hex(),
+			Status:       models.HeartbeatStatusRevealed,
+			CommittedAt:  timestamp,
+			RevealedAt:   &timestamp,
+		}
+		if err := b.db.Create(&heartbeat).Error; err != nil {
+			return false, fmt.Errorf("failed to create heartbeat: %w", err)
+		}
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to query heartbeat: %w", err)
+	case heartbeat.Status != models.HeartbeatStatusRevealed:
+		if err := b.db.Model(&heartbeat).Updates(map[string]interface{}{
+			"status":         models.HeartbeatStatusRevealed,
+			"reveal_tx_hash": event.Raw.TxHash.Hex(),
+			"revealed_at":    timestamp,
+		}).Error; err != nil {
+			return false, fmt.Errorf("failed to update heartbeat: %w", err)
+		}
+	}
+
+	return false, nil
+}
+
+// importLedger records the deposits, withdrawals and claims of every known
+// vault in [start, end]
+func (b *Backfiller) importLedger(ctx context.Context, start, end uint64) (int, error) {
+	var vaults []models.Vault
+	if err := b.db.Find(&vaults).Error; err != nil {
+		return 0, fmt.Errorf("failed to load vaults: %w", err)
+	}
+
+	blockTimes := make(map[uint64]time.Time)
+	created := 0
+	for _, vault := range vaults {
+		filterer, err := bindings.NewIndividualVaultFilterer(common.HexToAddress(vault.ContractAddress), b.blockchain)
+		if err != nil {
+			return created, fmt.Errorf("failed to bind vault filterer: %w", err)
+		}
+		opts := &bind.FilterOpts{Start: start, End: &end, Context: ctx}
+
+		var movements []ledgerMovement
+
+		deposits, err := filterer.FilterDeposited(opts, nil)
+		if err != nil {
+			return created, err
+		}
+		for deposits.Next() {
+			movements = append(movements, ledgerMovement{models.LedgerEntryDeposit, deposits.Event.From, deposits.Event.Amount, deposits.Event.Raw})
+		}
+		err = deposits.Error()
+		deposits.Close()
+		if err != nil {
+			return created, err
+		}
+
+		withdrawals, err := filterer.FilterWithdrawn(opts, nil)
+		if err != nil {
+			return created, err
+		}
+		for withdrawals.Next() {
+			movements = append(movements, ledgerMovement{models.LedgerEntryWithdrawal, withdrawals.Event.Owner, withdrawals.Event.Amount, withdrawals.Event.Raw})
+		}
+		err = withdrawals.Error()
+		withdrawals.Close()
+		if err != nil {
+			return created, err
+		}
+
+		claims, err := filterer.FilterInheritanceClaimed(opts, nil)
+		if err != nil {
+			return created, err
+		}
+		for claims.Next() {
+			movements = append(movements, ledgerMovement{models.LedgerEntryClaim, claims.Event.Heir, claims.Event.Amount, claims.Event.Raw})
+		}
+		err = claims.Error()
+		claims.Close()
+		if err != nil {
+			return created, err
+		}
+
+		for _, m := range movements {
+			timestamp, ok := blockTimes[m.log.BlockNumber]
+			if !ok {
+				timestamp, err = b.ix.blockTime(ctx, m.log)
+				if err != nil {
+					return created, err
+				}
+				blockTimes[m.log.BlockNumber] = timestamp
+			}
+
+			isNew, err := createLedgerEntry(b.db, &vault, m.kind, m.addr, m.amount, m.log, timestamp)
+			if err != nil {
+				return created, err
+			}
+			if isNew {
+				created++
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// ledgerMovement is a fund movement read from a vault event iterator
+type ledgerMovement struct {
+	kind   models.LedgerEntryKind
+	addr   common.Address
+	amount *big.Int
+	log    types.Log
+}
+
+// reconcile overwrites the derived vault and heir columns with the on-chain
+// state at the given block, which is where the live indexer picks up
+func (b *Backfiller) reconcile(ctx context.Context, block uint64) (int, error) {
+	var vaults []models.Vault
+	if err := b.db.Preload("Heirs").Find(&vaults).Error; err != nil {
+		return 0, fmt.Errorf("failed to load vaults: %w", err)
+	}
+
+	reconciled := 0
+	for _, vault := range vaults {
+		state, err := b.blockchain.GetVaultState(ctx, common.HexToAddress(vault.ContractAddress), new(big.Int).SetUint64(block))
+		if err != nil {
+			return reconciled, fmt.Errorf("failed to read vault %s: %w", vault.ContractAddress, err)
+		}
+
+		// Not deployed yet at this block
+		if state.Config.Owner == (common.Address{}) {
+			continue
+		}
+
+		if err := b.db.Transaction(func(tx *gorm.DB) error {
+			return applyVaultState(tx, &vault, state)
+		}); err != nil {
+			return reconciled, fmt.Errorf("failed to reconcile vault %s: %w", vault.ContractAddress, err)
+		}
+		reconciled++
+	}
+
+	return reconciled, nil
+}
+
+// applyVaultState writes an on-chain vault state to the vault and its heirs
+func applyVaultState(tx *gorm.DB, vault *models.Vault, state *service.VaultState) error {
+	cfg := state.Config
+	lastHeartbeat := time.Unix(cfg.LastHeartbeat.Int64(), 0)
+
+	updates := map[string]interface{}{
+		"status":               models.VaultStatusLocked,
+		"balance":              state.Balance.String(),
+		"paused":               state.Paused,
+		"last_heartbeat":       lastHeartbeat,
+		"unlocked_at":          nil,
+		"grace_period_ends_at": nil,
+	}
+
+	if !cfg.IsLocked {
+		// checkAndUnlock sets unlockTime = block.timestamp + gracePeriod
+		graceEndsAt := time.Unix(cfg.UnlockTime.Int64(), 0)
+		unlockedAt := time.Unix(cfg.UnlockTime.Int64()-cfg.GracePeriod.Int64(), 0)
+
+		updates["status"] = models.VaultStatusUnlocked
+		updates["unlocked_at"] = unlockedAt
+		updates["grace_period_ends_at"] = graceEndsAt
+
+		allClaimed := len(cfg.Heirs) > 0
+		for _, heir := range cfg.Heirs {
+			allClaimed = allClaimed && state.HeirClaimed[heir]
+		}
+		if allClaimed {
+			updates["status"] = models.VaultStatusClaimed
+		}
+	}
+
+	if err := updateVault(tx, vault, updates); err != nil {
+		return err
+	}
+
+	for _, heir := range cfg.Heirs {
+		if err := updateHeir(tx, vault, heir, map[string]interface{}{
+			"has_approved": state.HeirApproved[heir],
+			"has_claimed":  state.HeirClaimed[heir],
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/dbtest"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var otherVaultAddress = common.HexToAddress("0x0000000000000000000000000000000000000B02")
+
+// newTestBackfiller returns a backfiller over a fresh database and chain
+func newTestBackfiller(t *testing.T, cfg *config.Config, chunkSize uint64) (*Backfiller, *fakeChain, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t)
+	chain := newFakeChain()
+
+	backfiller, err := NewBackfiller(db, chain, cfg, chunkSize)
+	require.NoError(t, err)
+	return backfiller, chain, db
+}
+
+// lockedState is the on-chain state of a locked vault created by
+// createVaultLog, with a balance and last heartbeat
+func (f *fakeChain) lockedState(vault common.Address, balance int64, lastHeartbeat time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vaultConfig := *f.vaults[vault]
+	vaultConfig.LastHeartbeat = big.NewInt(lastHeartbeat.Unix())
+	vaultConfig.UnlockTime = big.NewInt(0)
+	f.states[vault] = &service.VaultState{
+		Config:       &vaultConfig,
+		Balance:      big.NewInt(balance),
+		HeirApproved: map[common.Address]bool{},
+		HeirClaimed:  map[common.Address]bool{},
+	}
+}
+
+// mineHistory mines two vaults with a heartbeat and fund movements, eight
+// blocks in total
+func mineHistory(t *testing.T, chain *fakeChain) {
+	t.Helper()
+	heartbeatAt := blockTimeOf(4)
+
+	chain.mineEmpty(1)
+	chain.mine(chain.createVaultLog(t, testVaultAddress, ownerAddress, 0, heirAddress))
+	chain.mineEmpty(1)
+	chain.mine(vaultLog(t, testVaultAddress, "Heartbeat", big.NewInt(heartbeatAt.Unix()), [32]byte(crypto.Keccak256Hash([]byte("commit")))))
+	chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(1000)))
+	chain.mineEmpty(1)
+	chain.mine(chain.createVaultLog(t, otherVaultAddress, ownerAddress, 1, coHeirAddress))
+	chain.mine(vaultLog(t, testVaultAddress, "Withdrawn", ownerAddress, big.NewInt(400)))
+
+	chain.lockedState(testVaultAddress, 600, heartbeatAt)
+	chain.lockedState(otherVaultAddress, 0, blockTimeOf(7))
+}
+
+func TestBackfillWalksRangeInChunks(t *testing.T) {
+	backfiller, chain, db := newTestBackfiller(t, testConfig(), 3)
+	mineHistory(t, chain)
+
+	var reports []BackfillProgress
+	require.NoError(t, backfiller.Run(context.Background(), 1, 8, func(p BackfillProgress) {
+		reports = append(reports, p)
+	}))
+
+	// Every filter request stays inside a chunk
+	for _, r := range chain.ranges {
+		assert.Contains(t, [][2]uint64{{1, 3}, {4, 6}, {7, 8}}, r)
+	}
+
+	require.Len(t, reports, 4, "one report per chunk and one after reconciling")
+	assert.Equal(t, []uint64{3, 6, 8, 8}, []uint64{reports[0].Current, reports[1].Current, reports[2].Current, reports[3].Current})
+	assert.Equal(t, 1, reports[0].VaultsCreated)
+	assert.Equal(t, 1, reports[1].HeartbeatsCreated)
+	assert.Equal(t, 1, reports[1].LedgerEntries)
+
+	last := reports[3]
+	assert.Equal(t, BackfillProgress{
+		From:              1,
+		To:                8,
+		Current:           8,
+		VaultsCreated:     2,
+		HeartbeatsCreated: 1,
+		LedgerEntries:     2,
+		VaultsReconciled:  2,
+	}, last)
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, int64(1), vault.VaultID)
+	assert.Equal(t, "600", vault.Balance)
+	require.NotNil(t, vault.LastHeartbeat)
+	assert.True(t, blockTimeOf(4).Equal(*vault.LastHeartbeat))
+	assert.Len(t, ledger(t, db, vault), 2)
+	assert.Equal(t, int64(2), loadVault(t, db, otherVaultAddress).VaultID)
+
+	var heartbeat models.Heartbeat
+	require.NoError(t, db.Where("vault_id = ?", vault.ID).First(&heartbeat).Error)
+	assert.Equal(t, models.HeartbeatStatusRevealed, heartbeat.Status)
+}
+
+func TestBackfillIsIdempotent(t *testing.T) {
+	backfiller, chain, db := newTestBackfiller(t, testConfig(), 3)
+	mineHistory(t, chain)
+	ctx := context.Background()
+
+	require.NoError(t, backfiller.Run(ctx, 1, 8, nil))
+
+	var last BackfillProgress
+	require.NoError(t, backfiller.Run(ctx, 1, 8, func(p BackfillProgress) { last = p }))
+	assert.Zero(t, last.VaultsCreated)
+	assert.Zero(t, last.HeartbeatsCreated)
+	assert.Zero(t, last.LedgerEntries)
+
+	var vaults, heartbeats int64
+	require.NoError(t, db.Model(&models.Vault{}).Count(&vaults).Error)
+	require.NoError(t, db.Model(&models.Heartbeat{}).Count(&heartbeats).Error)
+	assert.Equal(t, int64(2), vaults)
+	assert.Equal(t, int64(1), heartbeats)
+	assert.Len(t, ledger(t, db, loadVault(t, db, testVaultAddress)), 2)
+}
+
+func TestBackfillRejectsInvalidRange(t *testing.T) {
+	backfiller, _, _ := newTestBackfiller(t, testConfig(), 3)
+	assert.Error(t, backfiller.Run(context.Background(), 5, 4, nil))
+}
+
+func TestBackfillDefaultRange(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.Confirmations = 2
+	backfiller, chain, db := newTestBackfiller(t, cfg, 3)
+	ctx := context.Background()
+	chain.mineEmpty(10)
+
+	// Up to the latest confirmed block before the indexer has run
+	from, to, err := backfiller.DefaultRange(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), from)
+	assert.Equal(t, uint64(8), to)
+
+	// Up to the stored cursor afterwards, where the indexer took over
+	require.NoError(t, db.Create(&models.IndexerCursor{Name: CursorName, BlockNumber: 5}).Error)
+	from, to, err = backfiller.DefaultRange(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), from)
+	assert.Equal(t, uint64(5), to)
+}
+
+func TestIndexerResumesAfterBackfill(t *testing.T) {
+	backfiller, chain, db := newTestBackfiller(t, testConfig(), 3)
+	mineHistory(t, chain)
+	chain.lockedState(testVaultAddress, 1000, blockTimeOf(4)) // Before the withdrawal
+	ctx := context.Background()
+
+	require.NoError(t, backfiller.Run(ctx, 1, 6, nil))
+	assert.Equal(t, "1000", loadVault(t, db, testVaultAddress).Balance)
+	assert.Equal(t, uint64(6), cursor(t, db))
+
+	// The indexer continues after the backfilled range, so the second vault
+	// and the withdrawal are indexed live
+	chain.ranges = nil
+	require.NoError(t, backfiller.ix.Sync(ctx))
+	assert.Equal(t, [][2]uint64{{7, 8}}, chain.ranges)
+	assert.Equal(t, uint64(8), cursor(t, db))
+	assert.Equal(t, int64(2), loadVault(t, db, otherVaultAddress).VaultID)
+	assert.Equal(t, "600", loadVault(t, db, testVaultAddress).Balance)
+
+	// Backfilling an earlier range again leaves the indexer's cursor alone
+	require.NoError(t, backfiller.Run(ctx, 1, 6, nil))
+	assert.Equal(t, uint64(8), cursor(t, db))
+}
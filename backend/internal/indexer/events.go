@@ -0,0 +1,448 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/haneumLee/legacychain/backend/internal/webhook"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// handleVaultCreated records a vault deployed through the factory unless the
+// API already stored it. It returns the vault and whether this call created it.
+func (ix *Indexer) handleVaultCreated(ctx context.Context, tx *gorm.DB, vLog types.Log, vaultID int64) (*models.Vault, bool, error) {
+	event, err := ix.factory.ParseVaultCreated(vLog)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse VaultCreated event: %w", err)
+	}
+
+	var existing models.Vault
+	err = tx.Where("LOWER(contract_address) = LOWER(?)", event.VaultAddress.Hex()).First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	config, err := ix.blockchain.GetVaultConfig(ctx, event.VaultAddress)
+	if err != nil {
+		return nil, false, err
+	}
+
+	owner, err := findOrCreateUser(tx, event.Owner)
+	if err != nil {
+		return nil, false, err
+	}
+
+	createdAt := time.Unix(event.Timestamp.Int64(), 0)
+	vault := models.Vault{
+		VaultID:           vaultID,
+		ContractAddress:   event.VaultAddress.Hex(),
+		OwnerID:           owner.ID,
+		Status:            models.VaultStatusLocked,
+		HeartbeatInterval: config.HeartbeatInterval.Int64(),
+		GracePeriod:       config.GracePeriod.Int64(),
+		RequiredApprovals: int(config.RequiredApprovals.Int64()),
+		LastHeartbeat:     &createdAt,
+	}
+	if err := tx.Create(&vault).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create vault: %w", err)
+	}
+
+	for i, heirAddr := range config.Heirs {
+		heir := models.Heir{
+			VaultID:  vault.ID,
+			Address:  heirAddr.Hex(),
+			ShareBPS: int(config.HeirShares[i].Int64()),
+		}
+		if err := tx.Create(&heir).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to create heir: %w", err)
+		}
+	}
+
+	return &vault, true, nil
+}
+
+// handleHeartbeat marks the matching commit as revealed and refreshes the
+// vault's heartbeat
+func (ix *Indexer) handleHeartbeat(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseHeartbeat(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse Heartbeat event: %w", err)
+	}
+
+	timestamp := time.Unix(event.Timestamp.Int64(), 0)
+	commitHash := common.Hash(event.Commitment).Hex()
+
+	var heartbeat models.Heartbeat
+	err = tx.Where("vault_id = ? AND commit_hash = ?", vault.ID, commitHash).First(&heartbeat).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Revealed directly from the owner's wallet, not through the API
+		heartbeat = models.Heartbeat{
+			VaultID:      vault.ID,
+			CommitHash:   commitHash,
+			RevealTxHash: vLog.TxHash.Hex(),
+			Status:       models.HeartbeatStatusRevealed,
+			CommittedAt:  timestamp,
+			RevealedAt:   &timestamp,
+		}
+		if err := tx.Create(&heartbeat).Error; err != nil {
+			return fmt.Errorf("failed to create heartbeat: %w", err)
+		}
+	case err != nil:
+		return err
+	case heartbeat.Status != models.HeartbeatStatusRevealed:
+		if err := tx.Model(&heartbeat).Updates(map[string]interface{}{
+			"status":         models.HeartbeatStatusRevealed,
+			"reveal_tx_hash": vLog.TxHash.Hex(),
+			"revealed_at":    timestamp,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update heartbeat: %w", err)
+		}
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{
+		"last_heartbeat": timestamp,
+		"status":         models.VaultStatusLocked,
+	}); err != nil {
+		return err
+	}
+
+	return ix.emit(tx, vault, models.WebhookEventHeartbeatRevealed, vLog, map[string]interface{}{
+		"commitment": commitHash,
+		"timestamp":  timestamp.UTC(),
+	})
+}
+
+// handleVaultUnlocked marks the vault as unlocked once checkAndUnlock succeeds
+func (ix *Indexer) handleVaultUnlocked(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	if _, err := ix.vault.ParseVaultUnlocked(vLog); err != nil {
+		return fmt.Errorf("failed to parse VaultUnlocked event: %w", err)
+	}
+
+	unlockedAt, err := ix.blockTime(ctx, vLog)
+	if err != nil {
+		return err
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{
+		"status":      models.VaultStatusUnlocked,
+		"unlocked_at": unlockedAt,
+	}); err != nil {
+		return err
+	}
+
+	return ix.emit(tx, vault, models.WebhookEventVaultUnlocked, vLog, map[string]interface{}{
+		"unlocked_at": unlockedAt.UTC(),
+	})
+}
+
+// handleGracePeriodStarted records when heirs may start claiming
+func (ix *Indexer) handleGracePeriodStarted(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseGracePeriodStarted(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse GracePeriodStarted event: %w", err)
+	}
+
+	endsAt := time.Unix(event.EndTime.Int64(), 0)
+	if err := updateVault(tx, vault, map[string]interface{}{
+		"grace_period_ends_at": endsAt,
+	}); err != nil {
+		return err
+	}
+
+	return ix.emit(tx, vault, models.WebhookEventGracePeriodStarted, vLog, map[string]interface{}{
+		"grace_period_ends_at": endsAt.UTC(),
+	})
+}
+
+// handleUnlockCancelled relocks the vault and resets heir approvals, mirroring
+// revealHeartbeat during the grace period
+func (ix *Indexer) handleUnlockCancelled(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	if _, err := ix.vault.ParseUnlockCancelled(vLog); err != nil {
+		return fmt.Errorf("failed to parse UnlockCancelled event: %w", err)
+	}
+
+	if err := tx.Model(&models.Heir{}).Where("vault_id = ?", vault.ID).
+		Update("has_approved", false).Error; err != nil {
+		return fmt.Errorf("failed to reset heir approvals: %w", err)
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{
+		"status":               models.VaultStatusLocked,
+		"unlocked_at":          nil,
+		"grace_period_ends_at": nil,
+	}); err != nil {
+		return err
+	}
+
+	ix.publish(vault, "vault.unlock_cancelled", vLog, nil)
+	return nil
+}
+
+// handleInheritanceApproved records an heir's approval
+func (ix *Indexer) handleInheritanceApproved(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseInheritanceApproved(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse InheritanceApproved event: %w", err)
+	}
+
+	if err := updateHeir(tx, vault, event.Heir, map[string]interface{}{
+		"has_approved": true,
+	}); err != nil {
+		return err
+	}
+
+	return ix.emit(tx, vault, models.WebhookEventInheritanceApproved, vLog, map[string]interface{}{
+		"heir": event.Heir.Hex(),
+	})
+}
+
+// handleInheritanceClaimed records an heir's claim and marks the vault as
+// claimed once every heir has been paid out
+func (ix *Indexer) handleInheritanceClaimed(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseInheritanceClaimed(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse InheritanceClaimed event: %w", err)
+	}
+
+	if err := updateHeir(tx, vault, event.Heir, map[string]interface{}{
+		"has_claimed": true,
+	}); err != nil {
+		return err
+	}
+
+	if err := ix.recordLedger(ctx, tx, vault, models.LedgerEntryClaim, event.Heir, event.Amount, vLog); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"balance": gorm.Expr("balance - ?", event.Amount.String()),
+	}
+
+	var unclaimed int64
+	if err := tx.Model(&models.Heir{}).Where("vault_id = ? AND has_claimed = ?", vault.ID, false).
+		Count(&unclaimed).Error; err != nil {
+		return fmt.Errorf("failed to count unclaimed heirs: %w", err)
+	}
+	if unclaimed == 0 {
+		updates["status"] = models.VaultStatusClaimed
+	}
+
+	if err := updateVault(tx, vault, updates); err != nil {
+		return err
+	}
+
+	return ix.emit(tx, vault, models.WebhookEventInheritanceClaimed, vLog, map[string]interface{}{
+		"heir":          event.Heir.Hex(),
+		"amount":        event.Amount.String(),
+		"fully_claimed": unclaimed == 0,
+	})
+}
+
+// handleDeposited adds a deposit to the vault balance and ledger
+func (ix *Indexer) handleDeposited(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseDeposited(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse Deposited event: %w", err)
+	}
+
+	if err := ix.recordLedger(ctx, tx, vault, models.LedgerEntryDeposit, event.From, event.Amount, vLog); err != nil {
+		return err
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{
+		"balance": gorm.Expr("balance + ?", event.Amount.String()),
+	}); err != nil {
+		return err
+	}
+
+	ix.publish(vault, "vault.deposited", vLog, map[string]interface{}{
+		"from":   event.From.Hex(),
+		"amount": event.Amount.String(),
+	})
+	return nil
+}
+
+// handleWithdrawn subtracts an owner withdrawal from the vault balance and
+// adds it to the ledger
+func (ix *Indexer) handleWithdrawn(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseWithdrawn(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse Withdrawn event: %w", err)
+	}
+
+	if err := ix.recordLedger(ctx, tx, vault, models.LedgerEntryWithdrawal, event.Owner, event.Amount, vLog); err != nil {
+		return err
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{
+		"balance": gorm.Expr("balance - ?", event.Amount.String()),
+	}); err != nil {
+		return err
+	}
+
+	return ix.emit(tx, vault, models.WebhookEventWithdrawn, vLog, map[string]interface{}{
+		"owner":  event.Owner.Hex(),
+		"amount": event.Amount.String(),
+	})
+}
+
+// handlePaused marks the vault as paused and confirms its audit entry
+func (ix *Indexer) handlePaused(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParsePaused(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse Paused event: %w", err)
+	}
+
+	if err := ix.recordAudit(ctx, tx, vault, models.VaultAuditPause, event.Account, vLog); err != nil {
+		return err
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{"paused": true}); err != nil {
+		return err
+	}
+
+	ix.publish(vault, "vault.paused", vLog, map[string]interface{}{"account": event.Account.Hex()})
+	return nil
+}
+
+// handleUnpaused clears the vault's paused flag and confirms its audit entry
+func (ix *Indexer) handleUnpaused(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error {
+	event, err := ix.vault.ParseUnpaused(vLog)
+	if err != nil {
+		return fmt.Errorf("failed to parse Unpaused event: %w", err)
+	}
+
+	if err := ix.recordAudit(ctx, tx, vault, models.VaultAuditUnpause, event.Account, vLog); err != nil {
+		return err
+	}
+
+	if err := updateVault(tx, vault, map[string]interface{}{"paused": false}); err != nil {
+		return err
+	}
+
+	ix.publish(vault, "vault.unpaused", vLog, map[string]interface{}{"account": event.Account.Hex()})
+	return nil
+}
+
+// recordAudit confirms the audit entry the API created for the transaction, or
+// creates one for a pause sent outside the API
+func (ix *Indexer) recordAudit(ctx context.Context, tx *gorm.DB, vault *models.Vault, action models.VaultAuditAction, actor common.Address, vLog types.Log) error {
+	timestamp, err := ix.blockTime(ctx, vLog)
+	if err != nil {
+		return err
+	}
+
+	blockNumber := vLog.BlockNumber
+	entry := models.VaultAuditLog{
+		VaultID:     vault.ID,
+		Action:      action,
+		Actor:       actor.Hex(),
+		TxHash:      vLog.TxHash.Hex(),
+		BlockNumber: &blockNumber,
+		ConfirmedAt: &timestamp,
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tx_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_number", "confirmed_at"}),
+	}).Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// recordLedger stores the fund movement of a log at its block time
+func (ix *Indexer) recordLedger(ctx context.Context, tx *gorm.DB, vault *models.Vault, kind models.LedgerEntryKind, addr common.Address, amount *big.Int, vLog types.Log) error {
+	timestamp, err := ix.blockTime(ctx, vLog)
+	if err != nil {
+		return err
+	}
+
+	_, err = createLedgerEntry(tx, vault, kind, addr, amount, vLog, timestamp)
+	return err
+}
+
+// createLedgerEntry inserts a ledger entry unless the log was already recorded,
+// e.g. by the backfill
+func createLedgerEntry(tx *gorm.DB, vault *models.Vault, kind models.LedgerEntryKind, addr common.Address, amount *big.Int, vLog types.Log, timestamp time.Time) (bool, error) {
+	entry := models.VaultLedgerEntry{
+		VaultID:     vault.ID,
+		Kind:        kind,
+		Address:     addr.Hex(),
+		Amount:      amount.String(),
+		TxHash:      vLog.TxHash.Hex(),
+		LogIndex:    vLog.Index,
+		BlockNumber: vLog.BlockNumber,
+		Timestamp:   timestamp,
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create ledger entry: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// emit queues webhook deliveries for an applied event and pushes it to live
+// streams, under the same name
+func (ix *Indexer) emit(tx *gorm.DB, vault *models.Vault, eventType models.WebhookEventType, vLog types.Log, data map[string]interface{}) error {
+	if err := webhook.Enqueue(tx, vault, eventType, vLog, data); err != nil {
+		return err
+	}
+
+	ix.publish(vault, string(eventType), vLog, data)
+	return nil
+}
+
+// publish buffers a stream update, sent once the batch is committed
+func (ix *Indexer) publish(vault *models.Vault, updateType string, vLog types.Log, data map[string]interface{}) {
+	ix.updates = append(ix.updates, stream.NewUpdate(updateType, vault.ID, vLog.TxHash.Hex(), data))
+}
+
+func updateVault(tx *gorm.DB, vault *models.Vault, updates map[string]interface{}) error {
+	if err := tx.Model(&models.Vault{}).Where("id = ?", vault.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update vault: %w", err)
+	}
+	return nil
+}
+
+func updateHeir(tx *gorm.DB, vault *models.Vault, heirAddr common.Address, updates map[string]interface{}) error {
+	if err := tx.Model(&models.Heir{}).
+		Where("vault_id = ? AND LOWER(address) = LOWER(?)", vault.ID, heirAddr.Hex()).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update heir: %w", err)
+	}
+	return nil
+}
+
+// findOrCreateUser returns the user for an on-chain address, creating it if
+// the address never logged in
+func findOrCreateUser(tx *gorm.DB, addr common.Address) (*models.User, error) {
+	var user models.User
+	err := tx.Where("LOWER(address) = LOWER(?)", addr.Hex()).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	user = models.User{Address: addr.Hex()}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,326 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var testVaultAddress = common.HexToAddress("0x0000000000000000000000000000000000000B01")
+
+// indexVault creates the test vault with the given heirs through the factory
+// and indexes it
+func indexVault(t *testing.T, ix *Indexer, chain *fakeChain, db *gorm.DB, heirs ...common.Address) models.Vault {
+	t.Helper()
+	chain.mine(chain.createVaultLog(t, testVaultAddress, ownerAddress, 0, heirs...))
+	require.NoError(t, ix.Sync(context.Background()))
+	return loadVault(t, db, testVaultAddress)
+}
+
+// heirOf returns the vault's heir with the address
+func heirOf(t *testing.T, vault models.Vault, address common.Address) models.Heir {
+	t.Helper()
+	for _, heir := range vault.Heirs {
+		if common.HexToAddress(heir.Address) == address {
+			return heir
+		}
+	}
+	t.Fatalf("no heir %s", address.Hex())
+	return models.Heir{}
+}
+
+// ledger returns the vault's ledger entries in chain order
+func ledger(t *testing.T, db *gorm.DB, vault models.Vault) []models.VaultLedgerEntry {
+	t.Helper()
+	var entries []models.VaultLedgerEntry
+	require.NoError(t, db.Where("vault_id = ?", vault.ID).Order("block_number, log_index").Find(&entries).Error)
+	return entries
+}
+
+func TestHandleVaultCreated(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	vault := indexVault(t, ix, chain, db, heirAddress, coHeirAddress)
+
+	assert.Equal(t, int64(1), vault.VaultID)
+	assert.Equal(t, models.VaultStatusLocked, vault.Status)
+	assert.Equal(t, int64(30*24*60*60), vault.HeartbeatInterval)
+	assert.Equal(t, int64(90*24*60*60), vault.GracePeriod)
+	assert.Equal(t, 1, vault.RequiredApprovals)
+	require.NotNil(t, vault.LastHeartbeat)
+	assert.True(t, genesisTime.Equal(*vault.LastHeartbeat))
+
+	require.Len(t, vault.Heirs, 2)
+	assert.Equal(t, 5000, heirOf(t, vault, heirAddress).ShareBPS)
+	assert.Equal(t, 5000, heirOf(t, vault, coHeirAddress).ShareBPS)
+
+	// The owner gets a user row although they never logged in
+	var owner models.User
+	require.NoError(t, db.First(&owner, "id = ?", vault.OwnerID).Error)
+	assert.Equal(t, ownerAddress.Hex(), owner.Address)
+
+	// The event is recorded with the vault it created, for rollbacks
+	var event models.ChainEvent
+	require.NoError(t, db.Where("event_name = ?", "VaultCreated").First(&event).Error)
+	var state undoState
+	require.NoError(t, json.Unmarshal([]byte(event.Undo), &state))
+	require.NotNil(t, state.CreatedVaultID)
+	assert.Equal(t, vault.ID, *state.CreatedVaultID)
+}
+
+func TestHandleVaultCreatedKeepsVaultStoredByAPI(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+
+	owner := models.User{Address: ownerAddress.Hex()}
+	require.NoError(t, db.Create(&owner).Error)
+	stored := models.Vault{
+		VaultID:           1,
+		ContractAddress:   testVaultAddress.Hex(),
+		OwnerID:           owner.ID,
+		HeartbeatInterval: 7 * 24 * 60 * 60,
+		GracePeriod:       30 * 24 * 60 * 60,
+		RequiredApprovals: 1,
+		Status:            models.VaultStatusLocked,
+	}
+	require.NoError(t, db.Create(&stored).Error)
+
+	vault := indexVault(t, ix, chain, db, heirAddress)
+	assert.Equal(t, stored.ID, vault.ID)
+	assert.Equal(t, int64(7*24*60*60), vault.HeartbeatInterval)
+
+	// Rolling the event back must not delete a vault it did not create
+	var event models.ChainEvent
+	require.NoError(t, db.Where("event_name = ?", "VaultCreated").First(&event).Error)
+	var state undoState
+	require.NoError(t, json.Unmarshal([]byte(event.Undo), &state))
+	assert.Nil(t, state.CreatedVaultID)
+}
+
+func TestHandleHeartbeat(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	vault := indexVault(t, ix, chain, db, heirAddress)
+
+	// Committed through the API
+	commitment := crypto.Keccak256Hash([]byte("committed"))
+	committed := models.Heartbeat{
+		VaultID:     vault.ID,
+		CommitHash:  commitment.Hex(),
+		Status:      models.HeartbeatStatusCommitted,
+		CommittedAt: genesisTime,
+	}
+	require.NoError(t, db.Create(&committed).Error)
+
+	// Revealed from the owner's wallet without the API
+	direct := crypto.Keccak256Hash([]byte("direct"))
+
+	revealedAt := genesisTime.Add(10 * 24 * time.Hour)
+	number := chain.mine(
+		vaultLog(t, testVaultAddress, "Heartbeat", big.NewInt(revealedAt.Unix()), [32]byte(commitment)),
+		vaultLog(t, testVaultAddress, "Heartbeat", big.NewInt(revealedAt.Add(time.Hour).Unix()), [32]byte(direct)),
+	)
+	require.NoError(t, ix.Sync(context.Background()))
+
+	var heartbeat models.Heartbeat
+	require.NoError(t, db.First(&heartbeat, "id = ?", committed.ID).Error)
+	assert.Equal(t, models.HeartbeatStatusRevealed, heartbeat.Status)
+	assert.Equal(t, chain.logs[number][0].TxHash.Hex(), heartbeat.RevealTxHash)
+	require.NotNil(t, heartbeat.RevealedAt)
+	assert.True(t, revealedAt.Equal(*heartbeat.RevealedAt))
+
+	var created models.Heartbeat
+	require.NoError(t, db.Where("vault_id = ? AND commit_hash = ?", vault.ID, direct.Hex()).First(&created).Error)
+	assert.Equal(t, models.HeartbeatStatusRevealed, created.Status)
+	assert.Equal(t, chain.logs[number][1].TxHash.Hex(), created.RevealTxHash)
+
+	// The vault's heartbeat is the latest one
+	vault = loadVault(t, db, testVaultAddress)
+	assert.Equal(t, models.VaultStatusLocked, vault.Status)
+	require.NotNil(t, vault.LastHeartbeat)
+	assert.True(t, revealedAt.Add(time.Hour).Equal(*vault.LastHeartbeat))
+}
+
+func TestHandleUnlockAndGracePeriod(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress)
+
+	endsAt := genesisTime.Add(100 * 24 * time.Hour)
+	number := chain.mine(
+		vaultLog(t, testVaultAddress, "VaultUnlocked", big.NewInt(endsAt.Unix())),
+		vaultLog(t, testVaultAddress, "GracePeriodStarted", big.NewInt(endsAt.Unix())),
+	)
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, models.VaultStatusUnlocked, vault.Status)
+	require.NotNil(t, vault.UnlockedAt)
+	assert.True(t, blockTimeOf(number).Equal(*vault.UnlockedAt), "unlocked at the block time")
+	require.NotNil(t, vault.GracePeriodEndsAt)
+	assert.True(t, endsAt.Equal(*vault.GracePeriodEndsAt))
+}
+
+func TestHandleUnlockCancelled(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress, coHeirAddress)
+
+	endsAt := genesisTime.Add(100 * 24 * time.Hour)
+	chain.mine(
+		vaultLog(t, testVaultAddress, "VaultUnlocked", big.NewInt(endsAt.Unix())),
+		vaultLog(t, testVaultAddress, "GracePeriodStarted", big.NewInt(endsAt.Unix())),
+		vaultLog(t, testVaultAddress, "InheritanceApproved", heirAddress),
+	)
+	chain.mine(vaultLog(t, testVaultAddress, "UnlockCancelled", ownerAddress, big.NewInt(endsAt.Unix())))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, models.VaultStatusLocked, vault.Status)
+	assert.Nil(t, vault.UnlockedAt)
+	assert.Nil(t, vault.GracePeriodEndsAt)
+	assert.False(t, heirOf(t, vault, heirAddress).HasApproved, "approvals are reset")
+}
+
+func TestHandleInheritanceApproved(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress, coHeirAddress)
+
+	chain.mine(vaultLog(t, testVaultAddress, "InheritanceApproved", heirAddress))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.True(t, heirOf(t, vault, heirAddress).HasApproved)
+	assert.False(t, heirOf(t, vault, coHeirAddress).HasApproved)
+}
+
+func TestHandleDepositedAndWithdrawn(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress)
+
+	deposited := chain.mine(
+		vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(5000)),
+		vaultLog(t, testVaultAddress, "Deposited", heirAddress, big.NewInt(700)),
+	)
+	withdrawn := chain.mine(vaultLog(t, testVaultAddress, "Withdrawn", ownerAddress, big.NewInt(1200)))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "4500", vault.Balance)
+
+	entries := ledger(t, db, vault)
+	require.Len(t, entries, 3)
+	assert.Equal(t, models.LedgerEntryDeposit, entries[0].Kind)
+	assert.Equal(t, ownerAddress.Hex(), entries[0].Address)
+	assert.Equal(t, "5000", entries[0].Amount)
+	assert.Equal(t, chain.logs[deposited][0].TxHash.Hex(), entries[0].TxHash)
+	assert.True(t, blockTimeOf(deposited).Equal(entries[0].Timestamp))
+	assert.Equal(t, heirAddress.Hex(), entries[1].Address)
+	assert.Equal(t, uint(1), entries[1].LogIndex)
+	assert.Equal(t, models.LedgerEntryWithdrawal, entries[2].Kind)
+	assert.Equal(t, "1200", entries[2].Amount)
+	assert.Equal(t, withdrawn, entries[2].BlockNumber)
+}
+
+func TestHandleInheritanceClaimed(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress, coHeirAddress)
+
+	endsAt := genesisTime.Add(100 * 24 * time.Hour)
+	chain.mine(
+		vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(1000)),
+		vaultLog(t, testVaultAddress, "VaultUnlocked", big.NewInt(endsAt.Unix())),
+	)
+	chain.mine(vaultLog(t, testVaultAddress, "InheritanceClaimed", heirAddress, big.NewInt(500)))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "500", vault.Balance)
+	assert.True(t, heirOf(t, vault, heirAddress).HasClaimed)
+	assert.Equal(t, models.VaultStatusUnlocked, vault.Status, "one heir has not claimed yet")
+
+	chain.mine(vaultLog(t, testVaultAddress, "InheritanceClaimed", coHeirAddress, big.NewInt(500)))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault = loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "0", vault.Balance)
+	assert.Equal(t, models.VaultStatusClaimed, vault.Status)
+
+	entries := ledger(t, db, vault)
+	require.Len(t, entries, 3)
+	assert.Equal(t, models.LedgerEntryClaim, entries[1].Kind)
+	assert.Equal(t, heirAddress.Hex(), entries[1].Address)
+	assert.Equal(t, models.LedgerEntryClaim, entries[2].Kind)
+	assert.Equal(t, coHeirAddress.Hex(), entries[2].Address)
+}
+
+func TestHandlePausedAndUnpaused(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	vault := indexVault(t, ix, chain, db, heirAddress)
+
+	// Pause requested through the API, with a reason
+	pauseTx := crypto.Keccak256Hash([]byte("pause"))
+	requestedAt := genesisTime
+	require.NoError(t, db.Create(&models.VaultAuditLog{
+		VaultID:     vault.ID,
+		Action:      models.VaultAuditPause,
+		Actor:       ownerAddress.Hex(),
+		Reason:      "lost my phone",
+		TxHash:      pauseTx.Hex(),
+		RequestedAt: &requestedAt,
+	}).Error)
+
+	pauseLog := vaultLog(t, testVaultAddress, "Paused", ownerAddress)
+	pauseLog.TxHash = pauseTx
+	paused := chain.mine(pauseLog)
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault = loadVault(t, db, testVaultAddress)
+	assert.True(t, vault.Paused)
+
+	var pause models.VaultAuditLog
+	require.NoError(t, db.Where("tx_hash = ?", pauseTx.Hex()).First(&pause).Error)
+	assert.Equal(t, "lost my phone", pause.Reason)
+	require.NotNil(t, pause.BlockNumber)
+	assert.Equal(t, paused, *pause.BlockNumber)
+	require.NotNil(t, pause.ConfirmedAt)
+	assert.True(t, blockTimeOf(paused).Equal(*pause.ConfirmedAt))
+
+	// Unpaused from the owner's wallet: the indexer creates the entry
+	number := chain.mine(vaultLog(t, testVaultAddress, "Unpaused", ownerAddress))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	vault = loadVault(t, db, testVaultAddress)
+	assert.False(t, vault.Paused)
+
+	var unpause models.VaultAuditLog
+	require.NoError(t, db.Where("tx_hash = ?", chain.logs[number][0].TxHash.Hex()).First(&unpause).Error)
+	assert.Equal(t, models.VaultAuditUnpause, unpause.Action)
+	assert.Equal(t, ownerAddress.Hex(), unpause.Actor)
+	assert.Nil(t, unpause.RequestedAt)
+	assert.NotNil(t, unpause.ConfirmedAt)
+}
+
+func TestApplyLogOnlyOnce(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress)
+
+	number := chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(1000)))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	// Processing the range again, e.g. after a crash before the cursor was
+	// saved, does not count the deposit twice
+	require.NoError(t, ix.processRange(context.Background(), number, number, number))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "1000", vault.Balance)
+	assert.Len(t, ledger(t, db, vault), 1)
+
+	var events int64
+	require.NoError(t, db.Model(&models.ChainEvent{}).Where("event_name = ?", "Deposited").Count(&events).Error)
+	assert.Equal(t, int64(1), events)
+}
@@ -0,0 +1,367 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"gorm.io/gorm"
+)
+
+// CursorName is the indexer_cursors row used by the vault indexer
+const CursorName = "vault_indexer"
+
+// errReorgDetected aborts a batch whose blocks no longer match the chain
+var errReorgDetected = errors.New("chain reorganization detected")
+
+// logHandler applies a single vault log to the database inside a batch transaction
+type logHandler func(ctx context.Context, tx *gorm.DB, vault *models.Vault, vLog types.Log) error
+
+// Indexer keeps the vaults, heirs and heartbeats tables in sync with
+// VaultFactory and IndividualVault events. It polls the chain in block
+// ranges and commits the derived rows together with the block cursor, so
+// a restart resumes exactly where the previous run stopped.
+//
+// Blocks are only indexed once they have the configured number of
+// confirmations. Every applied event is stored with its block hash and the
+// state it overwrote, so events from reorged blocks can be rolled back.
+//
+// Applied events are pushed to live streams through the publisher, if any,
+// once their batch is committed.
+type Indexer struct {
+	db         *gorm.DB
+	blockchain service.BlockchainService
+	publisher  stream.Publisher
+	cfg        config.IndexerConfig
+
+	factory *bindings.VaultFactoryFilterer
+	vault   *bindings.IndividualVaultFilterer
+
+	vaultCreatedTopic common.Hash
+	heartbeatTopic    common.Hash
+	handlers          map[common.Hash]logHandler
+	eventNames        map[common.Hash]string
+
+	// updates buffers the stream updates of the batch being processed
+	updates []stream.Update
+
+	// mu serializes syncing and rollbacks triggered by removed logs
+	mu sync.Mutex
+}
+
+// New creates a new Indexer. publisher may be nil.
+func New(db *gorm.DB, blockchain service.BlockchainService, publisher stream.Publisher, cfg *config.Config) (*Indexer, error) {
+	// Filterers are only used for log parsing, so they don't need a backend
+	factory, err := bindings.NewVaultFactoryFilterer(blockchain.VaultFactoryAddress(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind VaultFactory filterer: %w", err)
+	}
+
+	vault, err := bindings.NewIndividualVaultFilterer(common.Address{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind IndividualVault filterer: %w", err)
+	}
+
+	factoryABI, err := bindings.VaultFactoryMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VaultFactory ABI: %w", err)
+	}
+
+	vaultABI, err := bindings.IndividualVaultMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse IndividualVault ABI: %w", err)
+	}
+
+	ix := &Indexer{
+		db:                db,
+		blockchain:        blockchain,
+		publisher:         publisher,
+		cfg:               cfg.Indexer,
+		factory:           factory,
+		vault:             vault,
+		vaultCreatedTopic: factoryABI.Events["VaultCreated"].ID,
+		heartbeatTopic:    vaultABI.Events["Heartbeat"].ID,
+	}
+
+	ix.handlers = map[common.Hash]logHandler{
+		vaultABI.Events["Heartbeat"].ID:           ix.handleHeartbeat,
+		vaultABI.Events["VaultUnlocked"].ID:       ix.handleVaultUnlocked,
+		vaultABI.Events["GracePeriodStarted"].ID:  ix.handleGracePeriodStarted,
+		vaultABI.Events["UnlockCancelled"].ID:     ix.handleUnlockCancelled,
+		vaultABI.Events["InheritanceApproved"].ID: ix.handleInheritanceApproved,
+		vaultABI.Events["InheritanceClaimed"].ID:  ix.handleInheritanceClaimed,
+		vaultABI.Events["Deposited"].ID:           ix.handleDeposited,
+		vaultABI.Events["Withdrawn"].ID:           ix.handleWithdrawn,
+		vaultABI.Events["Paused"].ID:              ix.handlePaused,
+		vaultABI.Events["Unpaused"].ID:            ix.handleUnpaused,
+	}
+
+	ix.eventNames = map[common.Hash]string{ix.vaultCreatedTopic: "VaultCreated"}
+	for _, event := range vaultABI.Events {
+		if _, ok := ix.handlers[event.ID]; ok {
+			ix.eventNames[event.ID] = event.Name
+		}
+	}
+
+	return ix, nil
+}
+
+// Run syncs the indexer every poll interval until the context is cancelled.
+// A log subscription wakes the loop early and rolls back removed logs.
+func (ix *Indexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(ix.cfg.PollInterval)
+	defer ticker.Stop()
+
+	wake := make(chan struct{}, 1)
+	go ix.watch(ctx, wake)
+
+	for {
+		if err := ix.Sync(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Indexer sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// Sync processes every confirmed block between the stored cursor and the chain head
+func (ix *Indexer) Sync(ctx context.Context) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	head, err := ix.blockchain.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head < ix.cfg.Confirmations {
+		return nil
+	}
+	target := head - ix.cfg.Confirmations
+
+	if err := ix.checkReorg(ctx); err != nil {
+		return err
+	}
+
+	for {
+		from, err := ix.nextBlock()
+		if err != nil {
+			return err
+		}
+		if from > target {
+			return ix.pruneBlocks(head)
+		}
+
+		to := from + ix.cfg.BatchSize - 1
+		if ix.cfg.BatchSize == 0 || to > target {
+			to = target
+		}
+
+		if err := ix.processRange(ctx, from, to, head); err != nil {
+			return fmt.Errorf("failed to index blocks %d-%d: %w", from, to, err)
+		}
+	}
+}
+
+// nextBlock returns the first block that has not been indexed yet
+func (ix *Indexer) nextBlock() (uint64, error) {
+	var cursor models.IndexerCursor
+	err := ix.db.Where("name = ?", CursorName).First(&cursor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ix.cfg.StartBlock, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load indexer cursor: %w", err)
+	}
+
+	return cursor.BlockNumber + 1, nil
+}
+
+// topics returns the event signatures the indexer consumes
+func (ix *Indexer) topics() []common.Hash {
+	topics := make([]common.Hash, 0, len(ix.eventNames))
+	for topic := range ix.eventNames {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// processRange indexes the logs of blocks [from, to] and advances the cursor
+// in a single database transaction
+func (ix *Indexer) processRange(ctx context.Context, from, to, head uint64) error {
+	headers, err := ix.recentHeaders(ctx, from, to, head)
+	if err != nil {
+		return err
+	}
+
+	// Vault addresses are open-ended, so filter by topic and match the
+	// emitting contract against the factory and known vaults below
+	logs, err := ix.blockchain.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Topics:    [][]common.Hash{ix.topics()},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, vLog := range logs {
+		if header, ok := headers[vLog.BlockNumber]; ok && header.Hash() != vLog.BlockHash {
+			return errReorgDetected
+		}
+	}
+
+	vaultIDs, err := ix.assignVaultIDs(ctx, logs)
+	if err != nil {
+		return err
+	}
+
+	ix.updates = ix.updates[:0]
+	if err := ix.db.Transaction(func(tx *gorm.DB) error {
+		for _, vLog := range logs {
+			if err := ix.applyLog(ctx, tx, vLog, vaultIDs); err != nil {
+				return fmt.Errorf("failed to apply log %s#%d: %w", vLog.TxHash.Hex(), vLog.Index, err)
+			}
+		}
+
+		for _, header := range headers {
+			block := models.IndexedBlock{
+				Number:     header.Number.Uint64(),
+				Hash:       header.Hash().Hex(),
+				ParentHash: header.ParentHash.Hex(),
+			}
+			if err := tx.Save(&block).Error; err != nil {
+				return fmt.Errorf("failed to save indexed block: %w", err)
+			}
+		}
+
+		cursor := models.IndexerCursor{Name: CursorName, BlockNumber: to}
+		return tx.Save(&cursor).Error
+	}); err != nil {
+		return err
+	}
+
+	stream.Publish(ctx, ix.publisher, ix.updates...)
+	return nil
+}
+
+// applyLog dispatches a log to the matching event handler and records it
+// together with the state needed to revert it
+func (ix *Indexer) applyLog(ctx context.Context, tx *gorm.DB, vLog types.Log, vaultIDs map[logKey]int64) error {
+	if len(vLog.Topics) == 0 {
+		return nil
+	}
+
+	var applied int64
+	if err := tx.Model(&models.ChainEvent{}).
+		Where("tx_hash = ? AND log_index = ?", vLog.TxHash.Hex(), vLog.Index).
+		Count(&applied).Error; err != nil {
+		return fmt.Errorf("failed to check chain event: %w", err)
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if vLog.Address == ix.blockchain.VaultFactoryAddress() && vLog.Topics[0] == ix.vaultCreatedTopic {
+		vault, created, err := ix.handleVaultCreated(ctx, tx, vLog, vaultIDs[keyOf(vLog)])
+		if err != nil {
+			return err
+		}
+
+		// Only a vault created by this event is removed on rollback
+		state := &undoState{}
+		if created {
+			state.CreatedVaultID = &vault.ID
+		}
+		return ix.recordEvent(tx, vLog, vault, state)
+	}
+
+	handler, ok := ix.handlers[vLog.Topics[0]]
+	if !ok {
+		return nil
+	}
+
+	var vault models.Vault
+	err := tx.Where("LOWER(contract_address) = LOWER(?)", vLog.Address.Hex()).First(&vault).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Not one of our vaults
+	}
+	if err != nil {
+		return err
+	}
+
+	state, err := ix.captureUndo(tx, &vault, vLog)
+	if err != nil {
+		return err
+	}
+
+	if err := handler(ctx, tx, &vault, vLog); err != nil {
+		return err
+	}
+
+	return ix.recordEvent(tx, vLog, &vault, state)
+}
+
+// logKey identifies a log within the chain
+type logKey struct {
+	block uint64
+	index uint
+}
+
+func keyOf(vLog types.Log) logKey {
+	return logKey{block: vLog.BlockNumber, index: vLog.Index}
+}
+
+// assignVaultIDs derives the factory-wide sequence number of every
+// VaultCreated log. The event only carries the per-owner index, so the
+// number is computed from totalVaults at the end of the block minus the
+// vaults created later in that same block.
+func (ix *Indexer) assignVaultIDs(ctx context.Context, logs []types.Log) (map[logKey]int64, error) {
+	created := make(map[uint64][]types.Log)
+	for _, vLog := range logs {
+		if len(vLog.Topics) > 0 && vLog.Address == ix.blockchain.VaultFactoryAddress() && vLog.Topics[0] == ix.vaultCreatedTopic {
+			created[vLog.BlockNumber] = append(created[vLog.BlockNumber], vLog)
+		}
+	}
+
+	ids := make(map[logKey]int64)
+	for block, blockLogs := range created {
+		total, err := ix.blockchain.GetTotalVaults(ctx, new(big.Int).SetUint64(block))
+		if err != nil {
+			return nil, err
+		}
+
+		// Logs are returned in index order, so the last one has the highest ID
+		for i, vLog := range blockLogs {
+			ids[keyOf(vLog)] = total.Int64() - int64(len(blockLogs)-1-i)
+		}
+	}
+
+	return ids, nil
+}
+
+// blockTime returns the timestamp of the block containing the log
+func (ix *Indexer) blockTime(ctx context.Context, vLog types.Log) (time.Time, error) {
+	header, err := ix.blockchain.HeaderByNumber(ctx, new(big.Int).SetUint64(vLog.BlockNumber))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(int64(header.Time), 0), nil
+}
@@ -0,0 +1,454 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/dbtest"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var (
+	factoryAddress = common.HexToAddress("0x00000000000000000000000000000000000FAC70")
+	ownerAddress   = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	heirAddress    = common.HexToAddress("0x00000000000000000000000000000000000000A1")
+	coHeirAddress  = common.HexToAddress("0x00000000000000000000000000000000000000A2")
+)
+
+// genesisTime is the timestamp of block 0; every block is 12 seconds later
+var genesisTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeChain is a chain of blocks and logs the indexer reads through
+// BlockchainService. Blocks can be replaced to simulate a reorganization.
+// Calling any other method panics on the nil embedded interface.
+type fakeChain struct {
+	service.BlockchainService
+
+	mu      sync.Mutex
+	headers []*types.Header
+	logs    map[uint64][]types.Log
+	vaults  map[common.Address]*service.VaultConfig
+	states  map[common.Address]*service.VaultState
+
+	// ranges are the block ranges FilterLogs was called with
+	ranges [][2]uint64
+
+	// fork distinguishes the hashes of replacement blocks
+	fork byte
+}
+
+func newFakeChain() *fakeChain {
+	chain := &fakeChain{
+		logs:   make(map[uint64][]types.Log),
+		vaults: make(map[common.Address]*service.VaultConfig),
+		states: make(map[common.Address]*service.VaultState),
+	}
+	chain.headers = []*types.Header{chain.header(0, common.Hash{})}
+	return chain
+}
+
+func (f *fakeChain) header(number uint64, parent common.Hash) *types.Header {
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		ParentHash: parent,
+		Time:       uint64(genesisTime.Unix()) + number*12,
+		Difficulty: big.NewInt(0),
+		Extra:      []byte{f.fork},
+	}
+}
+
+// mine appends a block holding the logs and returns its number. Logs get the
+// block's number and hash, their index, and a transaction hash unless set.
+func (f *fakeChain) mine(logs ...types.Log) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parent := f.headers[len(f.headers)-1]
+	header := f.header(uint64(len(f.headers)), parent.Hash())
+	f.headers = append(f.headers, header)
+
+	number := header.Number.Uint64()
+	for i := range logs {
+		logs[i].BlockNumber = number
+		logs[i].BlockHash = header.Hash()
+		logs[i].Index = uint(i)
+		if logs[i].TxHash == (common.Hash{}) {
+			logs[i].TxHash = crypto.Keccak256Hash(header.Hash().Bytes(), []byte{byte(i)})
+		}
+	}
+	f.logs[number] = logs
+	return number
+}
+
+// mineEmpty appends n blocks without logs
+func (f *fakeChain) mineEmpty(n int) {
+	for i := 0; i < n; i++ {
+		f.mine()
+	}
+}
+
+// reorg drops every block after ancestor, so the next mined blocks replace
+// them with different hashes
+func (f *fakeChain) reorg(ancestor uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for number := ancestor + 1; number < uint64(len(f.headers)); number++ {
+		delete(f.logs, number)
+	}
+	f.headers = f.headers[:ancestor+1]
+	f.fork++
+}
+
+func (f *fakeChain) VaultFactoryAddress() common.Address {
+	return factoryAddress
+}
+
+func (f *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return uint64(len(f.headers) - 1), nil
+}
+
+func (f *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if number == nil {
+		return f.headers[len(f.headers)-1], nil
+	}
+	if !number.IsUint64() || number.Uint64() >= uint64(len(f.headers)) {
+		return nil, ethereum.NotFound
+	}
+	return f.headers[number.Uint64()], nil
+}
+
+func (f *fakeChain) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	from, to := query.FromBlock.Uint64(), uint64(len(f.headers)-1)
+	if query.ToBlock != nil && query.ToBlock.Uint64() < to {
+		to = query.ToBlock.Uint64()
+	}
+	f.ranges = append(f.ranges, [2]uint64{from, to})
+
+	var logs []types.Log
+	for number := from; number <= to; number++ {
+		for _, vLog := range f.logs[number] {
+			if matchesQuery(vLog, query) {
+				logs = append(logs, vLog)
+			}
+		}
+	}
+	return logs, nil
+}
+
+func (f *fakeChain) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, ethereum.NotFound
+}
+
+func (f *fakeChain) GetTotalVaults(ctx context.Context, blockNumber *big.Int) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	total := int64(0)
+	for number := uint64(0); number <= blockNumber.Uint64() && number < uint64(len(f.headers)); number++ {
+		for _, vLog := range f.logs[number] {
+			if vLog.Address == factoryAddress && vLog.Topics[0] == factoryABI.Events["VaultCreated"].ID {
+				total++
+			}
+		}
+	}
+	return big.NewInt(total), nil
+}
+
+func (f *fakeChain) GetVaultConfig(ctx context.Context, vaultAddress common.Address) (*service.VaultConfig, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vaultConfig, ok := f.vaults[vaultAddress]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return vaultConfig, nil
+}
+
+func (f *fakeChain) GetVaultState(ctx context.Context, vaultAddress common.Address, blockNumber *big.Int) (*service.VaultState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, ok := f.states[vaultAddress]
+	if !ok {
+		return &service.VaultState{Config: &service.VaultConfig{}}, nil
+	}
+	return state, nil
+}
+
+// matchesQuery applies the address and topic filters of query to a log
+func matchesQuery(vLog types.Log, query ethereum.FilterQuery) bool {
+	if len(query.Addresses) > 0 {
+		found := false
+		for _, address := range query.Addresses {
+			found = found || address == vLog.Address
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for i, topics := range query.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if i >= len(vLog.Topics) {
+			return false
+		}
+		found := false
+		for _, topic := range topics {
+			found = found || topic == vLog.Topics[i]
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	factoryABI = mustABI(bindings.VaultFactoryMetaData)
+	vaultABI   = mustABI(bindings.IndividualVaultMetaData)
+)
+
+func mustABI(metaData *bind.MetaData) *abi.ABI {
+	parsed, err := metaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// eventLog encodes an event of contractABI emitted by address, with args in
+// the order of the event's inputs
+func eventLog(t *testing.T, contractABI *abi.ABI, address common.Address, name string, args ...interface{}) types.Log {
+	t.Helper()
+	event, ok := contractABI.Events[name]
+	require.True(t, ok, "unknown event %s", name)
+	require.Len(t, args, len(event.Inputs))
+
+	vLog := types.Log{Address: address, Topics: []common.Hash{event.ID}}
+	var data []interface{}
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			data = append(data, args[i])
+			continue
+		}
+		topics, err := abi.MakeTopics([]interface{}{args[i]})
+		require.NoError(t, err)
+		vLog.Topics = append(vLog.Topics, topics[0][0])
+	}
+
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	require.NoError(t, err)
+	vLog.Data = packed
+	return vLog
+}
+
+// vaultLog encodes an IndividualVault event emitted by the vault
+func vaultLog(t *testing.T, vault common.Address, name string, args ...interface{}) types.Log {
+	return eventLog(t, vaultABI, vault, name, args...)
+}
+
+// createVaultLog encodes the VaultCreated event of a vault deployed by the
+// factory for owner, and registers its configuration on the chain
+func (f *fakeChain) createVaultLog(t *testing.T, vault, owner common.Address, index int64, heirs ...common.Address) types.Log {
+	shares := make([]*big.Int, len(heirs))
+	for i := range heirs {
+		shares[i] = big.NewInt(int64(10000 / len(heirs)))
+	}
+
+	f.mu.Lock()
+	f.vaults[vault] = &service.VaultConfig{
+		Owner:             owner,
+		Heirs:             heirs,
+		HeirShares:        shares,
+		HeartbeatInterval: big.NewInt(30 * 24 * 60 * 60),
+		GracePeriod:       big.NewInt(90 * 24 * 60 * 60),
+		RequiredApprovals: big.NewInt(1),
+		IsLocked:          true,
+	}
+	f.mu.Unlock()
+
+	return eventLog(t, factoryABI, factoryAddress, "VaultCreated", vault, owner, big.NewInt(index), big.NewInt(genesisTime.Unix()))
+}
+
+// blockTimeOf returns the timestamp of a block of the fake chain
+func blockTimeOf(number uint64) time.Time {
+	return genesisTime.Add(time.Duration(number) * 12 * time.Second)
+}
+
+// testConfig is an indexer configuration without confirmations
+func testConfig() *config.Config {
+	return &config.Config{
+		Indexer: config.IndexerConfig{
+			StartBlock:   1,
+			BatchSize:    100,
+			PollInterval: time.Second,
+			ReorgWindow:  16,
+		},
+	}
+}
+
+// newTestIndexer returns an indexer over a fresh database and chain
+func newTestIndexer(t *testing.T, cfg *config.Config) (*Indexer, *fakeChain, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t)
+	chain := newFakeChain()
+
+	ix, err := New(db, chain, nil, cfg)
+	require.NoError(t, err)
+	return ix, chain, db
+}
+
+// loadVault returns the indexed vault at the contract address
+func loadVault(t *testing.T, db *gorm.DB, address common.Address) models.Vault {
+	t.Helper()
+	var vault models.Vault
+	require.NoError(t, db.Preload("Heirs").Where("contract_address = ?", address.Hex()).First(&vault).Error)
+	return vault
+}
+
+// cursor returns the block the indexer cursor points at
+func cursor(t *testing.T, db *gorm.DB) uint64 {
+	t.Helper()
+	var stored models.IndexerCursor
+	require.NoError(t, db.Where("name = ?", CursorName).First(&stored).Error)
+	return stored.BlockNumber
+}
+
+func TestSyncIndexesConfirmedBlocksInBatches(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.BatchSize = 3
+	cfg.Indexer.Confirmations = 2
+	ix, chain, db := newTestIndexer(t, cfg)
+	ctx := context.Background()
+
+	vault := common.HexToAddress("0x0000000000000000000000000000000000000B01")
+	chain.mineEmpty(3)
+	chain.mine(chain.createVaultLog(t, vault, ownerAddress, 0, heirAddress))
+	chain.mineEmpty(6) // Head 10
+
+	require.NoError(t, ix.Sync(ctx))
+	assert.Equal(t, [][2]uint64{{1, 3}, {4, 6}, {7, 8}}, chain.ranges)
+	assert.Equal(t, uint64(8), cursor(t, db))
+	assert.Equal(t, int64(1), loadVault(t, db, vault).VaultID)
+
+	// Nothing new is confirmed
+	chain.ranges = nil
+	require.NoError(t, ix.Sync(ctx))
+	assert.Empty(t, chain.ranges)
+
+	// The next sync starts after the cursor
+	chain.mineEmpty(2)
+	require.NoError(t, ix.Sync(ctx))
+	assert.Equal(t, [][2]uint64{{9, 10}}, chain.ranges)
+	assert.Equal(t, uint64(10), cursor(t, db))
+}
+
+func TestSyncResumesFromStoredCursor(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	ctx := context.Background()
+
+	chain.mineEmpty(5)
+	require.NoError(t, ix.Sync(ctx))
+	assert.Equal(t, uint64(5), cursor(t, db))
+
+	// A restarted indexer continues from the cursor, not the start block
+	restarted, err := New(db, chain, nil, testConfig())
+	require.NoError(t, err)
+
+	chain.mineEmpty(2)
+	chain.ranges = nil
+	require.NoError(t, restarted.Sync(ctx))
+	assert.Equal(t, [][2]uint64{{6, 7}}, chain.ranges)
+	assert.Equal(t, uint64(7), cursor(t, db))
+}
+
+func TestSyncStartsAtStartBlock(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.StartBlock = 4
+	ix, chain, db := newTestIndexer(t, cfg)
+
+	chain.mineEmpty(6)
+	require.NoError(t, ix.Sync(context.Background()))
+	assert.Equal(t, [][2]uint64{{4, 6}}, chain.ranges)
+	assert.Equal(t, uint64(6), cursor(t, db))
+}
+
+func TestSyncWithoutConfirmedBlocks(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.Confirmations = 5
+	ix, chain, db := newTestIndexer(t, cfg)
+
+	chain.mineEmpty(3)
+	require.NoError(t, ix.Sync(context.Background()))
+	assert.Empty(t, chain.ranges)
+
+	var cursors int64
+	require.NoError(t, db.Model(&models.IndexerCursor{}).Count(&cursors).Error)
+	assert.Zero(t, cursors)
+}
+
+func TestSyncAssignsVaultIDsWithinBlock(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+
+	first := common.HexToAddress("0x0000000000000000000000000000000000000B01")
+	second := common.HexToAddress("0x0000000000000000000000000000000000000B02")
+	third := common.HexToAddress("0x0000000000000000000000000000000000000B03")
+	chain.mine(chain.createVaultLog(t, first, ownerAddress, 0, heirAddress))
+	chain.mine(
+		chain.createVaultLog(t, second, ownerAddress, 1, heirAddress),
+		chain.createVaultLog(t, third, heirAddress, 0, ownerAddress),
+	)
+
+	require.NoError(t, ix.Sync(context.Background()))
+	assert.Equal(t, int64(1), loadVault(t, db, first).VaultID)
+	assert.Equal(t, int64(2), loadVault(t, db, second).VaultID)
+	assert.Equal(t, int64(3), loadVault(t, db, third).VaultID)
+}
+
+func TestSyncIgnoresUnknownContracts(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+
+	// A Deposited event from a contract that is not one of our vaults, and a
+	// VaultCreated lookalike not emitted by the factory
+	stranger := common.HexToAddress("0x0000000000000000000000000000000000000BAD")
+	chain.mine(
+		vaultLog(t, stranger, "Deposited", ownerAddress, big.NewInt(1000)),
+		eventLog(t, factoryABI, stranger, "VaultCreated", stranger, ownerAddress, big.NewInt(0), big.NewInt(0)),
+	)
+
+	require.NoError(t, ix.Sync(context.Background()))
+
+	var vaults, events int64
+	require.NoError(t, db.Model(&models.Vault{}).Count(&vaults).Error)
+	require.NoError(t, db.Model(&models.ChainEvent{}).Count(&events).Error)
+	assert.Zero(t, vaults)
+	assert.Zero(t, events)
+	assert.Equal(t, uint64(1), cursor(t, db))
+}
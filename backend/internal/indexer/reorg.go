@@ -0,0 +1,428 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// vaultSnapshot holds the vault columns derived from events
+type vaultSnapshot struct {
+	Status            models.VaultStatus `json:"status"`
+	Balance           string             `json:"balance"`
+	Paused            bool               `json:"paused"`
+	LastHeartbeat     *time.Time         `json:"last_heartbeat"`
+	UnlockedAt        *time.Time         `json:"unlocked_at"`
+	GracePeriodEndsAt *time.Time         `json:"grace_period_ends_at"`
+}
+
+// heirSnapshot holds the heir columns derived from events
+type heirSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	HasApproved bool      `json:"has_approved"`
+	HasClaimed  bool      `json:"has_claimed"`
+}
+
+// heartbeatSnapshot holds the heartbeat columns derived from events
+type heartbeatSnapshot struct {
+	ID           uuid.UUID              `json:"id"`
+	Status       models.HeartbeatStatus `json:"status"`
+	RevealTxHash string                 `json:"reveal_tx_hash"`
+	RevealedAt   *time.Time             `json:"revealed_at"`
+}
+
+// undoState is the state an event overwrote, stored in ChainEvent.Undo
+type undoState struct {
+	CreatedVaultID   *uuid.UUID         `json:"created_vault_id,omitempty"`
+	Vault            *vaultSnapshot     `json:"vault,omitempty"`
+	Heirs            []heirSnapshot     `json:"heirs,omitempty"`
+	Heartbeat        *heartbeatSnapshot `json:"heartbeat,omitempty"`
+	CreatedHeartbeat string             `json:"created_heartbeat,omitempty"`
+}
+
+// captureUndo snapshots the rows a vault log is about to change
+func (ix *Indexer) captureUndo(tx *gorm.DB, vault *models.Vault, vLog types.Log) (*undoState, error) {
+	state := &undoState{
+		Vault: &vaultSnapshot{
+			Status:            vault.Status,
+			Balance:           vault.Balance,
+			Paused:            vault.Paused,
+			LastHeartbeat:     vault.LastHeartbeat,
+			UnlockedAt:        vault.UnlockedAt,
+			GracePeriodEndsAt: vault.GracePeriodEndsAt,
+		},
+	}
+
+	var heirs []models.Heir
+	if err := tx.Where("vault_id = ?", vault.ID).Find(&heirs).Error; err != nil {
+		return nil, fmt.Errorf("failed to snapshot heirs: %w", err)
+	}
+	for _, heir := range heirs {
+		state.Heirs = append(state.Heirs, heirSnapshot{
+			ID:          heir.ID,
+			HasApproved: heir.HasApproved,
+			HasClaimed:  heir.HasClaimed,
+		})
+	}
+
+	if vLog.Topics[0] == ix.heartbeatTopic {
+		event, err := ix.vault.ParseHeartbeat(vLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Heartbeat event: %w", err)
+		}
+
+		commitHash := common.Hash(event.Commitment).Hex()
+		var heartbeat models.Heartbeat
+		err = tx.Where("vault_id = ? AND commit_hash = ?", vault.ID, commitHash).First(&heartbeat).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			state.CreatedHeartbeat = commitHash
+		case err != nil:
+			return nil, fmt.Errorf("failed to snapshot heartbeat: %w", err)
+		default:
+			state.Heartbeat = &heartbeatSnapshot{
+				ID:           heartbeat.ID,
+				Status:       heartbeat.Status,
+				RevealTxHash: heartbeat.RevealTxHash,
+				RevealedAt:   heartbeat.RevealedAt,
+			}
+		}
+	}
+
+	return state, nil
+}
+
+// recordEvent stores an applied log with its block hash and undo state
+func (ix *Indexer) recordEvent(tx *gorm.DB, vLog types.Log, vault *models.Vault, state *undoState) error {
+	undo, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode undo state: %w", err)
+	}
+
+	event := models.ChainEvent{
+		BlockNumber:     vLog.BlockNumber,
+		BlockHash:       vLog.BlockHash.Hex(),
+		TxHash:          vLog.TxHash.Hex(),
+		LogIndex:        vLog.Index,
+		ContractAddress: vLog.Address.Hex(),
+		EventName:       ix.eventNames[vLog.Topics[0]],
+		Undo:            string(undo),
+	}
+	if vault != nil {
+		event.VaultID = &vault.ID
+	}
+
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record chain event: %w", err)
+	}
+	return nil
+}
+
+// revertEvent restores the state an event overwrote
+func revertEvent(tx *gorm.DB, event *models.ChainEvent) error {
+	var state undoState
+	if err := json.Unmarshal([]byte(event.Undo), &state); err != nil {
+		return fmt.Errorf("failed to decode undo state: %w", err)
+	}
+
+	// Fund movements are recorded per log
+	if err := tx.Where("tx_hash = ? AND log_index = ?", event.TxHash, event.LogIndex).
+		Delete(&models.VaultLedgerEntry{}).Error; err != nil {
+		return fmt.Errorf("failed to delete ledger entry: %w", err)
+	}
+
+	// Undelivered webhooks for the event are withdrawn; delivered ones cannot be
+	if err := withdrawDeliveries(tx, "tx_hash = ? AND log_index = ?", event.TxHash, event.LogIndex); err != nil {
+		return err
+	}
+
+	// Pause audit entries lose their confirmation; those the indexer created
+	// without an API request go away with it
+	if err := tx.Where("tx_hash = ? AND requested_at IS NULL", event.TxHash).
+		Delete(&models.VaultAuditLog{}).Error; err != nil {
+		return fmt.Errorf("failed to delete audit entry: %w", err)
+	}
+	if err := tx.Model(&models.VaultAuditLog{}).Where("tx_hash = ?", event.TxHash).Updates(map[string]interface{}{
+		"block_number": nil,
+		"confirmed_at": nil,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to restore audit entry: %w", err)
+	}
+
+	if state.CreatedVaultID != nil {
+		vaultID := *state.CreatedVaultID
+		if err := tx.Unscoped().Where("vault_id = ?", vaultID).Delete(&models.Heartbeat{}).Error; err != nil {
+			return fmt.Errorf("failed to delete heartbeats: %w", err)
+		}
+		if err := tx.Where("vault_id = ?", vaultID).Delete(&models.VaultLedgerEntry{}).Error; err != nil {
+			return fmt.Errorf("failed to delete ledger entries: %w", err)
+		}
+		if err := tx.Where("vault_id = ?", vaultID).Delete(&models.VaultAuditLog{}).Error; err != nil {
+			return fmt.Errorf("failed to delete audit entries: %w", err)
+		}
+		if err := withdrawDeliveries(tx, "vault_id = ?", vaultID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("vault_id = ?", vaultID).Delete(&models.Heir{}).Error; err != nil {
+			return fmt.Errorf("failed to delete heirs: %w", err)
+		}
+		if err := tx.Unscoped().Where("id = ?", vaultID).Delete(&models.Vault{}).Error; err != nil {
+			return fmt.Errorf("failed to delete vault: %w", err)
+		}
+	}
+
+	if state.Vault != nil && event.VaultID != nil {
+		if err := tx.Model(&models.Vault{}).Where("id = ?", *event.VaultID).Updates(map[string]interface{}{
+			"status":               state.Vault.Status,
+			"balance":              state.Vault.Balance,
+			"paused":               state.Vault.Paused,
+			"last_heartbeat":       state.Vault.LastHeartbeat,
+			"unlocked_at":          state.Vault.UnlockedAt,
+			"grace_period_ends_at": state.Vault.GracePeriodEndsAt,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to restore vault: %w", err)
+		}
+	}
+
+	for _, heir := range state.Heirs {
+		if err := tx.Model(&models.Heir{}).Where("id = ?", heir.ID).Updates(map[string]interface{}{
+			"has_approved": heir.HasApproved,
+			"has_claimed":  heir.HasClaimed,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to restore heir: %w", err)
+		}
+	}
+
+	if state.Heartbeat != nil {
+		if err := tx.Model(&models.Heartbeat{}).Where("id = ?", state.Heartbeat.ID).Updates(map[string]interface{}{
+			"status":         state.Heartbeat.Status,
+			"reveal_tx_hash": state.Heartbeat.RevealTxHash,
+			"revealed_at":    state.Heartbeat.RevealedAt,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to restore heartbeat: %w", err)
+		}
+	}
+
+	if state.CreatedHeartbeat != "" && event.VaultID != nil {
+		if err := tx.Unscoped().
+			Where("vault_id = ? AND commit_hash = ? AND reveal_tx_hash = ?", *event.VaultID, state.CreatedHeartbeat, event.TxHash).
+			Delete(&models.Heartbeat{}).Error; err != nil {
+			return fmt.Errorf("failed to delete heartbeat: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// withdrawDeliveries deletes the undelivered webhook deliveries matching
+// query, along with their dead letters
+func withdrawDeliveries(tx *gorm.DB, query string, args ...interface{}) error {
+	undelivered := func() *gorm.DB {
+		return tx.Model(&models.WebhookDelivery{}).Select("id").
+			Where("status <> ?", models.WebhookDeliveryDelivered).
+			Where(query, args...)
+	}
+
+	if err := tx.Where("delivery_id IN (?)", undelivered()).Delete(&models.WebhookDeadLetter{}).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook dead letters: %w", err)
+	}
+	if err := tx.Where("id IN (?)", undelivered()).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// recentHeaders fetches the headers of blocks [from, to] that fall inside the
+// reorg window and checks that they extend the already indexed chain
+func (ix *Indexer) recentHeaders(ctx context.Context, from, to, head uint64) (map[uint64]*types.Header, error) {
+	headers := make(map[uint64]*types.Header)
+	if ix.cfg.ReorgWindow == 0 {
+		return headers, nil
+	}
+
+	start := from
+	if head >= ix.cfg.ReorgWindow && start < head-ix.cfg.ReorgWindow+1 {
+		start = head - ix.cfg.ReorgWindow + 1
+	}
+
+	for number := start; number <= to; number++ {
+		header, err := ix.blockchain.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, err
+		}
+
+		parent, ok := headers[number-1]
+		if ok {
+			if header.ParentHash != parent.Hash() {
+				return nil, errReorgDetected
+			}
+		} else if number > 0 {
+			var stored models.IndexedBlock
+			err := ix.db.Where("number = ?", number-1).First(&stored).Error
+			if err == nil && common.HexToHash(stored.Hash) != header.ParentHash {
+				return nil, errReorgDetected
+			}
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("failed to load indexed block: %w", err)
+			}
+		}
+
+		headers[number] = header
+	}
+
+	return headers, nil
+}
+
+// checkReorg compares the latest indexed block with the chain and rolls back
+// to the common ancestor if it was replaced
+func (ix *Indexer) checkReorg(ctx context.Context) error {
+	var blocks []models.IndexedBlock
+	if err := ix.db.Order("number DESC").Find(&blocks).Error; err != nil {
+		return fmt.Errorf("failed to load indexed blocks: %w", err)
+	}
+
+	for i, block := range blocks {
+		header, err := ix.blockchain.HeaderByNumber(ctx, new(big.Int).SetUint64(block.Number))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return err
+		}
+		if header != nil && header.Hash() == common.HexToHash(block.Hash) {
+			if i == 0 {
+				return nil
+			}
+
+			log.Printf("Chain reorganization detected: rolling back to block %d", block.Number)
+			return ix.rewind(block.Number)
+		}
+	}
+
+	if len(blocks) > 0 {
+		return fmt.Errorf("chain reorganization deeper than the %d block window", ix.cfg.ReorgWindow)
+	}
+	return nil
+}
+
+// rewind reverts every event after the given block and moves the cursor back to it
+func (ix *Indexer) rewind(ancestor uint64) error {
+	return ix.db.Transaction(func(tx *gorm.DB) error {
+		var events []models.ChainEvent
+		if err := tx.Where("block_number > ?", ancestor).
+			Order("block_number DESC, log_index DESC").
+			Find(&events).Error; err != nil {
+			return fmt.Errorf("failed to load chain events: %w", err)
+		}
+
+		for i := range events {
+			if err := revertEvent(tx, &events[i]); err != nil {
+				return fmt.Errorf("failed to revert %s %s#%d: %w", events[i].EventName, events[i].TxHash, events[i].LogIndex, err)
+			}
+		}
+
+		if err := tx.Where("block_number > ?", ancestor).Delete(&models.ChainEvent{}).Error; err != nil {
+			return fmt.Errorf("failed to delete chain events: %w", err)
+		}
+		if err := tx.Where("number > ?", ancestor).Delete(&models.IndexedBlock{}).Error; err != nil {
+			return fmt.Errorf("failed to delete indexed blocks: %w", err)
+		}
+
+		return tx.Model(&models.IndexerCursor{}).
+			Where("name = ? AND block_number > ?", CursorName, ancestor).
+			Update("block_number", ancestor).Error
+	})
+}
+
+// pruneBlocks drops the block hashes and applied events that fell out of the
+// reorg window, since a reorg that deep is not rolled back. Without a window
+// every applied event is dropped.
+func (ix *Indexer) pruneBlocks(head uint64) error {
+	if head < ix.cfg.ReorgWindow {
+		return nil
+	}
+	last := head - ix.cfg.ReorgWindow
+
+	if err := ix.db.Where("number <= ?", last).Delete(&models.IndexedBlock{}).Error; err != nil {
+		return fmt.Errorf("failed to prune indexed blocks: %w", err)
+	}
+	if err := ix.db.Where("block_number <= ?", last).Delete(&models.ChainEvent{}).Error; err != nil {
+		return fmt.Errorf("failed to prune chain events: %w", err)
+	}
+	return nil
+}
+
+// watch subscribes to the indexed events. New logs wake the sync loop and
+// removed logs roll back the events they produced.
+func (ix *Indexer) watch(ctx context.Context, wake chan<- struct{}) {
+	for ctx.Err() == nil {
+		logs := make(chan types.Log, 64)
+		sub, err := ix.blockchain.SubscribeFilterLogs(ctx, ethereum.FilterQuery{
+			Topics: [][]common.Hash{ix.topics()},
+		}, logs)
+		if err != nil {
+			log.Printf("Indexer subscription failed: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(ix.cfg.PollInterval):
+			}
+			continue
+		}
+
+		ix.consume(ctx, sub, logs, wake)
+		sub.Unsubscribe()
+	}
+}
+
+// consume handles subscription logs until the subscription fails or ctx ends
+func (ix *Indexer) consume(ctx context.Context, sub ethereum.Subscription, logs <-chan types.Log, wake chan<- struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			log.Printf("Indexer subscription error: %v", err)
+			return
+		case vLog := <-logs:
+			if !vLog.Removed {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
+			if err := ix.handleRemovedLog(vLog); err != nil {
+				log.Printf("Failed to roll back removed log %s#%d: %v", vLog.TxHash.Hex(), vLog.Index, err)
+			}
+		}
+	}
+}
+
+// handleRemovedLog rolls back to the block before an applied log that the
+// node reported as removed
+func (ix *Indexer) handleRemovedLog(vLog types.Log) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	var event models.ChainEvent
+	err := ix.db.Where("tx_hash = ? AND log_index = ? AND block_hash = ?",
+		vLog.TxHash.Hex(), vLog.Index, vLog.BlockHash.Hex()).First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Not applied yet, e.g. still waiting for confirmations
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load chain event: %w", err)
+	}
+
+	log.Printf("Removed log %s#%d: rolling back to block %d", vLog.TxHash.Hex(), vLog.Index, event.BlockNumber-1)
+	return ix.rewind(event.BlockNumber - 1)
+}
@@ -0,0 +1,254 @@
+package indexer
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// indexedBlocks returns the numbers of the blocks whose hashes are kept
+func indexedBlocks(t *testing.T, db *gorm.DB) []uint64 {
+	t.Helper()
+	var numbers []uint64
+	require.NoError(t, db.Model(&models.IndexedBlock{}).Order("number").Pluck("number", &numbers).Error)
+	return numbers
+}
+
+// chainEvents returns the names of the applied events in chain order
+func chainEvents(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var names []string
+	require.NoError(t, db.Model(&models.ChainEvent{}).Order("block_number, log_index").Pluck("event_name", &names).Error)
+	return names
+}
+
+func TestSyncRewindsReorganizedBlocks(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress)
+	ctx := context.Background()
+
+	chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(1000)))
+	chain.mine(vaultLog(t, testVaultAddress, "Withdrawn", ownerAddress, big.NewInt(400)))
+	require.NoError(t, ix.Sync(ctx))
+	assert.Equal(t, "600", loadVault(t, db, testVaultAddress).Balance)
+
+	// Blocks 2 and 3 are replaced by a smaller deposit and an empty block
+	chain.reorg(1)
+	replaced := chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(300)))
+	chain.mineEmpty(1)
+	require.NoError(t, ix.Sync(ctx))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "300", vault.Balance)
+
+	entries := ledger(t, db, vault)
+	require.Len(t, entries, 1)
+	assert.Equal(t, chain.logs[replaced][0].TxHash.Hex(), entries[0].TxHash)
+
+	assert.Equal(t, []string{"VaultCreated", "Deposited"}, chainEvents(t, db))
+	assert.Equal(t, uint64(3), cursor(t, db))
+
+	var block models.IndexedBlock
+	require.NoError(t, db.First(&block, "number = ?", replaced).Error)
+	assert.Equal(t, chain.logs[replaced][0].BlockHash.Hex(), block.Hash)
+}
+
+func TestRewindDeletesCreatedVault(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	ctx := context.Background()
+
+	chain.mineEmpty(1)
+	chain.mine(chain.createVaultLog(t, testVaultAddress, ownerAddress, 0, heirAddress))
+	chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(1000)))
+	require.NoError(t, ix.Sync(ctx))
+	loadVault(t, db, testVaultAddress)
+
+	chain.reorg(1)
+	chain.mineEmpty(3)
+	require.NoError(t, ix.Sync(ctx))
+
+	var vaults, heirs, entries int64
+	require.NoError(t, db.Unscoped().Model(&models.Vault{}).Count(&vaults).Error)
+	require.NoError(t, db.Unscoped().Model(&models.Heir{}).Count(&heirs).Error)
+	require.NoError(t, db.Model(&models.VaultLedgerEntry{}).Count(&entries).Error)
+	assert.Zero(t, vaults)
+	assert.Zero(t, heirs)
+	assert.Zero(t, entries)
+	assert.Empty(t, chainEvents(t, db))
+}
+
+func TestRewindRestoresHeartbeatsAndHeirs(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	vault := indexVault(t, ix, chain, db, heirAddress)
+	ctx := context.Background()
+
+	commitment := crypto.Keccak256Hash([]byte("committed"))
+	committed := models.Heartbeat{
+		VaultID:     vault.ID,
+		CommitHash:  commitment.Hex(),
+		Status:      models.HeartbeatStatusCommitted,
+		CommittedAt: genesisTime,
+	}
+	require.NoError(t, db.Create(&committed).Error)
+
+	direct := crypto.Keccak256Hash([]byte("direct"))
+	revealedAt := blockTimeOf(2)
+	chain.mine(
+		vaultLog(t, testVaultAddress, "Heartbeat", big.NewInt(revealedAt.Unix()), [32]byte(commitment)),
+		vaultLog(t, testVaultAddress, "Heartbeat", big.NewInt(revealedAt.Unix()), [32]byte(direct)),
+		vaultLog(t, testVaultAddress, "InheritanceApproved", heirAddress),
+	)
+	require.NoError(t, ix.Sync(ctx))
+
+	chain.reorg(1)
+	chain.mineEmpty(2)
+	require.NoError(t, ix.Sync(ctx))
+
+	var heartbeat models.Heartbeat
+	require.NoError(t, db.First(&heartbeat, "id = ?", committed.ID).Error)
+	assert.Equal(t, models.HeartbeatStatusCommitted, heartbeat.Status)
+	assert.Empty(t, heartbeat.RevealTxHash)
+	assert.Nil(t, heartbeat.RevealedAt)
+
+	var created int64
+	require.NoError(t, db.Unscoped().Model(&models.Heartbeat{}).Where("commit_hash = ?", direct.Hex()).Count(&created).Error)
+	assert.Zero(t, created, "the heartbeat revealed without the API goes away")
+
+	vault = loadVault(t, db, testVaultAddress)
+	require.NotNil(t, vault.LastHeartbeat)
+	assert.True(t, genesisTime.Equal(*vault.LastHeartbeat))
+	assert.False(t, heirOf(t, vault, heirAddress).HasApproved)
+}
+
+func TestRewindRestoresPauseAudit(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	vault := indexVault(t, ix, chain, db, heirAddress)
+	ctx := context.Background()
+
+	pauseTx := crypto.Keccak256Hash([]byte("pause"))
+	requestedAt := genesisTime
+	require.NoError(t, db.Create(&models.VaultAuditLog{
+		VaultID:     vault.ID,
+		Action:      models.VaultAuditPause,
+		Actor:       ownerAddress.Hex(),
+		Reason:      "lost my phone",
+		TxHash:      pauseTx.Hex(),
+		RequestedAt: &requestedAt,
+	}).Error)
+
+	pauseLog := vaultLog(t, testVaultAddress, "Paused", ownerAddress)
+	pauseLog.TxHash = pauseTx
+	chain.mine(pauseLog)
+	chain.mine(vaultLog(t, testVaultAddress, "Unpaused", ownerAddress))
+	require.NoError(t, ix.Sync(ctx))
+
+	chain.reorg(1)
+	chain.mineEmpty(2)
+	require.NoError(t, ix.Sync(ctx))
+
+	assert.False(t, loadVault(t, db, testVaultAddress).Paused)
+
+	// The requested pause is kept, waiting to be mined again
+	var pause models.VaultAuditLog
+	require.NoError(t, db.Where("tx_hash = ?", pauseTx.Hex()).First(&pause).Error)
+	assert.Equal(t, "lost my phone", pause.Reason)
+	assert.Nil(t, pause.BlockNumber)
+	assert.Nil(t, pause.ConfirmedAt)
+
+	var unpauses int64
+	require.NoError(t, db.Model(&models.VaultAuditLog{}).Where("action = ?", models.VaultAuditUnpause).Count(&unpauses).Error)
+	assert.Zero(t, unpauses)
+}
+
+func TestHandleRemovedLog(t *testing.T) {
+	ix, chain, db := newTestIndexer(t, testConfig())
+	indexVault(t, ix, chain, db, heirAddress)
+	ctx := context.Background()
+
+	chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(1000)))
+	number := chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(500)))
+	require.NoError(t, ix.Sync(ctx))
+
+	// A log from another fork of the block was never applied
+	unknown := chain.logs[number][0]
+	unknown.BlockHash = common.HexToHash("0x01")
+	unknown.Removed = true
+	require.NoError(t, ix.handleRemovedLog(unknown))
+	assert.Equal(t, "1500", loadVault(t, db, testVaultAddress).Balance)
+
+	removed := chain.logs[number][0]
+	removed.Removed = true
+	require.NoError(t, ix.handleRemovedLog(removed))
+
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "1000", vault.Balance)
+	assert.Len(t, ledger(t, db, vault), 1)
+	assert.Equal(t, number-1, cursor(t, db))
+	assert.Equal(t, []uint64{1, 2}, indexedBlocks(t, db))
+
+	// The block is indexed again if it is still in the chain
+	require.NoError(t, ix.Sync(ctx))
+	assert.Equal(t, "1500", loadVault(t, db, testVaultAddress).Balance)
+}
+
+func TestSyncFailsOnReorgDeeperThanWindow(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.ReorgWindow = 2
+	ix, chain, db := newTestIndexer(t, cfg)
+	ctx := context.Background()
+
+	chain.mineEmpty(5)
+	require.NoError(t, ix.Sync(ctx))
+	assert.Equal(t, []uint64{4, 5}, indexedBlocks(t, db))
+
+	chain.reorg(2)
+	chain.mineEmpty(3)
+	err := ix.Sync(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deeper than the 2 block window")
+	assert.Equal(t, uint64(5), cursor(t, db))
+}
+
+func TestPruneBlocksOutsideReorgWindow(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.ReorgWindow = 3
+	ix, chain, db := newTestIndexer(t, cfg)
+	indexVault(t, ix, chain, db, heirAddress)
+
+	for i := 0; i < 5; i++ {
+		chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(100)))
+	}
+	require.NoError(t, ix.Sync(context.Background()))
+
+	// Head 6: blocks 4 to 6 can still be rolled back
+	assert.Equal(t, []uint64{4, 5, 6}, indexedBlocks(t, db))
+	var blocks []uint64
+	require.NoError(t, db.Model(&models.ChainEvent{}).Order("block_number").Pluck("block_number", &blocks).Error)
+	assert.Equal(t, []uint64{4, 5, 6}, blocks)
+
+	// Pruning does not touch the indexed state
+	vault := loadVault(t, db, testVaultAddress)
+	assert.Equal(t, "500", vault.Balance)
+	assert.Len(t, ledger(t, db, vault), 5)
+}
+
+func TestPruneBlocksWithoutReorgWindow(t *testing.T) {
+	cfg := testConfig()
+	cfg.Indexer.ReorgWindow = 0
+	ix, chain, db := newTestIndexer(t, cfg)
+	indexVault(t, ix, chain, db, heirAddress)
+
+	chain.mine(vaultLog(t, testVaultAddress, "Deposited", ownerAddress, big.NewInt(100)))
+	require.NoError(t, ix.Sync(context.Background()))
+
+	assert.Empty(t, indexedBlocks(t, db))
+	assert.Empty(t, chainEvents(t, db))
+	assert.Equal(t, "100", loadVault(t, db, testVaultAddress).Balance)
+}
@@ -0,0 +1,391 @@
+package keeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// lockKey makes sure only one replica submits unlocks at a time
+	lockKey = "keeper:lock"
+
+	// defaultLockTTL bounds how long the lock outlives a replica that stopped
+	// renewing it
+	defaultLockTTL = time.Minute
+
+	// sendFailuresKey is a hash of vault ID to the JSON-encoded sendFailure of
+	// vaults whose unlock could not be sent, shared by every replica
+	sendFailuresKey = "keeper:send_failures"
+
+	// maxBackoff caps the wait between retries of the same vault
+	maxBackoff = time.Hour
+
+	unlockMethod = "checkAndUnlock"
+)
+
+// unlockScript deletes the lock only if this replica still holds it
+//
+// KEYS: lock   ARGV: token
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// renewScript extends the lock only if this replica still holds it
+//
+// KEYS: lock   ARGV: token, ttl (ms)
+var renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+var (
+	// ErrBusy is returned by Poll when another replica is running a round
+	ErrBusy = errors.New("keeper round already running")
+
+	// ErrLockLost is returned by Poll when the lock could not be renewed, so
+	// the round stopped before another replica could run one alongside it
+	ErrLockLost = errors.New("keeper lock lost")
+)
+
+// failedStatuses are the outcomes of an unlock transaction that count as a failed attempt
+var failedStatuses = []models.TransactionStatus{
+	models.TransactionStatusReverted,
+	models.TransactionStatusDropped,
+	models.TransactionStatusCancelled,
+}
+
+// Keeper calls checkAndUnlock on vaults whose heartbeat deadline
+// (last_heartbeat + heartbeat_interval) has passed, so heirs do not have to.
+//
+// Due vaults are selected from the indexed vaults table rather than by reading
+// every contract. Unlock transactions are handed to the tracker, and the
+// indexer records the resulting VaultUnlocked and GracePeriodStarted events.
+// A vault whose unlock is pending or mined is skipped; one whose unlock failed
+// is retried with exponential backoff, up to the configured attempts per
+// expiry. Paused vaults are left alone until the owner unpauses them.
+//
+// A round runs under a Redis lock that is renewed while it lasts, so replicas
+// and admin-triggered rounds never send unlocks concurrently.
+type Keeper struct {
+	db         *gorm.DB
+	redis      *redis.Client
+	blockchain service.BlockchainService
+	cfg        config.KeeperConfig
+	lockTTL    time.Duration
+}
+
+// sendFailure backs off a vault whose unlock could not be sent, e.g. because
+// the index is behind a heartbeat and gas estimation reverts
+type sendFailure struct {
+	Attempts  int       `json:"attempts"`
+	NextRetry time.Time `json:"next_retry"`
+}
+
+// New creates a new Keeper
+func New(db *gorm.DB, redisClient *redis.Client, blockchain service.BlockchainService, cfg *config.Config) *Keeper {
+	return &Keeper{
+		db:         db,
+		redis:      redisClient,
+		blockchain: blockchain,
+		cfg:        cfg.Keeper,
+		lockTTL:    defaultLockTTL,
+	}
+}
+
+// Run checks for expired vaults until ctx is cancelled
+func (k *Keeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(k.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := k.Poll(ctx); err != nil && !errors.Is(err, ErrBusy) && ctx.Err() == nil {
+			log.Printf("Keeper error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll submits checkAndUnlock for every vault that is due. It returns ErrBusy
+// without doing anything if another replica is already running a round, and
+// ErrLockLost if the round was stopped because its lock expired.
+func (k *Keeper) Poll(ctx context.Context) error {
+	token := uuid.New().String()
+	locked, err := k.redis.SetNX(ctx, lockKey, token, k.lockTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to acquire keeper lock: %w", err)
+	}
+	if !locked {
+		return ErrBusy
+	}
+	defer unlockScript.Run(context.Background(), k.redis, []string{lockKey}, token)
+
+	round, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go k.renewLock(round, cancel, token)
+
+	if err := k.runRound(round); err != nil {
+		if errors.Is(context.Cause(round), ErrLockLost) {
+			return ErrLockLost
+		}
+		return err
+	}
+	return nil
+}
+
+// runRound sends unlocks for the due vaults until ctx is cancelled
+func (k *Keeper) runRound(ctx context.Context) error {
+	// Deadlines are compared to chain time, which is what checkAndUnlock checks
+	head, err := k.blockchain.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	now := time.Unix(int64(head.Time), 0)
+
+	vaults, err := k.dueVaults(now)
+	if err != nil {
+		return err
+	}
+
+	if err := k.forgetSendFailures(ctx, vaults); err != nil {
+		return err
+	}
+
+	for i := range vaults {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := k.unlock(ctx, &vaults[i]); err != nil {
+			log.Printf("Failed to unlock vault %s: %v", vaults[i].ContractAddress, err)
+		}
+	}
+
+	return ctx.Err()
+}
+
+// renewLock extends the lock every third of its TTL until ctx ends. If the
+// lock cannot be renewed, the round is cancelled with ErrLockLost before it
+// expires and another replica can take it.
+func (k *Keeper) renewLock(ctx context.Context, cancel context.CancelCauseFunc, token string) {
+	ticker := time.NewTicker(k.lockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := renewScript.Run(ctx, k.redis, []string{lockKey}, token, k.lockTTL.Milliseconds()).Int()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to renew keeper lock, stopping the round: %v", err)
+		} else if renewed == 0 {
+			log.Printf("Keeper lock expired, stopping the round")
+		}
+		if err != nil || renewed == 0 {
+			cancel(ErrLockLost)
+			return
+		}
+	}
+}
+
+// dueVaults returns the locked, unpaused vaults whose deadline has passed by
+// now, oldest deadline first. Vaults with an unlock pending or mined since
+// their last heartbeat, or that used up their attempts, are left out.
+func (k *Keeper) dueVaults(now time.Time) ([]models.Vault, error) {
+	inFlight := k.db.Model(&models.Transaction{}).
+		Select("1").
+		Where("transactions.vault_id = vaults.id AND transactions.method = ? AND transactions.created_at > vaults.last_heartbeat", unlockMethod).
+		Where("transactions.status IN ?", []models.TransactionStatus{models.TransactionStatusPending, models.TransactionStatusMined})
+
+	failed := k.db.Model(&models.Transaction{}).
+		Select("COUNT(*)").
+		Where("transactions.vault_id = vaults.id AND transactions.method = ? AND transactions.created_at > vaults.last_heartbeat", unlockMethod).
+		Where("transactions.status IN ?", failedStatuses)
+
+	var vaults []models.Vault
+	query := k.db.Where("status = ? AND paused = ? AND last_heartbeat IS NOT NULL", models.VaultStatusLocked, false).
+		Where("last_heartbeat + heartbeat_interval * INTERVAL '1 second' <= ?", now).
+		Where("NOT EXISTS (?)", inFlight)
+	if k.cfg.MaxAttempts > 0 {
+		query = query.Where("(?) < ?", failed, k.cfg.MaxAttempts)
+	}
+
+	if err := query.Order("last_heartbeat + heartbeat_interval * INTERVAL '1 second' ASC").
+		Limit(k.cfg.BatchSize).
+		Find(&vaults).Error; err != nil {
+		return nil, fmt.Errorf("failed to query due vaults: %w", err)
+	}
+
+	return vaults, nil
+}
+
+// unlock sends checkAndUnlock for a vault unless it is backing off from a
+// failed attempt, and hands the transaction to the tracker
+func (k *Keeper) unlock(ctx context.Context, vault *models.Vault) error {
+	ready, err := k.backoffElapsed(ctx, vault)
+	if err != nil || !ready {
+		return err
+	}
+
+	txHash, err := k.blockchain.CheckAndUnlock(ctx, common.HexToAddress(vault.ContractAddress))
+	if err != nil {
+		if recordErr := k.recordSendFailure(ctx, vault.ID); recordErr != nil {
+			log.Printf("Failed to record unlock failure of vault %s: %v", vault.ContractAddress, recordErr)
+		}
+		return err
+	}
+	if err := k.redis.HDel(ctx, sendFailuresKey, vault.ID.String()).Err(); err != nil {
+		log.Printf("Failed to clear unlock failures of vault %s: %v", vault.ContractAddress, err)
+	}
+
+	tx, _, err := k.blockchain.GetTransactionByHash(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to load unlock transaction %s: %w", txHash, err)
+	}
+
+	if _, err := tracker.Record(k.db, tx, k.blockchain.RelayerAddress(), unlockMethod, tracker.Link{VaultID: &vault.ID}); err != nil {
+		return err
+	}
+
+	log.Printf("Sent checkAndUnlock for vault %s: %s", vault.ContractAddress, txHash)
+	return nil
+}
+
+// backoffElapsed reports whether enough time has passed since the vault's last
+// failed unlock, counting both transactions that failed on-chain since its
+// last heartbeat and attempts that could not be sent
+func (k *Keeper) backoffElapsed(ctx context.Context, vault *models.Vault) (bool, error) {
+	failure, err := k.sendFailure(ctx, vault.ID)
+	if err != nil {
+		return false, err
+	}
+	if failure != nil && time.Now().Before(failure.NextRetry) {
+		return false, nil
+	}
+
+	var failed struct {
+		Attempts   int
+		LastFailed *time.Time
+	}
+	if err := k.db.Model(&models.Transaction{}).
+		Select("COUNT(*) AS attempts, MAX(confirmed_at) AS last_failed").
+		Where("vault_id = ? AND method = ? AND created_at > ? AND status IN ?",
+			vault.ID, unlockMethod, vault.LastHeartbeat, failedStatuses).
+		Scan(&failed).Error; err != nil {
+		return false, fmt.Errorf("failed to query failed unlocks: %w", err)
+	}
+	if failed.Attempts == 0 || failed.LastFailed == nil {
+		return true, nil
+	}
+
+	return time.Since(*failed.LastFailed) >= k.backoff(failed.Attempts), nil
+}
+
+// sendFailure returns the stored send failure of a vault, or nil
+func (k *Keeper) sendFailure(ctx context.Context, vaultID uuid.UUID) (*sendFailure, error) {
+	raw, err := k.redis.HGet(ctx, sendFailuresKey, vaultID.String()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load unlock failures: %w", err)
+	}
+
+	var failure sendFailure
+	if err := json.Unmarshal(raw, &failure); err != nil {
+		return nil, fmt.Errorf("failed to decode unlock failures: %w", err)
+	}
+	return &failure, nil
+}
+
+// recordSendFailure counts a failed send and schedules the next attempt
+func (k *Keeper) recordSendFailure(ctx context.Context, vaultID uuid.UUID) error {
+	failure, err := k.sendFailure(ctx, vaultID)
+	if err != nil {
+		return err
+	}
+	if failure == nil {
+		failure = &sendFailure{}
+	}
+
+	failure.Attempts++
+	failure.NextRetry = time.Now().Add(k.backoff(failure.Attempts))
+
+	raw, err := json.Marshal(failure)
+	if err != nil {
+		return fmt.Errorf("failed to encode unlock failures: %w", err)
+	}
+	if err := k.redis.HSet(ctx, sendFailuresKey, vaultID.String(), raw).Err(); err != nil {
+		return fmt.Errorf("failed to store unlock failures: %w", err)
+	}
+	return nil
+}
+
+// forgetSendFailures drops the send failures of vaults that are no longer due,
+// e.g. after a heartbeat
+func (k *Keeper) forgetSendFailures(ctx context.Context, due []models.Vault) error {
+	ids, err := k.redis.HKeys(ctx, sendFailuresKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load unlock failures: %w", err)
+	}
+
+	isDue := make(map[string]bool, len(due))
+	for _, vault := range due {
+		isDue[vault.ID.String()] = true
+	}
+
+	var stale []string
+	for _, id := range ids {
+		if !isDue[id] {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if err := k.redis.HDel(ctx, sendFailuresKey, stale...).Err(); err != nil {
+		return fmt.Errorf("failed to clear unlock failures: %w", err)
+	}
+	return nil
+}
+
+// backoff returns the wait after the given number of failed attempts: the
+// retry backoff, doubled after each further failure
+func (k *Keeper) backoff(attempts int) time.Duration {
+	wait := k.cfg.RetryBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
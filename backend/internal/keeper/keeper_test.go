@@ -0,0 +1,337 @@
+package keeper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/dbtest"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var relayerAddress = common.HexToAddress("0x00000000000000000000000000000000000000F1")
+
+// chainTime is the timestamp of the fake chain's latest block, kept close to
+// the wall clock that stamps created_at
+var chainTime = time.Now().Truncate(time.Second)
+
+// fakeChain records the unlocks the keeper sends through BlockchainService.
+// Calling any other method panics on the nil embedded interface.
+type fakeChain struct {
+	service.BlockchainService
+
+	mu      sync.Mutex
+	sent    []common.Address
+	txs     map[string]*types.Transaction
+	sendErr error
+
+	// header replaces the latest header lookup if set
+	header func(ctx context.Context) (*types.Header, error)
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{txs: make(map[string]*types.Transaction)}
+}
+
+func (f *fakeChain) RelayerAddress() common.Address {
+	return relayerAddress
+}
+
+func (f *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if f.header != nil {
+		return f.header(ctx)
+	}
+	return &types.Header{Time: uint64(chainTime.Unix())}, nil
+}
+
+func (f *fakeChain) CheckAndUnlock(ctx context.Context, vaultAddress common.Address) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.sendErr != nil {
+		return "", f.sendErr
+	}
+	f.sent = append(f.sent, vaultAddress)
+	tx := types.NewTx(&types.LegacyTx{Nonce: uint64(len(f.sent)), To: &vaultAddress, GasPrice: big.NewInt(1)})
+	f.txs[tx.Hash().Hex()] = tx
+	return tx.Hash().Hex(), nil
+}
+
+func (f *fakeChain) GetTransactionByHash(ctx context.Context, txHash string) (*types.Transaction, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.txs[txHash], true, nil
+}
+
+// newTestKeeper returns a keeper over miniredis and the database, which may be nil
+func newTestKeeper(t *testing.T, db *gorm.DB) (*Keeper, *fakeChain, *miniredis.Miniredis) {
+	t.Helper()
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	chain := newFakeChain()
+	cfg := &config.Config{
+		Keeper: config.KeeperConfig{BatchSize: 10, MaxAttempts: 3, RetryBackoff: time.Minute},
+	}
+	return New(db, redisClient, chain, cfg), chain, mr
+}
+
+func TestPollReturnsBusyWhileLocked(t *testing.T) {
+	k, chain, mr := newTestKeeper(t, nil)
+	require.NoError(t, mr.Set(lockKey, "other replica"))
+
+	assert.ErrorIs(t, k.Poll(context.Background()), ErrBusy)
+	assert.Empty(t, chain.sent)
+
+	held, err := mr.Get(lockKey)
+	require.NoError(t, err)
+	assert.Equal(t, "other replica", held)
+}
+
+func TestPollReleasesLock(t *testing.T) {
+	k, chain, mr := newTestKeeper(t, nil)
+	chain.header = func(ctx context.Context) (*types.Header, error) {
+		assert.True(t, mr.Exists(lockKey), "held during the round")
+		return nil, errors.New("node unavailable")
+	}
+
+	assert.EqualError(t, k.Poll(context.Background()), "node unavailable")
+	assert.False(t, mr.Exists(lockKey))
+}
+
+func TestPollRenewsLockDuringRound(t *testing.T) {
+	k, chain, mr := newTestKeeper(t, nil)
+	k.lockTTL = 300 * time.Millisecond
+
+	// A round that outlasts the TTL keeps its lock
+	chain.header = func(ctx context.Context) (*types.Header, error) {
+		for i := 0; i < 10; i++ {
+			time.Sleep(100 * time.Millisecond)
+			mr.FastForward(50 * time.Millisecond)
+			if !mr.Exists(lockKey) {
+				return nil, errors.New("lock expired")
+			}
+		}
+		return nil, errors.New("done")
+	}
+
+	assert.EqualError(t, k.Poll(context.Background()), "done")
+}
+
+func TestPollStopsWhenLockIsLost(t *testing.T) {
+	k, chain, mr := newTestKeeper(t, nil)
+	k.lockTTL = 30 * time.Millisecond
+
+	// The lock expires and another replica takes it mid-round
+	chain.header = func(ctx context.Context) (*types.Header, error) {
+		require.NoError(t, mr.Set(lockKey, "other replica"))
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	assert.ErrorIs(t, k.Poll(context.Background()), ErrLockLost)
+
+	held, err := mr.Get(lockKey)
+	require.NoError(t, err)
+	assert.Equal(t, "other replica", held, "the other replica's lock is left alone")
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 3, want: 4 * time.Minute},
+		{attempts: 6, want: 32 * time.Minute},
+		{attempts: 7, want: time.Hour},
+		{attempts: 100, want: time.Hour},
+	}
+
+	k := &Keeper{cfg: config.KeeperConfig{RetryBackoff: time.Minute}}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, k.backoff(tt.attempts), "after %d attempts", tt.attempts)
+	}
+}
+
+func TestSendFailuresAreShared(t *testing.T) {
+	k, _, _ := newTestKeeper(t, nil)
+	ctx := context.Background()
+	vault := models.Vault{ID: uuid.New()}
+	other := models.Vault{ID: uuid.New()}
+
+	require.NoError(t, k.recordSendFailure(ctx, vault.ID))
+	require.NoError(t, k.recordSendFailure(ctx, vault.ID))
+	require.NoError(t, k.recordSendFailure(ctx, other.ID))
+
+	// Another replica, or this one after a restart, sees the same failures
+	replica := New(nil, k.redis, k.blockchain, &config.Config{Keeper: k.cfg})
+	failure, err := replica.sendFailure(ctx, vault.ID)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, 2, failure.Attempts)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), failure.NextRetry, 5*time.Second)
+
+	// Only vaults that are still due keep theirs
+	require.NoError(t, replica.forgetSendFailures(ctx, []models.Vault{vault}))
+	failure, err = replica.sendFailure(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Nil(t, failure)
+	failure, err = replica.sendFailure(ctx, vault.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, failure)
+}
+
+// createVault stores a vault whose heartbeat deadline is due after the given
+// time relative to chain time
+func createVault(t *testing.T, db *gorm.DB, index int64, dueIn time.Duration, mutate func(*models.Vault)) models.Vault {
+	t.Helper()
+	owner := models.User{Address: common.BigToAddress(big.NewInt(0xA000 + index)).Hex()}
+	require.NoError(t, db.Create(&owner).Error)
+
+	interval := 30 * 24 * time.Hour
+	lastHeartbeat := chainTime.Add(dueIn - interval)
+	vault := models.Vault{
+		VaultID:           index,
+		ContractAddress:   common.BigToAddress(big.NewInt(0xB000 + index)).Hex(),
+		OwnerID:           owner.ID,
+		HeartbeatInterval: int64(interval / time.Second),
+		GracePeriod:       int64(90 * 24 * time.Hour / time.Second),
+		RequiredApprovals: 1,
+		Status:            models.VaultStatusLocked,
+		LastHeartbeat:     &lastHeartbeat,
+	}
+	if mutate != nil {
+		mutate(&vault)
+	}
+	require.NoError(t, db.Create(&vault).Error)
+	return vault
+}
+
+// unlockTransaction stores a checkAndUnlock transaction of the vault
+func unlockTransaction(t *testing.T, db *gorm.DB, vault models.Vault, status models.TransactionStatus, confirmedAt *time.Time) {
+	t.Helper()
+	id := uuid.New()
+	require.NoError(t, db.Create(&models.Transaction{
+		Hash:        common.BytesToHash(id[:]).Hex(),
+		FromAddress: relayerAddress.Hex(),
+		ToAddress:   vault.ContractAddress,
+		VaultID:     &vault.ID,
+		Method:      unlockMethod,
+		Status:      status,
+		ConfirmedAt: confirmedAt,
+	}).Error)
+}
+
+func TestPollUnlocksDueVaults(t *testing.T) {
+	db := dbtest.Open(t)
+	k, chain, _ := newTestKeeper(t, db)
+	ctx := context.Background()
+
+	overdue := createVault(t, db, 1, -2*time.Hour, nil)
+	due := createVault(t, db, 2, -time.Hour, nil)
+	createVault(t, db, 3, time.Hour, nil)
+	createVault(t, db, 4, -time.Hour, func(v *models.Vault) { v.Paused = true })
+	createVault(t, db, 5, -time.Hour, func(v *models.Vault) { v.Status = models.VaultStatusUnlocked })
+	pending := createVault(t, db, 6, -time.Hour, nil)
+	unlockTransaction(t, db, pending, models.TransactionStatusPending, nil)
+
+	require.NoError(t, k.Poll(ctx))
+
+	// Oldest deadline first
+	assert.Equal(t, []common.Address{
+		common.HexToAddress(overdue.ContractAddress),
+		common.HexToAddress(due.ContractAddress),
+	}, chain.sent)
+
+	var tracked []models.Transaction
+	require.NoError(t, db.Where("vault_id IN ?", []uuid.UUID{overdue.ID, due.ID}).Find(&tracked).Error)
+	require.Len(t, tracked, 2)
+	for _, tx := range tracked {
+		assert.Equal(t, models.TransactionStatusPending, tx.Status)
+		assert.Equal(t, unlockMethod, tx.Method)
+		assert.Equal(t, relayerAddress.Hex(), tx.FromAddress)
+	}
+
+	// Their unlocks are now in flight
+	require.NoError(t, k.Poll(ctx))
+	assert.Len(t, chain.sent, 2)
+}
+
+func TestPollBacksOffAfterFailedUnlocks(t *testing.T) {
+	db := dbtest.Open(t)
+	k, chain, _ := newTestKeeper(t, db)
+	ctx := context.Background()
+
+	// Reverted a moment ago: wait one backoff
+	recentlyFailed := createVault(t, db, 1, -time.Hour, nil)
+	justNow := time.Now()
+	unlockTransaction(t, db, recentlyFailed, models.TransactionStatusReverted, &justNow)
+
+	// Failed twice, the last time 90 seconds ago: the backoff doubled to two minutes
+	stillWaiting := createVault(t, db, 2, -time.Hour, nil)
+	lastFailed := time.Now().Add(-90 * time.Second)
+	unlockTransaction(t, db, stillWaiting, models.TransactionStatusReverted, &lastFailed)
+	unlockTransaction(t, db, stillWaiting, models.TransactionStatusDropped, &lastFailed)
+
+	// Failed once, long ago: retried
+	retried := createVault(t, db, 3, -time.Hour, nil)
+	longAgo := time.Now().Add(-time.Hour)
+	unlockTransaction(t, db, retried, models.TransactionStatusReverted, &longAgo)
+
+	// Used up its attempts for this expiry
+	givenUp := createVault(t, db, 4, -time.Hour, nil)
+	for i := 0; i < 3; i++ {
+		unlockTransaction(t, db, givenUp, models.TransactionStatusReverted, &longAgo)
+	}
+
+	require.NoError(t, k.Poll(ctx))
+	assert.Equal(t, []common.Address{common.HexToAddress(retried.ContractAddress)}, chain.sent)
+}
+
+func TestPollBacksOffAfterSendFailures(t *testing.T) {
+	db := dbtest.Open(t)
+	k, chain, _ := newTestKeeper(t, db)
+	ctx := context.Background()
+	vault := createVault(t, db, 1, -time.Hour, nil)
+
+	chain.sendErr = errors.New("execution reverted: Heartbeat not expired")
+	require.NoError(t, k.Poll(ctx))
+
+	failure, err := k.sendFailure(ctx, vault.ID)
+	require.NoError(t, err)
+	require.NotNil(t, failure)
+	assert.Equal(t, 1, failure.Attempts)
+
+	// Not retried before the backoff elapses
+	chain.sendErr = nil
+	require.NoError(t, k.Poll(ctx))
+	assert.Empty(t, chain.sent)
+
+	k.cfg.RetryBackoff = time.Millisecond
+	require.NoError(t, k.recordSendFailure(ctx, vault.ID))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, k.Poll(ctx))
+	assert.Len(t, chain.sent, 1)
+
+	failure, err = k.sendFailure(ctx, vault.ID)
+	require.NoError(t, err)
+	assert.Nil(t, failure, "cleared once sent")
+}
@@ -0,0 +1,16 @@
+package notify
+
+import "context"
+
+// Message is a plain-text notification to one recipient
+type Message struct {
+	To      string // Email address
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users. Implementations must be safe for
+// concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sendTimeout bounds the delivery of a single notification
+const sendTimeout = 30 * time.Second
+
+// ReminderScheduler reminds vault owners to send a heartbeat before the
+// deadline (last_heartbeat + heartbeat_interval) at each configured offset,
+// and escalates once the vault is unlocked and its grace period is running.
+//
+// Vaults are selected from the indexed tables, and every reminder is claimed
+// in heartbeat_reminders before it is sent, so it goes out once per deadline.
+// Owners without an email address are skipped.
+type ReminderScheduler struct {
+	db       *gorm.DB
+	notifier Notifier
+	cfg      config.NotificationConfig
+	offsets  []time.Duration // Longest first
+}
+
+// NewReminderScheduler creates a ReminderScheduler that delivers through notifier
+func NewReminderScheduler(db *gorm.DB, notifier Notifier, cfg *config.Config) *ReminderScheduler {
+	offsets := append([]time.Duration(nil), cfg.Notifications.ReminderOffsets...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+
+	return &ReminderScheduler{
+		db:       db,
+		notifier: notifier,
+		cfg:      cfg.Notifications,
+		offsets:  offsets,
+	}
+}
+
+// Run sends due reminders until ctx is cancelled
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Reminder scheduler error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll sends every reminder and escalation that is due
+func (s *ReminderScheduler) Poll(ctx context.Context) error {
+	now := time.Now()
+
+	if err := s.remindHeartbeats(ctx, now); err != nil {
+		return err
+	}
+	return s.escalateGracePeriods(ctx, now)
+}
+
+// remindHeartbeats reminds the owners of locked vaults whose deadline is
+// within the longest offset. Only the closest offset that has been reached is
+// sent, so a scheduler that was down does not send several at once.
+func (s *ReminderScheduler) remindHeartbeats(ctx context.Context, now time.Time) error {
+	if len(s.offsets) == 0 {
+		return nil
+	}
+
+	var vaults []models.Vault
+	if err := s.withOwnerEmail().
+		Where("vaults.status = ? AND vaults.last_heartbeat IS NOT NULL", models.VaultStatusLocked).
+		Where("vaults.last_heartbeat + vaults.heartbeat_interval * INTERVAL '1 second' BETWEEN ? AND ?", now, now.Add(s.offsets[0])).
+		Find(&vaults).Error; err != nil {
+		return fmt.Errorf("failed to query vaults to remind: %w", err)
+	}
+
+	for i := range vaults {
+		vault := &vaults[i]
+		deadline := vault.LastHeartbeat.Add(time.Duration(vault.HeartbeatInterval) * time.Second)
+
+		offset, ok := reminderStage(s.offsets, deadline.Sub(now))
+		if !ok {
+			continue
+		}
+
+		// A closer reminder for the same deadline supersedes this one
+		var sent int64
+		if err := s.db.Model(&models.HeartbeatReminder{}).
+			Where("vault_id = ? AND kind = ? AND deadline = ? AND offset_seconds <= ?",
+				vault.ID, models.ReminderKindHeartbeat, deadline, int64(offset.Seconds())).
+			Count(&sent).Error; err != nil {
+			return fmt.Errorf("failed to query reminders: %w", err)
+		}
+		if sent > 0 {
+			continue
+		}
+
+		msg := heartbeatReminder(vault, deadline, offset)
+		if err := s.send(ctx, vault, models.ReminderKindHeartbeat, deadline, offset, msg); err != nil {
+			log.Printf("Failed to remind owner of vault %s: %v", vault.ContractAddress, err)
+		}
+	}
+
+	return nil
+}
+
+// escalateGracePeriods warns the owners of unlocked vaults once, while they
+// can still cancel the unlock with a heartbeat
+func (s *ReminderScheduler) escalateGracePeriods(ctx context.Context, now time.Time) error {
+	var vaults []models.Vault
+	if err := s.withOwnerEmail().
+		Where("vaults.status = ? AND vaults.grace_period_ends_at > ?", models.VaultStatusUnlocked, now).
+		Find(&vaults).Error; err != nil {
+		return fmt.Errorf("failed to query vaults in grace period: %w", err)
+	}
+
+	for i := range vaults {
+		vault := &vaults[i]
+		msg := gracePeriodEscalation(vault)
+		if err := s.send(ctx, vault, models.ReminderKindGracePeriod, *vault.GracePeriodEndsAt, 0, msg); err != nil {
+			log.Printf("Failed to warn owner of vault %s: %v", vault.ContractAddress, err)
+		}
+	}
+
+	return nil
+}
+
+// withOwnerEmail selects vaults whose owner has an email address, with the owner loaded
+func (s *ReminderScheduler) withOwnerEmail() *gorm.DB {
+	return s.db.Joins("JOIN users ON users.id = vaults.owner_id AND users.deleted_at IS NULL").
+		Where("users.email <> ''").
+		Preload("Owner")
+}
+
+// send claims a reminder and delivers it, releasing the claim if delivery
+// fails so the next poll retries. It does nothing if the reminder was already
+// claimed, e.g. by another replica.
+func (s *ReminderScheduler) send(ctx context.Context, vault *models.Vault, kind models.ReminderKind, deadline time.Time, offset time.Duration, msg Message) error {
+	reminder := models.HeartbeatReminder{
+		VaultID:       vault.ID,
+		Kind:          kind,
+		Deadline:      deadline,
+		OffsetSeconds: int64(offset.Seconds()),
+		Email:         vault.Owner.Email,
+	}
+
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+	if result.Error != nil {
+		return fmt.Errorf("failed to claim reminder: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	msg.To = vault.Owner.Email
+	if err := s.notifier.Notify(sendCtx, msg); err != nil {
+		if releaseErr := s.db.Delete(&reminder).Error; releaseErr != nil {
+			return fmt.Errorf("%v (and failed to release reminder: %v)", err, releaseErr)
+		}
+		return err
+	}
+
+	return s.db.Model(&reminder).Update("sent_at", time.Now()).Error
+}
+
+// reminderStage returns the closest offset (offsets longest first) that a
+// deadline remaining away has reached
+func reminderStage(offsets []time.Duration, remaining time.Duration) (time.Duration, bool) {
+	var stage time.Duration
+	reached := false
+	for _, offset := range offsets {
+		if remaining <= offset {
+			stage = offset
+			reached = true
+		}
+	}
+	return stage, reached
+}
+
+// heartbeatReminder composes the reminder sent offset before a heartbeat deadline
+func heartbeatReminder(vault *models.Vault, deadline time.Time, offset time.Duration) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your LegacyChain vault #%d (%s) needs a heartbeat.\n\n", vault.VaultID, vault.ContractAddress)
+	fmt.Fprintf(&body, "Deadline: %s\n\n", deadline.UTC().Format(time.RFC1123))
+	fmt.Fprintf(&body, "If no heartbeat is revealed by then, the vault can be unlocked and your heirs can claim it after a grace period of %s.\n", formatDuration(time.Duration(vault.GracePeriod)*time.Second))
+	if vault.Paused {
+		body.WriteString("\nThe vault is paused. Unpause it first, since heartbeats are rejected while it is paused.\n")
+	}
+
+	return Message{
+		Subject: fmt.Sprintf("Heartbeat due in %s for vault #%d", formatDuration(offset), vault.VaultID),
+		Body:    body.String(),
+	}
+}
+
+// gracePeriodEscalation composes the warning sent once a vault is unlocked
+func gracePeriodEscalation(vault *models.Vault) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your LegacyChain vault #%d (%s) was unlocked because its heartbeat deadline passed.\n\n", vault.VaultID, vault.ContractAddress)
+	fmt.Fprintf(&body, "Your heirs can claim it after %s.\n\n", vault.GracePeriodEndsAt.UTC().Format(time.RFC1123))
+	body.WriteString("If you still have access to your wallet, reveal a heartbeat before then to cancel the unlock and lock the vault again.\n")
+
+	return Message{
+		Subject: fmt.Sprintf("URGENT: vault #%d is unlocked and its grace period has started", vault.VaultID),
+		Body:    body.String(),
+	}
+}
+
+// formatDuration renders a duration in whole days, hours or minutes
+func formatDuration(d time.Duration) string {
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int64(d/time.Hour), "hour")
+	default:
+		return unit(int64(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOffsets = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour}
+
+// Test that the closest reached offset is picked
+func TestReminderStage(t *testing.T) {
+	tests := []struct {
+		remaining time.Duration
+		stage     time.Duration
+		reached   bool
+	}{
+		{8 * 24 * time.Hour, 0, false},
+		{7 * 24 * time.Hour, 7 * 24 * time.Hour, true},
+		{3 * 24 * time.Hour, 7 * 24 * time.Hour, true},
+		{24 * time.Hour, 24 * time.Hour, true},
+		{5 * time.Hour, 24 * time.Hour, true},
+		{30 * time.Minute, time.Hour, true},
+		{0, time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		stage, reached := reminderStage(testOffsets, tt.remaining)
+		assert.Equal(t, tt.reached, reached, "remaining %v", tt.remaining)
+		assert.Equal(t, tt.stage, stage, "remaining %v", tt.remaining)
+	}
+
+	_, reached := reminderStage(nil, time.Minute)
+	assert.False(t, reached)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "7 days", formatDuration(7*24*time.Hour))
+	assert.Equal(t, "1 day", formatDuration(24*time.Hour))
+	assert.Equal(t, "36 hours", formatDuration(36*time.Hour))
+	assert.Equal(t, "1 hour", formatDuration(time.Hour))
+	assert.Equal(t, "90 minutes", formatDuration(90*time.Minute))
+}
+
+func testVault() *models.Vault {
+	return &models.Vault{
+		VaultID:           7,
+		ContractAddress:   "0x1234567890123456789012345678901234567890",
+		HeartbeatInterval: 30 * 24 * 60 * 60,
+		GracePeriod:       30 * 24 * 60 * 60,
+	}
+}
+
+func TestHeartbeatReminderMessage(t *testing.T) {
+	vault := testVault()
+	deadline := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	msg := heartbeatReminder(vault, deadline, 24*time.Hour)
+	assert.Equal(t, "Heartbeat due in 1 day for vault #7", msg.Subject)
+	assert.Contains(t, msg.Body, vault.ContractAddress)
+	assert.Contains(t, msg.Body, "Sun, 01 Mar 2026 12:00:00 UTC")
+	assert.Contains(t, msg.Body, "grace period of 30 days")
+	assert.NotContains(t, msg.Body, "paused")
+
+	// Paused owners are told to unpause first
+	vault.Paused = true
+	msg = heartbeatReminder(vault, deadline, time.Hour)
+	assert.Equal(t, "Heartbeat due in 1 hour for vault #7", msg.Subject)
+	assert.Contains(t, msg.Body, "Unpause it first")
+}
+
+func TestGracePeriodEscalationMessage(t *testing.T) {
+	vault := testVault()
+	endsAt := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	vault.GracePeriodEndsAt = &endsAt
+
+	msg := gracePeriodEscalation(vault)
+	assert.Contains(t, msg.Subject, "URGENT")
+	assert.Contains(t, msg.Body, "Wed, 01 Apr 2026 00:00:00 UTC")
+	assert.Contains(t, msg.Body, "cancel the unlock")
+}
+
+// Test that a composed reminder arrives intact over SMTP
+func TestHeartbeatReminderOverSMTP(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier := NewSMTPNotifier(server.config())
+
+	msg := heartbeatReminder(testVault(), time.Now().Add(7*24*time.Hour), 7*24*time.Hour)
+	msg.To = "owner@example.com"
+	require.NoError(t, notifier.Notify(context.Background(), msg))
+
+	received := server.messages()
+	require.Len(t, received, 1)
+
+	_, subject, body := parseMail(t, received[0].Data)
+	assert.Equal(t, "Heartbeat due in 7 days for vault #7", subject)
+	assert.Contains(t, body, "needs a heartbeat")
+}
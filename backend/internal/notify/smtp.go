@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/haneumLee/legacychain/backend/config"
+)
+
+// SMTPNotifier sends notifications as plain-text email. It upgrades the
+// connection with STARTTLS when the server offers it and authenticates only
+// if a username is configured.
+type SMTPNotifier struct {
+	cfg config.SMTPConfig
+}
+
+// NewSMTPNotifier creates a Notifier that delivers through the SMTP server in cfg
+func NewSMTPNotifier(cfg config.SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg}
+}
+
+// Notify sends msg, giving up when ctx is done
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.cfg.Host, n.cfg.Port))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if n.cfg.Username != "" {
+		auth := smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.cfg.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(n.compose(msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// compose renders msg with its headers. Line endings are converted to CRLF by
+// the SMTP data writer.
+func (n *SMTPNotifier) compose(msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + headerValue(n.cfg.From) + "\n")
+	b.WriteString("To: " + headerValue(msg.To) + "\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", headerValue(msg.Subject)) + "\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\n")
+	b.WriteString("MIME-Version: 1.0\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\n")
+	b.WriteString("\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+// headerValue strips line breaks so a value cannot add headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(value)
+}
@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedMail is a message accepted by the fake SMTP server
+type receivedMail struct {
+	From string
+	To   []string
+	Auth string // Decoded AUTH PLAIN credentials
+	Data string
+}
+
+// fakeSMTPServer is a minimal local SMTP server that records what it receives
+type fakeSMTPServer struct {
+	listener   net.Listener
+	rejectRcpt bool // Answer RCPT with 550
+	silent     bool // Never send the greeting
+
+	mu       sync.Mutex
+	received []receivedMail
+}
+
+// startFakeSMTPServer starts a server on a free local port, applying options
+// before it accepts connections
+func startFakeSMTPServer(t *testing.T, options ...func(*fakeSMTPServer)) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakeSMTPServer{listener: listener}
+	for _, option := range options {
+		option(server)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+// config returns an SMTP configuration pointing at the server
+func (s *fakeSMTPServer) config() config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return config.SMTPConfig{Host: host, Port: port, From: "noreply@legacychain.test"}
+}
+
+func (s *fakeSMTPServer) messages() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.received...)
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	if s.silent {
+		io.Copy(io.Discard, conn)
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var mail receivedMail
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-fake")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			parts := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+			mail.Auth = string(decoded)
+			reply("235 Authenticated")
+		case "MAIL":
+			mail.From = addressOf(line)
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt {
+				reply("550 No such user")
+				continue
+			}
+			mail.To = append(mail.To, addressOf(line))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			mail.Data = data.String()
+			s.mu.Lock()
+			s.received = append(s.received, mail)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// addressOf extracts the address from MAIL FROM:<a> or RCPT TO:<a>
+func addressOf(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+// parseMail parses received message data and decodes its subject
+func parseMail(t *testing.T, data string) (*mail.Message, string, string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(msg.Body)
+	require.NoError(t, err)
+
+	return msg, subject, string(body)
+}
+
+// Test that a message is delivered with its envelope, headers and body
+func TestSMTPNotifierDelivers(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier := NewSMTPNotifier(server.config())
+
+	err := notifier.Notify(context.Background(), Message{
+		To:      "owner@example.com",
+		Subject: "Heartbeat due in 1 day — vault #7",
+		Body:    "First line\nSecond line\n.leading dot\n",
+	})
+	require.NoError(t, err)
+
+	received := server.messages()
+	require.Len(t, received, 1)
+	assert.Equal(t, "noreply@legacychain.test", received[0].From)
+	assert.Equal(t, []string{"owner@example.com"}, received[0].To)
+	assert.Empty(t, received[0].Auth)
+
+	msg, subject, body := parseMail(t, received[0].Data)
+	assert.Equal(t, "Heartbeat due in 1 day — vault #7", subject)
+	assert.Equal(t, "owner@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+	assert.Equal(t, "First line\r\nSecond line\r\n.leading dot\r\n", body)
+}
+
+// Test that line breaks in header values cannot add headers
+func TestSMTPNotifierStripsHeaderLineBreaks(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier := NewSMTPNotifier(server.config())
+
+	err := notifier.Notify(context.Background(), Message{
+		To:      "owner@example.com",
+		Subject: "Reminder\r\nBcc: attacker@example.com",
+		Body:    "Body\n",
+	})
+	require.NoError(t, err)
+
+	received := server.messages()
+	require.Len(t, received, 1)
+
+	msg, subject, _ := parseMail(t, received[0].Data)
+	assert.Empty(t, msg.Header.Get("Bcc"))
+	assert.Equal(t, "Reminder Bcc: attacker@example.com", subject)
+}
+
+// Test that credentials are sent when a username is configured
+func TestSMTPNotifierAuthenticates(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	cfg := server.config()
+	cfg.Username = "mailer"
+	cfg.Password = "secret"
+
+	err := NewSMTPNotifier(cfg).Notify(context.Background(), Message{To: "owner@example.com", Subject: "Hi", Body: "Body\n"})
+	require.NoError(t, err)
+
+	received := server.messages()
+	require.Len(t, received, 1)
+	assert.Equal(t, "\x00mailer\x00secret", received[0].Auth)
+}
+
+// Test that a rejected recipient is reported
+func TestSMTPNotifierRejectedRecipient(t *testing.T) {
+	server := startFakeSMTPServer(t, func(s *fakeSMTPServer) { s.rejectRcpt = true })
+
+	err := NewSMTPNotifier(server.config()).Notify(context.Background(), Message{To: "nobody@example.com", Subject: "Hi", Body: "Body\n"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "recipient")
+	assert.Empty(t, server.messages())
+}
+
+// Test that an unresponsive server does not block past the context deadline
+func TestSMTPNotifierContextDeadline(t *testing.T) {
+	server := startFakeSMTPServer(t, func(s *fakeSMTPServer) { s.silent = true })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := NewSMTPNotifier(server.config()).Notify(ctx, Message{To: "owner@example.com", Subject: "Hi", Body: "Body\n"})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
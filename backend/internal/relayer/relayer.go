@@ -0,0 +1,343 @@
+package relayer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"github.com/haneumLee/legacychain/backend/pkg/crypto"
+)
+
+// Errors returned by Relay for requests that must not be submitted
+var (
+	ErrDisabled          = errors.New("meta-transaction relayer is disabled")
+	ErrInvalidSignature  = errors.New("invalid typed-data signature")
+	ErrExpired           = errors.New("forward request deadline has passed")
+	ErrDeadlineTooFar    = errors.New("forward request deadline is too far in the future")
+	ErrNonceMismatch     = errors.New("forward request nonce does not match the forwarder nonce")
+	ErrReplayed          = errors.New("forward request has already been relayed")
+	ErrUnsupportedCall   = errors.New("call cannot be relayed")
+	ErrUnknownVault      = errors.New("forward request target is not a known vault")
+	ErrGasBudgetExceeded = errors.New("gas budget exceeded")
+	ErrWouldRevert       = errors.New("forward request would revert")
+)
+
+// RelayableMethods are the IndividualVault methods the relayer pays gas for
+var RelayableMethods = map[string]bool{
+	"approveInheritance": true,
+	"claimInheritance":   true,
+	"revealHeartbeat":    true,
+	"pause":              true,
+	"unpause":            true,
+}
+
+// gasMarginDivisor pads the gas a user signs for the vault call by 1/5, since
+// the forwarder appends the sender to calldata and rejects requests whose gas
+// runs out
+const gasMarginDivisor = 5
+
+// Relayer submits EIP-712 signed forward requests through the ERC-2771
+// forwarder from the server account, so heirs without ETH can approve and
+// claim, and owners can pause a vault they fear is compromised. Gas paid on a user's behalf is charged to a per-address budget that
+// resets every budget window.
+//
+// Replay protection is layered: the forwarder nonce and deadline are signed
+// and enforced on-chain, the request digest is unique in meta_transactions,
+// and a nonce with a pending relayed transaction is not submitted again.
+type Relayer struct {
+	vaults           repository.VaultRepository
+	transactions     repository.TransactionRepository
+	metaTransactions repository.MetaTransactionRepository
+	gasBudgets       repository.GasBudgetRepository
+	blockchain       service.BlockchainService
+	cfg              config.RelayerConfig
+}
+
+// New creates a new Relayer
+func New(repos *repository.Repositories, blockchain service.BlockchainService, cfg *config.Config) *Relayer {
+	return &Relayer{
+		vaults:           repos.Vaults,
+		transactions:     repos.Transactions,
+		metaTransactions: repos.MetaTransactions,
+		gasBudgets:       repos.GasBudgets,
+		blockchain:       blockchain,
+		cfg:              cfg.Relayer,
+	}
+}
+
+// Enabled reports whether meta-transactions are accepted
+func (r *Relayer) Enabled() bool {
+	return r.cfg.Enabled && r.blockchain.ForwarderAddress() != (common.Address{})
+}
+
+// Domain returns the forwarder's EIP-712 domain
+func (r *Relayer) Domain(ctx context.Context) (*crypto.EIP712Domain, error) {
+	if !r.Enabled() {
+		return nil, ErrDisabled
+	}
+	return r.blockchain.ForwarderDomain(ctx)
+}
+
+// Nonce returns the forwarder nonce the next request from an address must use
+func (r *Relayer) Nonce(ctx context.Context, from common.Address) (*big.Int, error) {
+	if !r.Enabled() {
+		return nil, ErrDisabled
+	}
+	return r.blockchain.ForwarderNonce(ctx, from)
+}
+
+// BuildRequest prepares an unsigned forward request for a vault call. The
+// returned request and domain are what the user signs with eth_signTypedData_v4.
+func (r *Relayer) BuildRequest(ctx context.Context, from, vaultAddr common.Address, method string, args ...interface{}) (*crypto.ForwardRequest, *crypto.EIP712Domain, error) {
+	if !r.Enabled() {
+		return nil, nil, ErrDisabled
+	}
+	if !RelayableMethods[method] {
+		return nil, nil, ErrUnsupportedCall
+	}
+
+	domain, err := r.blockchain.ForwarderDomain(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err := r.blockchain.ForwarderNonce(ctx, from)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Estimating the direct call also surfaces the vault's revert reason
+	call, err := r.blockchain.BuildVaultTransaction(ctx, from, vaultAddr, method, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrWouldRevert, err)
+	}
+
+	return &crypto.ForwardRequest{
+		From:     from,
+		To:       vaultAddr,
+		Value:    big.NewInt(0),
+		Gas:      new(big.Int).SetUint64(call.Gas + call.Gas/gasMarginDivisor),
+		Nonce:    nonce,
+		Deadline: uint64(time.Now().Add(r.cfg.MaxDeadline).Unix()),
+		Data:     common.FromHex(call.Data),
+	}, domain, nil
+}
+
+// Relay verifies a signed forward request and submits it from the relayer
+// account. The relayed transaction is handed to the tracker, and the request
+// is recorded in meta_transactions. Pauses and unpauses are audited with reason.
+func (r *Relayer) Relay(ctx context.Context, req crypto.ForwardRequest, signature, reason string) (*models.MetaTransaction, error) {
+	if !r.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	// 1. Only relay plain calls to known vaults
+	vault, err := r.vaults.FindByContractAddress(ctx, req.To.Hex())
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUnknownVault
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Value != nil && req.Value.Sign() != 0 {
+		return nil, fmt.Errorf("%w: value transfers are not relayed", ErrUnsupportedCall)
+	}
+
+	method, args, err := service.DecodeVaultCall(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedCall, err)
+	}
+	if !RelayableMethods[method] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCall, method)
+	}
+
+	// 2. Deadline must be in the future, but not indefinitely
+	now := time.Now()
+	deadline := time.Unix(int64(req.Deadline), 0)
+	if !deadline.After(now) {
+		return nil, ErrExpired
+	}
+	if deadline.After(now.Add(r.cfg.MaxDeadline)) {
+		return nil, ErrDeadlineTooFar
+	}
+
+	// 3. Verify the signature against the forwarder's domain
+	domain, err := r.blockchain.ForwarderDomain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := crypto.HashForwardRequest(*domain, req)
+	if err != nil {
+		return nil, err
+	}
+
+	valid, err := crypto.VerifyTypedDataSignature(req.From.Hex(), digest, signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	// 4. Replay protection
+	if err := r.checkReplay(ctx, req, digest); err != nil {
+		return nil, err
+	}
+
+	// 5. Estimate and reserve gas before spending anything
+	sigBytes := common.FromHex(signature)
+	if sigBytes[64] < 27 {
+		sigBytes[64] += 27 // OpenZeppelin's ECDSA expects v = 27/28
+	}
+
+	request := bindings.ERC2771ForwarderForwardRequestData{
+		From:      req.From,
+		To:        req.To,
+		Value:     big.NewInt(0),
+		Gas:       req.Gas,
+		Deadline:  new(big.Int).SetUint64(req.Deadline),
+		Data:      req.Data,
+		Signature: sigBytes,
+	}
+
+	gasLimit, err := r.blockchain.EstimateForwardRequest(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWouldRevert, err)
+	}
+
+	signer := req.From.Hex()
+	if err := r.reserveGas(ctx, signer, gasLimit); err != nil {
+		return nil, err
+	}
+
+	// 6. Submit, giving the reservation back if the transaction never left
+	tx, err := r.blockchain.ExecuteForwardRequest(ctx, request, gasLimit)
+	if err != nil {
+		if releaseErr := r.releaseGas(ctx, signer, gasLimit); releaseErr != nil {
+			return nil, fmt.Errorf("%v (and failed to release gas budget: %v)", err, releaseErr)
+		}
+		return nil, err
+	}
+
+	// 7. Record the request and the relayer transaction
+	metaTx := models.MetaTransaction{
+		Signer:   signer,
+		Nonce:    req.Nonce.Uint64(),
+		VaultID:  vault.ID,
+		Method:   method,
+		Deadline: deadline,
+		Digest:   digest.Hex(),
+		TxHash:   tx.Hash().Hex(),
+		GasLimit: gasLimit,
+	}
+
+	if _, err := r.transactions.RecordVaultCall(ctx, &repository.VaultCall{
+		Tx:     tx,
+		From:   r.blockchain.RelayerAddress(),
+		Sender: req.From,
+		Vault:  vault,
+		Method: method,
+		Args:   args,
+		Reason: reason,
+		MetaTx: &metaTx,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record meta-transaction %s: %w", tx.Hash().Hex(), err)
+	}
+
+	return &metaTx, nil
+}
+
+// checkReplay rejects a request that was already relayed, whose nonce is not
+// the forwarder's current nonce, or whose nonce already has a pending transaction
+func (r *Relayer) checkReplay(ctx context.Context, req crypto.ForwardRequest, digest common.Hash) error {
+	relayed, err := r.metaTransactions.ExistsByDigest(ctx, digest.Hex())
+	if err != nil {
+		return err
+	}
+	if relayed {
+		return ErrReplayed
+	}
+
+	nonce, err := r.blockchain.ForwarderNonce(ctx, req.From)
+	if err != nil {
+		return err
+	}
+	if req.Nonce == nil || req.Nonce.Cmp(nonce) != 0 {
+		return fmt.Errorf("%w: expected %s", ErrNonceMismatch, nonce.String())
+	}
+
+	pending, err := r.metaTransactions.HasPending(ctx, req.From.Hex(), nonce.Uint64())
+	if err != nil {
+		return err
+	}
+	if pending {
+		return fmt.Errorf("%w: nonce %s is already pending", ErrReplayed, nonce.String())
+	}
+
+	return nil
+}
+
+// Budget returns the gas budget of an address, with an expired window reset
+func (r *Relayer) Budget(ctx context.Context, address common.Address) (*models.GasBudget, error) {
+	budget, err := r.gasBudgets.Find(ctx, address.Hex())
+	if errors.Is(err, repository.ErrNotFound) {
+		budget, err = &models.GasBudget{Address: address.Hex(), WindowStart: time.Now()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.resetExpiredWindow(budget)
+	return budget, nil
+}
+
+// BudgetLimit returns the gas each address may use per window (0 = unlimited)
+func (r *Relayer) BudgetLimit() uint64 {
+	return r.cfg.GasBudget
+}
+
+// BudgetWindow returns how long a gas budget window lasts
+func (r *Relayer) BudgetWindow() time.Duration {
+	return r.cfg.BudgetWindow
+}
+
+// reserveGas charges gas to an address's budget, failing if it would exceed the limit
+func (r *Relayer) reserveGas(ctx context.Context, address string, gas uint64) error {
+	return r.gasBudgets.Update(ctx, address, func(budget *models.GasBudget) error {
+		r.resetExpiredWindow(budget)
+
+		if r.cfg.GasBudget > 0 && budget.GasUsed+gas > r.cfg.GasBudget {
+			return fmt.Errorf("%w: %d of %d gas used, request needs %d", ErrGasBudgetExceeded, budget.GasUsed, r.cfg.GasBudget, gas)
+		}
+
+		budget.GasUsed += gas
+		return nil
+	})
+}
+
+// releaseGas returns a reservation for a transaction that was never broadcast
+func (r *Relayer) releaseGas(ctx context.Context, address string, gas uint64) error {
+	return r.gasBudgets.Update(ctx, address, func(budget *models.GasBudget) error {
+		if budget.GasUsed >= gas {
+			budget.GasUsed -= gas
+		}
+		return nil
+	})
+}
+
+func (r *Relayer) resetExpiredWindow(budget *models.GasBudget) {
+	if time.Since(budget.WindowStart) >= r.cfg.BudgetWindow {
+		budget.WindowStart = time.Now()
+		budget.GasUsed = 0
+	}
+}
@@ -0,0 +1,365 @@
+package relayer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/repository"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"github.com/haneumLee/legacychain/backend/pkg/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	relayerAddress   = common.HexToAddress("0x00000000000000000000000000000000000000F1")
+	forwarderAddress = common.HexToAddress("0x00000000000000000000000000000000000000F2")
+	vaultAddress     = common.HexToAddress("0x00000000000000000000000000000000000000C1")
+)
+
+const (
+	testGasBudget   = 250000
+	testForwardGas  = 100000
+	testMaxDeadline = time.Hour
+)
+
+// fakeChain stands in for the forwarder: nonces only move when the test says
+// a relayed transaction was mined. Calling any other BlockchainService method
+// panics on the nil embedded interface.
+type fakeChain struct {
+	service.BlockchainService
+
+	nonces     map[common.Address]int64
+	executed   []bindings.ERC2771ForwarderForwardRequestData
+	executeErr error
+}
+
+func (f *fakeChain) ForwarderAddress() common.Address {
+	return forwarderAddress
+}
+
+func (f *fakeChain) ForwarderDomain(ctx context.Context) (*crypto.EIP712Domain, error) {
+	return &crypto.EIP712Domain{
+		Name:              "LegacyChainForwarder",
+		Version:           "1",
+		ChainID:           big.NewInt(31337),
+		VerifyingContract: forwarderAddress,
+	}, nil
+}
+
+func (f *fakeChain) ForwarderNonce(ctx context.Context, from common.Address) (*big.Int, error) {
+	return big.NewInt(f.nonces[from]), nil
+}
+
+func (f *fakeChain) EstimateForwardRequest(ctx context.Context, request bindings.ERC2771ForwarderForwardRequestData) (uint64, error) {
+	return testForwardGas, nil
+}
+
+func (f *fakeChain) ExecuteForwardRequest(ctx context.Context, request bindings.ERC2771ForwarderForwardRequestData, gasLimit uint64) (*types.Transaction, error) {
+	if f.executeErr != nil {
+		return nil, f.executeErr
+	}
+	f.executed = append(f.executed, request)
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    uint64(len(f.executed)),
+		To:       &forwarderAddress,
+		Gas:      gasLimit,
+		GasPrice: big.NewInt(1),
+	}), nil
+}
+
+func (f *fakeChain) BuildVaultTransaction(ctx context.Context, from, vaultAddr common.Address, method string, args ...interface{}) (*service.UnsignedTx, error) {
+	vaultABI, err := bindings.IndividualVaultMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	data, err := vaultABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &service.UnsignedTx{From: from.Hex(), To: vaultAddr.Hex(), Data: hexutil.Encode(data), Gas: testForwardGas}, nil
+}
+
+func (f *fakeChain) RelayerAddress() common.Address {
+	return relayerAddress
+}
+
+type testRelayer struct {
+	*Relayer
+	chain *fakeChain
+	repos *repository.Repositories
+	key   *ecdsa.PrivateKey
+	heir  common.Address
+}
+
+// newTestRelayer returns a relayer over memory repositories with one vault
+// whose heir is the returned key
+func newTestRelayer(t *testing.T) *testRelayer {
+	t.Helper()
+	ctx := context.Background()
+
+	key, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+	heir := ethcrypto.PubkeyToAddress(key.PublicKey)
+
+	repos := repository.NewMemory()
+	owner, err := repos.Users.FindOrCreate(ctx, "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	require.NoError(t, err)
+	_, err = repos.Vaults.CreateWithHeirs(ctx, &models.Vault{
+		VaultID:         1,
+		ContractAddress: vaultAddress.Hex(),
+		OwnerID:         owner.ID,
+	}, []models.Heir{{Address: heir.Hex(), ShareBPS: 10000}}, "")
+	require.NoError(t, err)
+
+	chain := &fakeChain{nonces: make(map[common.Address]int64)}
+	cfg := &config.Config{Relayer: config.RelayerConfig{
+		Enabled:      true,
+		GasBudget:    testGasBudget,
+		BudgetWindow: 24 * time.Hour,
+		MaxDeadline:  testMaxDeadline,
+	}}
+
+	return &testRelayer{Relayer: New(repos, chain, cfg), chain: chain, repos: repos, key: key, heir: heir}
+}
+
+// request returns an approveInheritance request from the heir at its current forwarder nonce
+func (tr *testRelayer) request(t *testing.T) crypto.ForwardRequest {
+	t.Helper()
+	vaultABI, err := bindings.IndividualVaultMetaData.GetAbi()
+	require.NoError(t, err)
+	data, err := vaultABI.Pack("approveInheritance")
+	require.NoError(t, err)
+
+	return crypto.ForwardRequest{
+		From:     tr.heir,
+		To:       vaultAddress,
+		Value:    big.NewInt(0),
+		Gas:      big.NewInt(testForwardGas),
+		Nonce:    big.NewInt(tr.chain.nonces[tr.heir]),
+		Deadline: uint64(time.Now().Add(testMaxDeadline / 2).Unix()),
+		Data:     data,
+	}
+}
+
+// sign signs the request the way eth_signTypedData_v4 does
+func (tr *testRelayer) sign(t *testing.T, req crypto.ForwardRequest) string {
+	t.Helper()
+	domain, err := tr.chain.ForwarderDomain(context.Background())
+	require.NoError(t, err)
+	digest, err := crypto.HashForwardRequest(*domain, req)
+	require.NoError(t, err)
+	signature, err := ethcrypto.Sign(digest.Bytes(), tr.key)
+	require.NoError(t, err)
+	signature[64] += 27
+	return hexutil.Encode(signature)
+}
+
+// gasUsed returns the gas charged to the heir's budget
+func (tr *testRelayer) gasUsed(t *testing.T) uint64 {
+	t.Helper()
+	budget, err := tr.Budget(context.Background(), tr.heir)
+	require.NoError(t, err)
+	return budget.GasUsed
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRelayer(t)
+	req := tr.request(t)
+
+	metaTx, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+	require.NoError(t, err)
+	assert.Equal(t, tr.heir.Hex(), metaTx.Signer)
+	assert.Equal(t, "approveInheritance", metaTx.Method)
+	assert.Equal(t, uint64(testForwardGas), metaTx.GasLimit)
+	require.Len(t, tr.chain.executed, 1)
+	assert.Contains(t, []byte{27, 28}, tr.chain.executed[0].Signature[64], "OpenZeppelin expects v = 27/28")
+
+	record, err := tr.repos.Transactions.FindByHash(ctx, metaTx.TxHash)
+	require.NoError(t, err)
+	assert.Equal(t, relayerAddress.Hex(), record.FromAddress)
+	assert.Equal(t, uint64(testForwardGas), tr.gasUsed(t))
+
+	t.Run("wrong signer", func(t *testing.T) {
+		other, err := ethcrypto.GenerateKey()
+		require.NoError(t, err)
+		impostor := &testRelayer{Relayer: tr.Relayer, chain: tr.chain, key: other, heir: tr.heir}
+		next := tr.request(t)
+		next.Deadline++
+
+		_, err = tr.Relay(ctx, next, impostor.sign(t, next), "")
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("unknown vault", func(t *testing.T) {
+		next := tr.request(t)
+		next.To = common.HexToAddress("0x00000000000000000000000000000000000000C2")
+
+		_, err := tr.Relay(ctx, next, tr.sign(t, next), "")
+		assert.ErrorIs(t, err, ErrUnknownVault)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := New(tr.repos, tr.chain, &config.Config{})
+		_, err := disabled.Relay(ctx, req, tr.sign(t, req), "")
+		assert.ErrorIs(t, err, ErrDisabled)
+	})
+}
+
+func TestRelayReplay(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRelayer(t)
+	req := tr.request(t)
+	signature := tr.sign(t, req)
+
+	_, err := tr.Relay(ctx, req, signature, "")
+	require.NoError(t, err)
+
+	t.Run("same request", func(t *testing.T) {
+		_, err := tr.Relay(ctx, req, signature, "")
+		assert.ErrorIs(t, err, ErrReplayed)
+	})
+
+	t.Run("same nonce while pending", func(t *testing.T) {
+		// A fresh signature over a new deadline is a new digest for the same nonce
+		again := req
+		again.Deadline++
+
+		_, err := tr.Relay(ctx, again, tr.sign(t, again), "")
+		assert.ErrorIs(t, err, ErrReplayed)
+		assert.Contains(t, err.Error(), "already pending")
+	})
+
+	t.Run("stale nonce once mined", func(t *testing.T) {
+		tr.chain.nonces[tr.heir] = 1
+		stale := req
+		stale.Deadline += 2
+
+		_, err := tr.Relay(ctx, stale, tr.sign(t, stale), "")
+		assert.ErrorIs(t, err, ErrNonceMismatch)
+	})
+
+	t.Run("future nonce", func(t *testing.T) {
+		future := tr.request(t)
+		future.Nonce = big.NewInt(5)
+
+		_, err := tr.Relay(ctx, future, tr.sign(t, future), "")
+		assert.ErrorIs(t, err, ErrNonceMismatch)
+	})
+
+	t.Run("next nonce", func(t *testing.T) {
+		next := tr.request(t)
+		_, err := tr.Relay(ctx, next, tr.sign(t, next), "")
+		assert.NoError(t, err)
+	})
+
+	assert.Len(t, tr.chain.executed, 2)
+}
+
+func TestRelayDeadline(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRelayer(t)
+
+	tests := []struct {
+		name     string
+		deadline time.Time
+		wantErr  error
+	}{
+		{"passed", time.Now().Add(-time.Second), ErrExpired},
+		{"now", time.Now(), ErrExpired},
+		{"too far", time.Now().Add(testMaxDeadline + time.Minute), ErrDeadlineTooFar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tr.request(t)
+			req.Deadline = uint64(tt.deadline.Unix())
+
+			_, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	// Nothing was submitted or charged
+	assert.Empty(t, tr.chain.executed)
+	assert.Zero(t, tr.gasUsed(t))
+
+	t.Run("built requests stay within the limit", func(t *testing.T) {
+		req, _, err := tr.BuildRequest(ctx, tr.heir, vaultAddress, "approveInheritance")
+		require.NoError(t, err)
+		assert.LessOrEqual(t, req.Deadline, uint64(time.Now().Add(testMaxDeadline).Unix()))
+
+		_, err = tr.Relay(ctx, *req, tr.sign(t, *req), "")
+		assert.NoError(t, err)
+	})
+}
+
+func TestRelayGasBudget(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRelayer(t)
+
+	// The budget fits two requests
+	for i := 0; i < 2; i++ {
+		req := tr.request(t)
+		_, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+		require.NoError(t, err)
+		tr.chain.nonces[tr.heir]++
+	}
+	assert.Equal(t, uint64(2*testForwardGas), tr.gasUsed(t))
+
+	t.Run("exceeded", func(t *testing.T) {
+		req := tr.request(t)
+		_, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+		assert.ErrorIs(t, err, ErrGasBudgetExceeded)
+		assert.Len(t, tr.chain.executed, 2)
+		assert.Equal(t, uint64(2*testForwardGas), tr.gasUsed(t))
+	})
+
+	t.Run("window reset", func(t *testing.T) {
+		require.NoError(t, tr.repos.GasBudgets.Update(ctx, tr.heir.Hex(), func(budget *models.GasBudget) error {
+			budget.WindowStart = time.Now().Add(-tr.BudgetWindow())
+			return nil
+		}))
+		assert.Zero(t, tr.gasUsed(t))
+
+		req := tr.request(t)
+		_, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(testForwardGas), tr.gasUsed(t))
+		tr.chain.nonces[tr.heir]++
+	})
+
+	t.Run("released when submission fails", func(t *testing.T) {
+		tr.chain.executeErr = errors.New("nonce too low")
+		defer func() { tr.chain.executeErr = nil }()
+
+		req := tr.request(t)
+		_, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+		assert.ErrorContains(t, err, "nonce too low")
+		assert.Equal(t, uint64(testForwardGas), tr.gasUsed(t))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		tr.cfg.GasBudget = 0
+		defer func() { tr.cfg.GasBudget = testGasBudget }()
+
+		for i := 0; i < 3; i++ {
+			req := tr.request(t)
+			_, err := tr.Relay(ctx, req, tr.sign(t, req), "")
+			require.NoError(t, err)
+			tr.chain.nonces[tr.heir]++
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// gormHeartbeatRepository is the Postgres HeartbeatRepository
+type gormHeartbeatRepository struct {
+	db *gorm.DB
+}
+
+// NewHeartbeatRepository returns a HeartbeatRepository backed by db
+func NewHeartbeatRepository(db *gorm.DB) HeartbeatRepository {
+	return &gormHeartbeatRepository{db: db}
+}
+
+// Create stores a new heartbeat
+func (r *gormHeartbeatRepository) Create(ctx context.Context, heartbeat *models.Heartbeat) error {
+	return wrap(r.db.WithContext(ctx).Create(heartbeat).Error, "create heartbeat")
+}
+
+// UpdateStatus sets the status of heartbeat
+func (r *gormHeartbeatRepository) UpdateStatus(ctx context.Context, heartbeat *models.Heartbeat, status models.HeartbeatStatus) error {
+	return wrap(r.db.WithContext(ctx).Model(heartbeat).Update("status", status).Error, "update heartbeat")
+}
+
+// FindCommitted returns the latest heartbeat of the vault committed with the
+// nonce and not yet revealed
+func (r *gormHeartbeatRepository) FindCommitted(ctx context.Context, vaultID uuid.UUID, nonce string) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ? AND nonce = ? AND status = ?", vaultID, nonce, models.HeartbeatStatusCommitted).
+		Order("committed_at DESC").
+		First(&heartbeat).Error
+	if err != nil {
+		return nil, wrap(err, "query heartbeat")
+	}
+	return &heartbeat, nil
+}
+
+// Latest returns the vault's most recently committed heartbeat
+func (r *gormHeartbeatRepository) Latest(ctx context.Context, vaultID uuid.UUID) (*models.Heartbeat, error) {
+	var heartbeat models.Heartbeat
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ?", vaultID).
+		Order("committed_at DESC").
+		First(&heartbeat).Error
+	if err != nil {
+		return nil, wrap(err, "query heartbeat")
+	}
+	return &heartbeat, nil
+}
+
+// ListByVault returns the vault's heartbeats, newest first
+func (r *gormHeartbeatRepository) ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.Heartbeat, error) {
+	heartbeats := []models.Heartbeat{}
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ?", vaultID).
+		Order("committed_at DESC").
+		Find(&heartbeats).Error
+	if err != nil {
+		return nil, wrap(err, "query heartbeats")
+	}
+	return heartbeats, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// gormHeirRepository is the Postgres HeirRepository
+type gormHeirRepository struct {
+	db *gorm.DB
+}
+
+// NewHeirRepository returns a HeirRepository backed by db
+func NewHeirRepository(db *gorm.DB) HeirRepository {
+	return &gormHeirRepository{db: db}
+}
+
+// FindByVaultAndAddress returns the vault's heir with the wallet address
+func (r *gormHeirRepository) FindByVaultAndAddress(ctx context.Context, vaultID uuid.UUID, address string) (*models.Heir, error) {
+	var heir models.Heir
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ? AND LOWER(address) = LOWER(?)", vaultID, address).
+		First(&heir).Error
+	if err != nil {
+		return nil, wrap(err, "query heir")
+	}
+	return &heir, nil
+}
+
+// ListByVault returns the vault's heirs in the order they were added
+func (r *gormHeirRepository) ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.Heir, error) {
+	heirs := []models.Heir{}
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ?", vaultID).
+		Order("created_at, id").
+		Find(&heirs).Error
+	if err != nil {
+		return nil, wrap(err, "query heirs")
+	}
+	return heirs, nil
+}
+
+// CountByVault returns the number of heirs of the vault
+func (r *gormHeirRepository) CountByVault(ctx context.Context, vaultID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Heir{}).Where("vault_id = ?", vaultID).Count(&count).Error
+	if err != nil {
+		return 0, wrap(err, "count heirs")
+	}
+	return count, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// gormLedgerRepository is the Postgres LedgerRepository
+type gormLedgerRepository struct {
+	db *gorm.DB
+}
+
+// NewLedgerRepository returns a LedgerRepository backed by db
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &gormLedgerRepository{db: db}
+}
+
+// Create stores a ledger entry
+func (r *gormLedgerRepository) Create(ctx context.Context, entry *models.VaultLedgerEntry) error {
+	return wrap(r.db.WithContext(ctx).Create(entry).Error, "create ledger entry")
+}
+
+// ListByVault returns a page of the vault's entries, newest first in chain order
+func (r *gormLedgerRepository) ListByVault(ctx context.Context, vaultID uuid.UUID, limit, offset int) ([]models.VaultLedgerEntry, error) {
+	entries := []models.VaultLedgerEntry{}
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ?", vaultID).
+		Order("block_number DESC, log_index DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, wrap(err, "query ledger")
+	}
+	return entries, nil
+}
+
+// gormAuditRepository is the Postgres AuditRepository
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository returns an AuditRepository backed by db
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &gormAuditRepository{db: db}
+}
+
+// ListByVault returns the vault's audit entries, newest first
+func (r *gormAuditRepository) ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.VaultAuditLog, error) {
+	entries := []models.VaultAuditLog{}
+	err := r.db.WithContext(ctx).
+		Where("vault_id = ?", vaultID).
+		Order("created_at DESC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, wrap(err, "query audit log")
+	}
+	return entries, nil
+}
@@ -0,0 +1,693 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+)
+
+// memoryStore holds the records of the in-memory repositories, in the order
+// they were created. Records are stored and returned as copies, without
+// relationships, as rows would be.
+type memoryStore struct {
+	mu               sync.Mutex
+	users            []models.User
+	vaults           []models.Vault
+	heirs            []models.Heir
+	heartbeats       []models.Heartbeat
+	transactions     []models.Transaction
+	ledger           []models.VaultLedgerEntry
+	auditLogs        []models.VaultAuditLog
+	metaTransactions []models.MetaTransaction
+	gasBudgets       []models.GasBudget
+}
+
+// NewMemory returns repositories that keep everything in memory, for tests.
+// They behave like the Postgres ones: IDs and timestamps are assigned on
+// create, contract addresses, vault IDs and transaction hashes are unique, and
+// addresses compare case-insensitively. Transactions are never replaced, since
+// only the tracker replaces them.
+func NewMemory() *Repositories {
+	store := &memoryStore{}
+	return &Repositories{
+		Users:            &memoryUserRepository{store},
+		Vaults:           &memoryVaultRepository{store},
+		Heirs:            &memoryHeirRepository{store},
+		Heartbeats:       &memoryHeartbeatRepository{store},
+		Transactions:     &memoryTransactionRepository{store},
+		Ledger:           &memoryLedgerRepository{store},
+		Audit:            &memoryAuditRepository{store},
+		MetaTransactions: &memoryMetaTransactionRepository{store},
+		GasBudgets:       &memoryGasBudgetRepository{store},
+	}
+}
+
+// user returns the stored user with the ID. The caller holds mu.
+func (s *memoryStore) user(id uuid.UUID) *models.User {
+	for i := range s.users {
+		if s.users[i].ID == id {
+			return &s.users[i]
+		}
+	}
+	return nil
+}
+
+// vault returns a copy of the stored vault with its owner and the given
+// relationships. The caller holds mu.
+func (s *memoryStore) vault(stored models.Vault, preload []string) (models.Vault, error) {
+	vault := stored
+	if owner := s.user(vault.OwnerID); owner != nil {
+		vault.Owner = *owner
+	}
+
+	for _, relationship := range preload {
+		switch relationship {
+		case "Heirs":
+			vault.Heirs = s.vaultHeirs(vault.ID)
+		case "Heartbeats":
+			vault.Heartbeats = []models.Heartbeat{}
+			for _, heartbeat := range s.heartbeats {
+				if heartbeat.VaultID == vault.ID {
+					vault.Heartbeats = append(vault.Heartbeats, heartbeat)
+				}
+			}
+		default:
+			return models.Vault{}, fmt.Errorf("failed to query vault: unsupported relationship %q", relationship)
+		}
+	}
+
+	return vault, nil
+}
+
+// vaultHeirs returns copies of the vault's heirs. The caller holds mu.
+func (s *memoryStore) vaultHeirs(vaultID uuid.UUID) []models.Heir {
+	heirs := []models.Heir{}
+	for _, heir := range s.heirs {
+		if heir.VaultID == vaultID {
+			heirs = append(heirs, heir)
+		}
+	}
+	return heirs
+}
+
+// linkTransaction links the transaction record with the hash, if any, to the
+// vault. The caller holds mu.
+func (s *memoryStore) linkTransaction(hash string, vaultID uuid.UUID) {
+	for i := range s.transactions {
+		if hash != "" && s.transactions[i].Hash == hash {
+			id := vaultID
+			s.transactions[i].VaultID = &id
+		}
+	}
+}
+
+type memoryUserRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryUserRepository) FindByAddress(ctx context.Context, address string) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.findByAddress(address)
+}
+
+func (r *memoryUserRepository) FindOrCreate(ctx context.Context, address string) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user, err := r.findByAddress(address); err == nil {
+		return user, nil
+	}
+
+	now := time.Now()
+	user := models.User{ID: uuid.New(), Address: address, CreatedAt: now, UpdatedAt: now}
+	r.store.users = append(r.store.users, user)
+	return &user, nil
+}
+
+func (r *memoryUserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored := r.store.user(user.ID)
+	if stored == nil {
+		return ErrNotFound
+	}
+	user.UpdatedAt = time.Now()
+	stored.Email, stored.Nickname, stored.UpdatedAt = user.Email, user.Nickname, user.UpdatedAt
+	return nil
+}
+
+// findByAddress looks up a user. The caller holds mu.
+func (r *memoryUserRepository) findByAddress(address string) (*models.User, error) {
+	for _, user := range r.store.users {
+		if strings.EqualFold(user.Address, address) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+type memoryVaultRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryVaultRepository) FindByID(ctx context.Context, id uuid.UUID, preload ...string) (*models.Vault, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, stored := range r.store.vaults {
+		if stored.ID == id {
+			vault, err := r.store.vault(stored, preload)
+			if err != nil {
+				return nil, err
+			}
+			return &vault, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryVaultRepository) FindByContractAddress(ctx context.Context, address string) (*models.Vault, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, vault := range r.store.vaults {
+		if strings.EqualFold(vault.ContractAddress, address) {
+			return &vault, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryVaultRepository) ListByOwnerAddress(ctx context.Context, address string, preload ...string) ([]models.Vault, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	vaults := []models.Vault{}
+	for _, stored := range r.store.vaults {
+		owner := r.store.user(stored.OwnerID)
+		if owner == nil || !strings.EqualFold(owner.Address, address) {
+			continue
+		}
+		vault, err := r.store.vault(stored, preload)
+		if err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, vault)
+	}
+	return vaults, nil
+}
+
+func (r *memoryVaultRepository) VisibleIDs(ctx context.Context, address string, ids []uuid.UUID) ([]uuid.UUID, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	requested := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	visible := []uuid.UUID{}
+	for _, vault := range r.store.vaults {
+		if ids != nil && !requested[vault.ID] {
+			continue
+		}
+		if r.visible(vault, address) {
+			visible = append(visible, vault.ID)
+		}
+	}
+	return visible, nil
+}
+
+// visible reports whether address owns or is an heir of the vault. The
+// caller holds mu.
+func (r *memoryVaultRepository) visible(vault models.Vault, address string) bool {
+	if owner := r.store.user(vault.OwnerID); owner != nil && strings.EqualFold(owner.Address, address) {
+		return true
+	}
+	for _, heir := range r.store.heirs {
+		if heir.VaultID == vault.ID && strings.EqualFold(heir.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryVaultRepository) CreateWithHeirs(ctx context.Context, vault *models.Vault, heirs []models.Heir, deployTxHash string) (*models.Vault, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, stored := range r.store.vaults {
+		if strings.EqualFold(stored.ContractAddress, vault.ContractAddress) {
+			r.store.linkTransaction(deployTxHash, stored.ID)
+			existing, err := r.store.vault(stored, []string{"Heirs"})
+			if err != nil {
+				return nil, err
+			}
+			return &existing, nil
+		}
+		if stored.VaultID == vault.VaultID {
+			return nil, fmt.Errorf("failed to create vault: vault_id %d already exists", vault.VaultID)
+		}
+	}
+	if r.store.user(vault.OwnerID) == nil {
+		return nil, fmt.Errorf("failed to create vault: owner %s does not exist", vault.OwnerID)
+	}
+
+	now := time.Now()
+	if vault.ID == uuid.Nil {
+		vault.ID = uuid.New()
+	}
+	vault.CreatedAt, vault.UpdatedAt = now, now
+	stored := *vault
+	stored.Owner, stored.Heirs, stored.Heartbeats = models.User{}, nil, nil
+	r.store.vaults = append(r.store.vaults, stored)
+
+	for i := range heirs {
+		if heirs[i].ID == uuid.Nil {
+			heirs[i].ID = uuid.New()
+		}
+		heirs[i].VaultID = vault.ID
+		heirs[i].CreatedAt, heirs[i].UpdatedAt = now, now
+		heir := heirs[i]
+		heir.Vault = models.Vault{}
+		r.store.heirs = append(r.store.heirs, heir)
+	}
+	r.store.linkTransaction(deployTxHash, vault.ID)
+
+	created, err := r.store.vault(stored, []string{"Heirs"})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+type memoryHeirRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryHeirRepository) FindByVaultAndAddress(ctx context.Context, vaultID uuid.UUID, address string) (*models.Heir, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, heir := range r.store.heirs {
+		if heir.VaultID == vaultID && strings.EqualFold(heir.Address, address) {
+			return &heir, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryHeirRepository) ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.Heir, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.store.vaultHeirs(vaultID), nil
+}
+
+func (r *memoryHeirRepository) CountByVault(ctx context.Context, vaultID uuid.UUID) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return int64(len(r.store.vaultHeirs(vaultID))), nil
+}
+
+type memoryHeartbeatRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryHeartbeatRepository) Create(ctx context.Context, heartbeat *models.Heartbeat) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if heartbeat.ID == uuid.Nil {
+		heartbeat.ID = uuid.New()
+	}
+	if heartbeat.Status == "" {
+		heartbeat.Status = models.HeartbeatStatusCommitted
+	}
+	now := time.Now()
+	heartbeat.CreatedAt, heartbeat.UpdatedAt = now, now
+
+	stored := *heartbeat
+	stored.Vault = models.Vault{}
+	r.store.heartbeats = append(r.store.heartbeats, stored)
+	return nil
+}
+
+func (r *memoryHeartbeatRepository) UpdateStatus(ctx context.Context, heartbeat *models.Heartbeat, status models.HeartbeatStatus) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i := range r.store.heartbeats {
+		if r.store.heartbeats[i].ID == heartbeat.ID {
+			heartbeat.Status, heartbeat.UpdatedAt = status, time.Now()
+			r.store.heartbeats[i].Status = heartbeat.Status
+			r.store.heartbeats[i].UpdatedAt = heartbeat.UpdatedAt
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *memoryHeartbeatRepository) FindCommitted(ctx context.Context, vaultID uuid.UUID, nonce string) (*models.Heartbeat, error) {
+	for _, heartbeat := range r.newestFirst(vaultID) {
+		if heartbeat.Nonce == nonce && heartbeat.Status == models.HeartbeatStatusCommitted {
+			return &heartbeat, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryHeartbeatRepository) Latest(ctx context.Context, vaultID uuid.UUID) (*models.Heartbeat, error) {
+	heartbeats := r.newestFirst(vaultID)
+	if len(heartbeats) == 0 {
+		return nil, ErrNotFound
+	}
+	return &heartbeats[0], nil
+}
+
+func (r *memoryHeartbeatRepository) ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.Heartbeat, error) {
+	return r.newestFirst(vaultID), nil
+}
+
+// newestFirst returns copies of the vault's heartbeats by commit time, newest
+// first
+func (r *memoryHeartbeatRepository) newestFirst(vaultID uuid.UUID) []models.Heartbeat {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	heartbeats := []models.Heartbeat{}
+	for _, heartbeat := range r.store.heartbeats {
+		if heartbeat.VaultID == vaultID {
+			heartbeats = append(heartbeats, heartbeat)
+		}
+	}
+	sort.SliceStable(heartbeats, func(i, j int) bool {
+		return heartbeats[i].CommittedAt.After(heartbeats[j].CommittedAt)
+	})
+	return heartbeats
+}
+
+type memoryTransactionRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryTransactionRepository) FindByHash(ctx context.Context, hash string) (*models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, record := range r.store.transactions {
+		if strings.EqualFold(record.Hash, hash) {
+			return &record, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTransactionRepository) Record(ctx context.Context, tx *types.Transaction, from common.Address, method string, link tracker.Link) (*models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	record, err := r.newRecord(tx, from, method)
+	if err != nil {
+		return nil, err
+	}
+	record.VaultID, record.HeartbeatID, record.HeirID = link.VaultID, link.HeartbeatID, link.HeirID
+
+	if method == "revealHeartbeat" && link.HeartbeatID != nil {
+		for i := range r.store.heartbeats {
+			if r.store.heartbeats[i].ID == *link.HeartbeatID {
+				r.store.heartbeats[i].RevealTxHash = record.Hash
+			}
+		}
+	}
+
+	r.store.transactions = append(r.store.transactions, record)
+	return &record, nil
+}
+
+func (r *memoryTransactionRepository) RecordVaultCall(ctx context.Context, call *VaultCall) (*models.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if call.MetaTx != nil {
+		for _, metaTx := range r.store.metaTransactions {
+			if metaTx.Digest == call.MetaTx.Digest {
+				return nil, fmt.Errorf("failed to record transaction: digest %s already exists", metaTx.Digest)
+			}
+		}
+	}
+
+	record, err := r.newRecord(call.Tx, call.From, call.Method)
+	if err != nil {
+		return nil, err
+	}
+	hash, now := record.Hash, record.CreatedAt
+	record.VaultID = &call.Vault.ID
+
+	// Link the rows the call updates, as tracker.LinkVaultCall does
+	switch call.Method {
+	case "approveInheritance", "claimInheritance":
+		for _, heir := range r.store.heirs {
+			if heir.VaultID == call.Vault.ID && strings.EqualFold(heir.Address, call.Sender.Hex()) {
+				record.HeirID = &heir.ID
+			}
+		}
+
+	case "revealHeartbeat":
+		nonce, ok := call.Args[0].([32]byte)
+		if !ok {
+			return nil, fmt.Errorf("failed to record transaction: unexpected revealHeartbeat argument %T", call.Args[0])
+		}
+		commitHash := crypto.Keccak256Hash(call.Sender.Bytes(), nonce[:]).Hex()
+
+		var revealed *models.Heartbeat
+		for i := range r.store.heartbeats {
+			heartbeat := &r.store.heartbeats[i]
+			if heartbeat.VaultID == call.Vault.ID && heartbeat.CommitHash == commitHash && heartbeat.Status == models.HeartbeatStatusCommitted &&
+				(revealed == nil || heartbeat.CommittedAt.After(revealed.CommittedAt)) {
+				revealed = heartbeat
+			}
+		}
+		if revealed != nil {
+			revealed.RevealTxHash = hash
+			record.HeartbeatID = &revealed.ID
+		}
+	}
+
+	if call.Heartbeat != nil {
+		call.Heartbeat.ID = uuid.New()
+		call.Heartbeat.CreatedAt, call.Heartbeat.UpdatedAt = now, now
+		heartbeat := *call.Heartbeat
+		heartbeat.Vault = models.Vault{}
+		r.store.heartbeats = append(r.store.heartbeats, heartbeat)
+		record.HeartbeatID = &heartbeat.ID
+	}
+
+	r.store.transactions = append(r.store.transactions, record)
+
+	if action, ok := tracker.AuditAction(call.Method); ok {
+		r.store.auditLogs = append(r.store.auditLogs, models.VaultAuditLog{
+			ID:          uuid.New(),
+			VaultID:     call.Vault.ID,
+			Action:      action,
+			Actor:       call.Sender.Hex(),
+			Reason:      call.Reason,
+			TxHash:      hash,
+			RequestedAt: &now,
+			CreatedAt:   now,
+		})
+	}
+
+	if call.MetaTx != nil {
+		call.MetaTx.ID = uuid.New()
+		call.MetaTx.CreatedAt = now
+		r.store.metaTransactions = append(r.store.metaTransactions, *call.MetaTx)
+	}
+
+	return &record, nil
+}
+
+// newRecord returns the pending record of tx. The caller holds mu.
+func (r *memoryTransactionRepository) newRecord(tx *types.Transaction, from common.Address, method string) (models.Transaction, error) {
+	hash := tx.Hash().Hex()
+	for _, record := range r.store.transactions {
+		if record.Hash == hash {
+			return models.Transaction{}, fmt.Errorf("failed to record transaction: hash %s already exists", hash)
+		}
+	}
+
+	now := time.Now()
+	record := models.Transaction{
+		ID:          uuid.New(),
+		Hash:        hash,
+		FromAddress: from.Hex(),
+		Method:      method,
+		Nonce:       tx.Nonce(),
+		Status:      models.TransactionStatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if tx.To() != nil {
+		record.ToAddress = tx.To().Hex()
+	}
+	return record, nil
+}
+
+type memoryLedgerRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryLedgerRepository) Create(ctx context.Context, entry *models.VaultLedgerEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, stored := range r.store.ledger {
+		if stored.TxHash == entry.TxHash && stored.LogIndex == entry.LogIndex {
+			return fmt.Errorf("failed to create ledger entry: log %s/%d already exists", entry.TxHash, entry.LogIndex)
+		}
+	}
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	entry.CreatedAt = time.Now()
+	r.store.ledger = append(r.store.ledger, *entry)
+	return nil
+}
+
+func (r *memoryLedgerRepository) ListByVault(ctx context.Context, vaultID uuid.UUID, limit, offset int) ([]models.VaultLedgerEntry, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entries := []models.VaultLedgerEntry{}
+	for _, entry := range r.store.ledger {
+		if entry.VaultID == vaultID {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].BlockNumber != entries[j].BlockNumber {
+			return entries[i].BlockNumber > entries[j].BlockNumber
+		}
+		return entries[i].LogIndex > entries[j].LogIndex
+	})
+	return page(entries, limit, offset), nil
+}
+
+// page returns the records from offset, at most limit of them
+func page[T any](records []T, limit, offset int) []T {
+	if offset >= len(records) {
+		return records[:0]
+	}
+	return records[offset:min(offset+limit, len(records))]
+}
+
+type memoryAuditRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAuditRepository) ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.VaultAuditLog, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Stored in creation order
+	entries := []models.VaultAuditLog{}
+	for i := len(r.store.auditLogs) - 1; i >= 0; i-- {
+		if r.store.auditLogs[i].VaultID == vaultID {
+			entries = append(entries, r.store.auditLogs[i])
+		}
+	}
+	return entries, nil
+}
+
+type memoryMetaTransactionRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryMetaTransactionRepository) ExistsByDigest(ctx context.Context, digest string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, metaTx := range r.store.metaTransactions {
+		if metaTx.Digest == digest {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryMetaTransactionRepository) HasPending(ctx context.Context, signer string, nonce uint64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, metaTx := range r.store.metaTransactions {
+		if !strings.EqualFold(metaTx.Signer, signer) || metaTx.Nonce != nonce {
+			continue
+		}
+		for _, record := range r.store.transactions {
+			if record.Hash == metaTx.TxHash && record.Status == models.TransactionStatusPending {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+type memoryGasBudgetRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryGasBudgetRepository) Find(ctx context.Context, address string) (*models.GasBudget, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if budget := r.budget(address); budget != nil {
+		found := *budget
+		return &found, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryGasBudgetRepository) Update(ctx context.Context, address string, update func(budget *models.GasBudget) error) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored := r.budget(address)
+	budget := models.GasBudget{Address: address, WindowStart: time.Now()}
+	if stored != nil {
+		budget = *stored
+	}
+
+	if err := update(&budget); err != nil {
+		return err
+	}
+
+	budget.UpdatedAt = time.Now()
+	if stored != nil {
+		*stored = budget
+	} else {
+		r.store.gasBudgets = append(r.store.gasBudgets, budget)
+	}
+	return nil
+}
+
+// budget returns the stored gas budget of the address. The caller holds mu.
+func (r *memoryGasBudgetRepository) budget(address string) *models.GasBudget {
+	for i := range r.store.gasBudgets {
+		if r.store.gasBudgets[i].Address == address {
+			return &r.store.gasBudgets[i]
+		}
+	}
+	return nil
+}
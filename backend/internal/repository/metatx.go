@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormMetaTransactionRepository is the Postgres MetaTransactionRepository
+type gormMetaTransactionRepository struct {
+	db *gorm.DB
+}
+
+// NewMetaTransactionRepository returns a MetaTransactionRepository backed by db
+func NewMetaTransactionRepository(db *gorm.DB) MetaTransactionRepository {
+	return &gormMetaTransactionRepository{db: db}
+}
+
+// ExistsByDigest reports whether the request with the EIP-712 digest was
+// already relayed
+func (r *gormMetaTransactionRepository) ExistsByDigest(ctx context.Context, digest string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.MetaTransaction{}).
+		Where("digest = ?", digest).
+		Count(&count).Error; err != nil {
+		return false, wrap(err, "query meta-transactions")
+	}
+	return count > 0, nil
+}
+
+// HasPending reports whether a request of signer with the forwarder nonce has
+// a pending relayer transaction
+func (r *gormMetaTransactionRepository) HasPending(ctx context.Context, signer string, nonce uint64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.MetaTransaction{}).
+		Joins("JOIN transactions ON transactions.hash = meta_transactions.tx_hash").
+		Where("LOWER(meta_transactions.signer) = LOWER(?) AND meta_transactions.nonce = ? AND transactions.status = ?",
+			signer, nonce, models.TransactionStatusPending).
+		Count(&count).Error
+	if err != nil {
+		return false, wrap(err, "query pending meta-transactions")
+	}
+	return count > 0, nil
+}
+
+// gormGasBudgetRepository is the Postgres GasBudgetRepository
+type gormGasBudgetRepository struct {
+	db *gorm.DB
+}
+
+// NewGasBudgetRepository returns a GasBudgetRepository backed by db
+func NewGasBudgetRepository(db *gorm.DB) GasBudgetRepository {
+	return &gormGasBudgetRepository{db: db}
+}
+
+// Find returns the gas budget of the address
+func (r *gormGasBudgetRepository) Find(ctx context.Context, address string) (*models.GasBudget, error) {
+	var budget models.GasBudget
+	if err := r.db.WithContext(ctx).Where("address = ?", address).First(&budget).Error; err != nil {
+		return nil, wrap(err, "query gas budget")
+	}
+	return &budget, nil
+}
+
+// Update loads the address's budget, locking its row until it is saved, and
+// saves it if update returns nil
+func (r *gormGasBudgetRepository) Update(ctx context.Context, address string, update func(budget *models.GasBudget) error) error {
+	var updateErr error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		budget := models.GasBudget{Address: address, WindowStart: time.Now()}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(models.GasBudget{Address: address}).
+			FirstOrCreate(&budget).Error; err != nil {
+			return err
+		}
+
+		if updateErr = update(&budget); updateErr != nil {
+			return updateErr
+		}
+
+		return tx.Model(&budget).Updates(map[string]interface{}{
+			"window_start": budget.WindowStart,
+			"gas_used":     budget.GasUsed,
+		}).Error
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	return wrap(err, "update gas budget")
+}
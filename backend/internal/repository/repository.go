@@ -0,0 +1,184 @@
+// Package repository holds the queries for users, vaults, heirs, heartbeats
+// and the transactions, ledger entries, audit entries and meta-transactions
+// recorded for them, so handlers do not hand-write GORM conditions. Vault ownership
+// is always resolved through Vault.OwnerID and the owner's User row; wallet
+// addresses compare case-insensitively.
+//
+// NewGorm returns the Postgres implementation the server runs on. NewMemory
+// returns an in-memory one with the same behavior, for handler tests that run
+// without a database.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no record matches
+var ErrNotFound = errors.New("record not found")
+
+// UserRepository stores users
+type UserRepository interface {
+	// FindByAddress returns the user with the wallet address
+	FindByAddress(ctx context.Context, address string) (*models.User, error)
+	// FindOrCreate returns the user with the wallet address, creating it on
+	// first login
+	FindOrCreate(ctx context.Context, address string) (*models.User, error)
+	// UpdateProfile saves the user's email and nickname
+	UpdateProfile(ctx context.Context, user *models.User) error
+}
+
+// VaultRepository stores vaults
+type VaultRepository interface {
+	// FindByID returns the vault with its owner and the given relationships
+	// (Heirs, Heartbeats) loaded
+	FindByID(ctx context.Context, id uuid.UUID, preload ...string) (*models.Vault, error)
+	// FindByContractAddress returns the vault deployed at the address
+	FindByContractAddress(ctx context.Context, address string) (*models.Vault, error)
+	// ListByOwnerAddress returns the vaults of the user with the wallet
+	// address, oldest first, with their owner and the given relationships
+	// loaded
+	ListByOwnerAddress(ctx context.Context, address string, preload ...string) ([]models.Vault, error)
+	// VisibleIDs returns the IDs of the vaults the wallet address owns or is
+	// an heir of. If ids are given, only those among them are returned.
+	VisibleIDs(ctx context.Context, address string, ids []uuid.UUID) ([]uuid.UUID, error)
+	// CreateWithHeirs stores a vault confirmed on-chain together with its
+	// heirs and returns it with its owner and heirs loaded. If the contract is
+	// already recorded, that vault is returned instead. A non-empty
+	// deployTxHash links that transaction record to the vault atomically.
+	CreateWithHeirs(ctx context.Context, vault *models.Vault, heirs []models.Heir, deployTxHash string) (*models.Vault, error)
+}
+
+// HeirRepository stores heirs
+type HeirRepository interface {
+	// FindByVaultAndAddress returns the vault's heir with the wallet address
+	FindByVaultAndAddress(ctx context.Context, vaultID uuid.UUID, address string) (*models.Heir, error)
+	// ListByVault returns the vault's heirs in the order they were added
+	ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.Heir, error)
+	// CountByVault returns the number of heirs of the vault
+	CountByVault(ctx context.Context, vaultID uuid.UUID) (int64, error)
+}
+
+// HeartbeatRepository stores heartbeats
+type HeartbeatRepository interface {
+	// Create stores a new heartbeat
+	Create(ctx context.Context, heartbeat *models.Heartbeat) error
+	// UpdateStatus sets the status of heartbeat
+	UpdateStatus(ctx context.Context, heartbeat *models.Heartbeat, status models.HeartbeatStatus) error
+	// FindCommitted returns the latest heartbeat of the vault committed with
+	// the nonce and not yet revealed
+	FindCommitted(ctx context.Context, vaultID uuid.UUID, nonce string) (*models.Heartbeat, error)
+	// Latest returns the vault's most recently committed heartbeat
+	Latest(ctx context.Context, vaultID uuid.UUID) (*models.Heartbeat, error)
+	// ListByVault returns the vault's heartbeats, newest first
+	ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.Heartbeat, error)
+}
+
+// TransactionRepository stores the transactions the tracker follows
+type TransactionRepository interface {
+	// FindByHash returns the transaction with the hash, also by a hash it
+	// replaced with higher fees
+	FindByHash(ctx context.Context, hash string) (*models.Transaction, error)
+	// Record stores a transaction sent from the server account as pending,
+	// linked to the rows it updates once mined
+	Record(ctx context.Context, tx *types.Transaction, from common.Address, method string, link tracker.Link) (*models.Transaction, error)
+	// RecordVaultCall stores a broadcast vault call as pending, linked to the
+	// rows it updates once mined, together with the rows it creates
+	RecordVaultCall(ctx context.Context, call *VaultCall) (*models.Transaction, error)
+}
+
+// VaultCall is a broadcast call to a vault, to be tracked until it is mined
+type VaultCall struct {
+	Tx     *types.Transaction
+	From   common.Address // Account that sent Tx: the caller, or the relayer for meta-transactions
+	Sender common.Address // Account the vault sees as msg.sender
+	Vault  *models.Vault
+	Method string
+	Args   []interface{}
+	Reason string // Why the vault is paused or unpaused, kept in its audit log
+
+	Heartbeat *models.Heartbeat       // Heartbeat a commitHeartbeat opens, created with the call
+	MetaTx    *models.MetaTransaction // Forward request the call relays, created with it
+}
+
+// LedgerRepository stores the indexed deposits, withdrawals and claims of vaults
+type LedgerRepository interface {
+	// Create stores a ledger entry
+	Create(ctx context.Context, entry *models.VaultLedgerEntry) error
+	// ListByVault returns a page of the vault's entries, newest first in
+	// chain order
+	ListByVault(ctx context.Context, vaultID uuid.UUID, limit, offset int) ([]models.VaultLedgerEntry, error)
+}
+
+// AuditRepository stores the pause history of vaults
+type AuditRepository interface {
+	// ListByVault returns the vault's audit entries, newest first
+	ListByVault(ctx context.Context, vaultID uuid.UUID) ([]models.VaultAuditLog, error)
+}
+
+// MetaTransactionRepository stores relayed forward requests
+type MetaTransactionRepository interface {
+	// ExistsByDigest reports whether the request with the EIP-712 digest was
+	// already relayed
+	ExistsByDigest(ctx context.Context, digest string) (bool, error)
+	// HasPending reports whether a request of signer with the forwarder nonce
+	// has a pending relayer transaction
+	HasPending(ctx context.Context, signer string, nonce uint64) (bool, error)
+}
+
+// GasBudgetRepository stores the gas the relayer paid per address
+type GasBudgetRepository interface {
+	// Find returns the gas budget of the address
+	Find(ctx context.Context, address string) (*models.GasBudget, error)
+	// Update loads the address's budget, created with the window starting now
+	// if missing, and saves it if update returns nil. Concurrent updates of
+	// the same address wait for each other.
+	Update(ctx context.Context, address string, update func(budget *models.GasBudget) error) error
+}
+
+// Repositories are the repositories handlers are built with
+type Repositories struct {
+	Users            UserRepository
+	Vaults           VaultRepository
+	Heirs            HeirRepository
+	Heartbeats       HeartbeatRepository
+	Transactions     TransactionRepository
+	Ledger           LedgerRepository
+	Audit            AuditRepository
+	MetaTransactions MetaTransactionRepository
+	GasBudgets       GasBudgetRepository
+}
+
+// NewGorm returns the Postgres repositories, backed by db
+func NewGorm(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:            NewUserRepository(db),
+		Vaults:           NewVaultRepository(db),
+		Heirs:            NewHeirRepository(db),
+		Heartbeats:       NewHeartbeatRepository(db),
+		Transactions:     NewTransactionRepository(db),
+		Ledger:           NewLedgerRepository(db),
+		Audit:            NewAuditRepository(db),
+		MetaTransactions: NewMetaTransactionRepository(db),
+		GasBudgets:       NewGasBudgetRepository(db),
+	}
+}
+
+// wrap maps gorm.ErrRecordNotFound to ErrNotFound and annotates other errors
+func wrap(err error, action string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
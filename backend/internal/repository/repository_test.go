@@ -0,0 +1,530 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/internal/dbtest"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	ownerAddress   = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	heirAddress    = "0x00000000000000000000000000000000000000A1"
+	otherAddress   = "0x00000000000000000000000000000000000000B2"
+	relayerAddress = "0x00000000000000000000000000000000000000F1"
+)
+
+// implementations runs test against the in-memory repositories and, if
+// TEST_DATABASE_URL is set, the Postgres ones, which must behave the same
+func implementations(t *testing.T, test func(t *testing.T, repos *Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, NewGorm(dbtest.Open(t)))
+	})
+}
+
+// createVault stores a vault of owner with the given heirs, equal shares
+func createVault(t *testing.T, vaults VaultRepository, owner *models.User, vaultID int64, heirAddresses ...string) *models.Vault {
+	t.Helper()
+	heirs := make([]models.Heir, len(heirAddresses))
+	for i, address := range heirAddresses {
+		heirs[i] = models.Heir{Address: address, ShareBPS: 10000 / len(heirAddresses)}
+	}
+
+	vault, err := vaults.CreateWithHeirs(context.Background(), &models.Vault{
+		VaultID:           vaultID,
+		ContractAddress:   fmt.Sprintf("0x%040X", 0xABC0+vaultID),
+		OwnerID:           owner.ID,
+		HeartbeatInterval: 30 * 24 * 60 * 60,
+		GracePeriod:       90 * 24 * 60 * 60,
+		RequiredApprovals: 1,
+		Status:            models.VaultStatusLocked,
+	}, heirs, "")
+	require.NoError(t, err)
+	return vault
+}
+
+func TestUserRepository(t *testing.T) {
+	implementations(t, testUserRepository)
+}
+
+func testUserRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	users := repos.Users
+
+	_, err := users.FindByAddress(ctx, ownerAddress)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	created, err := users.FindOrCreate(ctx, ownerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, ownerAddress, created.Address)
+
+	// Addresses compare case-insensitively
+	again, err := users.FindOrCreate(ctx, strings.ToLower(ownerAddress))
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, again.ID)
+
+	created.Email, created.Nickname = "owner@example.com", "owner"
+	require.NoError(t, users.UpdateProfile(ctx, created))
+
+	found, err := users.FindByAddress(ctx, ownerAddress)
+	require.NoError(t, err)
+	assert.Equal(t, "owner@example.com", found.Email)
+	assert.Equal(t, "owner", found.Nickname)
+
+	// Clearing a field saves it too
+	found.Email = ""
+	require.NoError(t, users.UpdateProfile(ctx, found))
+	found, err = users.FindByAddress(ctx, ownerAddress)
+	require.NoError(t, err)
+	assert.Empty(t, found.Email)
+	assert.Equal(t, "owner", found.Nickname)
+}
+
+func TestVaultRepository(t *testing.T) {
+	implementations(t, testVaultRepository)
+}
+
+func testVaultRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	users, vaults := repos.Users, repos.Vaults
+
+	owner, err := users.FindOrCreate(ctx, ownerAddress)
+	require.NoError(t, err)
+	other, err := users.FindOrCreate(ctx, otherAddress)
+	require.NoError(t, err)
+
+	vault := createVault(t, vaults, owner, 1, heirAddress, otherAddress)
+	assert.Equal(t, ownerAddress, vault.Owner.Address)
+	require.Len(t, vault.Heirs, 2)
+	otherVault := createVault(t, vaults, other, 2, heirAddress)
+
+	t.Run("create existing contract", func(t *testing.T) {
+		// Recorded by the indexer in the meantime, maybe with other casing
+		existing, err := vaults.CreateWithHeirs(ctx, &models.Vault{
+			VaultID:         vault.VaultID,
+			ContractAddress: strings.ToLower(vault.ContractAddress),
+			OwnerID:         other.ID,
+		}, []models.Heir{{Address: otherAddress, ShareBPS: 10000}}, "")
+		require.NoError(t, err)
+		assert.Equal(t, vault.ID, existing.ID)
+		assert.Equal(t, owner.ID, existing.Owner.ID)
+		assert.Len(t, existing.Heirs, 2)
+	})
+
+	t.Run("find by ID", func(t *testing.T) {
+		found, err := vaults.FindByID(ctx, vault.ID, "Heirs", "Heartbeats")
+		require.NoError(t, err)
+		assert.Equal(t, ownerAddress, found.Owner.Address)
+		assert.Len(t, found.Heirs, 2)
+		assert.Empty(t, found.Heartbeats)
+
+		// Records are returned as copies
+		found.Owner.Email, found.Heirs[0].ShareBPS = "changed", 1
+		again, err := vaults.FindByID(ctx, vault.ID, "Heirs")
+		require.NoError(t, err)
+		assert.Empty(t, again.Owner.Email)
+		assert.Equal(t, 5000, again.Heirs[0].ShareBPS)
+
+		_, err = vaults.FindByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list by owner address", func(t *testing.T) {
+		owned, err := vaults.ListByOwnerAddress(ctx, strings.ToLower(ownerAddress), "Heirs")
+		require.NoError(t, err)
+		require.Len(t, owned, 1)
+		assert.Equal(t, vault.ID, owned[0].ID)
+		assert.Equal(t, owner.ID, owned[0].Owner.ID)
+		assert.Len(t, owned[0].Heirs, 2)
+
+		owned, err = vaults.ListByOwnerAddress(ctx, heirAddress)
+		require.NoError(t, err)
+		assert.Empty(t, owned)
+	})
+
+	t.Run("visible IDs", func(t *testing.T) {
+		visible, err := vaults.VisibleIDs(ctx, ownerAddress, nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{vault.ID}, visible)
+
+		// Owner of one vault, heir of the other
+		visible, err = vaults.VisibleIDs(ctx, strings.ToLower(otherAddress), nil)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{vault.ID, otherVault.ID}, visible)
+
+		visible, err = vaults.VisibleIDs(ctx, heirAddress, []uuid.UUID{otherVault.ID, uuid.New()})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{otherVault.ID}, visible)
+
+		visible, err = vaults.VisibleIDs(ctx, "0x00000000000000000000000000000000000000C3", nil)
+		require.NoError(t, err)
+		assert.Empty(t, visible)
+	})
+}
+
+func TestHeirRepository(t *testing.T) {
+	implementations(t, testHeirRepository)
+}
+
+func testHeirRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	heirs := repos.Heirs
+
+	owner, err := repos.Users.FindOrCreate(ctx, ownerAddress)
+	require.NoError(t, err)
+	vault := createVault(t, repos.Vaults, owner, 1, heirAddress, otherAddress)
+	empty := createVault(t, repos.Vaults, owner, 2)
+
+	heir, err := heirs.FindByVaultAndAddress(ctx, vault.ID, strings.ToLower(heirAddress))
+	require.NoError(t, err)
+	assert.Equal(t, heirAddress, heir.Address)
+	assert.Equal(t, 5000, heir.ShareBPS)
+
+	_, err = heirs.FindByVaultAndAddress(ctx, empty.ID, heirAddress)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = heirs.FindByVaultAndAddress(ctx, vault.ID, ownerAddress)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	listed, err := heirs.ListByVault(ctx, vault.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.ElementsMatch(t, []string{heirAddress, otherAddress}, []string{listed[0].Address, listed[1].Address})
+
+	listed, err = heirs.ListByVault(ctx, empty.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, listed)
+	assert.Empty(t, listed)
+
+	count, err := heirs.CountByVault(ctx, vault.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestHeartbeatRepository(t *testing.T) {
+	implementations(t, testHeartbeatRepository)
+}
+
+func testHeartbeatRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	heartbeats := repos.Heartbeats
+
+	owner, err := repos.Users.FindOrCreate(ctx, ownerAddress)
+	require.NoError(t, err)
+	vault := createVault(t, repos.Vaults, owner, 1, heirAddress)
+
+	_, err = heartbeats.Latest(ctx, vault.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	now := time.Now()
+	older := &models.Heartbeat{VaultID: vault.ID, Nonce: "first", Status: models.HeartbeatStatusCommitted, CommittedAt: now.Add(-time.Hour)}
+	newer := &models.Heartbeat{VaultID: vault.ID, Nonce: "second", Status: models.HeartbeatStatusCommitted, CommittedAt: now}
+	require.NoError(t, heartbeats.Create(ctx, older))
+	require.NoError(t, heartbeats.Create(ctx, newer))
+
+	latest, err := heartbeats.Latest(ctx, vault.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newer.ID, latest.ID)
+
+	listed, err := heartbeats.ListByVault(ctx, vault.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, newer.ID, listed[0].ID)
+	assert.Equal(t, older.ID, listed[1].ID)
+
+	committed, err := heartbeats.FindCommitted(ctx, vault.ID, "first")
+	require.NoError(t, err)
+	assert.Equal(t, older.ID, committed.ID)
+
+	// Only committed heartbeats can be revealed
+	require.NoError(t, heartbeats.UpdateStatus(ctx, committed, models.HeartbeatStatusFailed))
+	assert.Equal(t, models.HeartbeatStatusFailed, committed.Status)
+	_, err = heartbeats.FindCommitted(ctx, vault.ID, "first")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = heartbeats.FindCommitted(ctx, uuid.New(), "second")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// vaultCall returns a call of method to the vault, sent by sender in its own
+// transaction with the nonce
+func vaultCall(vault *models.Vault, sender string, nonce uint64, method string, args ...interface{}) *VaultCall {
+	to := common.HexToAddress(vault.ContractAddress)
+	return &VaultCall{
+		Tx:     types.NewTx(&types.LegacyTx{Nonce: nonce, To: &to, Gas: 100000, GasPrice: big.NewInt(1)}),
+		From:   common.HexToAddress(sender),
+		Sender: common.HexToAddress(sender),
+		Vault:  vault,
+		Method: method,
+		Args:   args,
+	}
+}
+
+func TestTransactionRepository(t *testing.T) {
+	implementations(t, testTransactionRepository)
+}
+
+func testTransactionRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	transactions := repos.Transactions
+
+	owner, err := repos.Users.FindOrCreate(ctx, ownerAddress)
+	require.NoError(t, err)
+	vault := createVault(t, repos.Vaults, owner, 1, heirAddress)
+	heir, err := repos.Heirs.FindByVaultAndAddress(ctx, vault.ID, heirAddress)
+	require.NoError(t, err)
+
+	// The committed heartbeat is created with its transaction
+	nonce := [32]byte{1}
+	commitHash := crypto.Keccak256Hash(common.HexToAddress(ownerAddress).Bytes(), nonce[:])
+	commit := vaultCall(vault, ownerAddress, 0, "commitHeartbeat", [32]byte(commitHash))
+	commit.Heartbeat = &models.Heartbeat{
+		VaultID:      vault.ID,
+		CommitHash:   commitHash.Hex(),
+		CommitTxHash: commit.Tx.Hash().Hex(),
+		Status:       models.HeartbeatStatusCommitted,
+		CommittedAt:  time.Now(),
+	}
+	committed, err := transactions.RecordVaultCall(ctx, commit)
+	require.NoError(t, err)
+	assert.Equal(t, models.TransactionStatusPending, committed.Status)
+	assert.Equal(t, ownerAddress, committed.FromAddress)
+	assert.Equal(t, common.HexToAddress(vault.ContractAddress).Hex(), committed.ToAddress)
+	assert.Equal(t, &vault.ID, committed.VaultID)
+	require.NotNil(t, committed.HeartbeatID)
+	assert.Equal(t, commit.Heartbeat.ID, *committed.HeartbeatID)
+
+	// The reveal is linked to it by keccak256(sender, nonce)
+	reveal := vaultCall(vault, ownerAddress, 1, "revealHeartbeat", nonce)
+	revealed, err := transactions.RecordVaultCall(ctx, reveal)
+	require.NoError(t, err)
+	require.NotNil(t, revealed.HeartbeatID)
+	assert.Equal(t, commit.Heartbeat.ID, *revealed.HeartbeatID)
+
+	heartbeat, err := repos.Heartbeats.Latest(ctx, vault.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reveal.Tx.Hash().Hex(), heartbeat.RevealTxHash)
+	assert.Equal(t, models.HeartbeatStatusCommitted, heartbeat.Status, "until the reveal is mined")
+
+	// A relayed approval is linked to the heir who signed it
+	approve := vaultCall(vault, relayerAddress, 10, "approveInheritance")
+	approve.Sender = common.HexToAddress(heirAddress)
+	approve.MetaTx = &models.MetaTransaction{
+		Signer:   heirAddress,
+		Nonce:    7,
+		VaultID:  vault.ID,
+		Method:   "approveInheritance",
+		Deadline: time.Now().Add(time.Hour),
+		Digest:   crypto.Keccak256Hash([]byte("approve")).Hex(),
+		TxHash:   approve.Tx.Hash().Hex(),
+		GasLimit: 100000,
+	}
+	approved, err := transactions.RecordVaultCall(ctx, approve)
+	require.NoError(t, err)
+	assert.Equal(t, common.HexToAddress(relayerAddress).Hex(), approved.FromAddress)
+	require.NotNil(t, approved.HeirID)
+	assert.Equal(t, heir.ID, *approved.HeirID)
+
+	relayed, err := repos.MetaTransactions.ExistsByDigest(ctx, approve.MetaTx.Digest)
+	require.NoError(t, err)
+	assert.True(t, relayed)
+	pending, err := repos.MetaTransactions.HasPending(ctx, strings.ToLower(heirAddress), 7)
+	require.NoError(t, err)
+	assert.True(t, pending)
+	pending, err = repos.MetaTransactions.HasPending(ctx, heirAddress, 8)
+	require.NoError(t, err)
+	assert.False(t, pending)
+
+	// Pauses are audited with their reason
+	pause := vaultCall(vault, ownerAddress, 2, "pause")
+	pause.Reason = "lost my phone"
+	_, err = transactions.RecordVaultCall(ctx, pause)
+	require.NoError(t, err)
+
+	entries, err := repos.Audit.ListByVault(ctx, vault.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.VaultAuditPause, entries[0].Action)
+	assert.Equal(t, ownerAddress, entries[0].Actor)
+	assert.Equal(t, "lost my phone", entries[0].Reason)
+	assert.Equal(t, pause.Tx.Hash().Hex(), entries[0].TxHash)
+	assert.NotNil(t, entries[0].RequestedAt)
+	assert.Nil(t, entries[0].ConfirmedAt)
+
+	t.Run("record twice", func(t *testing.T) {
+		// Nothing is stored if the transaction is already recorded
+		_, err := transactions.RecordVaultCall(ctx, pause)
+		assert.Error(t, err)
+
+		entries, err := repos.Audit.ListByVault(ctx, vault.ID)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("server transaction", func(t *testing.T) {
+		// A deployment is recorded before its vault exists, then linked to it
+		factory := common.HexToAddress("0x00000000000000000000000000000000000000FA")
+		deployTx := types.NewTx(&types.LegacyTx{Nonce: 20, To: &factory, Gas: 100000, GasPrice: big.NewInt(1)})
+		deployed, err := transactions.Record(ctx, deployTx, common.HexToAddress(relayerAddress), "createVaultFor", tracker.Link{})
+		require.NoError(t, err)
+		assert.Nil(t, deployed.VaultID)
+
+		created, err := repos.Vaults.CreateWithHeirs(ctx, &models.Vault{
+			VaultID:         3,
+			ContractAddress: "0x0000000000000000000000000000000000000DE3",
+			OwnerID:         owner.ID,
+		}, []models.Heir{{Address: heirAddress, ShareBPS: 10000}}, deployTx.Hash().Hex())
+		require.NoError(t, err)
+		found, err := transactions.FindByHash(ctx, deployTx.Hash().Hex())
+		require.NoError(t, err)
+		assert.Equal(t, &created.ID, found.VaultID)
+
+		_, err = transactions.Record(ctx, deployTx, common.HexToAddress(relayerAddress), "createVaultFor", tracker.Link{})
+		assert.Error(t, err)
+	})
+
+	t.Run("find by hash", func(t *testing.T) {
+		found, err := transactions.FindByHash(ctx, strings.ToLower(approve.Tx.Hash().Hex()))
+		require.NoError(t, err)
+		assert.Equal(t, approved.ID, found.ID)
+		assert.Equal(t, "approveInheritance", found.Method)
+
+		_, err = transactions.FindByHash(ctx, common.Hash{}.Hex())
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestLedgerRepository(t *testing.T) {
+	implementations(t, testLedgerRepository)
+}
+
+func testLedgerRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	ledger := repos.Ledger
+
+	owner, err := repos.Users.FindOrCreate(ctx, ownerAddress)
+	require.NoError(t, err)
+	vault := createVault(t, repos.Vaults, owner, 1, heirAddress)
+	other := createVault(t, repos.Vaults, owner, 2, heirAddress)
+
+	// Stored out of chain order
+	logs := []struct {
+		vault    *models.Vault
+		block    uint64
+		logIndex uint
+	}{{vault, 2, 0}, {vault, 1, 3}, {other, 5, 0}, {vault, 3, 0}, {vault, 2, 1}}
+	for i, log := range logs {
+		require.NoError(t, ledger.Create(ctx, &models.VaultLedgerEntry{
+			VaultID:     log.vault.ID,
+			Kind:        models.LedgerEntryDeposit,
+			Address:     ownerAddress,
+			Amount:      fmt.Sprint(100 * (i + 1)),
+			TxHash:      crypto.Keccak256Hash([]byte{byte(log.block)}).Hex(),
+			LogIndex:    log.logIndex,
+			BlockNumber: log.block,
+			Timestamp:   time.Now(),
+		}))
+	}
+
+	chainOrder := func(entries []models.VaultLedgerEntry) [][2]uint64 {
+		order := [][2]uint64{}
+		for _, entry := range entries {
+			order = append(order, [2]uint64{entry.BlockNumber, uint64(entry.LogIndex)})
+		}
+		return order
+	}
+
+	entries, err := ledger.ListByVault(ctx, vault.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]uint64{{3, 0}, {2, 1}, {2, 0}, {1, 3}}, chainOrder(entries))
+
+	// Pages follow the same order
+	page, err := ledger.ListByVault(ctx, vault.ID, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]uint64{{2, 1}, {2, 0}}, chainOrder(page))
+	page, err = ledger.ListByVault(ctx, vault.ID, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]uint64{{1, 3}}, chainOrder(page))
+	page, err = ledger.ListByVault(ctx, vault.ID, 2, 4)
+	require.NoError(t, err)
+	assert.NotNil(t, page)
+	assert.Empty(t, page)
+
+	// A log is recorded once
+	assert.Error(t, ledger.Create(ctx, &models.VaultLedgerEntry{
+		VaultID:     vault.ID,
+		Kind:        models.LedgerEntryDeposit,
+		Address:     ownerAddress,
+		Amount:      "1",
+		TxHash:      entries[0].TxHash,
+		LogIndex:    entries[0].LogIndex,
+		BlockNumber: entries[0].BlockNumber,
+		Timestamp:   time.Now(),
+	}))
+
+	entries, err = ledger.ListByVault(ctx, uuid.New(), 10, 0)
+	require.NoError(t, err)
+	assert.NotNil(t, entries)
+	assert.Empty(t, entries)
+}
+
+func TestGasBudgetRepository(t *testing.T) {
+	implementations(t, testGasBudgetRepository)
+}
+
+func testGasBudgetRepository(t *testing.T, repos *Repositories) {
+	ctx := context.Background()
+	budgets := repos.GasBudgets
+
+	_, err := budgets.Find(ctx, heirAddress)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Created on first update, with the window starting now
+	require.NoError(t, budgets.Update(ctx, heirAddress, func(budget *models.GasBudget) error {
+		assert.Zero(t, budget.GasUsed)
+		assert.WithinDuration(t, time.Now(), budget.WindowStart, time.Minute)
+		budget.GasUsed = 100
+		return nil
+	}))
+
+	// Not saved if the update fails
+	errFull := errors.New("budget exceeded")
+	err = budgets.Update(ctx, heirAddress, func(budget *models.GasBudget) error {
+		assert.EqualValues(t, 100, budget.GasUsed)
+		budget.GasUsed = 1000
+		return errFull
+	})
+	assert.ErrorIs(t, err, errFull)
+
+	budget, err := budgets.Find(ctx, heirAddress)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, budget.GasUsed)
+
+	// A window reset is saved too
+	windowStart := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, budgets.Update(ctx, heirAddress, func(budget *models.GasBudget) error {
+		budget.WindowStart, budget.GasUsed = windowStart, 0
+		return nil
+	}))
+	budget, err = budgets.Find(ctx, heirAddress)
+	require.NoError(t, err)
+	assert.Zero(t, budget.GasUsed)
+	assert.True(t, windowStart.Equal(budget.WindowStart))
+
+	_, err = budgets.Find(ctx, otherAddress)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/internal/tracker"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// gormTransactionRepository is the Postgres TransactionRepository
+type gormTransactionRepository struct {
+	db *gorm.DB
+}
+
+// NewTransactionRepository returns a TransactionRepository backed by db
+func NewTransactionRepository(db *gorm.DB) TransactionRepository {
+	return &gormTransactionRepository{db: db}
+}
+
+// FindByHash returns the transaction with the hash, also by a hash it replaced
+// with higher fees
+func (r *gormTransactionRepository) FindByHash(ctx context.Context, hash string) (*models.Transaction, error) {
+	replaced := r.db.Model(&models.TransactionReplacement{}).
+		Select("transaction_id").
+		Where("LOWER(hash) = LOWER(?)", hash)
+
+	var record models.Transaction
+	if err := r.db.WithContext(ctx).
+		Where("LOWER(hash) = LOWER(?) OR id IN (?)", hash, replaced).
+		First(&record).Error; err != nil {
+		return nil, wrap(err, "query transaction")
+	}
+	return &record, nil
+}
+
+// Record stores a transaction sent from the server account as pending, linked
+// to the rows it updates once mined
+func (r *gormTransactionRepository) Record(ctx context.Context, tx *types.Transaction, from common.Address, method string, link tracker.Link) (*models.Transaction, error) {
+	record, err := tracker.Record(r.db.WithContext(ctx), tx, from, method, link)
+	if err != nil {
+		return nil, wrap(err, "record transaction")
+	}
+	return record, nil
+}
+
+// RecordVaultCall stores a broadcast vault call as pending, linked to the rows
+// it updates once mined, together with the rows it creates: the committed
+// heartbeat, the audit entry of a pause or unpause and the relayed forward
+// request. Nothing is stored if any of them fails.
+func (r *gormTransactionRepository) RecordVaultCall(ctx context.Context, call *VaultCall) (*models.Transaction, error) {
+	var record *models.Transaction
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		link, err := tracker.LinkVaultCall(tx, call.Vault, call.Sender, call.Method, call.Args)
+		if err != nil {
+			return err
+		}
+
+		if call.Heartbeat != nil {
+			if err := tx.Create(call.Heartbeat).Error; err != nil {
+				return err
+			}
+			link.HeartbeatID = &call.Heartbeat.ID
+		}
+
+		if record, err = tracker.Record(tx, call.Tx, call.From, call.Method, link); err != nil {
+			return err
+		}
+		if err := tracker.RecordAudit(tx, call.Vault, call.Sender, call.Method, call.Reason, record.Hash); err != nil {
+			return err
+		}
+
+		if call.MetaTx != nil {
+			return tx.Create(call.MetaTx).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrap(err, "record transaction")
+	}
+	return record, nil
+}
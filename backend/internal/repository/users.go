@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// gormUserRepository is the Postgres UserRepository
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository returns a UserRepository backed by db
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+// FindByAddress returns the user with the wallet address
+func (r *gormUserRepository) FindByAddress(ctx context.Context, address string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("LOWER(address) = LOWER(?)", address).First(&user).Error
+	if err != nil {
+		return nil, wrap(err, "query user")
+	}
+	return &user, nil
+}
+
+// FindOrCreate returns the user with the wallet address, creating it on first
+// login
+func (r *gormUserRepository) FindOrCreate(ctx context.Context, address string) (*models.User, error) {
+	user, err := r.FindByAddress(ctx, address)
+	if !errors.Is(err, ErrNotFound) {
+		return user, err
+	}
+
+	user = &models.User{Address: address}
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		return nil, wrap(err, "create user")
+	}
+	return user, nil
+}
+
+// UpdateProfile saves the user's email and nickname
+func (r *gormUserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	err := r.db.WithContext(ctx).Model(user).Select("email", "nickname").Updates(user).Error
+	return wrap(err, "update user")
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// gormVaultRepository is the Postgres VaultRepository
+type gormVaultRepository struct {
+	db *gorm.DB
+}
+
+// NewVaultRepository returns a VaultRepository backed by db
+func NewVaultRepository(db *gorm.DB) VaultRepository {
+	return &gormVaultRepository{db: db}
+}
+
+// FindByID returns the vault with its owner and the given relationships
+// (Heirs, Heartbeats) loaded
+func (r *gormVaultRepository) FindByID(ctx context.Context, id uuid.UUID, preload ...string) (*models.Vault, error) {
+	var vault models.Vault
+	if err := r.preload(ctx, preload).First(&vault, "vaults.id = ?", id).Error; err != nil {
+		return nil, wrap(err, "query vault")
+	}
+	return &vault, nil
+}
+
+// FindByContractAddress returns the vault deployed at the address
+func (r *gormVaultRepository) FindByContractAddress(ctx context.Context, address string) (*models.Vault, error) {
+	var vault models.Vault
+	if err := r.db.WithContext(ctx).Where("LOWER(contract_address) = LOWER(?)", address).First(&vault).Error; err != nil {
+		return nil, wrap(err, "query vault")
+	}
+	return &vault, nil
+}
+
+// ListByOwnerAddress returns the vaults of the user with the wallet address,
+// oldest first, with the given relationships loaded
+func (r *gormVaultRepository) ListByOwnerAddress(ctx context.Context, address string, preload ...string) ([]models.Vault, error) {
+	vaults := []models.Vault{}
+	err := r.preload(ctx, preload).
+		Joins("JOIN users ON users.id = vaults.owner_id").
+		Where("LOWER(users.address) = LOWER(?)", address).
+		Order("vaults.created_at").
+		Find(&vaults).Error
+	if err != nil {
+		return nil, wrap(err, "query vaults")
+	}
+	return vaults, nil
+}
+
+// VisibleIDs returns the IDs of the vaults the wallet address owns or is an
+// heir of. If ids are given, only those among them are returned.
+func (r *gormVaultRepository) VisibleIDs(ctx context.Context, address string, ids []uuid.UUID) ([]uuid.UUID, error) {
+	isHeir := r.db.Model(&models.Heir{}).
+		Select("vault_id").
+		Where("LOWER(address) = LOWER(?)", address)
+	query := r.db.WithContext(ctx).Model(&models.Vault{}).
+		Joins("JOIN users ON users.id = vaults.owner_id").
+		Where("LOWER(users.address) = LOWER(?) OR vaults.id IN (?)", address, isHeir)
+	if ids != nil {
+		query = query.Where("vaults.id IN ?", ids)
+	}
+
+	visible := []uuid.UUID{}
+	if err := query.Pluck("vaults.id", &visible).Error; err != nil {
+		return nil, wrap(err, "query vaults")
+	}
+	return visible, nil
+}
+
+// CreateWithHeirs stores a vault confirmed on-chain together with its heirs
+// and returns it with its owner and heirs loaded. If the contract is already
+// recorded, by the indexer for example, that vault is returned instead. A
+// non-empty deployTxHash links that transaction record to the vault in the
+// same database transaction.
+func (r *gormVaultRepository) CreateWithHeirs(ctx context.Context, vault *models.Vault, heirs []models.Heir, deployTxHash string) (*models.Vault, error) {
+	id := vault.ID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.Vault
+		err := tx.Where("LOWER(contract_address) = LOWER(?)", vault.ContractAddress).First(&existing).Error
+		switch {
+		case err == nil:
+			id = existing.ID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		default:
+			if err := tx.Create(vault).Error; err != nil {
+				return err
+			}
+			id = vault.ID
+
+			for i := range heirs {
+				heirs[i].VaultID = vault.ID
+				if err := tx.Create(&heirs[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if deployTxHash == "" {
+			return nil
+		}
+		return tx.Model(&models.Transaction{}).Where("hash = ?", deployTxHash).Update("vault_id", id).Error
+	})
+	if err != nil {
+		return nil, wrap(err, "create vault")
+	}
+
+	return r.FindByID(ctx, id, "Heirs")
+}
+
+// preload starts a vault query with the owner and relationships loaded
+func (r *gormVaultRepository) preload(ctx context.Context, relationships []string) *gorm.DB {
+	query := r.db.WithContext(ctx).Preload("Owner")
+	for _, relationship := range relationships {
+		query = query.Preload(relationship)
+	}
+	return query
+}
@@ -3,42 +3,83 @@ package service
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
 	"strings"
 
 	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/haneumLee/legacychain/backend/config"
 	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	legacycrypto "github.com/haneumLee/legacychain/backend/pkg/crypto"
+	"github.com/redis/go-redis/v9"
 )
 
 // BlockchainService defines the interface for blockchain operations
 type BlockchainService interface {
 	// Vault operations
-	CreateVault(ctx context.Context, heirs []common.Address, shares []*big.Int, heartbeatInterval, gracePeriod, requiredApprovals *big.Int) (txHash string, err error)
+	CreateVault(ctx context.Context, owner common.Address, heirs []common.Address, shares []*big.Int, heartbeatInterval, gracePeriod, requiredApprovals *big.Int) (txHash string, err error)
+	WaitForVaultCreated(ctx context.Context, txHash string) (*CreatedVault, error)
+	IsFactoryVault(ctx context.Context, owner, vaultAddress common.Address) (bool, error)
+	FindVaultCreated(ctx context.Context, owner, vaultAddress common.Address, fromBlock uint64) (*CreatedVault, error)
 	GetVaultOwner(ctx context.Context, vaultAddress common.Address) (common.Address, error)
 	GetVaultConfig(ctx context.Context, vaultAddress common.Address) (*VaultConfig, error)
+	GetHeirCount(ctx context.Context, vaultAddress common.Address) (*big.Int, error)
+	GetHeirShare(ctx context.Context, vaultAddress common.Address, index *big.Int) (*big.Int, error)
+	IsPaused(ctx context.Context, vaultAddress common.Address) (bool, error)
+	GetVaultState(ctx context.Context, vaultAddress common.Address, blockNumber *big.Int) (*VaultState, error)
 	
 	// Heartbeat operations
 	CommitHeartbeat(ctx context.Context, vaultAddr common.Address, commitHash [32]byte) (string, error)
 	RevealHeartbeat(ctx context.Context, vaultAddr common.Address, nonce [32]byte) (string, error)
 	GetLastHeartbeat(ctx context.Context, vaultAddr common.Address) (*big.Int, error)
+	CheckAndUnlock(ctx context.Context, vaultAddr common.Address) (string, error)
 	
 	// Heir operations
 	ApproveInheritance(ctx context.Context, vaultAddr common.Address) (string, error)
 	ClaimInheritance(ctx context.Context, vaultAddr common.Address) (string, error)
 	GetHeirApprovalStatus(ctx context.Context, vaultAddr common.Address, heirAddr common.Address) (bool, error)
 	
+	// Relay operations (user-signed transactions)
+	BuildVaultTransaction(ctx context.Context, from, vaultAddr common.Address, method string, args ...interface{}) (*UnsignedTx, error)
+	BuildDepositTransaction(ctx context.Context, from, vaultAddr common.Address, amount *big.Int) (*UnsignedTx, error)
+	DecodeRawTransaction(rawTx string) (*types.Transaction, common.Address, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	
+	// Meta-transaction operations (ERC-2771 forwarder)
+	ForwarderAddress() common.Address
+	ForwarderDomain(ctx context.Context) (*legacycrypto.EIP712Domain, error)
+	ForwarderNonce(ctx context.Context, from common.Address) (*big.Int, error)
+	EstimateForwardRequest(ctx context.Context, request bindings.ERC2771ForwarderForwardRequestData) (uint64, error)
+	ExecuteForwardRequest(ctx context.Context, request bindings.ERC2771ForwarderForwardRequestData, gasLimit uint64) (*types.Transaction, error)
+	
+	// Server signer transactions
+	RelayerAddress() common.Address
+	ReplaceTransaction(ctx context.Context, nonce uint64, cancel bool) (*types.Transaction, error)
+	
 	// Event listening
 	ListenVaultCreatedEvents(ctx context.Context, handler func(event *bindings.VaultFactoryVaultCreated)) error
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+	GetTotalVaults(ctx context.Context, blockNumber *big.Int) (*big.Int, error)
+	VaultFactoryAddress() common.Address
 	
 	// Utility
+	ChainID() *big.Int
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
 	GetTransactionReceipt(ctx context.Context, txHash string) (*types.Receipt, error)
+	GetTransactionByHash(ctx context.Context, txHash string) (tx *types.Transaction, isPending bool, err error)
+	GetRevertReason(ctx context.Context, txHash string, blockNumber *big.Int) (string, error)
+	IsValidSignature(ctx context.Context, account common.Address, hash common.Hash, signature []byte) (bool, error)
 	Close()
 }
 
@@ -58,19 +99,48 @@ type VaultConfig struct {
 	GracePeriodActive     bool
 }
 
+// VaultState represents a vault's on-chain state at a given block
+type VaultState struct {
+	Config       *VaultConfig
+	Balance      *big.Int
+	Paused       bool
+	HeirApproved map[common.Address]bool
+	HeirClaimed  map[common.Address]bool
+}
+
+// ChainClient is the Ethereum client the service reads and sends through.
+// *ethclient.Client implements it, as does the client of go-ethereum's
+// simulated backend.
+type ChainClient interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ethereum.ChainIDReader
+	ethereum.BlockNumberReader
+	ethereum.TransactionReader
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
 // ethBlockchainService is the implementation of BlockchainService
 type ethBlockchainService struct {
-	client           *ethclient.Client
-	wsClient         *ethclient.Client
+	client           ChainClient
+	wsClient         ChainClient
 	chainID          *big.Int
 	vaultFactory     *bindings.VaultFactory
 	vaultFactoryAddr common.Address
 	privateKey       *ecdsa.PrivateKey
 	fromAddress      common.Address
+	forwarder        *bindings.ERC2771Forwarder
+	forwarderAddr    common.Address
+	fees             FeeStrategy
+	nonces           *NonceAllocator
+	stopNonces       context.CancelFunc
+	closeClients     func()
 }
 
-// NewBlockchainService creates a new BlockchainService instance
-func NewBlockchainService(cfg *config.Config) (BlockchainService, error) {
+// NewBlockchainService creates a new BlockchainService instance. Nonces for the
+// server signer are allocated through Redis so replicas never reuse one; a nil
+// redisClient falls back to the node's pending nonce and suits read-only tools.
+func NewBlockchainService(cfg *config.Config, redisClient *redis.Client) (BlockchainService, error) {
 	// 1. Connect HTTP client (for RPC calls)
 	client, err := ethclient.Dial(cfg.Blockchain.RpcURL)
 	if err != nil {
@@ -99,25 +169,37 @@ func NewBlockchainService(cfg *config.Config) (BlockchainService, error) {
 		return nil, fmt.Errorf("chain ID mismatch: expected %d, got %d", cfg.Blockchain.ChainID, chainID.Int64())
 	}
 
-	// 5. Load VaultFactory contract
-	if cfg.Blockchain.VaultFactoryAddress == "" {
+	service, err := newBlockchainService(cfg, client, wsClient, chainID, redisClient)
+	if err != nil {
 		client.Close()
 		wsClient.Close()
+		return nil, err
+	}
+
+	service.closeClients = func() {
+		client.Close()
+		wsClient.Close()
+	}
+
+	return service, nil
+}
+
+// newBlockchainService creates the service on connected clients for the
+// configured chain. Closing the service does not close the clients.
+func newBlockchainService(cfg *config.Config, client, wsClient ChainClient, chainID *big.Int, redisClient *redis.Client) (*ethBlockchainService, error) {
+	// 5. Load VaultFactory contract
+	if cfg.Blockchain.VaultFactoryAddress == "" {
 		return nil, fmt.Errorf("VAULT_FACTORY_ADDRESS not set in config")
 	}
 
 	factoryAddr := common.HexToAddress(cfg.Blockchain.VaultFactoryAddress)
 	factory, err := bindings.NewVaultFactory(factoryAddr, client)
 	if err != nil {
-		client.Close()
-		wsClient.Close()
 		return nil, fmt.Errorf("failed to load VaultFactory contract: %w", err)
 	}
 
 	// 6. Load private key
 	if cfg.Blockchain.PrivateKey == "" {
-		client.Close()
-		wsClient.Close()
 		return nil, fmt.Errorf("BLOCKCHAIN_PRIVATE_KEY not set in config")
 	}
 
@@ -125,14 +207,23 @@ func NewBlockchainService(cfg *config.Config) (BlockchainService, error) {
 	privateKeyHex := strings.TrimPrefix(cfg.Blockchain.PrivateKey, "0x")
 	privateKey, err := crypto.HexToECDSA(privateKeyHex)
 	if err != nil {
-		client.Close()
-		wsClient.Close()
 		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 
 	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
 
-	return &ethBlockchainService{
+	// 7. Load ERC-2771 forwarder (optional, required by the meta-transaction relayer)
+	var forwarder *bindings.ERC2771Forwarder
+	var forwarderAddr common.Address
+	if cfg.Relayer.ForwarderAddress != "" {
+		forwarderAddr = common.HexToAddress(cfg.Relayer.ForwarderAddress)
+		forwarder, err = bindings.NewERC2771Forwarder(forwarderAddr, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ERC2771Forwarder contract: %w", err)
+		}
+	}
+
+	service := &ethBlockchainService{
 		client:           client,
 		wsClient:         wsClient,
 		chainID:          chainID,
@@ -140,25 +231,47 @@ func NewBlockchainService(cfg *config.Config) (BlockchainService, error) {
 		vaultFactoryAddr: factoryAddr,
 		privateKey:       privateKey,
 		fromAddress:      fromAddress,
-	}, nil
+		forwarder:        forwarder,
+		forwarderAddr:    forwarderAddr,
+		fees:             NewFeeStrategy(client, cfg.Fees),
+		stopNonces:       func() {},
+		closeClients:     func() {},
+	}
+
+	// 8. Start the shared nonce allocator for the server signer
+	if redisClient != nil {
+		service.nonces = NewNonceAllocator(redisClient, client, privateKey, chainID, cfg.Blockchain.NonceStuckTimeout)
+
+		if cfg.Blockchain.NonceResyncInterval > 0 {
+			ctx, cancel := context.WithCancel(context.Background())
+			service.stopNonces = cancel
+			go service.nonces.Run(ctx, cfg.Blockchain.NonceResyncInterval)
+		}
+	}
+
+	return service, nil
 }
 
-// CreateVault creates a new vault on the blockchain
+// CreateVault deploys a vault owned by owner through the factory. The server
+// signer must own the factory, since createVaultFor is restricted to it.
 func (s *ethBlockchainService) CreateVault(
 	ctx context.Context,
+	owner common.Address,
 	heirs []common.Address,
 	shares []*big.Int,
 	heartbeatInterval, gracePeriod, requiredApprovals *big.Int,
 ) (string, error) {
-	auth, err := s.getTransactor(ctx)
+	factoryABI, err := bindings.VaultFactoryMetaData.GetAbi()
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to parse factory ABI: %w", err)
 	}
 
-	// Estimate gas (optional but recommended)
-	auth.GasLimit = 5000000 // 5M gas limit
+	data, err := factoryABI.Pack("createVaultFor", owner, heirs, shares, heartbeatInterval, gracePeriod, requiredApprovals)
+	if err != nil {
+		return "", fmt.Errorf("failed to pack createVaultFor call: %w", err)
+	}
 
-	tx, err := s.vaultFactory.CreateVault(auth, heirs, shares, heartbeatInterval, gracePeriod, requiredApprovals)
+	tx, err := s.transact(ctx, s.vaultFactoryAddr, big.NewInt(0), data)
 	if err != nil {
 		return "", fmt.Errorf("failed to create vault: %w", err)
 	}
@@ -188,15 +301,15 @@ func (s *ethBlockchainService) GetVaultConfig(ctx context.Context, vaultAddress
 		return nil, fmt.Errorf("failed to load vault contract: %w", err)
 	}
 
-	config, err := vault.Config(&bind.CallOpts{Context: ctx})
+	config, err := vault.GetConfig(&bind.CallOpts{Context: ctx})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vault config: %w", err)
 	}
 
 	return &VaultConfig{
 		Owner:                config.Owner,
-		Heirs:                nil, // Will be populated separately if needed
-		HeirShares:           nil, // Will be populated separately if needed
+		Heirs:                config.Heirs,
+		HeirShares:           config.HeirShares,
 		HeartbeatInterval:    config.HeartbeatInterval,
 		LastHeartbeat:        config.LastHeartbeat,
 		UnlockTime:           config.UnlockTime,
@@ -209,43 +322,155 @@ func (s *ethBlockchainService) GetVaultConfig(ctx context.Context, vaultAddress
 	}, nil
 }
 
-// CommitHeartbeat commits a heartbeat hash
-func (s *ethBlockchainService) CommitHeartbeat(ctx context.Context, vaultAddr common.Address, commitHash [32]byte) (string, error) {
-	auth, err := s.getTransactor(ctx)
+// GetHeirCount returns the number of heirs of a vault
+func (s *ethBlockchainService) GetHeirCount(ctx context.Context, vaultAddress common.Address) (*big.Int, error) {
+	vault, err := bindings.NewIndividualVault(vaultAddress, s.client)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to load vault contract: %w", err)
 	}
 
-	vault, err := bindings.NewIndividualVault(vaultAddr, s.client)
+	count, err := vault.GetHeirCount(&bind.CallOpts{Context: ctx})
 	if err != nil {
-		return "", fmt.Errorf("failed to load vault contract: %w", err)
+		return nil, fmt.Errorf("failed to get heir count: %w", err)
 	}
 
-	auth.GasLimit = 200000
+	return count, nil
+}
 
-	tx, err := vault.CommitHeartbeat(auth, commitHash)
+// GetHeirShare returns the share in basis points of the heir at index
+func (s *ethBlockchainService) GetHeirShare(ctx context.Context, vaultAddress common.Address, index *big.Int) (*big.Int, error) {
+	vault, err := bindings.NewIndividualVault(vaultAddress, s.client)
 	if err != nil {
-		return "", fmt.Errorf("failed to commit heartbeat: %w", err)
+		return nil, fmt.Errorf("failed to load vault contract: %w", err)
 	}
 
-	return tx.Hash().Hex(), nil
+	share, err := vault.GetHeirShare(&bind.CallOpts{Context: ctx}, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heir share: %w", err)
+	}
+
+	return share, nil
 }
 
-// RevealHeartbeat reveals a committed heartbeat
-func (s *ethBlockchainService) RevealHeartbeat(ctx context.Context, vaultAddr common.Address, nonce [32]byte) (string, error) {
-	auth, err := s.getTransactor(ctx)
+// IsPaused reports whether the owner has paused the vault
+func (s *ethBlockchainService) IsPaused(ctx context.Context, vaultAddress common.Address) (bool, error) {
+	vault, err := bindings.NewIndividualVault(vaultAddress, s.client)
+	if err != nil {
+		return false, fmt.Errorf("failed to load vault contract: %w", err)
+	}
+
+	paused, err := vault.Paused(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return false, fmt.Errorf("failed to get pause status: %w", err)
+	}
+
+	return paused, nil
+}
+
+// IsFactoryVault reports whether the factory created vaultAddress for owner,
+// as opposed to a look-alike contract deployed elsewhere
+func (s *ethBlockchainService) IsFactoryVault(ctx context.Context, owner, vaultAddress common.Address) (bool, error) {
+	vaults, err := s.vaultFactory.GetOwnerVaults(&bind.CallOpts{Context: ctx}, owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to get owner vaults: %w", err)
+	}
+
+	for _, vault := range vaults {
+		if vault == vaultAddress {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetVaultState returns the vault's configuration, balance, pause flag and
+// per-heir approval and claim status at the given block (nil for latest)
+func (s *ethBlockchainService) GetVaultState(ctx context.Context, vaultAddress common.Address, blockNumber *big.Int) (*VaultState, error) {
+	vault, err := bindings.NewIndividualVault(vaultAddress, s.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vault contract: %w", err)
+	}
+
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: blockNumber}
+
+	config, err := vault.GetConfig(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault config: %w", err)
+	}
+
+	balance, err := vault.GetBalance(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault balance: %w", err)
+	}
+
+	paused, err := vault.Paused(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pause status: %w", err)
+	}
+
+	state := &VaultState{
+		Config: &VaultConfig{
+			Owner:                config.Owner,
+			Heirs:                config.Heirs,
+			HeirShares:           config.HeirShares,
+			HeartbeatInterval:    config.HeartbeatInterval,
+			LastHeartbeat:        config.LastHeartbeat,
+			UnlockTime:           config.UnlockTime,
+			GracePeriod:          config.GracePeriod,
+			RequiredApprovals:    config.RequiredApprovals,
+			ApprovalCount:        config.ApprovalCount,
+			TotalBalanceAtUnlock: config.TotalBalanceAtUnlock,
+			IsLocked:             config.IsLocked,
+			GracePeriodActive:    config.GracePeriodActive,
+		},
+		Balance:      balance,
+		Paused:       paused,
+		HeirApproved: make(map[common.Address]bool),
+		HeirClaimed:  make(map[common.Address]bool),
+	}
+
+	for _, heir := range config.Heirs {
+		approved, err := vault.HeirApprovals(opts, heir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get heir approval status: %w", err)
+		}
+
+		claimed, err := vault.HeirClaimed(opts, heir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get heir claim status: %w", err)
+		}
+
+		state.HeirApproved[heir] = approved
+		state.HeirClaimed[heir] = claimed
+	}
+
+	return state, nil
+}
+
+// CommitHeartbeat commits a heartbeat hash
+func (s *ethBlockchainService) CommitHeartbeat(ctx context.Context, vaultAddr common.Address, commitHash [32]byte) (string, error) {
+	data, err := packVaultCall("commitHeartbeat", commitHash)
 	if err != nil {
 		return "", err
 	}
 
-	vault, err := bindings.NewIndividualVault(vaultAddr, s.client)
+	tx, err := s.transact(ctx, vaultAddr, big.NewInt(0), data)
 	if err != nil {
-		return "", fmt.Errorf("failed to load vault contract: %w", err)
+		return "", fmt.Errorf("failed to commit heartbeat: %w", err)
 	}
 
-	auth.GasLimit = 200000
+	return tx.Hash().Hex(), nil
+}
 
-	tx, err := vault.RevealHeartbeat(auth, nonce)
+// RevealHeartbeat reveals a committed heartbeat
+func (s *ethBlockchainService) RevealHeartbeat(ctx context.Context, vaultAddr common.Address, nonce [32]byte) (string, error) {
+	data, err := packVaultCall("revealHeartbeat", nonce)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.transact(ctx, vaultAddr, big.NewInt(0), data)
 	if err != nil {
 		return "", fmt.Errorf("failed to reveal heartbeat: %w", err)
 	}
@@ -268,21 +493,30 @@ func (s *ethBlockchainService) GetLastHeartbeat(ctx context.Context, vaultAddr c
 	return config.LastHeartbeat, nil
 }
 
-// ApproveInheritance approves inheritance as an heir
-func (s *ethBlockchainService) ApproveInheritance(ctx context.Context, vaultAddr common.Address) (string, error) {
-	auth, err := s.getTransactor(ctx)
+// CheckAndUnlock unlocks a vault whose heartbeat has expired and starts its
+// grace period. Anyone may call it; the server signer pays the gas.
+func (s *ethBlockchainService) CheckAndUnlock(ctx context.Context, vaultAddr common.Address) (string, error) {
+	data, err := packVaultCall("checkAndUnlock")
 	if err != nil {
 		return "", err
 	}
 
-	vault, err := bindings.NewIndividualVault(vaultAddr, s.client)
+	tx, err := s.transact(ctx, vaultAddr, big.NewInt(0), data)
 	if err != nil {
-		return "", fmt.Errorf("failed to load vault contract: %w", err)
+		return "", fmt.Errorf("failed to check and unlock vault: %w", err)
 	}
 
-	auth.GasLimit = 150000
+	return tx.Hash().Hex(), nil
+}
+
+// ApproveInheritance approves inheritance as an heir
+func (s *ethBlockchainService) ApproveInheritance(ctx context.Context, vaultAddr common.Address) (string, error) {
+	data, err := packVaultCall("approveInheritance")
+	if err != nil {
+		return "", err
+	}
 
-	tx, err := vault.ApproveInheritance(auth)
+	tx, err := s.transact(ctx, vaultAddr, big.NewInt(0), data)
 	if err != nil {
 		return "", fmt.Errorf("failed to approve inheritance: %w", err)
 	}
@@ -292,19 +526,12 @@ func (s *ethBlockchainService) ApproveInheritance(ctx context.Context, vaultAddr
 
 // ClaimInheritance claims inheritance as an heir
 func (s *ethBlockchainService) ClaimInheritance(ctx context.Context, vaultAddr common.Address) (string, error) {
-	auth, err := s.getTransactor(ctx)
+	data, err := packVaultCall("claimInheritance")
 	if err != nil {
 		return "", err
 	}
 
-	vault, err := bindings.NewIndividualVault(vaultAddr, s.client)
-	if err != nil {
-		return "", fmt.Errorf("failed to load vault contract: %w", err)
-	}
-
-	auth.GasLimit = 300000
-
-	tx, err := vault.ClaimInheritance(auth)
+	tx, err := s.transact(ctx, vaultAddr, big.NewInt(0), data)
 	if err != nil {
 		return "", fmt.Errorf("failed to claim inheritance: %w", err)
 	}
@@ -348,6 +575,11 @@ func (s *ethBlockchainService) ListenVaultCreatedEvents(ctx context.Context, han
 				fmt.Printf("Event subscription error: %v\n", err)
 				return
 			case vLog := <-logs:
+				// Removed logs belong to blocks dropped by a reorg
+				if vLog.Removed {
+					continue
+				}
+
 				event, err := s.vaultFactory.ParseVaultCreated(vLog)
 				if err != nil {
 					fmt.Printf("Failed to parse VaultCreated event: %v\n", err)
@@ -364,6 +596,67 @@ func (s *ethBlockchainService) ListenVaultCreatedEvents(ctx context.Context, han
 	return nil
 }
 
+// ChainID returns the chain ID of the connected network
+func (s *ethBlockchainService) ChainID() *big.Int {
+	return new(big.Int).Set(s.chainID)
+}
+
+// FilterLogs returns the logs matching the given filter query
+func (s *ethBlockchainService) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := s.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter logs: %w", err)
+	}
+
+	return logs, nil
+}
+
+// SubscribeFilterLogs streams logs matching the query over the WebSocket client.
+// Logs from blocks dropped by a reorg are delivered again with Removed set.
+func (s *ethBlockchainService) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub, err := s.wsClient.SubscribeFilterLogs(ctx, query, ch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to logs: %w", err)
+	}
+
+	return sub, nil
+}
+
+// GetTotalVaults returns the factory's vault count at the given block (nil for latest)
+func (s *ethBlockchainService) GetTotalVaults(ctx context.Context, blockNumber *big.Int) (*big.Int, error) {
+	total, err := s.vaultFactory.TotalVaults(&bind.CallOpts{Context: ctx, BlockNumber: blockNumber})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total vaults: %w", err)
+	}
+
+	return total, nil
+}
+
+// VaultFactoryAddress returns the address of the VaultFactory contract
+func (s *ethBlockchainService) VaultFactoryAddress() common.Address {
+	return s.vaultFactoryAddr
+}
+
+// BlockNumber returns the latest block number
+func (s *ethBlockchainService) BlockNumber(ctx context.Context) (uint64, error) {
+	number, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+
+	return number, nil
+}
+
+// HeaderByNumber returns the block header for the given number (nil for latest)
+func (s *ethBlockchainService) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := s.client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block header: %w", err)
+	}
+
+	return header, nil
+}
+
 // GetTransactionReceipt gets the receipt of a transaction
 func (s *ethBlockchainService) GetTransactionReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
 	hash := common.HexToHash(txHash)
@@ -375,33 +668,136 @@ func (s *ethBlockchainService) GetTransactionReceipt(ctx context.Context, txHash
 	return receipt, nil
 }
 
+// GetTransactionByHash gets a transaction from the node, including the mempool.
+// The error wraps ethereum.NotFound if the node does not know the transaction.
+func (s *ethBlockchainService) GetTransactionByHash(ctx context.Context, txHash string) (*types.Transaction, bool, error) {
+	tx, isPending, err := s.client.TransactionByHash(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	return tx, isPending, nil
+}
+
+// GetRevertReason replays a reverted transaction against the state before its
+// block and returns the revert reason reported by the node
+func (s *ethBlockchainService) GetRevertReason(ctx context.Context, txHash string, blockNumber *big.Int) (string, error) {
+	tx, _, err := s.GetTransactionByHash(ctx, txHash)
+	if err != nil {
+		return "", err
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(s.chainID), tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to recover sender: %w", err)
+	}
+
+	_, callErr := s.client.CallContract(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}, new(big.Int).Sub(blockNumber, big.NewInt(1)))
+	if callErr == nil {
+		return "", nil // Not reproducible against the parent state
+	}
+
+	// Decode Error(string) revert data when the node returns it
+	var dataErr rpc.DataError
+	if errors.As(callErr, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if reason, err := abi.UnpackRevert(common.FromHex(data)); err == nil {
+				return reason, nil
+			}
+		}
+	}
+
+	return callErr.Error(), nil
+}
+
+// IsValidSignature asks a smart-contract wallet whether signature is valid
+// for hash (EIP-1271). It returns legacycrypto.ErrNotContract for accounts
+// without code.
+func (s *ethBlockchainService) IsValidSignature(ctx context.Context, account common.Address, hash common.Hash, signature []byte) (bool, error) {
+	return legacycrypto.VerifyEIP1271(ctx, s.client, account, hash, signature)
+}
+
 // Close closes the blockchain service connections
 func (s *ethBlockchainService) Close() {
-	s.client.Close()
-	s.wsClient.Close()
+	s.stopNonces()
+	s.closeClients()
 }
 
-// getTransactor creates a new transactor with current nonce and gas price
-func (s *ethBlockchainService) getTransactor(ctx context.Context) (*bind.TransactOpts, error) {
-	nonce, err := s.client.PendingNonceAt(ctx, s.fromAddress)
+// transact sends a call from the server signer with an estimated gas limit
+func (s *ethBlockchainService) transact(ctx context.Context, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	gas, err := s.fees.GasLimit(ctx, ethereum.CallMsg{
+		From:  s.fromAddress,
+		To:    &to,
+		Value: value,
+		Data:  data,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nonce: %w", err)
+		return nil, err
 	}
 
-	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	return s.send(ctx, to, value, gas, data)
+}
+
+// send signs and broadcasts a call from the server signer, priced by the fee
+// strategy. The nonce comes from the shared allocator and is handed back if
+// the transaction is never broadcast.
+func (s *ethBlockchainService) send(ctx context.Context, to common.Address, value *big.Int, gas uint64, data []byte) (*types.Transaction, error) {
+	fees, err := s.fees.Fees(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get gas price: %w", err)
+		return nil, err
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(s.privateKey, s.chainID)
+	nonce, err := s.nextNonce(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %w", err)
+		return nil, err
+	}
+
+	tx, err := types.SignTx(fees.NewTx(s.chainID, nonce, to, value, gas, data), types.LatestSignerForChainID(s.chainID), s.privateKey)
+	if err == nil {
+		err = s.client.SendTransaction(ctx, tx)
+	}
+
+	if s.nonces == nil {
+		return tx, err
 	}
 
-	auth.Nonce = big.NewInt(int64(nonce))
-	auth.Value = big.NewInt(0) // No ETH transfer
-	auth.GasPrice = gasPrice
-	auth.Context = ctx
+	if err != nil {
+		if isNonceTooLow(err) {
+			// The nonce was used outside the allocator; move past it
+			if resyncErr := s.nonces.Resync(ctx); resyncErr != nil {
+				log.Printf("Signer nonce resync error: %v", resyncErr)
+			}
+		} else if releaseErr := s.nonces.Release(ctx, nonce); releaseErr != nil {
+			log.Printf("Failed to release signer nonce %d: %v", nonce, releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := s.nonces.Sent(ctx, tx); err != nil {
+		log.Printf("Failed to record signer nonce %d: %v", tx.Nonce(), err)
+	}
+
+	return tx, nil
+}
+
+// nextNonce returns the server signer's next nonce
+func (s *ethBlockchainService) nextNonce(ctx context.Context) (uint64, error) {
+	var nonce uint64
+	var err error
+	if s.nonces != nil {
+		nonce, err = s.nonces.Next(ctx)
+	} else {
+		nonce, err = s.client.PendingNonceAt(ctx, s.fromAddress)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get nonce: %w", err)
+	}
 
-	return auth, nil
+	return nonce, nil
 }
@@ -5,84 +5,10 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
-// MockBlockchainService is a mock implementation of BlockchainService for testing
-type MockBlockchainService struct {
-	mock.Mock
-}
-
-func (m *MockBlockchainService) CreateVault(
-	heirs []common.Address,
-	shares []uint16,
-	heartbeatInterval uint64,
-	gracePeriod uint64,
-	requiredApprovals uint8,
-) (string, error) {
-	args := m.Called(heirs, shares, heartbeatInterval, gracePeriod, requiredApprovals)
-	return args.String(0), args.Error(1)
-}
-
-func (m *MockBlockchainService) CommitHeartbeat(vaultAddress string, commitHash [32]byte) (string, error) {
-	args := m.Called(vaultAddress, commitHash)
-	return args.String(0), args.Error(1)
-}
-
-func (m *MockBlockchainService) RevealHeartbeat(vaultAddress string, nonce [32]byte) (string, error) {
-	args := m.Called(vaultAddress, nonce)
-	return args.String(0), args.Error(1)
-}
-
-func (m *MockBlockchainService) ApproveInheritance(vaultAddress, heirAddress string) (string, error) {
-	args := m.Called(vaultAddress, heirAddress)
-	return args.String(0), args.Error(1)
-}
-
-func (m *MockBlockchainService) ClaimInheritance(vaultAddress, heirAddress string) (string, error) {
-	args := m.Called(vaultAddress, heirAddress)
-	return args.String(0), args.Error(1)
-}
-
-func (m *MockBlockchainService) GetVaultConfig(vaultAddress string) (*VaultConfig, error) {
-	args := m.Called(vaultAddress)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*VaultConfig), args.Error(1)
-}
-
-func (m *MockBlockchainService) Close() {
-	m.Called()
-}
-
-// Test mock implementation
-func TestMockBlockchainService(t *testing.T) {
-	mockService := new(MockBlockchainService)
-	
-	// Setup expectations
-	expectedTxHash := "0x1234567890abcdef"
-	mockService.On("CreateVault",
-		mock.Anything,
-		mock.Anything,
-		mock.AnythingOfType("uint64"),
-		mock.AnythingOfType("uint64"),
-		mock.AnythingOfType("uint8"),
-	).Return(expectedTxHash, nil)
-	
-	// Test
-	heirs := []common.Address{common.HexToAddress("0x123")}
-	shares := []uint16{10000}
-	
-	txHash, err := mockService.CreateVault(heirs, shares, 86400, 172800, 1)
-	
-	// Verify
-	assert.NoError(t, err)
-	assert.Equal(t, expectedTxHash, txHash)
-	mockService.AssertExpectations(t)
-}
-
 // Test VaultConfig struct
 func TestVaultConfig(t *testing.T) {
 	ownerAddr := common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb")
@@ -120,12 +46,11 @@ func TestCommitHash(t *testing.T) {
 	
 	assert.Equal(t, commitHash1, commitHash2)
 	assert.Equal(t, 32, len(commitHash1))
+	assert.Equal(t, crypto.Keccak256Hash(address.Bytes(), nonce[:]), common.Hash(commitHash1))
 }
 
 // Helper function to generate commit hash (same as in smart contract)
 func GenerateCommitHash(address common.Address, nonce [32]byte) [32]byte {
 	// keccak256(abi.encodePacked(msg.sender, _nonce))
-	data := append(address.Bytes(), nonce[:]...)
-	hash := common.BytesToHash(data)
-	return hash
+	return crypto.Keccak256Hash(address.Bytes(), nonce[:])
 }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+)
+
+var (
+	// ErrDeploymentReverted is returned when a vault deployment is mined but reverted
+	ErrDeploymentReverted = errors.New("vault deployment reverted")
+	// ErrVaultNotCreated is returned when the factory has no VaultCreated event for a vault
+	ErrVaultNotCreated = errors.New("vault not created by the factory")
+)
+
+// CreatedVault is a vault deployed through the factory, read from its VaultCreated event
+type CreatedVault struct {
+	Address     common.Address
+	Owner       common.Address
+	VaultID     int64 // Factory-wide sequence number, as assigned by the indexer
+	TxHash      common.Hash
+	BlockNumber uint64
+	CreatedAt   time.Time
+}
+
+// WaitForVaultCreated waits until a CreateVault transaction is mined and returns
+// the vault from its VaultCreated event. It returns the context's error if the
+// transaction is still pending when ctx is done.
+func (s *ethBlockchainService) WaitForVaultCreated(ctx context.Context, txHash string) (*CreatedVault, error) {
+	receipt, err := bind.WaitMinedHash(ctx, s.client, common.HexToHash(txHash))
+	if err != nil {
+		return nil, err
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		reason, err := s.GetRevertReason(ctx, txHash, receipt.BlockNumber)
+		if err != nil || reason == "" {
+			return nil, ErrDeploymentReverted
+		}
+		return nil, fmt.Errorf("%w: %s", ErrDeploymentReverted, reason)
+	}
+
+	factoryABI, err := bindings.VaultFactoryMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse factory ABI: %w", err)
+	}
+	topic := factoryABI.Events["VaultCreated"].ID
+
+	for _, vLog := range receipt.Logs {
+		if vLog.Address != s.vaultFactoryAddr || len(vLog.Topics) == 0 || vLog.Topics[0] != topic {
+			continue
+		}
+		return s.createdVault(ctx, *vLog, topic)
+	}
+
+	return nil, fmt.Errorf("no VaultCreated event in transaction %s", txHash)
+}
+
+// FindVaultCreated returns the vault from the factory's VaultCreated event for
+// owner and vaultAddress, searching from fromBlock. It returns
+// ErrVaultNotCreated if the factory never emitted one.
+func (s *ethBlockchainService) FindVaultCreated(ctx context.Context, owner, vaultAddress common.Address, fromBlock uint64) (*CreatedVault, error) {
+	factoryABI, err := bindings.VaultFactoryMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse factory ABI: %w", err)
+	}
+	topic := factoryABI.Events["VaultCreated"].ID
+
+	logs, err := s.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		Addresses: []common.Address{s.vaultFactoryAddr},
+		Topics: [][]common.Hash{
+			{topic},
+			{common.BytesToHash(vaultAddress.Bytes())},
+			{common.BytesToHash(owner.Bytes())},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		return nil, ErrVaultNotCreated
+	}
+
+	return s.createdVault(ctx, logs[0], topic)
+}
+
+// createdVault decodes a VaultCreated log of the factory
+func (s *ethBlockchainService) createdVault(ctx context.Context, vLog types.Log, topic common.Hash) (*CreatedVault, error) {
+	event, err := s.vaultFactory.ParseVaultCreated(vLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VaultCreated event: %w", err)
+	}
+
+	vaultID, err := s.vaultIDOf(ctx, vLog, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedVault{
+		Address:     event.VaultAddress,
+		Owner:       event.Owner,
+		VaultID:     vaultID,
+		TxHash:      vLog.TxHash,
+		BlockNumber: vLog.BlockNumber,
+		CreatedAt:   time.Unix(event.Timestamp.Int64(), 0),
+	}, nil
+}
+
+// vaultIDOf derives the factory-wide sequence number of a VaultCreated log the
+// same way the indexer does: totalVaults at the end of the block minus the
+// vaults created later in that block
+func (s *ethBlockchainService) vaultIDOf(ctx context.Context, vLog types.Log, topic common.Hash) (int64, error) {
+	total, err := s.GetTotalVaults(ctx, new(big.Int).SetUint64(vLog.BlockNumber))
+	if err != nil {
+		return 0, err
+	}
+
+	blockHash := vLog.BlockHash
+	logs, err := s.FilterLogs(ctx, ethereum.FilterQuery{
+		BlockHash: &blockHash,
+		Addresses: []common.Address{s.vaultFactoryAddr},
+		Topics:    [][]common.Hash{{topic}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	later := int64(0)
+	for _, other := range logs {
+		if other.Index > vLog.Index {
+			later++
+		}
+	}
+
+	return total.Int64() - later, nil
+}
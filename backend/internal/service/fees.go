@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/config"
+)
+
+const (
+	// baseFeeHeadroom is how many times the current base fee the fee cap allows,
+	// so a transaction stays includable through several full blocks
+	baseFeeHeadroom = 2
+
+	// minBumpPercent is the smallest fee increase nodes accept for a replacement
+	minBumpPercent = 10
+)
+
+// ErrFeeCeiling is returned when a replacement would have to exceed the configured fee ceilings
+var ErrFeeCeiling = errors.New("replacement fees exceed the configured ceiling")
+
+// FeeBackend is the part of the Ethereum client a fee strategy needs
+type FeeBackend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+}
+
+// FeeStrategy decides the gas limit and fees of outgoing transactions
+type FeeStrategy interface {
+	// GasLimit returns the gas to send msg with. It fails if the call would revert.
+	GasLimit(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+
+	// Fees returns the current fees to send a transaction with
+	Fees(ctx context.Context) (*TxFees, error)
+
+	// ReplacementFees returns fees high enough for a transaction with the same
+	// nonce to replace old in the mempool
+	ReplacementFees(ctx context.Context, old *types.Transaction) (*TxFees, error)
+}
+
+// TxFees holds either a legacy gas price or dynamic-fee (EIP-1559) caps
+type TxFees struct {
+	GasPrice  *big.Int // Set for legacy transactions
+	GasTipCap *big.Int // Set for dynamic-fee transactions
+	GasFeeCap *big.Int // Set for dynamic-fee transactions
+}
+
+// Dynamic reports whether the fees are for an EIP-1559 transaction
+func (f *TxFees) Dynamic() bool {
+	return f.GasPrice == nil
+}
+
+// NewTx builds an unsigned transaction priced with the fees
+func (f *TxFees) NewTx(chainID *big.Int, nonce uint64, to common.Address, value *big.Int, gas uint64, data []byte) *types.Transaction {
+	if !f.Dynamic() {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Value:    value,
+			Gas:      gas,
+			GasPrice: f.GasPrice,
+			Data:     data,
+		})
+	}
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		To:        &to,
+		Value:     value,
+		Gas:       gas,
+		GasTipCap: f.GasTipCap,
+		GasFeeCap: f.GasFeeCap,
+		Data:      data,
+	})
+}
+
+// estimatingFeeStrategy estimates gas with a safety multiplier and prices
+// transactions from the node's suggestions, capped by the configured ceilings.
+// Chains without a base fee (pre-London, e.g. private Besu networks) get
+// legacy pricing.
+type estimatingFeeStrategy struct {
+	backend FeeBackend
+	cfg     config.FeeConfig
+}
+
+// NewFeeStrategy creates the default FeeStrategy
+func NewFeeStrategy(backend FeeBackend, cfg config.FeeConfig) FeeStrategy {
+	return &estimatingFeeStrategy{
+		backend: backend,
+		cfg:     cfg,
+	}
+}
+
+// GasLimit estimates the gas of msg and adds the safety margin
+func (f *estimatingFeeStrategy) GasLimit(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	estimate, err := f.backend.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	if f.cfg.MaxGasLimit > 0 && estimate > f.cfg.MaxGasLimit {
+		return 0, fmt.Errorf("estimated gas %d exceeds the limit of %d", estimate, f.cfg.MaxGasLimit)
+	}
+
+	gas := estimate
+	if f.cfg.GasLimitMultiplier > 1 {
+		gas = uint64(math.Ceil(float64(estimate) * f.cfg.GasLimitMultiplier))
+	}
+	if f.cfg.MaxGasLimit > 0 && gas > f.cfg.MaxGasLimit {
+		gas = f.cfg.MaxGasLimit
+	}
+
+	return gas, nil
+}
+
+// Fees returns dynamic fees when the latest block has a base fee, legacy pricing otherwise
+func (f *estimatingFeeStrategy) Fees(ctx context.Context) (*TxFees, error) {
+	if !f.cfg.ForceLegacy {
+		head, err := f.backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest header: %w", err)
+		}
+
+		if head.BaseFee != nil {
+			return f.dynamicFees(ctx, head.BaseFee)
+		}
+	}
+
+	gasPrice, err := f.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+
+	return &TxFees{GasPrice: capFee(gasPrice, f.cfg.MaxFeePerGas)}, nil
+}
+
+func (f *estimatingFeeStrategy) dynamicFees(ctx context.Context, baseFee *big.Int) (*TxFees, error) {
+	tip, err := f.backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas tip cap: %w", err)
+	}
+	tip = capFee(tip, f.cfg.MaxPriorityFeePerGas)
+
+	feeCap := new(big.Int).Mul(baseFee, big.NewInt(baseFeeHeadroom))
+	feeCap.Add(feeCap, tip)
+	feeCap = capFee(feeCap, f.cfg.MaxFeePerGas)
+
+	// The tip can never exceed the fee cap
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	return &TxFees{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// ReplacementFees bumps the fees of old by the configured percentage, or uses
+// the current fees if they are higher. It fails with ErrFeeCeiling rather than
+// exceed the ceilings, since a capped replacement would be rejected anyway.
+func (f *estimatingFeeStrategy) ReplacementFees(ctx context.Context, old *types.Transaction) (*TxFees, error) {
+	current, err := f.Fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	percent := f.cfg.BumpPercent
+	if percent < minBumpPercent {
+		percent = minBumpPercent
+	}
+
+	if !current.Dynamic() {
+		price := maxFee(current.GasPrice, bumpFee(old.GasPrice(), percent))
+		if exceedsCeiling(price, f.cfg.MaxFeePerGas) {
+			return nil, ErrFeeCeiling
+		}
+		return &TxFees{GasPrice: price}, nil
+	}
+
+	// Legacy transactions report their gas price as both tip and fee cap
+	tip := maxFee(current.GasTipCap, bumpFee(old.GasTipCap(), percent))
+	feeCap := maxFee(current.GasFeeCap, bumpFee(old.GasFeeCap(), percent))
+	if tip.Cmp(feeCap) > 0 {
+		feeCap = tip
+	}
+
+	if exceedsCeiling(tip, f.cfg.MaxPriorityFeePerGas) || exceedsCeiling(feeCap, f.cfg.MaxFeePerGas) {
+		return nil, ErrFeeCeiling
+	}
+
+	return &TxFees{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// bumpFee raises fee by percent, rounding up and by at least 1 wei
+func bumpFee(fee *big.Int, percent uint64) *big.Int {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
+
+func maxFee(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// exceedsCeiling reports whether fee is above ceiling (nil or zero = no ceiling)
+func exceedsCeiling(fee, ceiling *big.Int) bool {
+	return ceiling != nil && ceiling.Sign() > 0 && fee.Cmp(ceiling) > 0
+}
+
+// capFee returns fee limited to ceiling (nil or zero = no ceiling)
+func capFee(fee, ceiling *big.Int) *big.Int {
+	if exceedsCeiling(fee, ceiling) {
+		return new(big.Int).Set(ceiling)
+	}
+	return fee
+}
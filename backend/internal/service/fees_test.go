@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubFeeBackend returns fixed chain values
+type stubFeeBackend struct {
+	baseFee     *big.Int // nil = pre-London chain
+	gasPrice    *big.Int
+	tipCap      *big.Int
+	estimate    uint64
+	estimateErr error
+}
+
+func (b *stubFeeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: b.baseFee}, nil
+}
+
+func (b *stubFeeBackend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return b.estimate, b.estimateErr
+}
+
+func (b *stubFeeBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return b.gasPrice, nil
+}
+
+func (b *stubFeeBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return b.tipCap, nil
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9))
+}
+
+// Test GasLimit applies the safety multiplier and ceiling
+func TestFeeStrategyGasLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.FeeConfig
+		estimate uint64
+		err      error
+		want     uint64
+		wantErr  bool
+	}{
+		{
+			name:     "Multiplier applied",
+			cfg:      config.FeeConfig{GasLimitMultiplier: 1.2, MaxGasLimit: 8000000},
+			estimate: 100000,
+			want:     120000,
+		},
+		{
+			name:     "Multiplier rounds up",
+			cfg:      config.FeeConfig{GasLimitMultiplier: 1.25},
+			estimate: 21001,
+			want:     26252,
+		},
+		{
+			name:     "No multiplier",
+			cfg:      config.FeeConfig{},
+			estimate: 50000,
+			want:     50000,
+		},
+		{
+			name:     "Margin capped at the ceiling",
+			cfg:      config.FeeConfig{GasLimitMultiplier: 1.5, MaxGasLimit: 120000},
+			estimate: 100000,
+			want:     120000,
+		},
+		{
+			name:     "Estimate above the ceiling",
+			cfg:      config.FeeConfig{GasLimitMultiplier: 1.2, MaxGasLimit: 80000},
+			estimate: 100000,
+			wantErr:  true,
+		},
+		{
+			name:    "Call would revert",
+			cfg:     config.FeeConfig{GasLimitMultiplier: 1.2},
+			err:     errors.New("execution reverted: Not owner"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := NewFeeStrategy(&stubFeeBackend{estimate: tt.estimate, estimateErr: tt.err}, tt.cfg)
+
+			gas, err := strategy.GasLimit(context.Background(), ethereum.CallMsg{})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, gas)
+			}
+		})
+	}
+}
+
+// Test Fees picks dynamic or legacy pricing and applies the ceilings
+func TestFeeStrategyFees(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.FeeConfig
+		backend     *stubFeeBackend
+		wantDynamic bool
+		wantPrice   *big.Int
+		wantTip     *big.Int
+		wantFeeCap  *big.Int
+	}{
+		{
+			name:        "Dynamic fees on London chains",
+			backend:     &stubFeeBackend{baseFee: gwei(10), tipCap: gwei(2), gasPrice: gwei(12)},
+			wantDynamic: true,
+			wantTip:     gwei(2),
+			wantFeeCap:  gwei(22), // 2 * base fee + tip
+		},
+		{
+			name:        "Fee cap ceiling",
+			cfg:         config.FeeConfig{MaxFeePerGas: gwei(15)},
+			backend:     &stubFeeBackend{baseFee: gwei(10), tipCap: gwei(2), gasPrice: gwei(12)},
+			wantDynamic: true,
+			wantTip:     gwei(2),
+			wantFeeCap:  gwei(15),
+		},
+		{
+			name:        "Priority fee ceiling",
+			cfg:         config.FeeConfig{MaxPriorityFeePerGas: gwei(1)},
+			backend:     &stubFeeBackend{baseFee: gwei(10), tipCap: gwei(5), gasPrice: gwei(15)},
+			wantDynamic: true,
+			wantTip:     gwei(1),
+			wantFeeCap:  gwei(21),
+		},
+		{
+			name:        "Tip never exceeds the fee cap",
+			cfg:         config.FeeConfig{MaxFeePerGas: gwei(3)},
+			backend:     &stubFeeBackend{baseFee: gwei(1), tipCap: gwei(5), gasPrice: gwei(6)},
+			wantDynamic: true,
+			wantTip:     gwei(3),
+			wantFeeCap:  gwei(3),
+		},
+		{
+			name:      "Legacy pricing without a base fee",
+			backend:   &stubFeeBackend{gasPrice: gwei(0)},
+			wantPrice: gwei(0),
+		},
+		{
+			name:      "Legacy gas price ceiling",
+			cfg:       config.FeeConfig{MaxFeePerGas: gwei(20)},
+			backend:   &stubFeeBackend{gasPrice: gwei(50)},
+			wantPrice: gwei(20),
+		},
+		{
+			name:      "Forced legacy pricing",
+			cfg:       config.FeeConfig{ForceLegacy: true},
+			backend:   &stubFeeBackend{baseFee: gwei(10), tipCap: gwei(2), gasPrice: gwei(12)},
+			wantPrice: gwei(12),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fees, err := NewFeeStrategy(tt.backend, tt.cfg).Fees(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantDynamic, fees.Dynamic())
+			if tt.wantDynamic {
+				assert.Equal(t, tt.wantTip, fees.GasTipCap)
+				assert.Equal(t, tt.wantFeeCap, fees.GasFeeCap)
+			} else {
+				assert.Equal(t, tt.wantPrice, fees.GasPrice)
+			}
+		})
+	}
+}
+
+// Test that transactions built from the strategy are accepted and mined by a London chain
+func TestFeeStrategySimulatedChain(t *testing.T) {
+	env := setupNonceTest(t)
+	ctx := context.Background()
+	strategy := NewFeeStrategy(env.client, config.FeeConfig{GasLimitMultiplier: 1.2})
+
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	gas, err := strategy.GasLimit(ctx, ethereum.CallMsg{From: env.address, To: &to, Value: big.NewInt(1)})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(25200), gas)
+
+	fees, err := strategy.Fees(ctx)
+	require.NoError(t, err)
+	require.True(t, fees.Dynamic())
+
+	tx, err := types.SignTx(fees.NewTx(env.chainID, 0, to, big.NewInt(1), gas, nil), types.LatestSignerForChainID(env.chainID), env.privateKey)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(types.DynamicFeeTxType), tx.Type())
+
+	require.NoError(t, env.client.SendTransaction(ctx, tx))
+	env.backend.Commit()
+
+	receipt, err := env.client.TransactionReceipt(ctx, tx.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	assert.Equal(t, uint64(21000), receipt.GasUsed)
+}
+
+// Test ReplacementFees bumps the old fees and respects the ceilings
+func TestFeeStrategyReplacementFees(t *testing.T) {
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	legacyTx := types.NewTx(&types.LegacyTx{To: &to, Gas: 21000, GasPrice: gwei(10)})
+	dynamicTx := types.NewTx(&types.DynamicFeeTx{To: &to, Gas: 21000, GasTipCap: gwei(2), GasFeeCap: gwei(20)})
+	zeroPriceTx := types.NewTx(&types.LegacyTx{To: &to, Gas: 21000, GasPrice: big.NewInt(0)})
+
+	tests := []struct {
+		name       string
+		cfg        config.FeeConfig
+		backend    *stubFeeBackend
+		old        *types.Transaction
+		wantPrice  *big.Int
+		wantTip    *big.Int
+		wantFeeCap *big.Int
+		wantErr    error
+	}{
+		{
+			name:      "Legacy bump",
+			cfg:       config.FeeConfig{BumpPercent: 15},
+			backend:   &stubFeeBackend{gasPrice: gwei(10)},
+			old:       legacyTx,
+			wantPrice: big.NewInt(11500000000),
+		},
+		{
+			name:      "Current price above the bump",
+			cfg:       config.FeeConfig{BumpPercent: 15},
+			backend:   &stubFeeBackend{gasPrice: gwei(30)},
+			old:       legacyTx,
+			wantPrice: gwei(30),
+		},
+		{
+			name:      "Bump of at least 10 percent",
+			cfg:       config.FeeConfig{BumpPercent: 1},
+			backend:   &stubFeeBackend{gasPrice: gwei(1)},
+			old:       legacyTx,
+			wantPrice: gwei(11),
+		},
+		{
+			name:      "Zero gas price is still bumped",
+			cfg:       config.FeeConfig{BumpPercent: 15},
+			backend:   &stubFeeBackend{gasPrice: big.NewInt(0)},
+			old:       zeroPriceTx,
+			wantPrice: big.NewInt(1),
+		},
+		{
+			name:       "Dynamic bump",
+			cfg:        config.FeeConfig{BumpPercent: 20},
+			backend:    &stubFeeBackend{baseFee: gwei(5), tipCap: gwei(1), gasPrice: gwei(6)},
+			old:        dynamicTx,
+			wantTip:    big.NewInt(2400000000),
+			wantFeeCap: gwei(24),
+		},
+		{
+			name:       "Dynamic replacement of a legacy transaction",
+			cfg:        config.FeeConfig{BumpPercent: 10},
+			backend:    &stubFeeBackend{baseFee: gwei(5), tipCap: gwei(1), gasPrice: gwei(6)},
+			old:        legacyTx,
+			wantTip:    gwei(11),
+			wantFeeCap: gwei(11),
+		},
+		{
+			name:    "Fee cap ceiling",
+			cfg:     config.FeeConfig{BumpPercent: 20, MaxFeePerGas: gwei(22)},
+			backend: &stubFeeBackend{baseFee: gwei(5), tipCap: gwei(1), gasPrice: gwei(6)},
+			old:     dynamicTx,
+			wantErr: ErrFeeCeiling,
+		},
+		{
+			name:    "Priority fee ceiling",
+			cfg:     config.FeeConfig{BumpPercent: 20, MaxPriorityFeePerGas: gwei(2)},
+			backend: &stubFeeBackend{baseFee: gwei(5), tipCap: gwei(1), gasPrice: gwei(6)},
+			old:     dynamicTx,
+			wantErr: ErrFeeCeiling,
+		},
+		{
+			name:    "Legacy ceiling",
+			cfg:     config.FeeConfig{BumpPercent: 15, MaxFeePerGas: gwei(11)},
+			backend: &stubFeeBackend{gasPrice: gwei(10)},
+			old:     legacyTx,
+			wantErr: ErrFeeCeiling,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fees, err := NewFeeStrategy(tt.backend, tt.cfg).ReplacementFees(context.Background(), tt.old)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.wantPrice != nil {
+				assert.False(t, fees.Dynamic())
+				assert.Equal(t, tt.wantPrice, fees.GasPrice)
+			} else {
+				assert.True(t, fees.Dynamic())
+				assert.Equal(t, tt.wantTip, fees.GasTipCap)
+				assert.Equal(t, tt.wantFeeCap, fees.GasFeeCap)
+			}
+		})
+	}
+}
+
+// Test that a stuck transaction is replaced in the mempool by its bumped copy
+func TestFeeStrategyReplacementSimulatedChain(t *testing.T) {
+	env := setupNonceTest(t)
+	ctx := context.Background()
+	strategy := NewFeeStrategy(env.client, config.FeeConfig{BumpPercent: 15})
+	signer := types.LatestSignerForChainID(env.chainID)
+
+	head, err := env.client.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	// Underpriced: the minimum tip
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	stuckFees := &TxFees{GasTipCap: big.NewInt(1), GasFeeCap: new(big.Int).Mul(head.BaseFee, big.NewInt(2))}
+	stuck, err := types.SignTx(stuckFees.NewTx(env.chainID, 0, to, big.NewInt(1), 21000, nil), signer, env.privateKey)
+	require.NoError(t, err)
+	require.NoError(t, env.client.SendTransaction(ctx, stuck))
+
+	// A cancellation with the same fees is rejected ...
+	sameFees, err := types.SignTx(stuckFees.NewTx(env.chainID, 0, env.address, big.NewInt(0), 21000, nil), signer, env.privateKey)
+	require.NoError(t, err)
+	assert.Error(t, env.client.SendTransaction(ctx, sameFees))
+
+	fees, err := strategy.ReplacementFees(ctx, stuck)
+	require.NoError(t, err)
+	assert.True(t, fees.GasTipCap.Cmp(stuck.GasTipCap()) > 0)
+	assert.True(t, fees.GasFeeCap.Cmp(stuck.GasFeeCap()) > 0)
+
+	// ... and the bumped cancellation takes its place
+	cancel, err := types.SignTx(fees.NewTx(env.chainID, 0, env.address, big.NewInt(0), 21000, nil), signer, env.privateKey)
+	require.NoError(t, err)
+	require.NoError(t, env.client.SendTransaction(ctx, cancel))
+	env.backend.Commit()
+
+	_, err = env.client.TransactionReceipt(ctx, stuck.Hash())
+	assert.Error(t, err)
+
+	receipt, err := env.client.TransactionReceipt(ctx, cancel.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+}
+
+// Test bumpFee rounds up and always raises the fee
+func TestBumpFee(t *testing.T) {
+	tests := []struct {
+		name    string
+		fee     *big.Int
+		percent uint64
+		want    *big.Int
+	}{
+		{name: "Exact percentage", fee: gwei(10), percent: 10, want: gwei(11)},
+		{name: "Rounds up", fee: big.NewInt(101), percent: 10, want: big.NewInt(112)},
+		{name: "At least 1 wei on small fees", fee: big.NewInt(1), percent: 10, want: big.NewInt(2)},
+		{name: "Zero fee", fee: big.NewInt(0), percent: 10, want: big.NewInt(1)},
+		{name: "Large bump", fee: gwei(3), percent: 100, want: gwei(6)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, bumpFee(tt.fee, tt.percent))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	legacycrypto "github.com/haneumLee/legacychain/backend/pkg/crypto"
+)
+
+// errNoForwarder is returned by forwarder operations when FORWARDER_ADDRESS is not set
+var errNoForwarder = fmt.Errorf("FORWARDER_ADDRESS not set in config")
+
+// ForwarderAddress returns the ERC-2771 forwarder address (zero if not configured)
+func (s *ethBlockchainService) ForwarderAddress() common.Address {
+	return s.forwarderAddr
+}
+
+// RelayerAddress returns the server account that pays for relayed requests
+func (s *ethBlockchainService) RelayerAddress() common.Address {
+	return s.fromAddress
+}
+
+// ForwarderDomain reads the forwarder's EIP-712 domain from the contract (EIP-5267)
+func (s *ethBlockchainService) ForwarderDomain(ctx context.Context) (*legacycrypto.EIP712Domain, error) {
+	if s.forwarder == nil {
+		return nil, errNoForwarder
+	}
+
+	domain, err := s.forwarder.Eip712Domain(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forwarder domain: %w", err)
+	}
+
+	return &legacycrypto.EIP712Domain{
+		Name:              domain.Name,
+		Version:           domain.Version,
+		ChainID:           domain.ChainId,
+		VerifyingContract: domain.VerifyingContract,
+	}, nil
+}
+
+// ForwarderNonce gets the next forwarder nonce of an address
+func (s *ethBlockchainService) ForwarderNonce(ctx context.Context, from common.Address) (*big.Int, error) {
+	if s.forwarder == nil {
+		return nil, errNoForwarder
+	}
+
+	nonce, err := s.forwarder.Nonces(&bind.CallOpts{Context: ctx}, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forwarder nonce: %w", err)
+	}
+
+	return nonce, nil
+}
+
+// EstimateForwardRequest estimates the gas the relayer needs to execute a signed
+// request. The forwarder reverts when the signature, nonce or deadline is invalid
+// or the vault call itself reverts, so a failed estimate means the request would fail.
+func (s *ethBlockchainService) EstimateForwardRequest(ctx context.Context, request bindings.ERC2771ForwarderForwardRequestData) (uint64, error) {
+	if s.forwarder == nil {
+		return 0, errNoForwarder
+	}
+
+	data, err := packForwardRequest(request)
+	if err != nil {
+		return 0, err
+	}
+
+	gas, err := s.client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  s.fromAddress,
+		To:    &s.forwarderAddr,
+		Value: request.Value,
+		Data:  data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	return gas, nil
+}
+
+// ExecuteForwardRequest submits a signed request to the forwarder from the relayer account
+func (s *ethBlockchainService) ExecuteForwardRequest(ctx context.Context, request bindings.ERC2771ForwarderForwardRequestData, gasLimit uint64) (*types.Transaction, error) {
+	if s.forwarder == nil {
+		return nil, errNoForwarder
+	}
+
+	data, err := packForwardRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// The relayer budgets gasLimit, so it is sent as is rather than re-estimated
+	tx, err := s.send(ctx, s.forwarderAddr, request.Value, gasLimit, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute forward request: %w", err)
+	}
+
+	return tx, nil
+}
+
+// packForwardRequest encodes a forwarder execute call
+func packForwardRequest(request bindings.ERC2771ForwarderForwardRequestData) ([]byte, error) {
+	forwarderABI, err := bindings.ERC2771ForwarderMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse forwarder ABI: %w", err)
+	}
+
+	data, err := forwarderABI.Pack("execute", request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack execute call: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// SignerNonceKeyPrefix is the Redis key prefix for server signer nonces
+	SignerNonceKeyPrefix = "signer_nonce:"
+
+	// nonceLockTTL bounds how long one replica may hold the resync lock
+	nonceLockTTL = 30 * time.Second
+
+	// cancelGasLimit is the gas of the zero-value self-transfer that fills a gap
+	cancelGasLimit = 21000
+)
+
+// Node error messages for a nonce or transaction it has already seen
+const (
+	errNonceTooLow  = "nonce too low"
+	errAlreadyKnown = "already known"
+)
+
+// NonceBackend is the part of the Ethereum client the nonce allocator needs
+type NonceBackend interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// NonceAllocator assigns nonces to transactions sent by the server signer.
+// The next nonce lives in Redis and is handed out by an atomic script, so
+// concurrent requests and API replicas never receive the same nonce.
+//
+// Every allocated nonce is tracked until the node has it: Sent stores the
+// signed transaction, Release returns a nonce that was never broadcast.
+// Resync reconciles that state with the chain, rebroadcasting transactions
+// the node lost and cancelling nonces that were allocated but never sent,
+// which would otherwise block every later transaction.
+type NonceAllocator struct {
+	redis        *redis.Client
+	backend      NonceBackend
+	privateKey   *ecdsa.PrivateKey
+	address      common.Address
+	chainID      *big.Int
+	stuckTimeout time.Duration
+	prefix       string
+}
+
+// allocateScript pops the lowest released nonce, or takes the next one.
+// Returns -1 if the allocator has not been synced from the chain yet.
+//
+// KEYS: next, released, inflight   ARGV: now (unix ms)
+var allocateScript = redis.NewScript(`
+local nonce
+local released = redis.call('ZRANGE', KEYS[2], 0, 0)
+if #released > 0 then
+	nonce = tonumber(released[1])
+	redis.call('ZREM', KEYS[2], released[1])
+else
+	local next = redis.call('GET', KEYS[1])
+	if not next then
+		return -1
+	end
+	nonce = tonumber(next)
+	redis.call('SET', KEYS[1], nonce + 1)
+end
+redis.call('HSET', KEYS[3], nonce, ARGV[1])
+return nonce
+`)
+
+// releaseScript returns an unsent nonce to the pool. The most recent nonce is
+// handed back by decrementing next; older ones are kept in the released set.
+//
+// KEYS: next, released, inflight   ARGV: nonce
+var releaseScript = redis.NewScript(`
+if redis.call('HDEL', KEYS[3], ARGV[1]) == 0 then
+	return 0
+end
+local next = tonumber(redis.call('GET', KEYS[1]))
+if next and next == tonumber(ARGV[1]) + 1 then
+	redis.call('SET', KEYS[1], ARGV[1])
+else
+	redis.call('ZADD', KEYS[2], ARGV[1], ARGV[1])
+end
+return 1
+`)
+
+// raiseScript moves next forward to at least ARGV[1]
+//
+// KEYS: next   ARGV: floor
+var raiseScript = redis.NewScript(`
+local next = tonumber(redis.call('GET', KEYS[1]))
+if not next or next < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+	return tonumber(ARGV[1])
+end
+return next
+`)
+
+// unlockScript deletes the resync lock only if this replica still holds it
+//
+// KEYS: lock   ARGV: token
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// NewNonceAllocator creates a NonceAllocator for the account of privateKey.
+// Nonces allocated longer than stuckTimeout ago that never reached the node
+// are cancelled by Resync.
+func NewNonceAllocator(redisClient *redis.Client, backend NonceBackend, privateKey *ecdsa.PrivateKey, chainID *big.Int, stuckTimeout time.Duration) *NonceAllocator {
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	return &NonceAllocator{
+		redis:        redisClient,
+		backend:      backend,
+		privateKey:   privateKey,
+		address:      address,
+		chainID:      new(big.Int).Set(chainID),
+		stuckTimeout: stuckTimeout,
+		prefix:       fmt.Sprintf("%s%s:%s", SignerNonceKeyPrefix, chainID, address.Hex()),
+	}
+}
+
+// Address returns the account the allocator assigns nonces for
+func (a *NonceAllocator) Address() common.Address {
+	return a.address
+}
+
+// Next allocates the next nonce. The caller must report the outcome with
+// Sent once the transaction is broadcast, or Release if it never was.
+func (a *NonceAllocator) Next(ctx context.Context) (uint64, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		nonce, err := allocateScript.Run(ctx, a.redis, a.keys("next", "released", "inflight"), time.Now().UnixMilli()).Int64()
+		if err != nil {
+			return 0, fmt.Errorf("failed to allocate nonce: %w", err)
+		}
+		if nonce >= 0 {
+			return uint64(nonce), nil
+		}
+
+		// First use, or Redis lost its state: start from the chain
+		if err := a.sync(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, fmt.Errorf("failed to allocate nonce: allocator not initialized")
+}
+
+// Sent records the broadcast transaction for its nonce so Resync can
+// rebroadcast it if the node drops it
+func (a *NonceAllocator) Sent(ctx context.Context, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	field := strconv.FormatUint(tx.Nonce(), 10)
+	_, err = a.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, a.key("sent"), field, hexutil.Encode(raw))
+		pipe.HDel(ctx, a.key("inflight"), field)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record sent nonce: %w", err)
+	}
+
+	return nil
+}
+
+// SentTransaction returns the last transaction broadcast with nonce, or nil
+// if the allocator has none (never sent, or already mined and pruned)
+func (a *NonceAllocator) SentTransaction(ctx context.Context, nonce uint64) (*types.Transaction, error) {
+	raw, err := a.redis.HGet(ctx, a.key("sent"), strconv.FormatUint(nonce, 10)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sent nonce: %w", err)
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(common.FromHex(raw)); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	return tx, nil
+}
+
+// Release returns a nonce whose transaction was never broadcast so the next
+// allocation reuses it
+func (a *NonceAllocator) Release(ctx context.Context, nonce uint64) error {
+	if err := releaseScript.Run(ctx, a.redis, a.keys("next", "released", "inflight"), nonce).Err(); err != nil {
+		return fmt.Errorf("failed to release nonce: %w", err)
+	}
+
+	return nil
+}
+
+// Resync reconciles the allocator with the chain. It skips the check if
+// another replica is already running it.
+func (a *NonceAllocator) Resync(ctx context.Context) error {
+	token := uuid.New().String()
+	locked, err := a.redis.SetNX(ctx, a.key("lock"), token, nonceLockTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to acquire nonce lock: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer unlockScript.Run(context.Background(), a.redis, a.keys("lock"), token)
+
+	return a.resync(ctx)
+}
+
+// Run resyncs the allocator every interval until ctx is cancelled
+func (a *NonceAllocator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Resync(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Signer nonce resync error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync initializes next from the node's pending nonce
+func (a *NonceAllocator) sync(ctx context.Context) error {
+	pending, err := a.backend.PendingNonceAt(ctx, a.address)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	if err := raiseScript.Run(ctx, a.redis, a.keys("next"), pending).Err(); err != nil {
+		return fmt.Errorf("failed to sync nonce: %w", err)
+	}
+
+	return nil
+}
+
+func (a *NonceAllocator) resync(ctx context.Context) error {
+	mined, err := a.backend.NonceAt(ctx, a.address, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get account nonce: %w", err)
+	}
+
+	pending, err := a.backend.PendingNonceAt(ctx, a.address)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	// Nonces used outside the allocator (another signer, lost Redis state) move next forward
+	next, err := raiseScript.Run(ctx, a.redis, a.keys("next"), pending).Uint64()
+	if err != nil {
+		return fmt.Errorf("failed to sync nonce: %w", err)
+	}
+
+	if err := a.prune(ctx, mined); err != nil {
+		return err
+	}
+
+	sent, err := a.nonceMap(ctx, "sent")
+	if err != nil {
+		return err
+	}
+	inflight, err := a.nonceMap(ctx, "inflight")
+	if err != nil {
+		return err
+	}
+	released, err := a.redis.ZRangeByScore(ctx, a.key("released"), &redis.ZRangeBy{Min: "-inf", Max: "+inf"}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to query released nonces: %w", err)
+	}
+	isReleased := make(map[uint64]bool, len(released))
+	for _, member := range released {
+		if nonce, err := strconv.ParseUint(member, 10, 64); err == nil {
+			isReleased[nonce] = true
+		}
+	}
+
+	// The highest broadcast nonce; gaps below it stall the account
+	var highestSent uint64
+	var hasSent bool
+	for nonce := range sent {
+		if !hasSent || nonce > highestSent {
+			highestSent, hasSent = nonce, true
+		}
+	}
+
+	now := time.Now()
+	for nonce := mined; nonce < next; nonce++ {
+		if raw, ok := sent[nonce]; ok {
+			// The node's pending nonce stops at the first transaction it does not have
+			if nonce >= pending {
+				if err := a.rebroadcast(ctx, nonce, raw); err != nil {
+					log.Printf("Failed to rebroadcast signer nonce %d: %v", nonce, err)
+				}
+			}
+			continue
+		}
+
+		if allocatedAt, ok := inflight[nonce]; ok {
+			ms, _ := strconv.ParseInt(allocatedAt, 10, 64)
+			if now.Sub(time.UnixMilli(ms)) < a.stuckTimeout {
+				continue // Still being sent
+			}
+
+			// Claim the nonce so a late Sent or Release cannot race the cancellation
+			removed, err := a.redis.HDel(ctx, a.key("inflight"), strconv.FormatUint(nonce, 10)).Result()
+			if err != nil {
+				return fmt.Errorf("failed to claim stuck nonce: %w", err)
+			}
+			if removed == 0 {
+				continue
+			}
+		} else if isReleased[nonce] {
+			// Released nonces are reused by the next allocation unless they block sent ones
+			if !hasSent || nonce > highestSent {
+				continue
+			}
+
+			removed, err := a.redis.ZRem(ctx, a.key("released"), strconv.FormatUint(nonce, 10)).Result()
+			if err != nil {
+				return fmt.Errorf("failed to claim released nonce: %w", err)
+			}
+			if removed == 0 {
+				continue
+			}
+		} else if nonce < pending {
+			continue // Known to the node but not sent by us
+		}
+
+		if err := a.cancel(ctx, nonce); err != nil {
+			log.Printf("Failed to cancel signer nonce %d: %v", nonce, err)
+		}
+	}
+
+	return nil
+}
+
+// prune forgets nonces below the account's mined nonce
+func (a *NonceAllocator) prune(ctx context.Context, mined uint64) error {
+	for _, name := range []string{"sent", "inflight"} {
+		nonces, err := a.nonceMap(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		var stale []string
+		for nonce := range nonces {
+			if nonce < mined {
+				stale = append(stale, strconv.FormatUint(nonce, 10))
+			}
+		}
+		if len(stale) > 0 {
+			if err := a.redis.HDel(ctx, a.key(name), stale...).Err(); err != nil {
+				return fmt.Errorf("failed to prune %s nonces: %w", name, err)
+			}
+		}
+	}
+
+	if mined > 0 {
+		max := strconv.FormatUint(mined-1, 10)
+		if err := a.redis.ZRemRangeByScore(ctx, a.key("released"), "-inf", max).Err(); err != nil {
+			return fmt.Errorf("failed to prune released nonces: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rebroadcast sends a stored transaction again after the node dropped it
+func (a *NonceAllocator) rebroadcast(ctx context.Context, nonce uint64, raw string) error {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(common.FromHex(raw)); err != nil {
+		return fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	if err := a.backend.SendTransaction(ctx, tx); err != nil {
+		if isKnownNonceError(err) {
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("Rebroadcast signer nonce %d (%s)", nonce, tx.Hash().Hex())
+	return nil
+}
+
+// cancel fills a nonce that was never broadcast with a zero-value transfer to self
+func (a *NonceAllocator) cancel(ctx context.Context, nonce uint64) error {
+	gasPrice, err := a.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get gas price: %w", err)
+	}
+
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       &a.address,
+		Value:    big.NewInt(0),
+		Gas:      cancelGasLimit,
+		GasPrice: gasPrice,
+	}), types.LatestSignerForChainID(a.chainID), a.privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign cancellation: %w", err)
+	}
+
+	if err := a.backend.SendTransaction(ctx, tx); err != nil {
+		if isKnownNonceError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to send cancellation: %w", err)
+	}
+
+	log.Printf("Cancelled stuck signer nonce %d (%s)", nonce, tx.Hash().Hex())
+	return a.Sent(ctx, tx)
+}
+
+// nonceMap reads one of the nonce-keyed hashes
+func (a *NonceAllocator) nonceMap(ctx context.Context, name string) (map[uint64]string, error) {
+	fields, err := a.redis.HGetAll(ctx, a.key(name)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s nonces: %w", name, err)
+	}
+
+	nonces := make(map[uint64]string, len(fields))
+	for field, value := range fields {
+		nonce, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		nonces[nonce] = value
+	}
+
+	return nonces, nil
+}
+
+func (a *NonceAllocator) key(name string) string {
+	return a.prefix + ":" + name
+}
+
+func (a *NonceAllocator) keys(names ...string) []string {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = a.key(name)
+	}
+	return keys
+}
+
+// isNonceTooLow reports whether the node rejected a transaction because its
+// nonce is already used
+func isNonceTooLow(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errNonceTooLow)
+}
+
+// isKnownNonceError reports whether the node already has a transaction for the nonce
+func isKnownNonceError(err error) bool {
+	return isNonceTooLow(err) || (err != nil && strings.Contains(err.Error(), errAlreadyKnown))
+}
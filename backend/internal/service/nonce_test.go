@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nonceTestEnv is a funded signer on a simulated chain with an in-memory Redis
+type nonceTestEnv struct {
+	backend    *simulated.Backend
+	client     simulated.Client
+	redis      *redis.Client
+	privateKey *ecdsa.PrivateKey
+	address    common.Address
+	chainID    *big.Int
+}
+
+func setupNonceTest(t *testing.T) *nonceTestEnv {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		address: {Balance: new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))},
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := backend.Client()
+	chainID, err := client.ChainID(context.Background())
+	require.NoError(t, err)
+
+	return &nonceTestEnv{
+		backend:    backend,
+		client:     client,
+		redis:      redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		privateKey: privateKey,
+		address:    address,
+		chainID:    chainID,
+	}
+}
+
+// allocator creates an allocator as a separate API replica would
+func (e *nonceTestEnv) allocator(stuckTimeout time.Duration) *NonceAllocator {
+	return NewNonceAllocator(e.redis, e.client, e.privateKey, e.chainID, stuckTimeout)
+}
+
+// transfer signs a small transfer from the signer with the given nonce
+func (e *nonceTestEnv) transfer(t *testing.T, nonce uint64) *types.Transaction {
+	gasPrice, err := e.client.SuggestGasPrice(context.Background())
+	require.NoError(t, err)
+
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		To:       &to,
+		Value:    big.NewInt(1),
+		Gas:      21000,
+		GasPrice: gasPrice,
+	}), types.LatestSignerForChainID(e.chainID), e.privateKey)
+	require.NoError(t, err)
+
+	return tx
+}
+
+// send broadcasts a transfer with an allocated nonce and records it
+func (e *nonceTestEnv) send(t *testing.T, a *NonceAllocator, nonce uint64) {
+	ctx := context.Background()
+	tx := e.transfer(t, nonce)
+	require.NoError(t, e.client.SendTransaction(ctx, tx))
+	require.NoError(t, a.Sent(ctx, tx))
+}
+
+func (e *nonceTestEnv) minedNonce(t *testing.T) uint64 {
+	nonce, err := e.client.NonceAt(context.Background(), e.address, nil)
+	require.NoError(t, err)
+	return nonce
+}
+
+// Test that concurrent allocations across replicas never share a nonce
+func TestNonceAllocatorConcurrentNext(t *testing.T) {
+	env := setupNonceTest(t)
+	replicas := []*NonceAllocator{env.allocator(time.Minute), env.allocator(time.Minute)}
+
+	const workers = 50
+	nonces := make([]uint64, workers)
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nonces[i], errs[i] = replicas[i%len(replicas)].Next(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Every nonce from 0 to workers-1 is handed out exactly once
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	for i, nonce := range nonces {
+		assert.Equal(t, uint64(i), nonce)
+	}
+}
+
+// Test that concurrently sent transactions are all mined
+func TestNonceAllocatorConcurrentSend(t *testing.T) {
+	env := setupNonceTest(t)
+	replicas := []*NonceAllocator{env.allocator(time.Minute), env.allocator(time.Minute)}
+
+	const workers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(a *NonceAllocator) {
+			defer wg.Done()
+			nonce, err := a.Next(context.Background())
+			if !assert.NoError(t, err) {
+				return
+			}
+			env.send(t, a, nonce)
+		}(replicas[i%len(replicas)])
+	}
+	wg.Wait()
+
+	env.backend.Commit()
+	assert.Equal(t, uint64(workers), env.minedNonce(t))
+}
+
+// Test that released nonces are reused before new ones
+func TestNonceAllocatorRelease(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(time.Minute)
+	ctx := context.Background()
+
+	for want := uint64(0); want < 3; want++ {
+		nonce, err := a.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, want, nonce)
+	}
+
+	// The latest nonce is handed back directly
+	require.NoError(t, a.Release(ctx, 2))
+	nonce, err := a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), nonce)
+
+	// An older nonce is reused by the next allocation
+	require.NoError(t, a.Release(ctx, 1))
+	nonce, err = a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), nonce)
+
+	nonce, err = a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), nonce)
+
+	// Releasing a nonce twice has no effect
+	require.NoError(t, a.Release(ctx, 3))
+	require.NoError(t, a.Release(ctx, 3))
+	nonce, err = a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), nonce)
+	nonce, err = a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), nonce)
+}
+
+// Test that nonces used outside the allocator are skipped
+func TestNonceAllocatorSyncsFromChain(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(time.Minute)
+	ctx := context.Background()
+
+	// Another signer uses the account before the allocator starts
+	require.NoError(t, env.client.SendTransaction(ctx, env.transfer(t, 0)))
+	require.NoError(t, env.client.SendTransaction(ctx, env.transfer(t, 1)))
+	env.backend.Commit()
+
+	nonce, err := a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), nonce)
+	env.send(t, a, nonce)
+
+	// ... and again while it is running
+	require.NoError(t, env.client.SendTransaction(ctx, env.transfer(t, 3)))
+	env.backend.Commit()
+
+	require.NoError(t, a.Resync(ctx))
+	nonce, err = a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), nonce)
+}
+
+// Test that a nonce allocated but never sent is cancelled so later ones are mined
+func TestNonceAllocatorCancelsStuckNonce(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		nonce, err := a.Next(ctx)
+		require.NoError(t, err)
+
+		// Nonce 1 is lost, as if the replica crashed before sending it
+		if nonce != 1 {
+			env.send(t, a, nonce)
+		}
+	}
+
+	env.backend.Commit()
+	require.Equal(t, uint64(1), env.minedNonce(t))
+
+	require.NoError(t, a.Resync(ctx))
+	env.backend.Commit()
+	assert.Equal(t, uint64(3), env.minedNonce(t))
+
+	// Nothing is left to repair
+	require.NoError(t, a.Resync(ctx))
+	nonce, err := a.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), nonce)
+}
+
+// Test that a recent allocation is left alone while it is being sent
+func TestNonceAllocatorKeepsRecentNonce(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(time.Minute)
+	ctx := context.Background()
+
+	nonce, err := a.Next(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, a.Resync(ctx))
+	env.backend.Commit()
+	assert.Equal(t, uint64(0), env.minedNonce(t))
+
+	env.send(t, a, nonce)
+	env.backend.Commit()
+	assert.Equal(t, uint64(1), env.minedNonce(t))
+}
+
+// Test that a released nonce below a sent one is cancelled
+func TestNonceAllocatorCancelsReleasedGap(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(time.Minute)
+	ctx := context.Background()
+
+	first, err := a.Next(ctx)
+	require.NoError(t, err)
+	second, err := a.Next(ctx)
+	require.NoError(t, err)
+
+	env.send(t, a, second)
+	require.NoError(t, a.Release(ctx, first))
+
+	env.backend.Commit()
+	require.Equal(t, uint64(0), env.minedNonce(t))
+
+	require.NoError(t, a.Resync(ctx))
+	env.backend.Commit()
+	assert.Equal(t, uint64(2), env.minedNonce(t))
+}
+
+// Test that transactions the node dropped are broadcast again
+func TestNonceAllocatorRebroadcastsDroppedTx(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		nonce, err := a.Next(ctx)
+		require.NoError(t, err)
+		env.send(t, a, nonce)
+	}
+
+	// Rollback empties the simulated mempool
+	env.backend.Rollback()
+	pending, err := env.client.PendingNonceAt(ctx, env.address)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), pending)
+
+	require.NoError(t, a.Resync(ctx))
+	env.backend.Commit()
+	assert.Equal(t, uint64(2), env.minedNonce(t))
+}
+
+// Test that only one replica resyncs at a time
+func TestNonceAllocatorResyncLock(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(0)
+	ctx := context.Background()
+
+	_, err := a.Next(ctx)
+	require.NoError(t, err)
+
+	// Another replica holds the lock, so the stuck nonce is left for it
+	require.NoError(t, env.redis.Set(ctx, a.key("lock"), "other", time.Minute).Err())
+	require.NoError(t, a.Resync(ctx))
+	env.backend.Commit()
+	assert.Equal(t, uint64(0), env.minedNonce(t))
+
+	require.NoError(t, env.redis.Del(ctx, a.key("lock")).Err())
+	require.NoError(t, a.Resync(ctx))
+	env.backend.Commit()
+	assert.Equal(t, uint64(1), env.minedNonce(t))
+}
+
+// Test that the last broadcast for a nonce is kept until it is mined
+func TestNonceAllocatorSentTransaction(t *testing.T) {
+	env := setupNonceTest(t)
+	a := env.allocator(time.Minute)
+	ctx := context.Background()
+
+	nonce, err := a.Next(ctx)
+	require.NoError(t, err)
+	env.send(t, a, nonce)
+
+	tx, err := a.SentTransaction(ctx, nonce)
+	require.NoError(t, err)
+	require.NotNil(t, tx)
+	assert.Equal(t, nonce, tx.Nonce())
+
+	unknown, err := a.SentTransaction(ctx, nonce+1)
+	require.NoError(t, err)
+	assert.Nil(t, unknown)
+
+	// Pruned once mined
+	env.backend.Commit()
+	require.NoError(t, a.Resync(ctx))
+	mined, err := a.SentTransaction(ctx, nonce)
+	require.NoError(t, err)
+	assert.Nil(t, mined)
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+)
+
+// UnsignedTx is a vault transaction for the user's wallet to sign. Type 2
+// (EIP-1559) transactions carry fee caps, type 0 (legacy) a gas price.
+type UnsignedTx struct {
+	From                 string `json:"from"`
+	To                   string `json:"to"`
+	Data                 string `json:"data"`
+	Value                string `json:"value"`
+	Gas                  uint64 `json:"gas"`
+	Type                 uint8  `json:"type"`
+	GasPrice             string `json:"gas_price,omitempty"`
+	MaxFeePerGas         string `json:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas,omitempty"`
+	Nonce                uint64 `json:"nonce"`
+	ChainID              int64  `json:"chain_id"`
+}
+
+// BuildVaultTransaction packs a call to an IndividualVault method and fills in
+// gas, nonce and fees for the sender. Gas estimation fails if the call would
+// revert, so callers get the revert reason before asking for a signature.
+func (s *ethBlockchainService) BuildVaultTransaction(ctx context.Context, from, vaultAddr common.Address, method string, args ...interface{}) (*UnsignedTx, error) {
+	data, err := packVaultCall(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildTransaction(ctx, from, vaultAddr, big.NewInt(0), data)
+}
+
+// BuildDepositTransaction builds a plain transfer of amount wei to the vault,
+// which its receive function records as a deposit
+func (s *ethBlockchainService) BuildDepositTransaction(ctx context.Context, from, vaultAddr common.Address, amount *big.Int) (*UnsignedTx, error) {
+	return s.buildTransaction(ctx, from, vaultAddr, amount, nil)
+}
+
+func (s *ethBlockchainService) buildTransaction(ctx context.Context, from, to common.Address, value *big.Int, data []byte) (*UnsignedTx, error) {
+	gas, err := s.fees.GasLimit(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    &to,
+		Value: value,
+		Data:  data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := s.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+
+	fees, err := s.fees.Fees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unsigned := &UnsignedTx{
+		From:    from.Hex(),
+		To:      to.Hex(),
+		Data:    hexutil.Encode(data),
+		Value:   value.String(),
+		Gas:     gas,
+		Nonce:   nonce,
+		ChainID: s.chainID.Int64(),
+	}
+	if fees.Dynamic() {
+		unsigned.Type = types.DynamicFeeTxType
+		unsigned.MaxFeePerGas = fees.GasFeeCap.String()
+		unsigned.MaxPriorityFeePerGas = fees.GasTipCap.String()
+	} else {
+		unsigned.Type = types.LegacyTxType
+		unsigned.GasPrice = fees.GasPrice.String()
+	}
+
+	return unsigned, nil
+}
+
+// DecodeRawTransaction decodes a signed transaction and recovers its sender.
+// Transactions signed for another chain are rejected.
+func (s *ethBlockchainService) DecodeRawTransaction(rawTx string) (*types.Transaction, common.Address, error) {
+	raw, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("failed to decode raw transaction: %w", err)
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, common.Address{}, fmt.Errorf("failed to decode raw transaction: %w", err)
+	}
+
+	if tx.Protected() && tx.ChainId().Cmp(s.chainID) != 0 {
+		return nil, common.Address{}, fmt.Errorf("chain ID mismatch: expected %d, got %d", s.chainID.Int64(), tx.ChainId().Int64())
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(s.chainID), tx)
+	if err != nil {
+		return nil, common.Address{}, fmt.Errorf("failed to recover sender: %w", err)
+	}
+
+	return tx, sender, nil
+}
+
+// SendTransaction broadcasts a signed transaction
+func (s *ethBlockchainService) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return nil
+}
+
+// packVaultCall encodes a call to an IndividualVault method
+func packVaultCall(method string, args ...interface{}) ([]byte, error) {
+	vaultABI, err := bindings.IndividualVaultMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vault ABI: %w", err)
+	}
+
+	data, err := vaultABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+
+	return data, nil
+}
+
+// DecodeVaultCall returns the IndividualVault method and arguments encoded in calldata
+func DecodeVaultCall(data []byte) (string, []interface{}, error) {
+	if len(data) < 4 {
+		return "", nil, fmt.Errorf("calldata too short")
+	}
+
+	vaultABI, err := bindings.IndividualVaultMetaData.GetAbi()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse vault ABI: %w", err)
+	}
+
+	method, err := vaultABI.MethodById(data[:4])
+	if err != nil {
+		return "", nil, fmt.Errorf("unknown vault method: %w", err)
+	}
+
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to unpack %s arguments: %w", method.Name, err)
+	}
+
+	return method.Name, args, nil
+}
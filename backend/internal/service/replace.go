@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNonceNotPending is returned when the server signer has no pending transaction for a nonce
+var ErrNonceNotPending = errors.New("no pending transaction for nonce")
+
+// ReplaceTransaction re-broadcasts the server signer's pending transaction at
+// nonce with bumped fees. With cancel set, the replacement is a zero-value
+// transfer to self, so the original call never executes.
+func (s *ethBlockchainService) ReplaceTransaction(ctx context.Context, nonce uint64, cancel bool) (*types.Transaction, error) {
+	if s.nonces == nil {
+		return nil, fmt.Errorf("signer nonce allocator not configured")
+	}
+
+	mined, err := s.client.NonceAt(ctx, s.fromAddress, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account nonce: %w", err)
+	}
+	if nonce < mined {
+		return nil, ErrNonceNotPending
+	}
+
+	original, err := s.nonces.SentTransaction(ctx, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, ErrNonceNotPending
+	}
+
+	fees, err := s.fees.ReplacementFees(ctx, original)
+	if err != nil {
+		return nil, err
+	}
+
+	to, value, gas, data := *original.To(), original.Value(), original.Gas(), original.Data()
+	if cancel {
+		to, value, gas, data = s.fromAddress, big.NewInt(0), cancelGasLimit, nil
+	}
+
+	tx, err := types.SignTx(fees.NewTx(s.chainID, nonce, to, value, gas, data), types.LatestSignerForChainID(s.chainID), s.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign replacement: %w", err)
+	}
+
+	if err := s.client.SendTransaction(ctx, tx); err != nil {
+		if isNonceTooLow(err) {
+			return nil, ErrNonceNotPending // Mined in the meantime
+		}
+		return nil, fmt.Errorf("failed to send replacement: %w", err)
+	}
+
+	if err := s.nonces.Sent(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupReplaceTest returns a service signing with the test signer and the
+// nonce it broadcast an underpriced transfer with
+func setupReplaceTest(t *testing.T) (*nonceTestEnv, *ethBlockchainService, *types.Transaction) {
+	env := setupNonceTest(t)
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		Blockchain: config.BlockchainConfig{
+			VaultFactoryAddress: "0x00000000000000000000000000000000000FAC70",
+			PrivateKey:          hexutil.Encode(crypto.FromECDSA(env.privateKey)),
+			NonceStuckTimeout:   time.Minute,
+		},
+		Fees: config.FeeConfig{BumpPercent: 15},
+	}
+	svc, err := newBlockchainService(cfg, env.client, env.client, env.chainID, env.redis)
+	require.NoError(t, err)
+	t.Cleanup(svc.Close)
+
+	head, err := env.client.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	nonce, err := svc.nonces.Next(ctx)
+	require.NoError(t, err)
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	stuckFees := &TxFees{GasTipCap: big.NewInt(1), GasFeeCap: new(big.Int).Mul(head.BaseFee, big.NewInt(2))}
+	stuck, err := types.SignTx(stuckFees.NewTx(env.chainID, nonce, to, big.NewInt(1), 30000, []byte{0x01}), types.LatestSignerForChainID(env.chainID), env.privateKey)
+	require.NoError(t, err)
+	require.NoError(t, env.client.SendTransaction(ctx, stuck))
+	require.NoError(t, svc.nonces.Sent(ctx, stuck))
+
+	return env, svc, stuck
+}
+
+// Test that stuck transactions are replaced under their own nonce
+func TestReplaceTransaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancel    bool
+		wantTo    func(env *nonceTestEnv, stuck *types.Transaction) common.Address
+		wantValue *big.Int
+		wantData  []byte
+	}{
+		{
+			name:      "Bumped copy",
+			wantTo:    func(env *nonceTestEnv, stuck *types.Transaction) common.Address { return *stuck.To() },
+			wantValue: big.NewInt(1),
+			wantData:  []byte{0x01},
+		},
+		{
+			name:      "Cancellation",
+			cancel:    true,
+			wantTo:    func(env *nonceTestEnv, stuck *types.Transaction) common.Address { return env.address },
+			wantValue: big.NewInt(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, svc, stuck := setupReplaceTest(t)
+			ctx := context.Background()
+
+			replacement, err := svc.ReplaceTransaction(ctx, stuck.Nonce(), tt.cancel)
+			require.NoError(t, err)
+			assert.Equal(t, stuck.Nonce(), replacement.Nonce())
+			assert.Equal(t, tt.wantTo(env, stuck), *replacement.To())
+			assert.Equal(t, tt.wantValue, replacement.Value())
+			assert.Equal(t, tt.wantData, replacement.Data())
+			assert.True(t, replacement.GasTipCap().Cmp(stuck.GasTipCap()) > 0)
+
+			// The nonce now points at the replacement, which is the one mined
+			sent, err := svc.nonces.SentTransaction(ctx, stuck.Nonce())
+			require.NoError(t, err)
+			require.NotNil(t, sent)
+			assert.Equal(t, replacement.Hash(), sent.Hash())
+
+			env.backend.Commit()
+			_, err = env.client.TransactionReceipt(ctx, stuck.Hash())
+			assert.Error(t, err)
+			receipt, err := env.client.TransactionReceipt(ctx, replacement.Hash())
+			require.NoError(t, err)
+			assert.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+			assert.Equal(t, stuck.Nonce()+1, env.minedNonce(t))
+		})
+	}
+}
+
+// Test that nonces without a pending transaction are not reused
+func TestReplaceTransactionNonceNotPending(t *testing.T) {
+	env, svc, stuck := setupReplaceTest(t)
+	ctx := context.Background()
+
+	// Never broadcast
+	_, err := svc.ReplaceTransaction(ctx, stuck.Nonce()+1, false)
+	assert.ErrorIs(t, err, ErrNonceNotPending)
+
+	// Already mined, here through a first replacement
+	_, err = svc.ReplaceTransaction(ctx, stuck.Nonce(), false)
+	require.NoError(t, err)
+	env.backend.Commit()
+	_, err = svc.ReplaceTransaction(ctx, stuck.Nonce(), false)
+	assert.ErrorIs(t, err, ErrNonceNotPending)
+	_, err = svc.ReplaceTransaction(ctx, stuck.Nonce(), true)
+	assert.ErrorIs(t, err, ErrNonceNotPending)
+	assert.Equal(t, stuck.Nonce()+1, env.minedNonce(t))
+}
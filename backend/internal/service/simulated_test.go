@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/pkg/bindings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	simHeartbeatInterval = 7 * 24 * time.Hour  // Shortest interval the factory accepts
+	simGracePeriod       = 30 * 24 * time.Hour // Shortest grace period the factory accepts
+)
+
+// contractsOut is where forge build writes the contract artifacts
+var contractsOut = filepath.Join("..", "..", "..", "contracts", "out")
+
+// simulatedChain is a simulated chain with VaultFactory deployed from its
+// build artifact, which in turn deploys the IndividualVault implementation
+// that every vault clones. The service's server signer owns the factory; the
+// owner and heirs sign their own vault calls, as their wallets would.
+type simulatedChain struct {
+	backend *simulated.Backend
+	service *ethBlockchainService
+	owner   *ecdsa.PrivateKey
+	heirs   []*ecdsa.PrivateKey
+}
+
+func setupSimulatedChain(t *testing.T, heirCount int) *simulatedChain {
+	bytecode := factoryBytecode(t)
+
+	signer := newSimulatedKey(t)
+	owner := newSimulatedKey(t)
+	heirs := make([]*ecdsa.PrivateKey, heirCount)
+	for i := range heirs {
+		heirs[i] = newSimulatedKey(t)
+	}
+
+	alloc := types.GenesisAlloc{}
+	for _, key := range append([]*ecdsa.PrivateKey{signer, owner}, heirs...) {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = types.Account{Balance: ether(1000)}
+	}
+	backend := simulated.NewBackend(alloc)
+	t.Cleanup(func() { backend.Close() })
+
+	ctx := context.Background()
+	client := backend.Client()
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+
+	// Deploy the factory without a trusted forwarder
+	opts, err := bind.NewKeyedTransactorWithChainID(signer, chainID)
+	require.NoError(t, err)
+	factoryABI, err := bindings.VaultFactoryMetaData.GetAbi()
+	require.NoError(t, err)
+	factoryAddr, tx, _, err := bind.DeployContract(opts, *factoryABI, bytecode, client, common.Address{})
+	require.NoError(t, err)
+	backend.Commit()
+	_, err = bind.WaitDeployed(ctx, client, tx)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Blockchain: config.BlockchainConfig{
+			ChainID:             chainID.Int64(),
+			VaultFactoryAddress: factoryAddr.Hex(),
+			PrivateKey:          hexutil.Encode(crypto.FromECDSA(signer)),
+		},
+		Fees: config.FeeConfig{GasLimitMultiplier: 1.2},
+	}
+	svc, err := newBlockchainService(cfg, client, client, chainID, nil)
+	require.NoError(t, err)
+	t.Cleanup(svc.Close)
+
+	return &simulatedChain{
+		backend: backend,
+		service: svc,
+		owner:   owner,
+		heirs:   heirs,
+	}
+}
+
+// factoryBytecode returns the VaultFactory creation code, which embeds the
+// IndividualVault implementation, from the forge build artifact or else from
+// bindings generated with --bin
+func factoryBytecode(t *testing.T) []byte {
+	t.Helper()
+
+	raw, err := os.ReadFile(filepath.Join(contractsOut, "VaultFactory.sol", "VaultFactory.json"))
+	if os.IsNotExist(err) && bindings.VaultFactoryMetaData.Bin != "" {
+		return common.FromHex(bindings.VaultFactoryMetaData.Bin)
+	}
+	require.NoError(t, err, "VaultFactory bytecode not found; run forge build in contracts/")
+
+	var artifact struct {
+		Bytecode struct {
+			Object string `json:"object"`
+		} `json:"bytecode"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &artifact))
+	require.NotEmpty(t, artifact.Bytecode.Object, "VaultFactory artifact has no bytecode")
+	return common.FromHex(artifact.Bytecode.Object)
+}
+
+func newSimulatedKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return key
+}
+
+func ether(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+}
+
+func keyAddress(key *ecdsa.PrivateKey) common.Address {
+	return crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// createVault deploys a vault for the owner through the factory, with equal
+// shares for every heir
+func (c *simulatedChain) createVault(t *testing.T, requiredApprovals int64) common.Address {
+	ctx := context.Background()
+	heirs := make([]common.Address, len(c.heirs))
+	shares := make([]*big.Int, len(c.heirs))
+	for i, heir := range c.heirs {
+		heirs[i] = keyAddress(heir)
+		shares[i] = big.NewInt(int64(10000 / len(c.heirs)))
+	}
+
+	txHash, err := c.service.CreateVault(ctx, keyAddress(c.owner), heirs, shares,
+		big.NewInt(int64(simHeartbeatInterval.Seconds())), big.NewInt(int64(simGracePeriod.Seconds())), big.NewInt(requiredApprovals))
+	require.NoError(t, err)
+	c.backend.Commit()
+
+	created, err := c.service.WaitForVaultCreated(ctx, txHash)
+	require.NoError(t, err)
+	return created.Address
+}
+
+// mine seals the pending transactions and returns the receipt of txHash,
+// which must have succeeded
+func (c *simulatedChain) mine(t *testing.T, txHash string) *types.Receipt {
+	c.backend.Commit()
+	receipt, err := c.service.GetTransactionReceipt(context.Background(), txHash)
+	require.NoError(t, err)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	return receipt
+}
+
+// call sends a vault call signed by key through the relay path: the service
+// builds the transaction, the wallet signs it and the raw transaction is
+// decoded and broadcast. It returns the build error if the call would revert.
+func (c *simulatedChain) call(t *testing.T, key *ecdsa.PrivateKey, vault common.Address, method string, args ...interface{}) (*types.Receipt, error) {
+	unsigned, err := c.service.BuildVaultTransaction(context.Background(), keyAddress(key), vault, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.relay(t, key, unsigned), nil
+}
+
+// deposit sends amount wei from key to the vault
+func (c *simulatedChain) deposit(t *testing.T, key *ecdsa.PrivateKey, vault common.Address, amount *big.Int) {
+	unsigned, err := c.service.BuildDepositTransaction(context.Background(), keyAddress(key), vault, amount)
+	require.NoError(t, err)
+	c.relay(t, key, unsigned)
+}
+
+func (c *simulatedChain) relay(t *testing.T, key *ecdsa.PrivateKey, unsigned *UnsignedTx) *types.Receipt {
+	ctx := context.Background()
+	tx, err := types.SignTx(unsignedToTx(t, unsigned), types.LatestSignerForChainID(c.service.ChainID()), key)
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	decoded, sender, err := c.service.DecodeRawTransaction(hexutil.Encode(raw))
+	require.NoError(t, err)
+	require.Equal(t, keyAddress(key), sender)
+	require.NoError(t, c.service.SendTransaction(ctx, decoded))
+
+	return c.mine(t, decoded.Hash().Hex())
+}
+
+func unsignedToTx(t *testing.T, unsigned *UnsignedTx) *types.Transaction {
+	to := common.HexToAddress(unsigned.To)
+	value, ok := new(big.Int).SetString(unsigned.Value, 10)
+	require.True(t, ok)
+	data := common.FromHex(unsigned.Data)
+	chainID := big.NewInt(unsigned.ChainID)
+
+	if unsigned.Type == types.DynamicFeeTxType {
+		feeCap, ok := new(big.Int).SetString(unsigned.MaxFeePerGas, 10)
+		require.True(t, ok)
+		tipCap, ok := new(big.Int).SetString(unsigned.MaxPriorityFeePerGas, 10)
+		require.True(t, ok)
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     unsigned.Nonce,
+			GasTipCap: tipCap,
+			GasFeeCap: feeCap,
+			Gas:       unsigned.Gas,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+
+	gasPrice, ok := new(big.Int).SetString(unsigned.GasPrice, 10)
+	require.True(t, ok)
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    unsigned.Nonce,
+		GasPrice: gasPrice,
+		Gas:      unsigned.Gas,
+		To:       &to,
+		Value:    value,
+		Data:     data,
+	})
+}
+
+// heartbeat commits and reveals a heartbeat as the owner
+func (c *simulatedChain) heartbeat(t *testing.T, vault common.Address, nonce [32]byte) *types.Receipt {
+	_, err := c.call(t, c.owner, vault, "commitHeartbeat", GenerateCommitHash(keyAddress(c.owner), nonce))
+	require.NoError(t, err)
+	receipt, err := c.call(t, c.owner, vault, "revealHeartbeat", nonce)
+	require.NoError(t, err)
+	return receipt
+}
+
+// unlock moves past the heartbeat interval and unlocks the vault as the server signer
+func (c *simulatedChain) unlock(t *testing.T, vault common.Address) {
+	require.NoError(t, c.backend.AdjustTime(simHeartbeatInterval))
+	txHash, err := c.service.CheckAndUnlock(context.Background(), vault)
+	require.NoError(t, err)
+	c.mine(t, txHash)
+}
+
+func (c *simulatedChain) state(t *testing.T, vault common.Address) *VaultState {
+	state, err := c.service.GetVaultState(context.Background(), vault, nil)
+	require.NoError(t, err)
+	return state
+}
+
+func (c *simulatedChain) balance(t *testing.T, account common.Address) *big.Int {
+	balance, err := c.backend.Client().BalanceAt(context.Background(), account, nil)
+	require.NoError(t, err)
+	return balance
+}
+
+// Test that a vault created through the service is read back from the chain
+func TestSimulatedCreateVault(t *testing.T) {
+	chain := setupSimulatedChain(t, 2)
+	ctx := context.Background()
+	vault := chain.createVault(t, 2)
+
+	isFactoryVault, err := chain.service.IsFactoryVault(ctx, keyAddress(chain.owner), vault)
+	require.NoError(t, err)
+	assert.True(t, isFactoryVault)
+
+	created, err := chain.service.FindVaultCreated(ctx, keyAddress(chain.owner), vault, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, created.VaultID)
+
+	_, err = chain.service.FindVaultCreated(ctx, keyAddress(chain.heirs[0]), vault, 0)
+	assert.ErrorIs(t, err, ErrVaultNotCreated)
+
+	total, err := chain.service.GetTotalVaults(ctx, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total.Int64())
+
+	vaultConfig, err := chain.service.GetVaultConfig(ctx, vault)
+	require.NoError(t, err)
+	assert.Equal(t, keyAddress(chain.owner), vaultConfig.Owner)
+	assert.Equal(t, []common.Address{keyAddress(chain.heirs[0]), keyAddress(chain.heirs[1])}, vaultConfig.Heirs)
+	assert.EqualValues(t, 5000, vaultConfig.HeirShares[0].Int64())
+	assert.EqualValues(t, simHeartbeatInterval.Seconds(), vaultConfig.HeartbeatInterval.Int64())
+	assert.EqualValues(t, 2, vaultConfig.RequiredApprovals.Int64())
+	assert.True(t, vaultConfig.IsLocked)
+}
+
+// Test that only the owner's revealed commitment counts as a heartbeat
+func TestSimulatedHeartbeat(t *testing.T) {
+	chain := setupSimulatedChain(t, 1)
+	ctx := context.Background()
+	vault := chain.createVault(t, 1)
+	nonce := [32]byte{1, 2, 3}
+
+	before, err := chain.service.GetLastHeartbeat(ctx, vault)
+	require.NoError(t, err)
+
+	_, err = chain.call(t, chain.heirs[0], vault, "commitHeartbeat", GenerateCommitHash(keyAddress(chain.heirs[0]), nonce))
+	assert.ErrorContains(t, err, "IndividualVault: not owner")
+
+	_, err = chain.call(t, chain.owner, vault, "commitHeartbeat", GenerateCommitHash(keyAddress(chain.owner), nonce))
+	require.NoError(t, err)
+
+	_, err = chain.call(t, chain.owner, vault, "revealHeartbeat", [32]byte{9})
+	assert.ErrorContains(t, err, "IndividualVault: invalid commitment")
+
+	require.NoError(t, chain.backend.AdjustTime(24*time.Hour))
+	receipt, err := chain.call(t, chain.owner, vault, "revealHeartbeat", nonce)
+	require.NoError(t, err)
+
+	header, err := chain.service.HeaderByNumber(ctx, receipt.BlockNumber)
+	require.NoError(t, err)
+	after, err := chain.service.GetLastHeartbeat(ctx, vault)
+	require.NoError(t, err)
+	assert.EqualValues(t, header.Time, after.Uint64())
+	assert.GreaterOrEqual(t, after.Uint64()-before.Uint64(), uint64((24 * time.Hour).Seconds()))
+
+	// A commitment can only be used once
+	_, err = chain.call(t, chain.owner, vault, "commitHeartbeat", GenerateCommitHash(keyAddress(chain.owner), nonce))
+	assert.ErrorContains(t, err, "IndividualVault: commitment already used")
+}
+
+// Test the full inheritance flow: unlock, approvals, grace period and claims
+func TestSimulatedInheritance(t *testing.T) {
+	chain := setupSimulatedChain(t, 2)
+	ctx := context.Background()
+	vault := chain.createVault(t, 2)
+	chain.deposit(t, chain.owner, vault, ether(10))
+
+	_, err := chain.call(t, chain.heirs[0], vault, "approveInheritance")
+	assert.ErrorContains(t, err, "IndividualVault: vault locked")
+
+	_, err = chain.service.CheckAndUnlock(ctx, vault)
+	assert.ErrorContains(t, err, "IndividualVault: heartbeat not expired")
+
+	chain.unlock(t, vault)
+	state := chain.state(t, vault)
+	assert.False(t, state.Config.IsLocked)
+	assert.True(t, state.Config.GracePeriodActive)
+
+	for _, heir := range chain.heirs {
+		_, err := chain.call(t, heir, vault, "approveInheritance")
+		require.NoError(t, err)
+	}
+	approved, err := chain.service.GetHeirApprovalStatus(ctx, vault, keyAddress(chain.heirs[1]))
+	require.NoError(t, err)
+	assert.True(t, approved)
+
+	_, err = chain.call(t, chain.heirs[0], vault, "claimInheritance")
+	assert.ErrorContains(t, err, "IndividualVault: grace period not ended")
+
+	require.NoError(t, chain.backend.AdjustTime(simGracePeriod))
+	for _, heir := range chain.heirs {
+		before := chain.balance(t, keyAddress(heir))
+		receipt, err := chain.call(t, heir, vault, "claimInheritance")
+		require.NoError(t, err)
+
+		gasCost := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+		received := new(big.Int).Sub(chain.balance(t, keyAddress(heir)), before)
+		assert.Equal(t, ether(5), received.Add(received, gasCost))
+	}
+
+	state = chain.state(t, vault)
+	assert.Zero(t, state.Balance.Sign())
+	assert.True(t, state.HeirClaimed[keyAddress(chain.heirs[0])])
+	assert.True(t, state.HeirClaimed[keyAddress(chain.heirs[1])])
+
+	_, err = chain.call(t, chain.heirs[0], vault, "claimInheritance")
+	assert.ErrorContains(t, err, "IndividualVault: already claimed")
+}
+
+// Test that the owner revealing a heartbeat during the grace period locks the
+// vault again and resets the heirs' approvals
+func TestSimulatedHeartbeatCancelsUnlock(t *testing.T) {
+	chain := setupSimulatedChain(t, 2)
+	vault := chain.createVault(t, 1)
+
+	chain.unlock(t, vault)
+	_, err := chain.call(t, chain.heirs[0], vault, "approveInheritance")
+	require.NoError(t, err)
+
+	chain.heartbeat(t, vault, [32]byte{7})
+
+	state := chain.state(t, vault)
+	assert.True(t, state.Config.IsLocked)
+	assert.False(t, state.Config.GracePeriodActive)
+	assert.Zero(t, state.Config.ApprovalCount.Sign())
+	assert.False(t, state.HeirApproved[keyAddress(chain.heirs[0])])
+
+	// The grace period is over, but the vault is locked again
+	require.NoError(t, chain.backend.AdjustTime(simGracePeriod))
+	_, err = chain.call(t, chain.heirs[0], vault, "claimInheritance")
+	assert.ErrorContains(t, err, "IndividualVault: vault locked")
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	deniedTokenPrefix   = "jwt:denied:"
+	deniedSessionPrefix = "jwt:denied_session:"
+)
+
+// DenyToken rejects one access token (by jti) until it expires
+func DenyToken(ctx context.Context, redisClient *redis.Client, claims *Claims) error {
+	if claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := redisClient.Set(ctx, deniedTokenPrefix+claims.ID, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to deny token: %w", err)
+	}
+	return nil
+}
+
+// DenySession rejects every access token issued for a session. Once the
+// session is revoked no new ones are issued, so the entry only has to outlive
+// the access token lifetime.
+func DenySession(ctx context.Context, redisClient *redis.Client, sessionID string, accessTokenTTL time.Duration) error {
+	if err := redisClient.Set(ctx, deniedSessionPrefix+sessionID, 1, accessTokenTTL).Err(); err != nil {
+		return fmt.Errorf("failed to deny session: %w", err)
+	}
+	return nil
+}
+
+// IsDenied reports whether the token or its session was revoked
+func IsDenied(ctx context.Context, redisClient *redis.Client, claims *Claims) (bool, error) {
+	n, err := redisClient.Exists(ctx, deniedTokenPrefix+claims.ID, deniedSessionPrefix+claims.SessionID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check denylist: %w", err)
+	}
+	return n > 0, nil
+}
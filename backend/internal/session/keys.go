@@ -0,0 +1,260 @@
+package session
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/haneumLee/legacychain/backend/config"
+)
+
+// signingMethods are the only algorithms tokens are issued or accepted with
+var signingMethods = []string{jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+// key is one key pair, or just the public half of a retired signing key
+type key struct {
+	id      string // kid: the RFC 7638 thumbprint of the public key
+	method  jwt.SigningMethod
+	private crypto.Signer // Nil for verification-only keys
+	public  crypto.PublicKey
+}
+
+// KeySet holds the key access tokens are signed with and every key they are
+// accepted with. Rotating keys:
+//
+//  1. Add the new key to JWT_VERIFICATION_KEY_FILES everywhere, so verifiers
+//     and JWKS consumers know it before any token uses it.
+//  2. Make it JWT_SIGNING_KEY_FILE and move the old key to the verification
+//     files.
+//  3. Remove the old key once the tokens it signed have expired
+//     (JWT_EXPIRES_IN).
+type KeySet struct {
+	signing *key
+	keys    map[string]*key
+}
+
+// LoadKeySet reads the signing and verification keys. Without a signing key
+// file, development servers get a temporary key; other environments fail.
+func LoadKeySet(cfg *config.Config) (*KeySet, error) {
+	var signing *key
+	var err error
+	if cfg.JWT.SigningKeyFile != "" {
+		signing, err = loadKeyFile(cfg.JWT.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if signing.private == nil {
+			return nil, fmt.Errorf("JWT signing key %s is not a private key", cfg.JWT.SigningKeyFile)
+		}
+	} else {
+		if cfg.Server.Env != "development" {
+			return nil, errors.New("JWT_SIGNING_KEY_FILE is required outside development")
+		}
+		log.Println("⚠️  JWT_SIGNING_KEY_FILE is not set, signing tokens with a temporary key. Tokens will not survive a restart or work across replicas.")
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate temporary signing key: %w", err)
+		}
+		if signing, err = newKey(private); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := &KeySet{
+		signing: signing,
+		keys:    map[string]*key{signing.id: signing},
+	}
+	for _, path := range cfg.JWT.VerificationKeyFiles {
+		k, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keys.keys[k.id]; !ok {
+			keys.keys[k.id] = k
+		}
+	}
+
+	return keys, nil
+}
+
+// SigningKeyID returns the kid of the key new tokens are signed with
+func (k *KeySet) SigningKeyID() string {
+	return k.signing.id
+}
+
+// sign signs claims with the signing key and names it in the kid header
+func (k *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signing.private)
+}
+
+// keyfunc picks the verification key by kid, and only for the algorithm that
+// key is for, so a token cannot switch algorithms to have a key misused
+func (k *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	verifying, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != verifying.method.Alg() {
+		return nil, fmt.Errorf("key %q is not for %s", kid, token.Method.Alg())
+	}
+	return verifying.public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys tokens are accepted with, signing key first
+func (k *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{jwkOf(k.signing)}}
+
+	var others []*key
+	for id, other := range k.keys {
+		if id != k.signing.id {
+			others = append(others, other)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].id < others[j].id })
+	for _, other := range others {
+		set.Keys = append(set.Keys, jwkOf(other))
+	}
+
+	return set
+}
+
+// loadKeyFile reads a PEM private key (PKCS #8 or SEC 1) or public key (PKIX)
+func loadKeyFile(path string) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %s is not PEM encoded", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("JWT key %s has unsupported PEM type %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", path, err)
+	}
+
+	k, err := newKey(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("JWT key %s: %w", path, err)
+	}
+	return k, nil
+}
+
+// newKey wraps a P-256 or Ed25519 private or public key
+func newKey(parsed interface{}) (*key, error) {
+	k := &key{}
+
+	switch typed := parsed.(type) {
+	case *ecdsa.PrivateKey:
+		k.private, k.public = typed, &typed.PublicKey
+	case ed25519.PrivateKey:
+		k.private, k.public = typed, typed.Public()
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		k.public = typed
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use ECDSA P-256 or Ed25519", parsed)
+	}
+
+	switch public := k.public.(type) {
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, errors.New("ECDSA keys must use the P-256 curve")
+		}
+		k.method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		k.method = jwt.SigningMethodEdDSA
+	}
+
+	k.id = thumbprint(k)
+	return k, nil
+}
+
+// jwkOf returns the public half of a key as a JWK
+func jwkOf(k *key) JWK {
+	jwk := JWK{
+		KeyID:     k.id,
+		Algorithm: k.method.Alg(),
+		Use:       "sig",
+	}
+
+	switch public := k.public.(type) {
+	case *ecdsa.PublicKey:
+		jwk.KeyType, jwk.Curve = "EC", "P-256"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, 32)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint: the SHA-256 of the required
+// members in lexicographic order, without whitespace
+func thumbprint(k *key) string {
+	jwk := jwkOf(k)
+
+	var members []byte
+	if jwk.KeyType == "EC" {
+		members, _ = json.Marshal(struct {
+			Curve   string `json:"crv"`
+			KeyType string `json:"kty"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y})
+	} else {
+		members, _ = json.Marshal(struct {
+			Curve   string `json:"crv"`
+			KeyType string `json:"kty"`
+			X       string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X})
+	}
+
+	sum := sha256.Sum256(members)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
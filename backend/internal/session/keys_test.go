@@ -0,0 +1,278 @@
+package session
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return private
+}
+
+func newP384Key(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	return private
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return private
+}
+
+// writePEM writes a PEM block to a file in the test's temporary directory
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// writeKey writes a private key as PKCS #8, like openssl genpkey
+func writeKey(t *testing.T, private crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+// writePublicKey writes the public half of a key as PKIX, like openssl pkey -pubout
+func writePublicKey(t *testing.T, private crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(private.Public())
+	require.NoError(t, err)
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+func testKeySet(t *testing.T, signingKeyFile string, verificationKeyFiles ...string) *KeySet {
+	t.Helper()
+	keys, err := LoadKeySet(&config.Config{
+		Server: config.ServerConfig{Env: "production"},
+		JWT: config.JWTConfig{
+			SigningKeyFile:       signingKeyFile,
+			VerificationKeyFiles: verificationKeyFiles,
+		},
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+// signWith signs claims outside of a KeySet, as an attacker or another issuer would
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	require.NoError(t, err)
+	return tokenString
+}
+
+func validClaims() *Claims {
+	now := time.Now()
+	return &Claims{
+		Address:   testAddress,
+		SessionID: uuid.New().String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(testTTL)),
+		},
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	ec := newECKey(t)
+	sec1, err := x509.MarshalECPrivateKey(ec)
+	require.NoError(t, err)
+
+	p384 := newP384Key(t)
+	notPEM := filepath.Join(t.TempDir(), "key.der")
+	require.NoError(t, os.WriteFile(notPEM, sec1, 0o600))
+
+	t.Run("formats", func(t *testing.T) {
+		for name, path := range map[string]string{
+			"PKCS #8 P-256":   writeKey(t, ec),
+			"SEC 1 P-256":     writePEM(t, "EC PRIVATE KEY", sec1),
+			"PKCS #8 Ed25519": writeKey(t, newEd25519Key(t)),
+		} {
+			t.Run(name, func(t *testing.T) {
+				keys := testKeySet(t, path)
+				assert.Len(t, keys.JWKS().Keys, 1)
+			})
+		}
+	})
+
+	t.Run("same key in both formats has one kid", func(t *testing.T) {
+		assert.Equal(t,
+			testKeySet(t, writeKey(t, ec)).SigningKeyID(),
+			testKeySet(t, writePEM(t, "EC PRIVATE KEY", sec1)).SigningKeyID())
+	})
+
+	for name, path := range map[string]string{
+		"public signing key": writePublicKey(t, ec),
+		"P-384 key":          writeKey(t, p384),
+		"unsupported PEM":    writePEM(t, "RSA PRIVATE KEY", []byte("nonsense")),
+		"not PEM":            notPEM,
+		"missing file":       filepath.Join(t.TempDir(), "missing.pem"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadKeySet(&config.Config{
+				Server: config.ServerConfig{Env: "production"},
+				JWT:    config.JWTConfig{SigningKeyFile: path},
+			})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("temporary key in development only", func(t *testing.T) {
+		keys, err := LoadKeySet(&config.Config{Server: config.ServerConfig{Env: "development"}})
+		require.NoError(t, err)
+		assert.NotEmpty(t, keys.SigningKeyID())
+
+		_, err = LoadKeySet(&config.Config{Server: config.ServerConfig{Env: "production"}})
+		assert.Error(t, err)
+	})
+}
+
+func TestEdDSAAccessToken(t *testing.T) {
+	keys := testKeySet(t, writeKey(t, newEd25519Key(t)))
+
+	tokenString, _, err := SignAccessToken(keys, testTTL, testAddress, nil, uuid.New(), time.Now())
+	require.NoError(t, err)
+
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+	assert.Equal(t, keys.SigningKeyID(), token.Header["kid"])
+
+	claims, err := ParseAccessToken(keys, tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, testAddress, claims.Address)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := newECKey(t), newEd25519Key(t)
+
+	before := testKeySet(t, writeKey(t, oldKey))
+	oldToken, _, err := SignAccessToken(before, testTTL, testAddress, nil, uuid.New(), time.Now())
+	require.NoError(t, err)
+
+	// The new key signs; tokens of the old key stay valid until removed
+	during := testKeySet(t, writeKey(t, newKey), writePublicKey(t, oldKey))
+	assert.NotEqual(t, before.SigningKeyID(), during.SigningKeyID())
+
+	newToken, _, err := SignAccessToken(during, testTTL, testAddress, nil, uuid.New(), time.Now())
+	require.NoError(t, err)
+	_, err = ParseAccessToken(during, oldToken)
+	assert.NoError(t, err)
+	_, err = ParseAccessToken(during, newToken)
+	assert.NoError(t, err)
+
+	// Servers still on the old key accept new tokens once the new key is
+	// among their verification keys
+	_, err = ParseAccessToken(before, newToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = ParseAccessToken(testKeySet(t, writeKey(t, oldKey), writePublicKey(t, newKey)), newToken)
+	assert.NoError(t, err)
+
+	after := testKeySet(t, writeKey(t, newKey))
+	_, err = ParseAccessToken(after, oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestParseAccessTokenRejectsAlgorithms(t *testing.T) {
+	ec, ed := newECKey(t), newEd25519Key(t)
+	keys := testKeySet(t, writeKey(t, ec), writePublicKey(t, ed))
+	ecKid := keys.SigningKeyID()
+	edKid := keys.JWKS().Keys[1].KeyID
+
+	publicDER, err := x509.MarshalPKIXPublicKey(ec.Public())
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	for name, tokenString := range map[string]string{
+		"alg none": signWith(t, jwt.SigningMethodNone, ecKid, jwt.UnsafeAllowNoneSignatureType, validClaims()),
+		// The published public key used as an HMAC secret
+		"HS256 with public key": signWith(t, jwt.SigningMethodHS256, ecKid, publicPEM, validClaims()),
+		"HS256 with public DER": signWith(t, jwt.SigningMethodHS256, ecKid, publicDER, validClaims()),
+		"missing kid":           signWith(t, jwt.SigningMethodES256, "", ec, validClaims()),
+		"unknown kid":           signWith(t, jwt.SigningMethodES256, "unknown", ec, validClaims()),
+		"kid of other alg key":  signWith(t, jwt.SigningMethodES256, edKid, ec, validClaims()),
+		"ES384":                 signWith(t, jwt.SigningMethodES384, ecKid, newP384Key(t), validClaims()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseAccessToken(keys, tokenString)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	// The same claims are accepted when properly signed
+	_, err = ParseAccessToken(keys, signWith(t, jwt.SigningMethodES256, ecKid, ec, validClaims()))
+	assert.NoError(t, err)
+	_, err = ParseAccessToken(keys, signWith(t, jwt.SigningMethodEdDSA, edKid, ed, validClaims()))
+	assert.NoError(t, err)
+}
+
+func TestThumbprint(t *testing.T) {
+	// RFC 8037, appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+
+	k, err := newKey(ed25519.PublicKey(x))
+	require.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", k.id)
+}
+
+func TestJWKS(t *testing.T) {
+	ec, ed := newECKey(t), newEd25519Key(t)
+	keys := testKeySet(t, writeKey(t, ec), writePublicKey(t, ed), writePublicKey(t, ec))
+
+	set := keys.JWKS()
+	require.Len(t, set.Keys, 2, "the signing key is listed once")
+
+	signing := set.Keys[0]
+	assert.Equal(t, keys.SigningKeyID(), signing.KeyID)
+	assert.Equal(t, "EC", signing.KeyType)
+	assert.Equal(t, "P-256", signing.Curve)
+	assert.Equal(t, "ES256", signing.Algorithm)
+	assert.Equal(t, "sig", signing.Use)
+	assert.Len(t, signing.X, 43)
+	assert.Len(t, signing.Y, 43)
+
+	verification := set.Keys[1]
+	assert.Equal(t, "OKP", verification.KeyType)
+	assert.Equal(t, "Ed25519", verification.Curve)
+	assert.Equal(t, "EdDSA", verification.Algorithm)
+	assert.Empty(t, verification.Y)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(ed.Public().(ed25519.PublicKey)), verification.X)
+
+	// No private key material is published
+	raw, err := json.Marshal(set)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), `"d"`)
+}
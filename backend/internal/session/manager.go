@@ -0,0 +1,242 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	// ErrRefreshTokenReused is returned when a refresh token that was already
+	// rotated is presented again. Either the client or an attacker holds a
+	// copy, so the session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused, session revoked")
+)
+
+// Tokens are the credentials issued at login and on every refresh
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration // Of the access token
+	SessionID    uuid.UUID
+}
+
+// Manager creates, refreshes and revokes sessions. Sessions live in Postgres;
+// revoked access tokens are denied through Redis until they expire.
+type Manager struct {
+	db     *gorm.DB
+	redis  *redis.Client
+	keys   *KeySet
+	cfg    config.JWTConfig
+	admins config.AdminConfig
+}
+
+// NewManager creates a new Manager
+func NewManager(db *gorm.DB, redisClient *redis.Client, keys *KeySet, cfg *config.Config) *Manager {
+	return &Manager{
+		db:     db,
+		redis:  redisClient,
+		keys:   keys,
+		cfg:    cfg.JWT,
+		admins: cfg.Admin,
+	}
+}
+
+// Start creates a session for a user who just logged in
+func (m *Manager) Start(ctx context.Context, user *models.User) (*Tokens, error) {
+	now := time.Now()
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: hash,
+		ExpiresAt:        now.Add(m.cfg.RefreshExpiresIn),
+	}
+	err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Drop the user's sessions that can no longer be refreshed
+		if err := tx.Where("user_id = ? AND expires_at < ?", user.ID, now).
+			Delete(&models.Session{}).Error; err != nil {
+			return fmt.Errorf("failed to delete expired sessions: %w", err)
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.tokens(user, session.ID, refreshToken, now)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. The presented token stops working.
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	now := time.Now()
+	hash := hashToken(refreshToken)
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.Session
+	var user models.User
+	var reused bool
+	err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ?", hash).
+			First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// A rotated token: revoke the session it belonged to
+			result := tx.Model(&models.Session{}).
+				Where("previous_token_hash = ? AND revoked_at IS NULL", hash).
+				Update("revoked_at", now)
+			if result.Error != nil {
+				return fmt.Errorf("failed to revoke session: %w", result.Error)
+			}
+			if result.RowsAffected > 0 {
+				reused = true
+				return tx.Where("previous_token_hash = ?", hash).First(&session).Error
+			}
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query session: %w", err)
+		}
+		if session.RevokedAt != nil || !now.Before(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, session.UserID).Error; err != nil {
+			return fmt.Errorf("failed to query user: %w", err)
+		}
+
+		return tx.Model(&session).Updates(map[string]interface{}{
+			"refresh_token_hash":  newHash,
+			"previous_token_hash": hash,
+			"expires_at":          now.Add(m.cfg.RefreshExpiresIn),
+			"last_refreshed_at":   now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		log.Printf("Refresh token reuse detected, revoked session %s", session.ID)
+		if err := DenySession(ctx, m.redis, session.ID.String(), m.cfg.ExpiresIn); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return m.tokens(&user, session.ID, newToken, now)
+}
+
+// Revoke ends the session of an access token (logout). The token and any
+// other access token of the session are rejected from now on.
+func (m *Manager) Revoke(ctx context.Context, claims *Claims) error {
+	if err := m.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", claims.SessionID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if err := DenyToken(ctx, m.redis, claims); err != nil {
+		return err
+	}
+	return DenySession(ctx, m.redis, claims.SessionID, m.cfg.ExpiresIn)
+}
+
+// RevokeAll ends every session of a user and returns how many were active
+func (m *Manager) RevokeAll(ctx context.Context, userID uuid.UUID) (int, error) {
+	var sessionIDs []uuid.UUID
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Pluck("id", &sessionIDs).Error; err != nil {
+			return fmt.Errorf("failed to query sessions: %w", err)
+		}
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("id IN ?", sessionIDs).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range sessionIDs {
+		if err := DenySession(ctx, m.redis, id.String(), m.cfg.ExpiresIn); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessionIDs), nil
+}
+
+// tokens signs an access token to go with a refresh token. Role changes
+// reach a session's access tokens on its next refresh.
+func (m *Manager) tokens(user *models.User, sessionID uuid.UUID, refreshToken string, now time.Time) (*Tokens, error) {
+	accessToken, _, err := SignAccessToken(m.keys, m.cfg.ExpiresIn, user.Address, m.roles(user), sessionID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    m.cfg.ExpiresIn,
+		SessionID:    sessionID,
+	}, nil
+}
+
+// roles returns a user's roles. ADMIN_ADDRESSES are always admins, so there
+// is someone to grant the first roles.
+func (m *Manager) roles(user *models.User) []string {
+	roles := append([]string(nil), user.Roles...)
+	if m.admins.IsAdmin(user.Address) && !user.Roles.Has(models.RoleAdmin) {
+		roles = append(roles, models.RoleAdmin)
+	}
+	return roles
+}
+
+// newRefreshToken returns a random refresh token and the hash stored for it
+func newRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken is the SHA-256 of a refresh token. Refresh tokens are random, so a
+// fast unsalted hash is enough to make a database leak useless.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
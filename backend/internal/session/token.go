@@ -0,0 +1,77 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned for access tokens that are malformed, wrongly
+// signed, expired, or issued before sessions existed
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims are the claims of an access token. ID (jti) identifies the token and
+// SessionID (sid) the login it was issued for, so either can be revoked.
+// Roles are the user's roles when the token was issued.
+type Claims struct {
+	Address   string   `json:"address"`
+	SessionID string   `json:"sid"`
+	Roles     []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the claims include any of roles
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, have := range c.Roles {
+		for _, role := range roles {
+			if have == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SignAccessToken issues an access token for a session, valid for ttl
+func SignAccessToken(keys *KeySet, ttl time.Duration, address string, roles []string, sessionID uuid.UUID, now time.Time) (string, *Claims, error) {
+	claims := &Claims{
+		Address:   address,
+		SessionID: sessionID.String(),
+		Roles:     roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	tokenString, err := keys.sign(claims)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return tokenString, claims, nil
+}
+
+// ParseAccessToken verifies an access token's signature and expiry. Only
+// ES256 and EdDSA tokens whose kid names one of keys are accepted. It does not
+// check whether the token was revoked; see IsDenied.
+func ParseAccessToken(keys *KeySet, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyfunc,
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// Tokens without jti and sid cannot be revoked, so they are not accepted
+	if claims.ID == "" || claims.SessionID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
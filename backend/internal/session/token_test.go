@@ -0,0 +1,159 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAddress = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+
+const testTTL = 15 * time.Minute
+
+func TestAccessTokenRoundTrip(t *testing.T) {
+	keys := testKeySet(t, writeKey(t, newECKey(t)))
+	sessionID := uuid.New()
+
+	tokenString, issued, err := SignAccessToken(keys, testTTL, testAddress, []string{models.RoleAuditor}, sessionID, time.Now())
+	require.NoError(t, err)
+
+	claims, err := ParseAccessToken(keys, tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, testAddress, claims.Address)
+	assert.Equal(t, sessionID.String(), claims.SessionID)
+	assert.Equal(t, []string{models.RoleAuditor}, claims.Roles)
+	assert.Equal(t, issued.ID, claims.ID)
+	assert.NotEmpty(t, claims.ID)
+
+	// Every token gets its own jti
+	_, other, err := SignAccessToken(keys, testTTL, testAddress, nil, sessionID, time.Now())
+	require.NoError(t, err)
+	assert.NotEqual(t, issued.ID, other.ID)
+}
+
+func TestRoles(t *testing.T) {
+	m := &Manager{admins: config.AdminConfig{Addresses: []string{testAddress}}}
+
+	admin := &models.User{Address: strings.ToLower(testAddress)}
+	assert.Equal(t, []string{models.RoleAdmin}, m.roles(admin))
+
+	admin.Roles = models.Roles{models.RoleAdmin, models.RoleAuditor}
+	assert.Equal(t, []string{models.RoleAdmin, models.RoleAuditor}, m.roles(admin))
+
+	user := &models.User{Address: "0x0000000000000000000000000000000000000001", Roles: models.Roles{models.RoleSupport}}
+	claims := &Claims{Roles: m.roles(user)}
+	assert.True(t, claims.HasRole(models.RoleAdmin, models.RoleSupport))
+	assert.False(t, claims.HasRole(models.RoleAdmin))
+	assert.False(t, (&Claims{}).HasRole(models.RoleAuditor))
+}
+
+func TestParseAccessTokenRejects(t *testing.T) {
+	private := newECKey(t)
+	keys := testKeySet(t, writeKey(t, private))
+
+	expired, _, err := SignAccessToken(keys, testTTL, testAddress, nil, uuid.New(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	forged, _, err := SignAccessToken(testKeySet(t, writeKey(t, newECKey(t))), testTTL, testAddress, nil, uuid.New(), time.Now())
+	require.NoError(t, err)
+
+	// Issued by Login before sessions: no jti or sid
+	legacy := signWith(t, jwt.SigningMethodES256, keys.SigningKeyID(), private, jwt.MapClaims{
+		"address": testAddress,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+
+	for name, tokenString := range map[string]string{
+		"expired":     expired,
+		"unknown key": forged,
+		"without jti": legacy,
+		"not a token": "not-a-token",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseAccessToken(keys, tokenString)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestDenylist(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	ctx := context.Background()
+	keys := testKeySet(t, writeKey(t, newECKey(t)))
+
+	sessionID := uuid.New()
+	_, first, err := SignAccessToken(keys, testTTL, testAddress, nil, sessionID, time.Now())
+	require.NoError(t, err)
+	_, second, err := SignAccessToken(keys, testTTL, testAddress, nil, sessionID, time.Now())
+	require.NoError(t, err)
+	_, otherSession, err := SignAccessToken(keys, testTTL, testAddress, nil, uuid.New(), time.Now())
+	require.NoError(t, err)
+
+	denied, err := IsDenied(ctx, client, first)
+	require.NoError(t, err)
+	assert.False(t, denied)
+
+	// Denying a token leaves the other tokens of its session alone
+	require.NoError(t, DenyToken(ctx, client, first))
+	denied, err = IsDenied(ctx, client, first)
+	require.NoError(t, err)
+	assert.True(t, denied)
+	denied, err = IsDenied(ctx, client, second)
+	require.NoError(t, err)
+	assert.False(t, denied)
+
+	// Denying a session covers all of its tokens, and only until they expire
+	require.NoError(t, DenySession(ctx, client, sessionID.String(), testTTL))
+	denied, err = IsDenied(ctx, client, second)
+	require.NoError(t, err)
+	assert.True(t, denied)
+	denied, err = IsDenied(ctx, client, otherSession)
+	require.NoError(t, err)
+	assert.False(t, denied)
+	assert.Equal(t, testTTL, mr.TTL(deniedSessionPrefix+sessionID.String()))
+
+	ttl := mr.TTL(deniedTokenPrefix + first.ID)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, testTTL)
+}
+
+func TestDenyExpiredTokenIsNoop(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	_, claims, err := SignAccessToken(testKeySet(t, writeKey(t, newECKey(t))), testTTL, testAddress, nil, uuid.New(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+
+	require.NoError(t, DenyToken(context.Background(), client, claims))
+	assert.Empty(t, mr.Keys())
+}
+
+func TestRefreshTokenHash(t *testing.T) {
+	token, hash, err := newRefreshToken()
+	require.NoError(t, err)
+	other, otherHash, err := newRefreshToken()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, token, other)
+	assert.NotEqual(t, hash, otherHash)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, hashToken(token))
+	assert.NotContains(t, hash, token)
+}
@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// subscriptionBuffer is how many updates a slow stream may fall behind
+// before it is closed
+const subscriptionBuffer = 64
+
+// Hub receives updates from Redis and fans them out to the streams of this
+// API instance, each subscribed to a set of vaults.
+type Hub struct {
+	redis *redis.Client
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewHub creates a new Hub
+func NewHub(redisClient *redis.Client) *Hub {
+	return &Hub{
+		redis:         redisClient,
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Run relays updates to subscriptions until ctx is cancelled. The Redis
+// client resubscribes after connection errors; updates published meanwhile
+// are lost.
+func (h *Hub) Run(ctx context.Context) {
+	pubsub := h.redis.Subscribe(ctx, Channel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+
+			var update Update
+			if err := json.Unmarshal([]byte(msg.Payload), &update); err != nil {
+				log.Printf("Ignoring malformed vault update: %v", err)
+				continue
+			}
+			h.dispatch(update)
+		}
+	}
+}
+
+// dispatch hands an update to every subscription for its vault. A
+// subscription whose buffer is full is closed as lagged rather than blocking
+// the others.
+func (h *Hub) dispatch(update Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscriptions {
+		if _, ok := sub.vaults[update.VaultID]; !ok {
+			continue
+		}
+		select {
+		case sub.updates <- update:
+		default:
+			sub.lagged = true
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts receiving the updates of the given vaults
+func (h *Hub) Subscribe(vaultIDs []uuid.UUID) *Subscription {
+	sub := &Subscription{
+		hub:     h,
+		vaults:  make(map[uuid.UUID]struct{}, len(vaultIDs)),
+		updates: make(chan Update, subscriptionBuffer),
+	}
+	for _, id := range vaultIDs {
+		sub.vaults[id] = struct{}{}
+	}
+
+	h.mu.Lock()
+	h.subscriptions[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// remove drops a subscription and closes its channel. h.mu must be held.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscriptions[sub]; ok {
+		delete(h.subscriptions, sub)
+		close(sub.updates)
+	}
+}
+
+// Subscription is one stream's view of the hub
+type Subscription struct {
+	hub     *Hub
+	vaults  map[uuid.UUID]struct{}
+	updates chan Update
+	lagged  bool // Guarded by hub.mu
+}
+
+// Updates returns the subscribed vaults' updates. The channel is closed by
+// Close, or when the subscription lags.
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Lagged reports whether updates were dropped because the stream fell behind
+func (s *Subscription) Lagged() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.lagged
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+// startHub runs a hub and waits until it is subscribed to Channel
+func startHub(t *testing.T, mr *miniredis.Miniredis, client *redis.Client) *Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	hub := NewHub(client)
+	go hub.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(Channel)[Channel] == 1
+	}, time.Second, 10*time.Millisecond)
+	return hub
+}
+
+func receive(t *testing.T, sub *Subscription) Update {
+	select {
+	case update, ok := <-sub.Updates():
+		require.True(t, ok, "subscription closed")
+		return update
+	case <-time.After(time.Second):
+		t.Fatal("no update received")
+		return Update{}
+	}
+}
+
+func TestHubDeliversSubscribedVaults(t *testing.T) {
+	mr, client := setupRedis(t)
+	hub := startHub(t, mr, client)
+
+	vaultA, vaultB := uuid.New(), uuid.New()
+	subA := hub.Subscribe([]uuid.UUID{vaultA})
+	defer subA.Close()
+	subBoth := hub.Subscribe([]uuid.UUID{vaultA, vaultB})
+	defer subBoth.Close()
+
+	publisher := NewRedisPublisher(client)
+	require.NoError(t, publisher.Publish(context.Background(),
+		NewUpdate("vault.deposited", vaultB, "0xb", map[string]interface{}{"amount": "1"}),
+		NewUpdate("heartbeat.revealed", vaultA, "0xa", nil),
+	))
+
+	update := receive(t, subBoth)
+	assert.Equal(t, "vault.deposited", update.Type)
+	assert.Equal(t, vaultB, update.VaultID)
+	assert.JSONEq(t, `{"amount":"1"}`, string(update.Data))
+	assert.Equal(t, "heartbeat.revealed", receive(t, subBoth).Type)
+
+	update = receive(t, subA)
+	assert.Equal(t, "heartbeat.revealed", update.Type)
+	assert.Equal(t, "0xa", update.TxHash)
+	select {
+	case update := <-subA.Updates():
+		t.Fatalf("unexpected update for vault %s", update.VaultID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHubClosesLaggingSubscription(t *testing.T) {
+	hub := NewHub(nil)
+	vaultID := uuid.New()
+	slow := hub.Subscribe([]uuid.UUID{vaultID})
+	other := hub.Subscribe([]uuid.UUID{vaultID})
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.dispatch(NewUpdate("vault.deposited", vaultID, "", nil))
+		if i < subscriptionBuffer {
+			<-other.Updates()
+		}
+	}
+
+	assert.True(t, slow.Lagged())
+	assert.False(t, other.Lagged())
+	for range slow.Updates() {
+		// Buffered updates drain, then the channel is closed
+	}
+	_, ok := <-other.Updates()
+	assert.True(t, ok, "other subscription should still receive updates")
+
+	// Closing after the hub removed it is a no-op
+	slow.Close()
+	other.Close()
+	_, ok = <-other.Updates()
+	assert.False(t, ok)
+}
+
+func TestPublishWithoutPublisher(t *testing.T) {
+	assert.NotPanics(t, func() {
+		Publish(context.Background(), nil, NewUpdate("vault.unlocked", uuid.New(), "", nil))
+	})
+}
+
+func TestTicketIsSingleUse(t *testing.T) {
+	mr, client := setupRedis(t)
+	ctx := context.Background()
+
+	ticket, err := IssueTicket(ctx, client, "0xAbC")
+	require.NoError(t, err)
+	assert.Len(t, ticket, 64)
+
+	address, err := RedeemTicket(ctx, client, ticket)
+	require.NoError(t, err)
+	assert.Equal(t, "0xAbC", address)
+
+	_, err = RedeemTicket(ctx, client, ticket)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+
+	ticket, err = IssueTicket(ctx, client, "0xAbC")
+	require.NoError(t, err)
+	mr.FastForward(TicketTTL + time.Second)
+	_, err = RedeemTicket(ctx, client, ticket)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+}
@@ -0,0 +1,50 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// TicketTTL is how long a stream ticket can be redeemed
+	TicketTTL = 30 * time.Second
+
+	ticketKeyPrefix = "stream:ticket:"
+)
+
+// ErrInvalidTicket is returned for unknown, expired or already used tickets
+var ErrInvalidTicket = errors.New("invalid or expired stream ticket")
+
+// IssueTicket creates a single-use ticket that authenticates one stream
+// request as address. Browsers cannot set headers on an EventSource, and a
+// ticket keeps the JWT itself out of URLs and access logs.
+func IssueTicket(ctx context.Context, redisClient *redis.Client, address string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	ticket := hex.EncodeToString(b)
+
+	if err := redisClient.Set(ctx, ticketKeyPrefix+ticket, address, TicketTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+// RedeemTicket consumes a ticket and returns the address it was issued to
+func RedeemTicket(ctx context.Context, redisClient *redis.Client, ticket string) (string, error) {
+	address, err := redisClient.GetDel(ctx, ticketKeyPrefix+ticket).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidTicket
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem ticket: %w", err)
+	}
+	return address, nil
+}
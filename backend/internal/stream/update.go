@@ -0,0 +1,81 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Channel is the Redis pub/sub channel vault updates are fanned out on
+const Channel = "stream:vault_updates"
+
+// Update is a change to a vault, pushed to the streams subscribed to it
+type Update struct {
+	Type       string          `json:"type"` // e.g. heartbeat.revealed, transaction.mined
+	VaultID    uuid.UUID       `json:"vault_id"`
+	TxHash     string          `json:"tx_hash,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	ObservedAt time.Time       `json:"observed_at"`
+}
+
+// NewUpdate creates an update observed now
+func NewUpdate(updateType string, vaultID uuid.UUID, txHash string, data map[string]interface{}) Update {
+	update := Update{
+		Type:       updateType,
+		VaultID:    vaultID,
+		TxHash:     txHash,
+		ObservedAt: time.Now().UTC(),
+	}
+	if data != nil {
+		if encoded, err := json.Marshal(data); err == nil {
+			update.Data = encoded
+		}
+	}
+	return update
+}
+
+// Publisher sends updates to every API instance
+type Publisher interface {
+	Publish(ctx context.Context, updates ...Update) error
+}
+
+// RedisPublisher publishes updates on Channel
+type RedisPublisher struct {
+	redis *redis.Client
+}
+
+// NewRedisPublisher creates a Publisher backed by Redis pub/sub
+func NewRedisPublisher(redisClient *redis.Client) *RedisPublisher {
+	return &RedisPublisher{redis: redisClient}
+}
+
+// Publish sends updates in order. Updates are best effort: instances that are
+// not subscribed at that moment never see them.
+func (p *RedisPublisher) Publish(ctx context.Context, updates ...Update) error {
+	for _, update := range updates {
+		payload, err := json.Marshal(update)
+		if err != nil {
+			return fmt.Errorf("failed to encode update: %w", err)
+		}
+		if err := p.redis.Publish(ctx, Channel, payload).Err(); err != nil {
+			return fmt.Errorf("failed to publish update: %w", err)
+		}
+	}
+	return nil
+}
+
+// Publish sends updates through publisher if there is one, logging failures,
+// since a missed update must not fail the work that produced it
+func Publish(ctx context.Context, publisher Publisher, updates ...Update) {
+	if publisher == nil || len(updates) == 0 {
+		return
+	}
+	if err := publisher.Publish(ctx, updates...); err != nil {
+		log.Printf("Failed to publish vault updates: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// Link ties a transaction to the rows it updates once it is mined
+type Link struct {
+	VaultID     *uuid.UUID
+	HeartbeatID *uuid.UUID
+	HeirID      *uuid.UUID
+}
+
+// LinkVaultCall finds the rows a vault call by sender affects: the heir for
+// approveInheritance and claimInheritance, and the committed heartbeat for
+// revealHeartbeat (matched by keccak256(sender, nonce)).
+func LinkVaultCall(db *gorm.DB, vault *models.Vault, sender common.Address, method string, args []interface{}) (Link, error) {
+	link := Link{VaultID: &vault.ID}
+
+	switch method {
+	case "approveInheritance", "claimInheritance":
+		var heir models.Heir
+		err := db.Where("vault_id = ? AND LOWER(address) = LOWER(?)", vault.ID, sender.Hex()).First(&heir).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return link, fmt.Errorf("failed to query heir: %w", err)
+		}
+		if err == nil {
+			link.HeirID = &heir.ID
+		}
+
+	case "revealHeartbeat":
+		nonce, ok := args[0].([32]byte)
+		if !ok {
+			return link, fmt.Errorf("unexpected revealHeartbeat argument %T", args[0])
+		}
+		commitHash := crypto.Keccak256Hash(sender.Bytes(), nonce[:])
+
+		var heartbeat models.Heartbeat
+		err := db.Where("vault_id = ? AND commit_hash = ? AND status = ?", vault.ID, commitHash.Hex(), models.HeartbeatStatusCommitted).
+			Order("committed_at DESC").
+			First(&heartbeat).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return link, fmt.Errorf("failed to query heartbeat: %w", err)
+		}
+		if err == nil {
+			link.HeartbeatID = &heartbeat.ID
+		}
+	}
+
+	return link, nil
+}
+
+// Record stores a broadcast transaction as pending so the tracker follows it.
+// A reveal also stores its hash on the linked heartbeat, which stays committed
+// until the reveal is mined.
+func Record(db *gorm.DB, tx *types.Transaction, from common.Address, method string, link Link) (*models.Transaction, error) {
+	record := models.Transaction{
+		Hash:        tx.Hash().Hex(),
+		FromAddress: from.Hex(),
+		VaultID:     link.VaultID,
+		HeartbeatID: link.HeartbeatID,
+		HeirID:      link.HeirID,
+		Method:      method,
+		Nonce:       tx.Nonce(),
+		Status:      models.TransactionStatusPending,
+	}
+	if tx.To() != nil {
+		record.ToAddress = tx.To().Hex()
+	}
+
+	err := db.Transaction(func(dbTx *gorm.DB) error {
+		if err := dbTx.Create(&record).Error; err != nil {
+			return err
+		}
+
+		if method == "revealHeartbeat" && link.HeartbeatID != nil {
+			return dbTx.Model(&models.Heartbeat{}).
+				Where("id = ?", *link.HeartbeatID).
+				Update("reveal_tx_hash", record.Hash).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record transaction: %w", err)
+	}
+
+	return &record, nil
+}
+
+// auditActions maps the vault methods that are audited to their action
+var auditActions = map[string]models.VaultAuditAction{
+	"pause":   models.VaultAuditPause,
+	"unpause": models.VaultAuditUnpause,
+}
+
+// AuditAction returns the audit action of a vault method, if it is audited
+func AuditAction(method string) (models.VaultAuditAction, bool) {
+	action, ok := auditActions[method]
+	return action, ok
+}
+
+// RecordAudit stores the audit entry of a pause or unpause sent by actor, to be
+// confirmed by the indexer once mined. Other methods are not audited.
+func RecordAudit(db *gorm.DB, vault *models.Vault, actor common.Address, method, reason, txHash string) error {
+	action, ok := AuditAction(method)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	if err := db.Create(&models.VaultAuditLog{
+		VaultID:     vault.ID,
+		Action:      action,
+		Actor:       actor.Hex(),
+		Reason:      reason,
+		TxHash:      txHash,
+		RequestedAt: &now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// RecordReplacement points a pending transaction at the same-nonce replacement
+// tx and keeps the superseded hash, since either may still be mined. Hashes
+// stored on the linked heartbeat, meta-transaction and pause audit entry
+// follow the replacement.
+func RecordReplacement(db *gorm.DB, record *models.Transaction, tx *types.Transaction, cancel bool) error {
+	oldHash := record.Hash
+	newHash := tx.Hash().Hex()
+	now := time.Now()
+
+	updates := map[string]interface{}{
+		"hash":        newHash,
+		"replaced_at": now,
+	}
+	if cancel {
+		updates["cancel_tx_hash"] = newHash
+	}
+
+	err := db.Transaction(func(dbTx *gorm.DB) error {
+		if err := dbTx.Create(&models.TransactionReplacement{
+			TransactionID: record.ID,
+			Hash:          oldHash,
+			ReplacedBy:    newHash,
+			Cancel:        cancel,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := dbTx.Model(record).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if record.HeartbeatID != nil {
+			for _, column := range []string{"commit_tx_hash", "reveal_tx_hash"} {
+				if err := dbTx.Model(&models.Heartbeat{}).
+					Where("id = ? AND "+column+" = ?", *record.HeartbeatID, oldHash).
+					Update(column, newHash).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		// A cancelled pause never happens, so its audit entry is dropped
+		audit := dbTx.Where("tx_hash = ? AND confirmed_at IS NULL", oldHash)
+		if cancel {
+			if err := audit.Delete(&models.VaultAuditLog{}).Error; err != nil {
+				return err
+			}
+		} else if err := audit.Model(&models.VaultAuditLog{}).Update("tx_hash", newHash).Error; err != nil {
+			return err
+		}
+
+		return dbTx.Model(&models.MetaTransaction{}).
+			Where("tx_hash = ?", oldHash).
+			Update("tx_hash", newHash).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record replacement: %w", err)
+	}
+
+	record.Hash = newHash
+	record.ReplacedAt = &now
+	if cancel {
+		record.CancelTxHash = newHash
+	}
+
+	return nil
+}
@@ -0,0 +1,390 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/internal/stream"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+)
+
+// batchSize is the number of pending transactions checked per poll
+const batchSize = 100
+
+// Tracker follows pending transactions until they are mined, reverted or
+// dropped. Handlers return as soon as a transaction is broadcast; the
+// heartbeat, heir and vault rows it affects are only updated once the receipt
+// shows it succeeded with enough confirmations.
+//
+// When the indexer runs, it owns those rows: it applies the transaction's
+// events together with the state needed to revert them on a reorg, so the
+// tracker only records the outcome. Without the indexer the tracker updates
+// the rows itself.
+//
+// Transactions sent by the server signer that stay unmined past the stuck
+// timeout are re-broadcast with bumped fees under the same nonce, so an
+// underpriced heartbeat does not sit in the mempool until the vault unlocks.
+//
+// Outcomes of vault transactions are pushed to live streams through the
+// publisher, if any, as transaction.<status> updates.
+type Tracker struct {
+	db         *gorm.DB
+	blockchain service.BlockchainService
+	publisher  stream.Publisher
+	cfg        config.TrackerConfig
+
+	// indexed is set when the indexer updates vault, heir and heartbeat rows
+	indexed bool
+}
+
+// New creates a new Tracker. publisher may be nil.
+func New(db *gorm.DB, blockchain service.BlockchainService, publisher stream.Publisher, cfg *config.Config) *Tracker {
+	return &Tracker{
+		db:         db,
+		blockchain: blockchain,
+		publisher:  publisher,
+		cfg:        cfg.Tracker,
+		indexed:    cfg.Indexer.Enabled,
+	}
+}
+
+// Run polls pending transactions until ctx is cancelled
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Transaction tracker error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every pending transaction once
+func (t *Tracker) Poll(ctx context.Context) error {
+	var pending []models.Transaction
+	if err := t.db.Where("status = ?", models.TransactionStatusPending).
+		Order("created_at ASC").
+		Limit(batchSize).
+		Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to query pending transactions: %w", err)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	head, err := t.blockchain.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if err := t.check(ctx, &pending[i], head); err != nil {
+			log.Printf("Failed to check transaction %s: %v", pending[i].Hash, err)
+		}
+	}
+
+	return nil
+}
+
+// check updates a single pending transaction from its receipt
+func (t *Tracker) check(ctx context.Context, record *models.Transaction, head uint64) error {
+	receipt, err := t.findReceipt(ctx, record)
+	if errors.Is(err, ethereum.NotFound) {
+		if t.isStuck(record) {
+			return t.replace(ctx, record)
+		}
+		return t.checkDropped(ctx, record)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Any broadcast for the nonce may be the one that was mined
+	record.Hash = receipt.TxHash.Hex()
+
+	// Wait for confirmations so a reorg does not undo a recorded outcome
+	blockNumber := receipt.BlockNumber.Uint64()
+	if head < blockNumber+t.cfg.Confirmations {
+		return nil
+	}
+
+	status := models.TransactionStatusMined
+	var revertReason string
+	if record.CancelTxHash != "" && record.Hash == record.CancelTxHash {
+		status = models.TransactionStatusCancelled
+	} else if receipt.Status != types.ReceiptStatusSuccessful {
+		status = models.TransactionStatusReverted
+
+		revertReason, err = t.blockchain.GetRevertReason(ctx, record.Hash, receipt.BlockNumber)
+		if err != nil {
+			revertReason = fmt.Sprintf("unknown (%v)", err)
+		}
+	}
+
+	applyState := status == models.TransactionStatusMined && !t.indexed
+	var blockTime time.Time
+	if applyState {
+		blockTime, err = t.blockTime(ctx, receipt.BlockNumber)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := t.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(record).Updates(map[string]interface{}{
+			"hash":          record.Hash,
+			"status":        status,
+			"block_number":  blockNumber,
+			"gas_used":      receipt.GasUsed,
+			"revert_reason": revertReason,
+			"confirmed_at":  now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+
+		if applyState {
+			return applySuccess(tx, record, blockTime)
+		}
+		if status != models.TransactionStatusMined {
+			return applyFailure(tx, record)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"method":       record.Method,
+		"block_number": blockNumber,
+	}
+	if revertReason != "" {
+		data["revert_reason"] = revertReason
+	}
+	t.publish(ctx, record, status, data)
+	return nil
+}
+
+// findReceipt returns the receipt of the transaction or of any broadcast it replaced
+func (t *Tracker) findReceipt(ctx context.Context, record *models.Transaction) (*types.Receipt, error) {
+	hashes := []string{record.Hash}
+	if record.ReplacedAt != nil {
+		var replaced []string
+		if err := t.db.Model(&models.TransactionReplacement{}).
+			Where("transaction_id = ?", record.ID).
+			Pluck("hash", &replaced).Error; err != nil {
+			return nil, fmt.Errorf("failed to query replacements: %w", err)
+		}
+		hashes = append(hashes, replaced...)
+	}
+
+	for _, hash := range hashes {
+		receipt, err := t.blockchain.GetTransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, ethereum.NotFound
+}
+
+// isStuck reports whether a server-signed transaction has waited long enough
+// since its last broadcast to be replaced with higher fees
+func (t *Tracker) isStuck(record *models.Transaction) bool {
+	if t.cfg.StuckTimeout <= 0 {
+		return false
+	}
+	if !strings.EqualFold(record.FromAddress, t.blockchain.RelayerAddress().Hex()) {
+		return false // User-signed transactions cannot be re-signed here
+	}
+
+	return time.Since(lastBroadcast(record)) >= t.cfg.StuckTimeout
+}
+
+// replace re-broadcasts a stuck transaction with bumped fees. A transaction
+// being cancelled is replaced by another, higher-priced cancellation.
+func (t *Tracker) replace(ctx context.Context, record *models.Transaction) error {
+	cancel := record.CancelTxHash != ""
+
+	tx, err := t.blockchain.ReplaceTransaction(ctx, record.Nonce, cancel)
+	if errors.Is(err, service.ErrNonceNotPending) {
+		return nil // Mined or replaced elsewhere; the next poll finds the receipt
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace stuck transaction: %w", err)
+	}
+
+	oldHash := record.Hash
+	if err := RecordReplacement(t.db, record, tx, cancel); err != nil {
+		return err
+	}
+
+	log.Printf("Replaced stuck transaction %s (nonce %d) with %s", oldHash, record.Nonce, record.Hash)
+	return nil
+}
+
+// checkDropped marks a transaction without a receipt as dropped once the node
+// no longer knows it and the drop timeout has passed
+func (t *Tracker) checkDropped(ctx context.Context, record *models.Transaction) error {
+	if time.Since(lastBroadcast(record)) < t.cfg.DropTimeout {
+		return nil
+	}
+
+	_, _, err := t.blockchain.GetTransactionByHash(ctx, record.Hash)
+	if err == nil {
+		return nil // Still in the mempool
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return err
+	}
+
+	if err := t.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(record).Updates(map[string]interface{}{
+			"status":       models.TransactionStatusDropped,
+			"confirmed_at": time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+		return applyFailure(tx, record)
+	}); err != nil {
+		return err
+	}
+
+	t.publish(ctx, record, models.TransactionStatusDropped, map[string]interface{}{
+		"method": record.Method,
+	})
+	return nil
+}
+
+// publish pushes the outcome of a vault transaction to live streams
+func (t *Tracker) publish(ctx context.Context, record *models.Transaction, status models.TransactionStatus, data map[string]interface{}) {
+	if record.VaultID == nil {
+		return
+	}
+	stream.Publish(ctx, t.publisher, stream.NewUpdate("transaction."+string(status), *record.VaultID, record.Hash, data))
+}
+
+// lastBroadcast returns when the transaction's current hash was broadcast
+func lastBroadcast(record *models.Transaction) time.Time {
+	if record.ReplacedAt != nil {
+		return *record.ReplacedAt
+	}
+	return record.CreatedAt
+}
+
+func (t *Tracker) blockTime(ctx context.Context, number *big.Int) (time.Time, error) {
+	header, err := t.blockchain.HeaderByNumber(ctx, number)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(header.Time), 0), nil
+}
+
+// applySuccess updates the rows linked to a mined transaction when the indexer
+// does not run
+func applySuccess(tx *gorm.DB, record *models.Transaction, blockTime time.Time) error {
+	switch record.Method {
+	case "revealHeartbeat":
+		if record.HeartbeatID != nil {
+			if err := tx.Model(&models.Heartbeat{}).Where("id = ?", *record.HeartbeatID).Updates(map[string]interface{}{
+				"status":         models.HeartbeatStatusRevealed,
+				"reveal_tx_hash": record.Hash,
+				"revealed_at":    blockTime,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update heartbeat: %w", err)
+			}
+		}
+		if record.VaultID != nil {
+			if err := tx.Model(&models.Vault{}).Where("id = ?", *record.VaultID).Updates(map[string]interface{}{
+				"last_heartbeat": blockTime,
+				"status":         models.VaultStatusLocked,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update vault: %w", err)
+			}
+		}
+
+	case "checkAndUnlock":
+		// Mirrors VaultUnlocked and GracePeriodStarted, which the indexer records as well
+		if record.VaultID != nil {
+			if err := tx.Model(&models.Vault{}).
+				Where("id = ? AND status = ?", *record.VaultID, models.VaultStatusLocked).
+				Updates(map[string]interface{}{
+					"status":               models.VaultStatusUnlocked,
+					"unlocked_at":          blockTime,
+					"grace_period_ends_at": gorm.Expr("?::timestamptz + grace_period * INTERVAL '1 second'", blockTime),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to update vault: %w", err)
+			}
+		}
+
+	case "approveInheritance":
+		if record.HeirID != nil {
+			if err := tx.Model(&models.Heir{}).Where("id = ?", *record.HeirID).
+				Update("has_approved", true).Error; err != nil {
+				return fmt.Errorf("failed to update heir: %w", err)
+			}
+		}
+
+	case "claimInheritance":
+		if record.HeirID != nil {
+			if err := tx.Model(&models.Heir{}).Where("id = ?", *record.HeirID).
+				Update("has_claimed", true).Error; err != nil {
+				return fmt.Errorf("failed to update heir: %w", err)
+			}
+		}
+		if record.VaultID != nil {
+			// The vault is claimed once every heir has claimed
+			var unclaimed int64
+			if err := tx.Model(&models.Heir{}).Where("vault_id = ? AND has_claimed = ?", *record.VaultID, false).
+				Count(&unclaimed).Error; err != nil {
+				return fmt.Errorf("failed to count unclaimed heirs: %w", err)
+			}
+			if unclaimed == 0 {
+				if err := tx.Model(&models.Vault{}).Where("id = ?", *record.VaultID).
+					Update("status", models.VaultStatusClaimed).Error; err != nil {
+					return fmt.Errorf("failed to update vault: %w", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyFailure marks the heartbeat of a reverted, dropped or cancelled commit or
+// reveal as failed, unless another reveal of the commit was already indexed
+func applyFailure(tx *gorm.DB, record *models.Transaction) error {
+	switch record.Method {
+	case "commitHeartbeat", "revealHeartbeat":
+		if record.HeartbeatID != nil {
+			if err := tx.Model(&models.Heartbeat{}).
+				Where("id = ? AND status = ?", *record.HeartbeatID, models.HeartbeatStatusCommitted).
+				Update("status", models.HeartbeatStatusFailed).Error; err != nil {
+				return fmt.Errorf("failed to update heartbeat: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
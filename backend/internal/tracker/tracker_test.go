@@ -0,0 +1,523 @@
+package tracker
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/internal/dbtest"
+	"github.com/haneumLee/legacychain/backend/internal/service"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var (
+	relayerAddress = common.HexToAddress("0x00000000000000000000000000000000000000F1")
+	ownerAddress   = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	heirAddress    = common.HexToAddress("0x00000000000000000000000000000000000000A1")
+)
+
+// genesisTime is the timestamp of block 0; every block is 12 seconds later
+var genesisTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeChain holds the receipts and mempool the tracker reads through
+// BlockchainService. Calling any other method panics on the nil embedded
+// interface.
+type fakeChain struct {
+	service.BlockchainService
+
+	mu       sync.Mutex
+	head     uint64
+	receipts map[string]*types.Receipt
+	mempool  map[string]bool
+
+	// replaced are the nonces ReplaceTransaction re-broadcast, with whether
+	// as a cancellation; notPending makes it report the nonce as mined
+	replaced   []replacement
+	notPending bool
+}
+
+type replacement struct {
+	nonce  uint64
+	cancel bool
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		receipts: make(map[string]*types.Receipt),
+		mempool:  make(map[string]bool),
+	}
+}
+
+// mine includes the transaction in the next block with the receipt status
+func (f *fakeChain) mine(hash string, status uint64) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.head++
+	f.receipts[hash] = &types.Receipt{
+		TxHash:      common.HexToHash(hash),
+		Status:      status,
+		BlockNumber: new(big.Int).SetUint64(f.head),
+		GasUsed:     21000,
+	}
+	delete(f.mempool, hash)
+	return f.head
+}
+
+func (f *fakeChain) RelayerAddress() common.Address {
+	return relayerAddress
+}
+
+func (f *fakeChain) BlockNumber(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.head, nil
+}
+
+func (f *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{
+		Number: number,
+		Time:   uint64(blockTimeOf(number.Uint64()).Unix()),
+	}, nil
+}
+
+func (f *fakeChain) GetTransactionReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	receipt, ok := f.receipts[txHash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (f *fakeChain) GetTransactionByHash(ctx context.Context, txHash string) (*types.Transaction, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.mempool[txHash] {
+		return nil, false, ethereum.NotFound
+	}
+	return types.NewTx(&types.LegacyTx{}), true, nil
+}
+
+func (f *fakeChain) ReplaceTransaction(ctx context.Context, nonce uint64, cancel bool) (*types.Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.notPending {
+		return nil, service.ErrNonceNotPending
+	}
+	f.replaced = append(f.replaced, replacement{nonce, cancel})
+	tx := types.NewTx(&types.LegacyTx{Nonce: nonce, GasPrice: big.NewInt(int64(len(f.replaced)))})
+	f.mempool[tx.Hash().Hex()] = true
+	return tx, nil
+}
+
+func (f *fakeChain) GetRevertReason(ctx context.Context, txHash string, blockNumber *big.Int) (string, error) {
+	return "NotHeir", nil
+}
+
+// blockTimeOf returns the timestamp of a block of the fake chain
+func blockTimeOf(number uint64) time.Time {
+	return genesisTime.Add(time.Duration(number) * 12 * time.Second)
+}
+
+// newTestTracker returns a tracker without confirmations over a fresh
+// database and chain. indexed tells it the indexer runs.
+func newTestTracker(t *testing.T, indexed bool) (*Tracker, *fakeChain, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t)
+	chain := newFakeChain()
+
+	cfg := &config.Config{
+		Tracker: config.TrackerConfig{DropTimeout: time.Minute},
+		Indexer: config.IndexerConfig{Enabled: indexed},
+	}
+	return New(db, chain, nil, cfg), chain, db
+}
+
+// createVault stores a locked vault of ownerAddress with heirAddress as heir
+func createVault(t *testing.T, db *gorm.DB) (models.Vault, models.Heir) {
+	t.Helper()
+	owner := models.User{Address: ownerAddress.Hex()}
+	require.NoError(t, db.Create(&owner).Error)
+
+	lastHeartbeat := genesisTime
+	vault := models.Vault{
+		VaultID:           1,
+		ContractAddress:   common.HexToAddress("0x0000000000000000000000000000000000000B01").Hex(),
+		OwnerID:           owner.ID,
+		HeartbeatInterval: 30 * 24 * 60 * 60,
+		GracePeriod:       90 * 24 * 60 * 60,
+		RequiredApprovals: 1,
+		Status:            models.VaultStatusLocked,
+		LastHeartbeat:     &lastHeartbeat,
+	}
+	require.NoError(t, db.Create(&vault).Error)
+
+	heir := models.Heir{VaultID: vault.ID, Address: heirAddress.Hex(), ShareBPS: 10000}
+	require.NoError(t, db.Create(&heir).Error)
+	return vault, heir
+}
+
+// commitHeartbeat stores a heartbeat committed through the API
+func commitHeartbeat(t *testing.T, db *gorm.DB, vault models.Vault) models.Heartbeat {
+	t.Helper()
+	heartbeat := models.Heartbeat{
+		VaultID:     vault.ID,
+		CommitHash:  crypto.Keccak256Hash([]byte(uuid.NewString())).Hex(),
+		Status:      models.HeartbeatStatusCommitted,
+		CommittedAt: genesisTime,
+	}
+	require.NoError(t, db.Create(&heartbeat).Error)
+	return heartbeat
+}
+
+// broadcast stores a pending transaction sent from the address
+func broadcast(t *testing.T, db *gorm.DB, from common.Address, method string, link Link) *models.Transaction {
+	t.Helper()
+	record := &models.Transaction{
+		Hash:        crypto.Keccak256Hash([]byte(uuid.NewString())).Hex(),
+		FromAddress: from.Hex(),
+		ToAddress:   common.HexToAddress("0x0000000000000000000000000000000000000B01").Hex(),
+		VaultID:     link.VaultID,
+		HeartbeatID: link.HeartbeatID,
+		HeirID:      link.HeirID,
+		Method:      method,
+		Status:      models.TransactionStatusPending,
+	}
+	require.NoError(t, db.Create(record).Error)
+	return record
+}
+
+// reload returns the stored row of model with the id
+func reload[T any](t *testing.T, db *gorm.DB, id uuid.UUID) T {
+	t.Helper()
+	var row T
+	require.NoError(t, db.First(&row, "id = ?", id).Error)
+	return row
+}
+
+func TestPollLeavesIndexedRowsToIndexer(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, true)
+	vault, heir := createVault(t, db)
+	heartbeat := commitHeartbeat(t, db, vault)
+
+	approve := broadcast(t, db, heirAddress, "approveInheritance", Link{VaultID: &vault.ID, HeirID: &heir.ID})
+	reveal := broadcast(t, db, ownerAddress, "revealHeartbeat", Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID})
+	chain.mine(approve.Hash, types.ReceiptStatusSuccessful)
+	chain.mine(reveal.Hash, types.ReceiptStatusSuccessful)
+	require.NoError(t, tracker.Poll(context.Background()))
+
+	assert.Equal(t, models.TransactionStatusMined, reload[models.Transaction](t, db, approve.ID).Status)
+	assert.Equal(t, models.TransactionStatusMined, reload[models.Transaction](t, db, reveal.ID).Status)
+
+	// The indexer applies the events, with the state to revert them on a reorg
+	assert.False(t, reload[models.Heir](t, db, heir.ID).HasApproved)
+	assert.Equal(t, models.HeartbeatStatusCommitted, reload[models.Heartbeat](t, db, heartbeat.ID).Status)
+	stored := reload[models.Vault](t, db, vault.ID)
+	require.NotNil(t, stored.LastHeartbeat)
+	assert.True(t, genesisTime.Equal(*stored.LastHeartbeat))
+}
+
+func TestPollKeepsIndexedRevealOfFailedTransaction(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, true)
+	vault, _ := createVault(t, db)
+	heartbeat := commitHeartbeat(t, db, vault)
+
+	reveal := broadcast(t, db, ownerAddress, "revealHeartbeat", Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID})
+	chain.mine(reveal.Hash, types.ReceiptStatusFailed)
+
+	// Another reveal of the commitment was mined and indexed first
+	require.NoError(t, db.Model(&heartbeat).Update("status", models.HeartbeatStatusRevealed).Error)
+	require.NoError(t, tracker.Poll(context.Background()))
+
+	assert.Equal(t, models.TransactionStatusReverted, reload[models.Transaction](t, db, reveal.ID).Status)
+	assert.Equal(t, models.HeartbeatStatusRevealed, reload[models.Heartbeat](t, db, heartbeat.ID).Status)
+}
+
+func TestPollWaitsForConfirmations(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, true)
+	tracker.cfg.Confirmations = 2
+	vault, _ := createVault(t, db)
+	ctx := context.Background()
+
+	record := broadcast(t, db, ownerAddress, "deposit", Link{VaultID: &vault.ID})
+	number := chain.mine(record.Hash, types.ReceiptStatusSuccessful)
+	require.NoError(t, tracker.Poll(ctx))
+	assert.Equal(t, models.TransactionStatusPending, reload[models.Transaction](t, db, record.ID).Status)
+
+	chain.mine(crypto.Keccak256Hash([]byte("other")).Hex(), types.ReceiptStatusSuccessful)
+	chain.mine(crypto.Keccak256Hash([]byte("another")).Hex(), types.ReceiptStatusSuccessful)
+	require.NoError(t, tracker.Poll(ctx))
+
+	stored := reload[models.Transaction](t, db, record.ID)
+	assert.Equal(t, models.TransactionStatusMined, stored.Status)
+	require.NotNil(t, stored.BlockNumber)
+	assert.Equal(t, number, *stored.BlockNumber)
+	assert.Equal(t, uint64(21000), stored.GasUsed)
+	assert.NotNil(t, stored.ConfirmedAt)
+}
+
+func TestPollAppliesMinedTransactions(t *testing.T) {
+	tests := []struct {
+		method string
+		check  func(t *testing.T, db *gorm.DB, vault models.Vault, heir models.Heir, heartbeat models.Heartbeat, record *models.Transaction, blockTime time.Time)
+	}{
+		{
+			method: "revealHeartbeat",
+			check: func(t *testing.T, db *gorm.DB, vault models.Vault, heir models.Heir, heartbeat models.Heartbeat, record *models.Transaction, blockTime time.Time) {
+				stored := reload[models.Heartbeat](t, db, heartbeat.ID)
+				assert.Equal(t, models.HeartbeatStatusRevealed, stored.Status)
+				assert.Equal(t, record.Hash, stored.RevealTxHash)
+				require.NotNil(t, stored.RevealedAt)
+				assert.True(t, blockTime.Equal(*stored.RevealedAt))
+
+				updated := reload[models.Vault](t, db, vault.ID)
+				assert.Equal(t, models.VaultStatusLocked, updated.Status)
+				require.NotNil(t, updated.LastHeartbeat)
+				assert.True(t, blockTime.Equal(*updated.LastHeartbeat))
+			},
+		},
+		{
+			method: "checkAndUnlock",
+			check: func(t *testing.T, db *gorm.DB, vault models.Vault, heir models.Heir, heartbeat models.Heartbeat, record *models.Transaction, blockTime time.Time) {
+				updated := reload[models.Vault](t, db, vault.ID)
+				assert.Equal(t, models.VaultStatusUnlocked, updated.Status)
+				require.NotNil(t, updated.UnlockedAt)
+				assert.True(t, blockTime.Equal(*updated.UnlockedAt))
+				require.NotNil(t, updated.GracePeriodEndsAt)
+				assert.True(t, blockTime.Add(90*24*time.Hour).Equal(*updated.GracePeriodEndsAt))
+			},
+		},
+		{
+			method: "approveInheritance",
+			check: func(t *testing.T, db *gorm.DB, vault models.Vault, heir models.Heir, heartbeat models.Heartbeat, record *models.Transaction, blockTime time.Time) {
+				assert.True(t, reload[models.Heir](t, db, heir.ID).HasApproved)
+				assert.Equal(t, models.VaultStatusLocked, reload[models.Vault](t, db, vault.ID).Status)
+			},
+		},
+		{
+			method: "claimInheritance",
+			check: func(t *testing.T, db *gorm.DB, vault models.Vault, heir models.Heir, heartbeat models.Heartbeat, record *models.Transaction, blockTime time.Time) {
+				assert.True(t, reload[models.Heir](t, db, heir.ID).HasClaimed)
+				assert.Equal(t, models.VaultStatusClaimed, reload[models.Vault](t, db, vault.ID).Status, "its only heir has claimed")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			tracker, chain, db := newTestTracker(t, false)
+			vault, heir := createVault(t, db)
+			heartbeat := commitHeartbeat(t, db, vault)
+
+			record := broadcast(t, db, ownerAddress, tt.method, Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID, HeirID: &heir.ID})
+			number := chain.mine(record.Hash, types.ReceiptStatusSuccessful)
+			require.NoError(t, tracker.Poll(context.Background()))
+
+			assert.Equal(t, models.TransactionStatusMined, reload[models.Transaction](t, db, record.ID).Status)
+			tt.check(t, db, vault, heir, heartbeat, record, blockTimeOf(number))
+		})
+	}
+}
+
+func TestPollRecordsReverts(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	vault, heir := createVault(t, db)
+	heartbeat := commitHeartbeat(t, db, vault)
+
+	commit := broadcast(t, db, ownerAddress, "commitHeartbeat", Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID})
+	approve := broadcast(t, db, heirAddress, "approveInheritance", Link{VaultID: &vault.ID, HeirID: &heir.ID})
+	chain.mine(commit.Hash, types.ReceiptStatusFailed)
+	chain.mine(approve.Hash, types.ReceiptStatusFailed)
+	require.NoError(t, tracker.Poll(context.Background()))
+
+	stored := reload[models.Transaction](t, db, commit.ID)
+	assert.Equal(t, models.TransactionStatusReverted, stored.Status)
+	assert.Equal(t, "NotHeir", stored.RevertReason)
+	assert.Equal(t, models.HeartbeatStatusFailed, reload[models.Heartbeat](t, db, heartbeat.ID).Status)
+
+	assert.Equal(t, models.TransactionStatusReverted, reload[models.Transaction](t, db, approve.ID).Status)
+	assert.False(t, reload[models.Heir](t, db, heir.ID).HasApproved)
+}
+
+func TestPollDropsUnknownTransactions(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	vault, _ := createVault(t, db)
+	heartbeat := commitHeartbeat(t, db, vault)
+	ctx := context.Background()
+
+	recent := broadcast(t, db, ownerAddress, "deposit", Link{VaultID: &vault.ID})
+	queued := broadcast(t, db, ownerAddress, "deposit", Link{VaultID: &vault.ID})
+	lost := broadcast(t, db, ownerAddress, "commitHeartbeat", Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID})
+
+	// Only transactions older than the drop timeout that the node no longer
+	// knows are dropped
+	old := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, db.Model(&models.Transaction{}).Where("id IN ?", []uuid.UUID{queued.ID, lost.ID}).
+		Update("created_at", old).Error)
+	chain.mempool[queued.Hash] = true
+	require.NoError(t, tracker.Poll(ctx))
+
+	assert.Equal(t, models.TransactionStatusPending, reload[models.Transaction](t, db, recent.ID).Status)
+	assert.Equal(t, models.TransactionStatusPending, reload[models.Transaction](t, db, queued.ID).Status)
+
+	stored := reload[models.Transaction](t, db, lost.ID)
+	assert.Equal(t, models.TransactionStatusDropped, stored.Status)
+	assert.NotNil(t, stored.ConfirmedAt)
+	assert.Equal(t, models.HeartbeatStatusFailed, reload[models.Heartbeat](t, db, heartbeat.ID).Status)
+}
+
+func TestPollFindsReceiptOfReplacedBroadcast(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	vault, heir := createVault(t, db)
+	ctx := context.Background()
+
+	// The original broadcast was mined although a fee-bumped replacement was sent
+	record := broadcast(t, db, relayerAddress, "approveInheritance", Link{VaultID: &vault.ID, HeirID: &heir.ID})
+	original := record.Hash
+	replacement := types.NewTx(&types.LegacyTx{Nonce: 7, GasPrice: big.NewInt(2)})
+	require.NoError(t, RecordReplacement(db, record, replacement, false))
+	chain.mine(original, types.ReceiptStatusSuccessful)
+	require.NoError(t, tracker.Poll(ctx))
+
+	stored := reload[models.Transaction](t, db, record.ID)
+	assert.Equal(t, models.TransactionStatusMined, stored.Status)
+	assert.Equal(t, original, stored.Hash)
+	assert.True(t, reload[models.Heir](t, db, heir.ID).HasApproved)
+}
+
+func TestPollRecordsCancellation(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	vault, _ := createVault(t, db)
+	heartbeat := commitHeartbeat(t, db, vault)
+
+	record := broadcast(t, db, relayerAddress, "commitHeartbeat", Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID})
+	cancel := types.NewTx(&types.LegacyTx{Nonce: 7, GasPrice: big.NewInt(3)})
+	require.NoError(t, RecordReplacement(db, record, cancel, true))
+	chain.mine(cancel.Hash().Hex(), types.ReceiptStatusSuccessful)
+	require.NoError(t, tracker.Poll(context.Background()))
+
+	assert.Equal(t, models.TransactionStatusCancelled, reload[models.Transaction](t, db, record.ID).Status)
+	assert.Equal(t, models.HeartbeatStatusFailed, reload[models.Heartbeat](t, db, heartbeat.ID).Status)
+}
+
+func TestIsStuck(t *testing.T) {
+	now := time.Now()
+	recently := now.Add(-30 * time.Second)
+	longAgo := now.Add(-2 * time.Minute)
+
+	tests := []struct {
+		name         string
+		stuckTimeout time.Duration
+		from         string
+		createdAt    time.Time
+		replacedAt   *time.Time
+		want         bool
+	}{
+		{name: "Replacement disabled", from: relayerAddress.Hex(), createdAt: longAgo},
+		{name: "Recent broadcast", stuckTimeout: time.Minute, from: relayerAddress.Hex(), createdAt: recently},
+		{name: "Past the threshold", stuckTimeout: time.Minute, from: relayerAddress.Hex(), createdAt: longAgo, want: true},
+		{name: "Address case ignored", stuckTimeout: time.Minute, from: "0x00000000000000000000000000000000000000f1", createdAt: longAgo, want: true},
+		{name: "Signed by a user", stuckTimeout: time.Minute, from: ownerAddress.Hex(), createdAt: longAgo},
+		{name: "Recently replaced", stuckTimeout: time.Minute, from: relayerAddress.Hex(), createdAt: longAgo, replacedAt: &recently},
+		{name: "Replacement stuck as well", stuckTimeout: time.Minute, from: relayerAddress.Hex(), createdAt: longAgo, replacedAt: &longAgo, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &Tracker{
+				blockchain: newFakeChain(),
+				cfg:        config.TrackerConfig{StuckTimeout: tt.stuckTimeout},
+			}
+			record := &models.Transaction{FromAddress: tt.from, CreatedAt: tt.createdAt, ReplacedAt: tt.replacedAt}
+			assert.Equal(t, tt.want, tracker.isStuck(record))
+		})
+	}
+}
+
+func TestPollReplacesStuckTransactions(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	tracker.cfg.StuckTimeout = time.Minute
+	vault, _ := createVault(t, db)
+	heartbeat := commitHeartbeat(t, db, vault)
+	ctx := context.Background()
+
+	record := broadcast(t, db, relayerAddress, "commitHeartbeat", Link{VaultID: &vault.ID, HeartbeatID: &heartbeat.ID})
+	require.NoError(t, db.Model(record).Updates(map[string]interface{}{
+		"nonce":      7,
+		"created_at": time.Now().Add(-2 * time.Minute),
+	}).Error)
+	require.NoError(t, db.Model(&heartbeat).Update("commit_tx_hash", record.Hash).Error)
+	require.NoError(t, tracker.Poll(ctx))
+
+	// Re-broadcast under the same nonce, keeping the old hash
+	require.Equal(t, []replacement{{nonce: 7}}, chain.replaced)
+	stored := reload[models.Transaction](t, db, record.ID)
+	assert.NotEqual(t, record.Hash, stored.Hash)
+	assert.Equal(t, uint64(7), stored.Nonce)
+	assert.Equal(t, models.TransactionStatusPending, stored.Status)
+	require.NotNil(t, stored.ReplacedAt)
+	assert.Equal(t, stored.Hash, reload[models.Heartbeat](t, db, heartbeat.ID).CommitTxHash)
+
+	var replaced models.TransactionReplacement
+	require.NoError(t, db.Where("transaction_id = ?", record.ID).First(&replaced).Error)
+	assert.Equal(t, record.Hash, replaced.Hash)
+	assert.Equal(t, stored.Hash, replaced.ReplacedBy)
+	assert.False(t, replaced.Cancel)
+
+	// Not again until the replacement has been waiting as long
+	require.NoError(t, tracker.Poll(ctx))
+	assert.Len(t, chain.replaced, 1)
+}
+
+func TestPollReplacesCancellationWithCancellation(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	tracker.cfg.StuckTimeout = time.Minute
+	vault, _ := createVault(t, db)
+
+	record := broadcast(t, db, relayerAddress, "checkAndUnlock", Link{VaultID: &vault.ID})
+	cancel := types.NewTx(&types.LegacyTx{Nonce: 3, GasPrice: big.NewInt(100)})
+	require.NoError(t, RecordReplacement(db, record, cancel, true))
+	require.NoError(t, db.Model(record).Updates(map[string]interface{}{
+		"nonce":       3,
+		"replaced_at": time.Now().Add(-2 * time.Minute),
+	}).Error)
+	require.NoError(t, tracker.Poll(context.Background()))
+
+	require.Equal(t, []replacement{{nonce: 3, cancel: true}}, chain.replaced)
+	stored := reload[models.Transaction](t, db, record.ID)
+	assert.Equal(t, stored.Hash, stored.CancelTxHash)
+	assert.NotEqual(t, cancel.Hash().Hex(), stored.Hash)
+}
+
+func TestPollSkipsReplacingMinedNonce(t *testing.T) {
+	tracker, chain, db := newTestTracker(t, false)
+	tracker.cfg.StuckTimeout = time.Minute
+	chain.notPending = true
+	vault, _ := createVault(t, db)
+
+	record := broadcast(t, db, relayerAddress, "checkAndUnlock", Link{VaultID: &vault.ID})
+	require.NoError(t, db.Model(record).Update("created_at", time.Now().Add(-2*time.Minute)).Error)
+	require.NoError(t, tracker.Poll(context.Background()))
+
+	// The next poll finds the receipt of whichever broadcast was mined
+	stored := reload[models.Transaction](t, db, record.ID)
+	assert.Equal(t, record.Hash, stored.Hash)
+	assert.Nil(t, stored.ReplacedAt)
+	assert.Equal(t, models.TransactionStatusPending, stored.Status)
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxURLLength matches the webhooks.url column
+const maxURLLength = 2048
+
+// ErrPrivateTarget is returned for URLs or connections to loopback, private
+// or otherwise non-public addresses
+var ErrPrivateTarget = errors.New("webhook target is not a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598)
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// ValidateURL checks that a webhook URL may be registered. Only https URLs
+// are accepted unless allowInsecure is set; host names are checked again
+// when connecting, since they may resolve to private addresses.
+func ValidateURL(raw string, allowInsecure bool) error {
+	if len(raw) > maxURLLength {
+		return fmt.Errorf("URL must be at most %d characters", maxURLLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Hostname() == "" {
+		return errors.New("URL must be absolute")
+	}
+	if u.User != nil {
+		return errors.New("URL must not contain credentials")
+	}
+
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowInsecure:
+	default:
+		return errors.New("URL must use https")
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !allowInsecure && !isPublic(ip) {
+		return ErrPrivateTarget
+	}
+	return nil
+}
+
+// newHTTPClient returns the client deliveries are sent with. It does not
+// follow redirects, and unless allowInsecure is set it refuses to connect to
+// non-public addresses, whatever the host name resolves to.
+func newHTTPClient(timeout time.Duration, allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return ErrPrivateTarget
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublic reports whether ip is a globally routable unicast address
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
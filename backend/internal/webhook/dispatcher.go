@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxBackoff caps the wait between attempts of the same delivery
+	maxBackoff = 6 * time.Hour
+
+	// maxErrorLength bounds the response excerpt kept in last_error
+	maxErrorLength = 512
+
+	userAgent = "LegacyChain-Webhooks/1.0"
+)
+
+// Dispatcher sends queued webhook deliveries. A 2xx response marks a delivery
+// as delivered; anything else is retried with exponential backoff until the
+// configured attempts run out, when the delivery moves to the dead-letter
+// table for manual replay.
+//
+// Due deliveries are claimed with SELECT ... FOR UPDATE SKIP LOCKED and
+// leased for the duration of an attempt, so several replicas can dispatch
+// without sending the same delivery twice. Deliveries to one endpoint are not
+// guaranteed to arrive in order.
+type Dispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	cfg    config.WebhookConfig
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(db *gorm.DB, cfg *config.Config) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: newHTTPClient(cfg.Webhooks.Timeout, cfg.Webhooks.AllowInsecure),
+		cfg:    cfg.Webhooks,
+	}
+}
+
+// Run sends due deliveries every poll interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Webhook dispatcher error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll claims one batch of due deliveries and attempts each of them
+func (d *Dispatcher) Poll(ctx context.Context) error {
+	deliveries, err := d.claim(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := d.attempt(ctx, &deliveries[i]); err != nil {
+			log.Printf("Failed to record webhook delivery %s: %v", deliveries[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// claim selects due deliveries of active webhooks and pushes their next
+// attempt past the lease, so other replicas skip them meanwhile
+func (d *Dispatcher) claim(now time.Time) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: models.WebhookDelivery{}.TableName()}, Options: "SKIP LOCKED"}).
+			Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id AND webhooks.deleted_at IS NULL AND webhooks.active").
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("webhook_deliveries.next_attempt_at").
+			Limit(d.cfg.BatchSize).
+			Find(&deliveries).Error; err != nil {
+			return fmt.Errorf("failed to query due deliveries: %w", err)
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]interface{}, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		if err := tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(d.cfg.Timeout+time.Minute)).Error; err != nil {
+			return fmt.Errorf("failed to lease deliveries: %w", err)
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// attempt sends a claimed delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var webhook models.Webhook
+	if err := d.db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		return fmt.Errorf("failed to load webhook: %w", err)
+	}
+
+	status, sendErr := d.send(ctx, &webhook, delivery)
+	now := time.Now()
+	attempts := delivery.Attempts + 1
+
+	if sendErr == nil {
+		return d.db.Model(delivery).Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryDelivered,
+			"attempts":        attempts,
+			"response_status": status,
+			"last_error":      "",
+			"delivered_at":    now,
+		}).Error
+	}
+
+	lastError := truncate(sendErr.Error(), maxErrorLength)
+	if d.cfg.MaxAttempts > 0 && attempts >= d.cfg.MaxAttempts {
+		log.Printf("Webhook delivery %s to %s dead-lettered after %d attempts: %s", delivery.ID, webhook.URL, attempts, lastError)
+
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(delivery).Updates(map[string]interface{}{
+				"status":          models.WebhookDeliveryDead,
+				"attempts":        attempts,
+				"response_status": status,
+				"last_error":      lastError,
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.WebhookDeadLetter{
+				DeliveryID: delivery.ID,
+				WebhookID:  delivery.WebhookID,
+				EventType:  delivery.EventType,
+				Attempts:   attempts,
+				LastError:  lastError,
+			}).Error
+		})
+	}
+
+	return d.db.Model(delivery).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": status,
+		"last_error":      lastError,
+		"next_attempt_at": now.Add(d.backoff(attempts)),
+	}).Error
+}
+
+// send POSTs the stored payload, signed with the webhook's secret. It returns
+// the response status, if any, and an error unless the status is 2xx.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (*int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	status := resp.StatusCode
+	if status >= 200 && status < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorLength))
+		return &status, nil
+	}
+
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	return &status, fmt.Errorf("endpoint responded with %d: %s", status, strings.TrimSpace(string(excerpt)))
+}
+
+// backoff returns the wait after a delivery's attempts-th failure
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.cfg.RetryBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// truncate shortens s to at most n bytes of valid UTF-8, as Postgres text requires
+func truncate(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.ToValidUTF8(s, "")
+}
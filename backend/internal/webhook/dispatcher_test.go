@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/config"
+	"github.com/haneumLee/legacychain/backend/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedRequest is a delivery recorded by a test endpoint
+type receivedRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// startEndpoint starts a local webhook endpoint that responds with status
+func startEndpoint(t *testing.T, status int) (*httptest.Server, func() []receivedRequest) {
+	var (
+		mu       sync.Mutex
+		received []receivedRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{Header: r.Header.Clone(), Body: body})
+		mu.Unlock()
+
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest(nil), received...)
+	}
+}
+
+func testDispatcher(allowInsecure bool) *Dispatcher {
+	return NewDispatcher(nil, &config.Config{Webhooks: config.WebhookConfig{
+		Timeout:       2 * time.Second,
+		MaxAttempts:   5,
+		RetryBackoff:  30 * time.Second,
+		AllowInsecure: allowInsecure,
+	}})
+}
+
+func testDelivery() *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:        uuid.New(),
+		EventType: models.WebhookEventVaultUnlocked,
+		Payload:   `{"type":"vault.unlocked","data":{"unlocked_at":"2026-01-01T00:00:00Z"}}`,
+	}
+}
+
+// Test that a delivery is POSTed with its headers and a verifiable signature
+func TestSendSigned(t *testing.T) {
+	server, received := startEndpoint(t, http.StatusNoContent)
+	hook := &models.Webhook{URL: server.URL + "/hooks", Secret: "whsec_test"}
+	delivery := testDelivery()
+
+	status, err := testDispatcher(true).send(context.Background(), hook, delivery)
+	require.NoError(t, err)
+	require.NotNil(t, status)
+	assert.Equal(t, http.StatusNoContent, *status)
+
+	requests := received()
+	require.Len(t, requests, 1)
+	req := requests[0]
+	assert.Equal(t, delivery.Payload, string(req.Body))
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "vault.unlocked", req.Header.Get(EventHeader))
+	assert.Equal(t, delivery.ID.String(), req.Header.Get(DeliveryHeader))
+	assert.NoError(t, Verify("whsec_test", req.Header.Get(SignatureHeader), req.Body, time.Minute, time.Now()))
+	assert.ErrorIs(t, Verify("whsec_other", req.Header.Get(SignatureHeader), req.Body, time.Minute, time.Now()), ErrInvalidSignature)
+}
+
+// Test that non-2xx responses fail with the status and a response excerpt
+func TestSendErrorResponse(t *testing.T) {
+	server, _ := startEndpoint(t, http.StatusServiceUnavailable)
+	hook := &models.Webhook{URL: server.URL, Secret: "whsec_test"}
+
+	status, err := testDispatcher(true).send(context.Background(), hook, testDelivery())
+	require.Error(t, err)
+	require.NotNil(t, status)
+	assert.Equal(t, http.StatusServiceUnavailable, *status)
+	assert.Contains(t, err.Error(), "503")
+	assert.Contains(t, err.Error(), "Service Unavailable")
+}
+
+// Test that redirects are not followed and count as failures
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	target, received := startEndpoint(t, http.StatusOK)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	t.Cleanup(redirect.Close)
+
+	status, err := testDispatcher(true).send(context.Background(), &models.Webhook{URL: redirect.URL, Secret: "whsec_test"}, testDelivery())
+	require.Error(t, err)
+	assert.Equal(t, http.StatusFound, *status)
+	assert.Empty(t, received())
+}
+
+// Test that loopback endpoints are refused unless insecure targets are allowed
+func TestSendRefusesPrivateTargets(t *testing.T) {
+	server, received := startEndpoint(t, http.StatusOK)
+	hook := &models.Webhook{URL: server.URL, Secret: "whsec_test"}
+
+	status, err := testDispatcher(false).send(context.Background(), hook, testDelivery())
+	assert.ErrorIs(t, err, ErrPrivateTarget)
+	assert.Nil(t, status)
+	assert.Empty(t, received())
+}
+
+// Test that a slow endpoint fails after the configured timeout
+func TestSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	dispatcher := testDispatcher(true)
+	dispatcher.client.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := dispatcher.send(context.Background(), &models.Webhook{URL: server.URL, Secret: "whsec_test"}, testDelivery())
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestBackoff(t *testing.T) {
+	dispatcher := testDispatcher(true)
+
+	assert.Equal(t, 30*time.Second, dispatcher.backoff(1))
+	assert.Equal(t, time.Minute, dispatcher.backoff(2))
+	assert.Equal(t, 2*time.Minute, dispatcher.backoff(3))
+	assert.Equal(t, maxBackoff, dispatcher.backoff(20))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "abc", truncate("abcdef", 3))
+	// A multi-byte rune cut in half is dropped
+	assert.Equal(t, "a", truncate("a€", 2))
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
+	"github.com/haneumLee/legacychain/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Event is the JSON body POSTed to webhook endpoints
+type Event struct {
+	ID          uuid.UUID               `json:"id"` // Delivery ID, also sent in DeliveryHeader
+	Type        models.WebhookEventType `json:"type"`
+	CreatedAt   time.Time               `json:"created_at"`
+	Vault       EventVault              `json:"vault"`
+	Transaction EventTransaction        `json:"transaction"`
+	Data        interface{}             `json:"data"`
+}
+
+type EventVault struct {
+	ID              uuid.UUID `json:"id"`
+	VaultID         int64     `json:"vault_id"`
+	ContractAddress string    `json:"contract_address"`
+}
+
+type EventTransaction struct {
+	Hash        string `json:"hash"`
+	BlockNumber uint64 `json:"block_number"`
+	LogIndex    uint   `json:"log_index"`
+}
+
+// Enqueue queues an event for every active webhook subscribed to it. It runs
+// inside the indexer's transaction, so deliveries exist exactly when the
+// event was applied.
+func Enqueue(tx *gorm.DB, vault *models.Vault, eventType models.WebhookEventType, vLog types.Log, data interface{}) error {
+	heirUsers := tx.Model(&models.User{}).
+		Select("users.id").
+		Joins("JOIN heirs ON LOWER(heirs.address) = LOWER(users.address) AND heirs.deleted_at IS NULL").
+		Where("heirs.vault_id = ?", vault.ID)
+
+	var webhooks []models.Webhook
+	if err := tx.Where("active = ? AND ',' || event_types || ',' LIKE ?", true, "%,"+string(eventType)+",%").
+		Where("all_vaults = ? OR user_id = ? OR user_id IN (?)", true, vault.OwnerID, heirUsers).
+		Find(&webhooks).Error; err != nil {
+		return fmt.Errorf("failed to query webhooks: %w", err)
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		delivery := models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     webhook.ID,
+			EventType:     eventType,
+			VaultID:       vault.ID,
+			TxHash:        vLog.TxHash.Hex(),
+			LogIndex:      vLog.Index,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+
+		payload, err := json.Marshal(Event{
+			ID:        delivery.ID,
+			Type:      eventType,
+			CreatedAt: now.UTC(),
+			Vault: EventVault{
+				ID:              vault.ID,
+				VaultID:         vault.VaultID,
+				ContractAddress: vault.ContractAddress,
+			},
+			Transaction: EventTransaction{
+				Hash:        vLog.TxHash.Hex(),
+				BlockNumber: vLog.BlockNumber,
+				LogIndex:    vLog.Index,
+			},
+			Data: data,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook event: %w", err)
+		}
+		delivery.Payload = string(payload)
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&delivery).Error; err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+
+	return nil
+}
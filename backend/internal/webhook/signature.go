@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-LegacyChain-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256>
+	EventHeader     = "X-LegacyChain-Event"
+	DeliveryHeader  = "X-LegacyChain-Delivery" // Same on every attempt, for deduplication
+)
+
+// secretPrefix marks webhook signing secrets
+const secretPrefix = "whsec_"
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// NewSecret generates a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header for body sent at timestamp. The HMAC
+// covers "<timestamp>.<body>", so a captured request cannot be replayed
+// later with a fresh timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := timestamp.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", t, hex.EncodeToString(mac(secret, t, body)))
+}
+
+// Verify checks a signature header against body, as receivers should. It
+// rejects signatures made more than tolerance away from now.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var (
+		t          int64
+		haveT      bool
+		signatures [][]byte
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			t, haveT = parsed, true
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if !haveT || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(t, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	expected := mac(secret, t, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret string, t int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(t, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	require.NoError(t, err)
+	b, err := NewSecret()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(a, secretPrefix))
+	assert.Len(t, a, len(secretPrefix)+64)
+	assert.NotEqual(t, a, b)
+}
+
+// Test that a signature verifies for the same secret, body and time window
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"vault.unlocked"}`)
+	now := time.Unix(1767225600, 0)
+	header := Sign("whsec_test", now, body)
+
+	assert.True(t, strings.HasPrefix(header, "t=1767225600,v1="))
+	assert.NoError(t, Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)))
+}
+
+func TestVerifyRejects(t *testing.T) {
+	body := []byte(`{"type":"vault.unlocked"}`)
+	now := time.Unix(1767225600, 0)
+	header := Sign("whsec_test", now, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		err    error
+	}{
+		{"wrong secret", "whsec_other", header, body, now, ErrInvalidSignature},
+		{"tampered body", "whsec_test", header, []byte(`{"type":"vault.withdrawn"}`), now, ErrInvalidSignature},
+		{"old timestamp", "whsec_test", header, body, now.Add(10 * time.Minute), ErrSignatureExpired},
+		{"future timestamp", "whsec_test", header, body, now.Add(-10 * time.Minute), ErrSignatureExpired},
+		{"missing timestamp", "whsec_test", header[strings.Index(header, "v1="):], body, now, ErrInvalidSignature},
+		{"missing signature", "whsec_test", "t=1767225600", body, now, ErrInvalidSignature},
+		{"replayed timestamp", "whsec_test", strings.Replace(header, "t=1767225600", "t=1767225660", 1), body, now, ErrInvalidSignature},
+		{"garbage", "whsec_test", "nonsense", body, now, ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now), tt.err)
+		})
+	}
+}
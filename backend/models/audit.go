@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type VaultAuditAction string
+
+const (
+	VaultAuditPause   VaultAuditAction = "pause"
+	VaultAuditUnpause VaultAuditAction = "unpause"
+)
+
+// VaultAuditLog records who paused or unpaused a vault, when and why. Entries
+// are created when the owner sends the transaction through the API and
+// confirmed by the indexer from the Paused and Unpaused events; pauses sent
+// outside the API are recorded by the indexer alone, without a reason.
+type VaultAuditLog struct {
+	ID          uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	VaultID     uuid.UUID        `gorm:"type:uuid;not null;index" json:"vault_id"`
+	Action      VaultAuditAction `gorm:"type:varchar(20);not null" json:"action"`
+	Actor       string           `gorm:"type:varchar(42);not null" json:"actor"`
+	Reason      string           `gorm:"type:text" json:"reason,omitempty"`
+	TxHash      string           `gorm:"type:varchar(66);uniqueIndex;not null" json:"tx_hash"`
+	RequestedAt *time.Time       `json:"requested_at,omitempty"` // Sent through the API
+	BlockNumber *uint64          `json:"block_number,omitempty"`
+	ConfirmedAt *time.Time       `json:"confirmed_at,omitempty"` // Block time of the event
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+func (l *VaultAuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+func (VaultAuditLog) TableName() string {
+	return "vault_audit_logs"
+}
@@ -21,7 +21,7 @@ type Heartbeat struct {
 	CommitHash  string          `gorm:"type:varchar(66)" json:"commit_hash"`  // Hash of the commit
 	CommitTxHash string         `gorm:"type:varchar(66)" json:"commit_tx_hash"` // Commit transaction hash
 	RevealTxHash string         `gorm:"type:varchar(66)" json:"reveal_tx_hash"` // Reveal transaction hash
-	Nonce       string          `gorm:"type:varchar(100)" json:"nonce"`       // Nonce used for commit
+	Nonce       string          `gorm:"type:varchar(100)" json:"nonce,omitempty"` // Nonce used for commit, hidden from heirs
 	Status      HeartbeatStatus `gorm:"type:varchar(20);not null;default:'committed'" json:"status"`
 	CommittedAt time.Time       `gorm:"index" json:"committed_at"`
 	RevealedAt  *time.Time      `json:"revealed_at,omitempty"`
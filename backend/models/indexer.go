@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IndexerCursor stores the last block fully processed by a chain indexer,
+// so a restart resumes from where it stopped.
+type IndexerCursor struct {
+	Name        string    `gorm:"type:varchar(50);primary_key" json:"name"`
+	BlockNumber uint64    `gorm:"not null" json:"block_number"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (IndexerCursor) TableName() string {
+	return "indexer_cursors"
+}
+
+// IndexedBlock is the hash of a recently indexed block, kept for the reorg
+// window so the indexer can find the common ancestor after a reorganization.
+type IndexedBlock struct {
+	Number     uint64    `gorm:"primary_key;autoIncrement:false" json:"number"`
+	Hash       string    `gorm:"type:varchar(66);not null" json:"hash"`
+	ParentHash string    `gorm:"type:varchar(66);not null" json:"parent_hash"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (IndexedBlock) TableName() string {
+	return "indexed_blocks"
+}
+
+// ChainEvent is a contract log applied by the indexer. Undo holds the derived
+// state it overwrote, so the event can be reverted if its block is reorged out.
+type ChainEvent struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	BlockNumber     uint64     `gorm:"not null;index" json:"block_number"`
+	BlockHash       string     `gorm:"type:varchar(66);not null" json:"block_hash"`
+	TxHash          string     `gorm:"type:varchar(66);not null;uniqueIndex:idx_chain_events_log" json:"tx_hash"`
+	LogIndex        uint       `gorm:"not null;uniqueIndex:idx_chain_events_log" json:"log_index"`
+	ContractAddress string     `gorm:"type:varchar(42);not null" json:"contract_address"`
+	EventName       string     `gorm:"type:varchar(50);not null" json:"event_name"`
+	VaultID         *uuid.UUID `gorm:"type:uuid;index" json:"vault_id,omitempty"`
+	Undo            string     `gorm:"type:jsonb" json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func (e *ChainEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ChainEvent) TableName() string {
+	return "chain_events"
+}
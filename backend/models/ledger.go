@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LedgerEntryKind string
+
+const (
+	LedgerEntryDeposit    LedgerEntryKind = "deposit"
+	LedgerEntryWithdrawal LedgerEntryKind = "withdrawal" // Owner withdrawal while the vault is locked
+	LedgerEntryClaim      LedgerEntryKind = "claim"      // Heir payout after unlock
+)
+
+// VaultLedgerEntry is a movement of funds in or out of a vault, recorded by
+// the indexer from Deposited, Withdrawn and InheritanceClaimed events. The
+// entries of a vault sum to its Balance.
+type VaultLedgerEntry struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primary_key" json:"id"`
+	VaultID     uuid.UUID       `gorm:"type:uuid;not null;index" json:"vault_id"`
+	Kind        LedgerEntryKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Address     string          `gorm:"type:varchar(42);not null" json:"address"` // Depositor, owner or heir
+	Amount      string          `gorm:"type:numeric(78,0);not null" json:"amount"`
+	TxHash      string          `gorm:"type:varchar(66);not null;uniqueIndex:idx_vault_ledger_log" json:"tx_hash"`
+	LogIndex    uint            `gorm:"not null;uniqueIndex:idx_vault_ledger_log" json:"log_index"`
+	BlockNumber uint64          `gorm:"not null;index" json:"block_number"`
+	Timestamp   time.Time       `gorm:"not null" json:"timestamp"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (e *VaultLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (VaultLedgerEntry) TableName() string {
+	return "vault_ledger_entries"
+}
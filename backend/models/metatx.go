@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MetaTransaction is an EIP-712 forward request the relayer submitted on behalf
+// of a user. The digest is unique, so the same signed request is relayed once.
+type MetaTransaction struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Signer    string    `gorm:"type:varchar(42);not null;index:idx_meta_transactions_signer_nonce" json:"signer"`
+	Nonce     uint64    `gorm:"not null;index:idx_meta_transactions_signer_nonce" json:"nonce"` // Forwarder nonce
+	VaultID   uuid.UUID `gorm:"type:uuid;not null;index" json:"vault_id"`
+	Method    string    `gorm:"type:varchar(50);not null" json:"method"`
+	Deadline  time.Time `gorm:"not null" json:"deadline"`
+	Digest    string    `gorm:"type:varchar(66);uniqueIndex;not null" json:"digest"`
+	TxHash    string    `gorm:"type:varchar(66);index" json:"tx_hash"` // Relayer transaction (see transactions.hash)
+	GasLimit  uint64    `gorm:"not null" json:"gas_limit"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m *MetaTransaction) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+func (MetaTransaction) TableName() string {
+	return "meta_transactions"
+}
+
+// GasBudget is the gas the relayer has spent for an address in the current window
+type GasBudget struct {
+	Address     string    `gorm:"type:varchar(42);primary_key" json:"address"`
+	WindowStart time.Time `gorm:"not null" json:"window_start"`
+	GasUsed     uint64    `gorm:"not null;default:0" json:"gas_used"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (GasBudget) TableName() string {
+	return "gas_budgets"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReminderKind string
+
+const (
+	ReminderKindHeartbeat   ReminderKind = "heartbeat"    // Before the heartbeat deadline
+	ReminderKindGracePeriod ReminderKind = "grace_period" // Escalation once the vault is unlocked
+)
+
+// HeartbeatReminder is a notification sent to a vault owner for one deadline.
+// A row is claimed before sending so each reminder goes out once, even with
+// several API replicas, and deleted again if delivery fails.
+type HeartbeatReminder struct {
+	ID            uuid.UUID    `gorm:"type:uuid;primary_key" json:"id"`
+	VaultID       uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_heartbeat_reminders_stage" json:"vault_id"`
+	Kind          ReminderKind `gorm:"type:varchar(20);not null;uniqueIndex:idx_heartbeat_reminders_stage" json:"kind"`
+	Deadline      time.Time    `gorm:"not null;uniqueIndex:idx_heartbeat_reminders_stage" json:"deadline"`       // Heartbeat deadline or end of the grace period
+	OffsetSeconds int64        `gorm:"not null;uniqueIndex:idx_heartbeat_reminders_stage" json:"offset_seconds"` // Before the deadline (0 for escalations)
+	Email         string       `gorm:"type:varchar(255);not null" json:"email"`
+	SentAt        *time.Time   `json:"sent_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+func (r *HeartbeatReminder) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (HeartbeatReminder) TableName() string {
+	return "heartbeat_reminders"
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session is one login of a user. Its refresh token is stored only as a
+// SHA-256 hash and replaced on every refresh; the hash it replaced is kept so
+// that replaying an already rotated (likely stolen) token revokes the session.
+type Session struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"type:varchar(64);index" json:"-"`
+	ExpiresAt         time.Time  `gorm:"not null" json:"expires_at"` // Of the current refresh token
+	LastRefreshedAt   *time.Time `json:"last_refreshed_at,omitempty"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TransactionStatus string
+
+const (
+	TransactionStatusPending   TransactionStatus = "pending"
+	TransactionStatusMined     TransactionStatus = "mined"     // Included with a successful receipt
+	TransactionStatusReverted  TransactionStatus = "reverted"  // Included but execution failed
+	TransactionStatusDropped   TransactionStatus = "dropped"   // Never mined and no longer known to the node
+	TransactionStatusCancelled TransactionStatus = "cancelled" // Its nonce was taken by a zero-value self-transfer
+)
+
+// Transaction is a vault transaction broadcast by the backend. The tracker
+// follows it until it is mined, reverted or dropped, and only then updates
+// the linked heartbeat, heir and vault rows, unless the indexer does. Hash is
+// the latest broadcast for the nonce; hashes it replaced are kept as
+// TransactionReplacement rows.
+type Transaction struct {
+	ID           uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	Hash         string            `gorm:"type:varchar(66);uniqueIndex;not null" json:"hash"`
+	FromAddress  string            `gorm:"type:varchar(42);not null;index" json:"from_address"`
+	ToAddress    string            `gorm:"type:varchar(42);not null" json:"to_address"`
+	VaultID      *uuid.UUID        `gorm:"type:uuid;index" json:"vault_id,omitempty"`
+	HeartbeatID  *uuid.UUID        `gorm:"type:uuid;index" json:"heartbeat_id,omitempty"`
+	HeirID       *uuid.UUID        `gorm:"type:uuid;index" json:"heir_id,omitempty"`
+	Method       string            `gorm:"type:varchar(50)" json:"method"` // Contract method called
+	Nonce        uint64            `gorm:"not null" json:"nonce"`
+	Status       TransactionStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	BlockNumber  *uint64           `json:"block_number,omitempty"`
+	GasUsed      uint64            `json:"gas_used,omitempty"`
+	RevertReason string            `gorm:"type:text" json:"revert_reason,omitempty"`
+	CancelTxHash string            `gorm:"type:varchar(66)" json:"cancel_tx_hash,omitempty"` // Set once the nonce is being cancelled
+	ReplacedAt   *time.Time        `json:"replaced_at,omitempty"`                            // Last fee-bumped re-broadcast
+	ConfirmedAt  *time.Time        `json:"confirmed_at,omitempty"`                           // When the final status was recorded
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+func (t *Transaction) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+func (Transaction) TableName() string {
+	return "transactions"
+}
+
+// TransactionReplacement is a broadcast superseded by a same-nonce replacement
+// with higher fees. Either hash may end up mined, so the tracker checks both.
+type TransactionReplacement struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	TransactionID uuid.UUID `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	Hash          string    `gorm:"type:varchar(66);uniqueIndex;not null" json:"hash"` // Superseded hash
+	ReplacedBy    string    `gorm:"type:varchar(66);not null" json:"replaced_by"`      // Hash of the replacement
+	Cancel        bool      `gorm:"not null;default:false" json:"cancel"`              // Replacement is a cancellation
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (r *TransactionReplacement) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (TransactionReplacement) TableName() string {
+	return "transaction_replacements"
+}
@@ -1,17 +1,75 @@
 package models
 
 import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Roles give operations staff access beyond their own vaults
+const (
+	RoleAdmin   = "admin"   // Everything, including signer nonces and roles
+	RoleSupport = "support" // Read everything and run keeper rounds
+	RoleAuditor = "auditor" // Read everything
+)
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleSupport, RoleAuditor:
+		return true
+	}
+	return false
+}
+
+// Roles are stored as a comma-separated list
+type Roles []string
+
+// Has reports whether role is among the roles
+func (r Roles) Has(role string) bool {
+	for _, have := range r {
+		if have == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer
+func (r Roles) Value() (driver.Value, error) {
+	return strings.Join(r, ","), nil
+}
+
+// Scan implements sql.Scanner
+func (r *Roles) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Roles", value)
+	}
+
+	*r = nil
+	if s != "" {
+		*r = strings.Split(s, ",")
+	}
+	return nil
+}
+
 type User struct {
 	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
 	Address   string         `gorm:"type:varchar(42);uniqueIndex;not null" json:"address"`
 	Email     string         `gorm:"type:varchar(255);index" json:"email,omitempty"`
 	Nickname  string         `gorm:"type:varchar(100)" json:"nickname,omitempty"`
+	Roles     Roles          `gorm:"type:varchar(100);not null;default:''" json:"roles,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
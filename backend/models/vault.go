@@ -25,8 +25,10 @@ type Vault struct {
 	HeartbeatInterval int64          `gorm:"not null" json:"heartbeat_interval"`
 	GracePeriod       int64          `gorm:"not null" json:"grace_period"`
 	RequiredApprovals int            `gorm:"not null" json:"required_approvals"`
+	Paused            bool           `gorm:"default:false" json:"paused"`
 	LastHeartbeat     *time.Time     `json:"last_heartbeat,omitempty"`
 	UnlockedAt        *time.Time     `json:"unlocked_at,omitempty"`
+	GracePeriodEndsAt *time.Time     `json:"grace_period_ends_at,omitempty"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
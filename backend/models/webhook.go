@@ -0,0 +1,135 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEventType string
+
+const (
+	WebhookEventHeartbeatRevealed   WebhookEventType = "heartbeat.revealed"
+	WebhookEventGracePeriodStarted  WebhookEventType = "vault.grace_period_started"
+	WebhookEventVaultUnlocked       WebhookEventType = "vault.unlocked"
+	WebhookEventInheritanceApproved WebhookEventType = "inheritance.approved"
+	WebhookEventInheritanceClaimed  WebhookEventType = "inheritance.claimed"
+	WebhookEventWithdrawn           WebhookEventType = "vault.withdrawn"
+)
+
+// WebhookEventTypes lists the event types an endpoint can subscribe to
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventHeartbeatRevealed,
+	WebhookEventGracePeriodStarted,
+	WebhookEventVaultUnlocked,
+	WebhookEventInheritanceApproved,
+	WebhookEventInheritanceClaimed,
+	WebhookEventWithdrawn,
+}
+
+// Webhook is an endpoint a user registered to receive vault lifecycle events.
+// It receives the events of vaults its user owns or is an heir of, or of
+// every vault if AllVaults is set (admins only).
+type Webhook struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	UserID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	URL        string         `gorm:"type:varchar(2048);not null" json:"url"`
+	Secret     string         `gorm:"type:varchar(100);not null" json:"-"` // HMAC-SHA256 signing key
+	EventTypes string         `gorm:"type:text;not null" json:"-"`         // Comma-separated WebhookEventType values
+	AllVaults  bool           `gorm:"default:false" json:"all_vaults"`
+	Active     bool           `gorm:"default:true" json:"active"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Events returns the event types the webhook is subscribed to
+func (w *Webhook) Events() []WebhookEventType {
+	var events []WebhookEventType
+	for _, event := range strings.Split(w.EventTypes, ",") {
+		if event != "" {
+			events = append(events, WebhookEventType(event))
+		}
+	}
+	return events
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryDead      WebhookDeliveryStatus = "dead" // Moved to webhook_dead_letters
+)
+
+// WebhookDelivery is one event queued for one endpoint. The indexer creates it
+// in the same database transaction as the event, and the dispatcher sends the
+// stored payload until the endpoint accepts it or attempts run out.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;primary_key" json:"id"`
+	WebhookID      uuid.UUID             `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_deliveries_event" json:"webhook_id"`
+	EventType      WebhookEventType      `gorm:"type:varchar(50);not null" json:"event_type"`
+	VaultID        uuid.UUID             `gorm:"type:uuid;not null;index" json:"vault_id"`
+	TxHash         string                `gorm:"type:varchar(66);not null;uniqueIndex:idx_webhook_deliveries_event" json:"tx_hash"`
+	LogIndex       uint                  `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event" json:"log_index"`
+	Payload        string                `gorm:"type:jsonb;not null" json:"-"` // Event JSON, sent as is on every attempt
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time             `gorm:"not null;index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	ResponseStatus *int                  `json:"response_status,omitempty"` // Of the last attempt
+	LastError      string                `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeadLetter records a delivery that failed every attempt. Replaying
+// it puts the delivery back in the queue with its original payload.
+type WebhookDeadLetter struct {
+	ID         uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
+	DeliveryID uuid.UUID        `gorm:"type:uuid;not null;index" json:"delivery_id"`
+	WebhookID  uuid.UUID        `gorm:"type:uuid;not null;index" json:"webhook_id"`
+	EventType  WebhookEventType `gorm:"type:varchar(50);not null" json:"event_type"`
+	Attempts   int              `gorm:"not null" json:"attempts"`
+	LastError  string           `gorm:"type:text" json:"last_error,omitempty"`
+	ReplayedAt *time.Time       `json:"replayed_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+
+	// Relationships
+	Delivery WebhookDelivery `gorm:"foreignKey:DeliveryID" json:"delivery,omitempty"`
+}
+
+func (d *WebhookDeadLetter) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+func (WebhookDeadLetter) TableName() string {
+	return "webhook_dead_letters"
+}
@@ -0,0 +1,83 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// EIP1271MagicValue is what isValidSignature(bytes32,bytes) returns for a
+// valid signature: bytes4(keccak256("isValidSignature(bytes32,bytes)"))
+var EIP1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+// ErrNotContract is returned when EIP-1271 verification is asked of an
+// account without code, e.g. an externally owned account
+var ErrNotContract = errors.New("account is not a contract")
+
+// isValidSignatureArgs are the ABI arguments of isValidSignature(bytes32,bytes)
+var isValidSignatureArgs = abi.Arguments{
+	{Type: mustNewType("bytes32")},
+	{Type: mustNewType("bytes")},
+}
+
+func mustNewType(t string) abi.Type {
+	typ, err := abi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	return typ
+}
+
+// VerifyEIP1271 asks a smart-contract account, such as a Safe multisig,
+// whether signature is valid for hash by calling its
+// isValidSignature(bytes32,bytes) (EIP-1271). A call that reverts counts as
+// an invalid signature, since that is how most wallets reject one.
+//
+// Parameters:
+//   - caller: RPC client used for eth_getCode and eth_call
+//   - account: The contract account that supposedly signed
+//   - hash: The signed digest, e.g. the EIP-191 hash from HashMessage
+//   - signature: Wallet-specific signature bytes, passed through as is
+//
+// Returns:
+//   - bool: true if the contract returned the magic value
+//   - error: ErrNotContract if account has no code, or an RPC error
+func VerifyEIP1271(ctx context.Context, caller bind.ContractCaller, account common.Address, hash common.Hash, signature []byte) (bool, error) {
+	code, err := caller.CodeAt(ctx, account, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to get account code: %w", err)
+	}
+	if len(code) == 0 {
+		return false, ErrNotContract
+	}
+
+	args, err := isValidSignatureArgs.Pack(hash, signature)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode isValidSignature call: %w", err)
+	}
+	selector := crypto.Keccak256([]byte("isValidSignature(bytes32,bytes)"))[:4]
+
+	result, err := caller.CallContract(ctx, ethereum.CallMsg{
+		To:   &account,
+		Data: append(selector, args...),
+	}, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "execution reverted") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to call isValidSignature: %w", err)
+	}
+
+	// The result is an ABI-encoded bytes4: the value, then 28 zero bytes
+	return len(result) == 32 &&
+		bytes.Equal(result[:4], EIP1271MagicValue[:]) &&
+		bytes.Equal(result[4:], make([]byte, 28)), nil
+}
@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// approvedHashWallet returns the runtime code of a minimal EIP-1271 wallet
+// that, like a Safe with an on-chain signed message, accepts any signature for
+// one approved hash and returns 0xffffffff for every other hash:
+//
+//	PUSH1 0x04 CALLDATALOAD PUSH32 <hash> EQ PUSH1 valid JUMPI
+//	PUSH4 0xffffffff PUSH1 0xe0 SHL PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+//	valid: JUMPDEST
+//	PUSH4 0x1626ba7e PUSH1 0xe0 SHL PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+func approvedHashWallet(hash common.Hash) []byte {
+	code := []byte{0x60, 0x04, 0x35, 0x7f}
+	code = append(code, hash.Bytes()...)
+	code = append(code,
+		0x14, 0x60, 0x38, 0x57,
+		0x63, 0xff, 0xff, 0xff, 0xff, 0x60, 0xe0, 0x1b, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3,
+		0x5b,
+		0x63, 0x16, 0x26, 0xba, 0x7e, 0x60, 0xe0, 0x1b, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3,
+	)
+	return code
+}
+
+// revertingWallet always reverts, as Safe does for an invalid signature:
+// PUSH1 0 PUSH1 0 REVERT
+var revertingWallet = []byte{0x60, 0x00, 0x60, 0x00, 0xfd}
+
+func TestVerifyEIP1271(t *testing.T) {
+	approved := HashMessage("localhost:3000 wants you to sign in with your Ethereum account:")
+	approvedHash := common.HexToHash(approved)
+
+	wallet := common.HexToAddress("0x00000000000000000000000000000000000a1271")
+	reverting := common.HexToAddress("0x00000000000000000000000000000000000b1271")
+	eoa := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		wallet:    {Code: approvedHashWallet(approvedHash), Balance: big.NewInt(0)},
+		reverting: {Code: revertingWallet, Balance: big.NewInt(0)},
+	})
+	t.Cleanup(func() { backend.Close() })
+	client := backend.Client()
+	ctx := context.Background()
+
+	t.Run("approved hash", func(t *testing.T) {
+		valid, err := VerifyEIP1271(ctx, client, wallet, approvedHash, []byte{})
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("other hash", func(t *testing.T) {
+		valid, err := VerifyEIP1271(ctx, client, wallet, common.HexToHash(HashMessage("something else")), []byte{0x01})
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("reverting wallet", func(t *testing.T) {
+		valid, err := VerifyEIP1271(ctx, client, reverting, approvedHash, []byte{0x01})
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("externally owned account", func(t *testing.T) {
+		_, err := VerifyEIP1271(ctx, client, eoa, approvedHash, []byte{0x01})
+		assert.ErrorIs(t, err, ErrNotContract)
+	})
+}
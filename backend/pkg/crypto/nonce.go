@@ -3,6 +3,7 @@ package crypto
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -54,6 +55,21 @@ func (nm *NonceManager) GenerateNonce(ctx context.Context) (nonce string, timest
 	return nonce, timestamp, nil
 }
 
+// GenerateSIWENonce is GenerateNonce with the hyphens removed, since EIP-4361
+// nonces must be alphanumeric. It is validated with ValidateNonce like any other.
+func (nm *NonceManager) GenerateSIWENonce(ctx context.Context) (nonce string, timestamp int64, err error) {
+	nonce = strings.ReplaceAll(uuid.New().String(), "-", "")
+	timestamp = time.Now().Unix()
+
+	key := NonceKeyPrefix + nonce
+	err = nm.redis.Set(ctx, key, timestamp, NonceExpiration).Err()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to store nonce in Redis: %w", err)
+	}
+
+	return nonce, timestamp, nil
+}
+
 // ValidateNonce checks if a nonce exists in Redis and has not been used.
 // If valid, it deletes the nonce to prevent reuse (one-time use).
 //
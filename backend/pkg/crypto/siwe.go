@@ -0,0 +1,291 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// SIWEVersion is the only message version defined by EIP-4361
+	SIWEVersion = "1"
+
+	// SIWEClockSkew is how far in the future issued-at and not-before may be
+	SIWEClockSkew = time.Minute
+
+	siwePreamble = " wants you to sign in with your Ethereum account:"
+)
+
+// ErrInvalidSIWEMessage is returned for messages that do not follow EIP-4361
+var ErrInvalidSIWEMessage = errors.New("invalid SIWE message")
+
+// siweNonce is the EIP-4361 nonce: at least 8 alphanumeric characters
+var siweNonce = regexp.MustCompile(`^[a-zA-Z0-9]{8,}$`)
+
+// SIWEMessage is a Sign-In with Ethereum (EIP-4361) message. Unlike a bare
+// nonce, it binds the signature to the site (Domain, URI) and chain it was
+// requested for, so a signature phished on another site cannot log in here.
+type SIWEMessage struct {
+	Scheme         string // Optional, e.g. https
+	Domain         string // RFC 3986 authority requesting the signature, e.g. app.legacychain.io
+	Address        common.Address
+	Statement      string // Optional human-readable line, without newlines
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// String formats the message as the wallet signs it
+//
+// Example output:
+//
+//	app.legacychain.io wants you to sign in with your Ethereum account:
+//	0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2
+//
+//	Sign in to LegacyChain.
+//
+//	URI: https://app.legacychain.io
+//	Version: 1
+//	Chain ID: 1337
+//	Nonce: 550e8400e29b41d4a716446655440000
+//	Issued At: 2024-01-01T00:00:00Z
+func (m *SIWEMessage) String() string {
+	var b strings.Builder
+
+	if m.Scheme != "" {
+		b.WriteString(m.Scheme + "://")
+	}
+	b.WriteString(m.Domain + siwePreamble + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "URI: %s\n", m.URI)
+	fmt.Fprintf(&b, "Version: %s\n", m.Version)
+	fmt.Fprintf(&b, "Chain ID: %d\n", m.ChainID)
+	fmt.Fprintf(&b, "Nonce: %s\n", m.Nonce)
+	fmt.Fprintf(&b, "Issued At: %s", m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		fmt.Fprintf(&b, "\nExpiration Time: %s", m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		fmt.Fprintf(&b, "\nNot Before: %s", m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		fmt.Fprintf(&b, "\nRequest ID: %s", m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, resource := range m.Resources {
+			b.WriteString("\n- " + resource)
+		}
+	}
+
+	return b.String()
+}
+
+// IsSIWEMessage reports whether message looks like an EIP-4361 message, as
+// opposed to the legacy login format
+func IsSIWEMessage(message string) bool {
+	firstLine, _, _ := strings.Cut(message, "\n")
+	return strings.HasSuffix(firstLine, siwePreamble)
+}
+
+// ParseSIWEMessage parses an EIP-4361 message. Fields must appear in the
+// order the specification defines; the address must be EIP-55 checksummed.
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidSIWEMessage, fmt.Sprintf(format, args...))
+	}
+
+	var m SIWEMessage
+
+	// Header: [scheme "://"] domain preamble, then the address and a blank line
+	if len(lines) < 3 || !strings.HasSuffix(lines[0], siwePreamble) {
+		return nil, invalid("missing preamble")
+	}
+	authority := strings.TrimSuffix(lines[0], siwePreamble)
+	if scheme, domain, ok := strings.Cut(authority, "://"); ok {
+		m.Scheme, authority = scheme, domain
+	}
+	if authority == "" || strings.ContainsAny(authority, " /") {
+		return nil, invalid("invalid domain %q", authority)
+	}
+	m.Domain = authority
+
+	if !common.IsHexAddress(lines[1]) || common.HexToAddress(lines[1]).Hex() != lines[1] {
+		return nil, invalid("address must be an EIP-55 checksummed address")
+	}
+	m.Address = common.HexToAddress(lines[1])
+
+	if lines[2] != "" {
+		return nil, invalid("expected a blank line after the address")
+	}
+
+	// Optional statement, followed by a blank line. Some older clients omit
+	// that blank line when there is no statement.
+	i := 3
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		if lines[i] != "" {
+			m.Statement = lines[i]
+			i++
+			if i >= len(lines) || lines[i] != "" {
+				return nil, invalid("expected a blank line after the statement")
+			}
+		}
+		i++
+	}
+
+	// field consumes the next line if it has the given label
+	field := func(label string) (string, bool) {
+		if i < len(lines) && strings.HasPrefix(lines[i], label+": ") {
+			value := strings.TrimPrefix(lines[i], label+": ")
+			i++
+			return value, true
+		}
+		return "", false
+	}
+	required := func(label string) (string, error) {
+		value, ok := field(label)
+		if !ok || value == "" {
+			return "", invalid("missing %s", label)
+		}
+		return value, nil
+	}
+	timestamp := func(label, value string) (time.Time, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, invalid("%s is not an RFC 3339 timestamp", label)
+		}
+		return t, nil
+	}
+
+	var err error
+	if m.URI, err = required("URI"); err != nil {
+		return nil, err
+	}
+	if uri, err := url.Parse(m.URI); err != nil || uri.Scheme == "" {
+		return nil, invalid("URI must be an absolute URI")
+	}
+	if m.Version, err = required("Version"); err != nil {
+		return nil, err
+	}
+	if m.Version != SIWEVersion {
+		return nil, invalid("unsupported version %q", m.Version)
+	}
+	chainID, err := required("Chain ID")
+	if err != nil {
+		return nil, err
+	}
+	if m.ChainID, err = strconv.ParseInt(chainID, 10, 64); err != nil || m.ChainID <= 0 {
+		return nil, invalid("invalid chain ID %q", chainID)
+	}
+	if m.Nonce, err = required("Nonce"); err != nil {
+		return nil, err
+	}
+	if !siweNonce.MatchString(m.Nonce) {
+		return nil, invalid("nonce must be at least 8 alphanumeric characters")
+	}
+	issuedAt, err := required("Issued At")
+	if err != nil {
+		return nil, err
+	}
+	if m.IssuedAt, err = timestamp("Issued At", issuedAt); err != nil {
+		return nil, err
+	}
+	if value, ok := field("Expiration Time"); ok {
+		t, err := timestamp("Expiration Time", value)
+		if err != nil {
+			return nil, err
+		}
+		m.ExpirationTime = &t
+	}
+	if value, ok := field("Not Before"); ok {
+		t, err := timestamp("Not Before", value)
+		if err != nil {
+			return nil, err
+		}
+		m.NotBefore = &t
+	}
+	if value, ok := field("Request ID"); ok {
+		m.RequestID = value
+	}
+	if i < len(lines) && lines[i] == "Resources:" {
+		i++
+		for i < len(lines) && strings.HasPrefix(lines[i], "- ") {
+			m.Resources = append(m.Resources, strings.TrimPrefix(lines[i], "- "))
+			i++
+		}
+	}
+
+	if i < len(lines) {
+		return nil, invalid("unexpected line %q", lines[i])
+	}
+
+	return &m, nil
+}
+
+// SIWEExpectations are what a message must be bound to for this server to
+// accept it
+type SIWEExpectations struct {
+	Domain  string
+	URI     string // Origin the message's URI must belong to, e.g. https://app.legacychain.io
+	ChainID int64
+	MaxAge  time.Duration // Oldest accepted Issued At
+	Now     time.Time
+}
+
+// Validate checks that the message was requested by this site, on this chain,
+// and is within its validity window. It does not check the signature or nonce.
+func (m *SIWEMessage) Validate(expected SIWEExpectations) error {
+	if !strings.EqualFold(m.Domain, expected.Domain) {
+		return fmt.Errorf("domain mismatch: message is for %q", m.Domain)
+	}
+
+	uri, err := url.Parse(m.URI)
+	if err != nil {
+		return fmt.Errorf("invalid URI: %w", err)
+	}
+	origin, err := url.Parse(expected.URI)
+	if err != nil {
+		return fmt.Errorf("invalid expected URI: %w", err)
+	}
+	if !strings.EqualFold(uri.Scheme, origin.Scheme) || !strings.EqualFold(uri.Host, origin.Host) {
+		return fmt.Errorf("URI mismatch: message is for %q", m.URI)
+	}
+
+	if m.ChainID != expected.ChainID {
+		return fmt.Errorf("chain ID mismatch: message is for chain %d", m.ChainID)
+	}
+
+	now := expected.Now
+	if m.IssuedAt.After(now.Add(SIWEClockSkew)) {
+		return fmt.Errorf("message issued in the future")
+	}
+	if expected.MaxAge > 0 && now.Sub(m.IssuedAt) > expected.MaxAge {
+		return fmt.Errorf("message expired: issued %s ago (max: %s)", now.Sub(m.IssuedAt).Truncate(time.Second), expected.MaxAge)
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return fmt.Errorf("message expired at %s", m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil && m.NotBefore.After(now.Add(SIWEClockSkew)) {
+		return fmt.Errorf("message not valid before %s", m.NotBefore.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
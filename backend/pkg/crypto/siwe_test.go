@@ -0,0 +1,214 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var siweIssuedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testSIWEMessage() SIWEMessage {
+	expiresAt := siweIssuedAt.Add(5 * time.Minute)
+	return SIWEMessage{
+		Domain:         "app.legacychain.io",
+		Address:        common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"),
+		Statement:      "Sign in to LegacyChain.",
+		URI:            "https://app.legacychain.io/login",
+		Version:        SIWEVersion,
+		ChainID:        1337,
+		Nonce:          "550e8400e29b41d4a716446655440000",
+		IssuedAt:       siweIssuedAt,
+		ExpirationTime: &expiresAt,
+	}
+}
+
+func testSIWEExpectations() SIWEExpectations {
+	return SIWEExpectations{
+		Domain:  "app.legacychain.io",
+		URI:     "https://app.legacychain.io",
+		ChainID: 1337,
+		MaxAge:  SignatureMaxAge,
+		Now:     siweIssuedAt.Add(time.Minute),
+	}
+}
+
+// Test the formatted message against the layout in the EIP-4361 specification
+func TestSIWEMessageString(t *testing.T) {
+	message := testSIWEMessage()
+
+	expected := "app.legacychain.io wants you to sign in with your Ethereum account:\n" +
+		"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2\n" +
+		"\n" +
+		"Sign in to LegacyChain.\n" +
+		"\n" +
+		"URI: https://app.legacychain.io/login\n" +
+		"Version: 1\n" +
+		"Chain ID: 1337\n" +
+		"Nonce: 550e8400e29b41d4a716446655440000\n" +
+		"Issued At: 2024-01-01T00:00:00Z\n" +
+		"Expiration Time: 2024-01-01T00:05:00Z"
+	assert.Equal(t, expected, message.String())
+	assert.True(t, IsSIWEMessage(message.String()))
+	assert.False(t, IsSIWEMessage(FormatLoginMessage("550e8400", 1673456789)))
+}
+
+func TestParseSIWEMessageRoundTrip(t *testing.T) {
+	notBefore := siweIssuedAt.Add(-time.Minute)
+	withOptional := testSIWEMessage()
+	withOptional.Scheme = "https"
+	withOptional.NotBefore = &notBefore
+	withOptional.RequestID = "req-1"
+	withOptional.Resources = []string{"ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq", "https://example.com/terms"}
+
+	noStatement := testSIWEMessage()
+	noStatement.Statement = ""
+
+	for name, message := range map[string]SIWEMessage{
+		"minimal":      testSIWEMessage(),
+		"all fields":   withOptional,
+		"no statement": noStatement,
+	} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := ParseSIWEMessage(message.String())
+			require.NoError(t, err)
+			assert.Equal(t, message.String(), parsed.String())
+			assert.Equal(t, message.Address, parsed.Address)
+			assert.Equal(t, message.Nonce, parsed.Nonce)
+			assert.Equal(t, message.ChainID, parsed.ChainID)
+			assert.True(t, message.IssuedAt.Equal(parsed.IssuedAt))
+		})
+	}
+}
+
+// Older clients omit the second blank line when there is no statement
+func TestParseSIWEMessageWithoutStatementLine(t *testing.T) {
+	message := "app.legacychain.io wants you to sign in with your Ethereum account:\n" +
+		"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2\n" +
+		"\n" +
+		"URI: https://app.legacychain.io\n" +
+		"Version: 1\n" +
+		"Chain ID: 1\n" +
+		"Nonce: 32891756\n" +
+		"Issued At: 2021-09-30T16:25:24Z"
+
+	parsed, err := ParseSIWEMessage(message)
+	require.NoError(t, err)
+	assert.Empty(t, parsed.Statement)
+	assert.Equal(t, "32891756", parsed.Nonce)
+}
+
+func TestParseSIWEMessageRejectsMalformed(t *testing.T) {
+	valid := testSIWEMessage()
+
+	tests := map[string]func(m *SIWEMessage) string{
+		"legacy message": func(m *SIWEMessage) string {
+			return FormatLoginMessage("550e8400-e29b-41d4-a716-446655440000", 1673456789)
+		},
+		"lowercase address": func(m *SIWEMessage) string {
+			return replaceLine(m.String(), 1, "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2")
+		},
+		"short nonce": func(m *SIWEMessage) string {
+			m.Nonce = "abc"
+			return m.String()
+		},
+		"hyphenated nonce": func(m *SIWEMessage) string {
+			m.Nonce = "550e8400-e29b-41d4-a716-446655440000"
+			return m.String()
+		},
+		"unsupported version": func(m *SIWEMessage) string {
+			m.Version = "2"
+			return m.String()
+		},
+		"relative URI": func(m *SIWEMessage) string {
+			m.URI = "/login"
+			return m.String()
+		},
+		"invalid chain ID": func(m *SIWEMessage) string {
+			return replaceLine(m.String(), 7, "Chain ID: one")
+		},
+		"invalid issued at": func(m *SIWEMessage) string {
+			return replaceLine(m.String(), 9, "Issued At: yesterday")
+		},
+		"trailing text": func(m *SIWEMessage) string {
+			return m.String() + "\nSend 1 ETH to 0x0"
+		},
+	}
+
+	for name, build := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := valid
+			_, err := ParseSIWEMessage(build(&m))
+			assert.ErrorIs(t, err, ErrInvalidSIWEMessage)
+		})
+	}
+}
+
+func TestSIWEMessageValidate(t *testing.T) {
+	message := testSIWEMessage()
+	assert.NoError(t, message.Validate(testSIWEExpectations()))
+
+	tests := map[string]func(m *SIWEMessage, e *SIWEExpectations){
+		"other domain":     func(m *SIWEMessage, e *SIWEExpectations) { m.Domain = "legacychain.phish.io" },
+		"other URI host":   func(m *SIWEMessage, e *SIWEExpectations) { m.URI = "https://legacychain.phish.io/login" },
+		"other URI scheme": func(m *SIWEMessage, e *SIWEExpectations) { m.URI = "http://app.legacychain.io/login" },
+		"other chain":      func(m *SIWEMessage, e *SIWEExpectations) { m.ChainID = 1 },
+		"issued in future": func(m *SIWEMessage, e *SIWEExpectations) { e.Now = siweIssuedAt.Add(-2 * time.Minute) },
+		"too old": func(m *SIWEMessage, e *SIWEExpectations) {
+			m.ExpirationTime = nil
+			e.Now = siweIssuedAt.Add(SignatureMaxAge + time.Second)
+		},
+		"expired": func(m *SIWEMessage, e *SIWEExpectations) {
+			expiresAt := siweIssuedAt.Add(30 * time.Second)
+			m.ExpirationTime = &expiresAt
+		},
+		"not yet valid": func(m *SIWEMessage, e *SIWEExpectations) {
+			notBefore := siweIssuedAt.Add(time.Hour)
+			m.NotBefore = &notBefore
+		},
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := testSIWEMessage()
+			e := testSIWEExpectations()
+			modify(&m, &e)
+			assert.Error(t, m.Validate(e))
+		})
+	}
+}
+
+// A wallet signs the formatted message with personal_sign, so the existing
+// EIP-191 verification applies to the parsed message's address
+func TestSIWEMessageSignature(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	message := testSIWEMessage()
+	message.Address = crypto.PubkeyToAddress(privateKey.PublicKey)
+	text := message.String()
+
+	hash := crypto.Keccak256Hash([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(text), text)))
+	signature, err := crypto.Sign(hash.Bytes(), privateKey)
+	require.NoError(t, err)
+	signature[64] += 27
+
+	parsed, err := ParseSIWEMessage(text)
+	require.NoError(t, err)
+	valid, err := VerifySignature(parsed.Address.Hex(), text, hexutil.Encode(signature))
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func replaceLine(message string, index int, line string) string {
+	lines := strings.Split(message, "\n")
+	lines[index] = line
+	return strings.Join(lines, "\n")
+}
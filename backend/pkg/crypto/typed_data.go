@@ -0,0 +1,175 @@
+package crypto
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// EIP712Domain identifies the contract and chain a typed-data signature is bound to.
+// A signature produced for one domain cannot be replayed against another forwarder
+// or on another chain.
+type EIP712Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract common.Address
+}
+
+// ForwardRequest is the EIP-712 message a user signs so that a relayer can submit
+// a vault call on their behalf through an ERC-2771 forwarder.
+// The layout matches OpenZeppelin's ERC2771Forwarder.
+type ForwardRequest struct {
+	From     common.Address
+	To       common.Address
+	Value    *big.Int
+	Gas      *big.Int
+	Nonce    *big.Int // Forwarder nonce of From, increments on every executed request
+	Deadline uint64   // Unix timestamp (uint48 on-chain)
+	Data     []byte
+}
+
+// forwardRequestTypes are the EIP-712 type definitions of ERC2771Forwarder
+var forwardRequestTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"ForwardRequest": {
+		{Name: "from", Type: "address"},
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "gas", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint48"},
+		{Name: "data", Type: "bytes"},
+	},
+}
+
+// ForwardRequestTypedData builds the typed data for a forward request.
+// The result serializes to the JSON expected by eth_signTypedData_v4.
+func ForwardRequestTypedData(domain EIP712Domain, req ForwardRequest) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       forwardRequestTypes,
+		PrimaryType: "ForwardRequest",
+		Domain: apitypes.TypedDataDomain{
+			Name:              domain.Name,
+			Version:           domain.Version,
+			ChainId:           (*math.HexOrDecimal256)(domain.ChainID),
+			VerifyingContract: domain.VerifyingContract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":     req.From.Hex(),
+			"to":       req.To.Hex(),
+			"value":    bigOrZero(req.Value).String(),
+			"gas":      bigOrZero(req.Gas).String(),
+			"nonce":    bigOrZero(req.Nonce).String(),
+			"deadline": new(big.Int).SetUint64(req.Deadline).String(),
+			"data":     hexutil.Encode(req.Data),
+		},
+	}
+}
+
+// HashForwardRequest returns the EIP-712 digest of a forward request:
+//
+//	keccak256("\x19\x01" || domainSeparator || hashStruct(request))
+func HashForwardRequest(domain EIP712Domain, req ForwardRequest) (common.Hash, error) {
+	if domain.ChainID == nil {
+		return common.Hash{}, fmt.Errorf("domain chain ID is required")
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(ForwardRequestTypedData(domain, req))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+
+	return common.BytesToHash(hash), nil
+}
+
+// VerifyForwardRequest verifies that a forward request was signed by its From address
+//
+// Parameters:
+//   - domain: The forwarder's EIP-712 domain
+//   - req: The forward request that was signed
+//   - signature: The eth_signTypedData_v4 signature (0x-prefixed hex, 65 bytes: r, s, v)
+//
+// Returns:
+//   - bool: true if the signature is valid and was produced by req.From
+//   - error: any error that occurred during verification
+func VerifyForwardRequest(domain EIP712Domain, req ForwardRequest, signature string) (bool, error) {
+	digest, err := HashForwardRequest(domain, req)
+	if err != nil {
+		return false, err
+	}
+
+	return VerifyTypedDataSignature(req.From.Hex(), digest, signature)
+}
+
+// VerifyTypedDataSignature verifies a signature over an EIP-712 digest.
+// Unlike VerifySignature, no EIP-191 prefix is applied: the digest already
+// carries the "\x19\x01" prefix and domain separator.
+//
+// High-s signatures are rejected, matching OpenZeppelin's ECDSA library, so a
+// malleated copy of a signature cannot be submitted as a second request.
+func VerifyTypedDataSignature(address string, digest common.Hash, signature string) (bool, error) {
+	recovered, err := RecoverTypedDataSigner(digest, signature)
+	if err != nil {
+		return false, err
+	}
+
+	claimedAddr := common.HexToAddress(address)
+	if !strings.EqualFold(recovered, claimedAddr.Hex()) {
+		return false, nil // Signature is valid but address doesn't match
+	}
+
+	return true, nil
+}
+
+// RecoverTypedDataSigner recovers the address that signed an EIP-712 digest
+func RecoverTypedDataSigner(digest common.Hash, signature string) (string, error) {
+	// 1. Decode the signature
+	sigBytes, err := hexutil.Decode(signature)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	// 2. Validate signature length (must be 65 bytes: r=32, s=32, v=1)
+	if len(sigBytes) != 65 {
+		return "", fmt.Errorf("invalid signature length: got %d bytes, expected 65", len(sigBytes))
+	}
+
+	// 3. Adjust recovery ID (v) from 27/28 to 0/1
+	if sigBytes[64] >= 27 {
+		sigBytes[64] -= 27
+	}
+
+	// 4. Validate r, s and v (s must be in the lower half of the curve order)
+	r := new(big.Int).SetBytes(sigBytes[:32])
+	s := new(big.Int).SetBytes(sigBytes[32:64])
+	if !crypto.ValidateSignatureValues(sigBytes[64], r, s, true) {
+		return "", fmt.Errorf("invalid signature values")
+	}
+
+	// 5. Recover the public key and derive the address
+	publicKey, err := crypto.SigToPub(digest.Bytes(), sigBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to recover public key: %w", err)
+	}
+
+	return crypto.PubkeyToAddress(*publicKey).Hex(), nil
+}
+
+func bigOrZero(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,249 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDomain() EIP712Domain {
+	return EIP712Domain{
+		Name:              "LegacyChainForwarder",
+		Version:           "1",
+		ChainID:           big.NewInt(1337),
+		VerifyingContract: common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3"),
+	}
+}
+
+func testForwardRequest(from common.Address) ForwardRequest {
+	return ForwardRequest{
+		From:     from,
+		To:       common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Value:    big.NewInt(0),
+		Gas:      big.NewInt(100000),
+		Nonce:    big.NewInt(0),
+		Deadline: 1893456000,
+		Data:     common.FromHex("0x52e5d8a7"),
+	}
+}
+
+// Test HashForwardRequest against a digest built by hand from the EIP-712 spec
+func TestHashForwardRequest(t *testing.T) {
+	domain := testDomain()
+	req := testForwardRequest(common.HexToAddress("0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0"))
+
+	domainTypeHash := crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	domainSeparator := crypto.Keccak256(
+		domainTypeHash,
+		crypto.Keccak256([]byte(domain.Name)),
+		crypto.Keccak256([]byte(domain.Version)),
+		common.LeftPadBytes(domain.ChainID.Bytes(), 32),
+		common.LeftPadBytes(domain.VerifyingContract.Bytes(), 32),
+	)
+
+	requestTypeHash := crypto.Keccak256([]byte("ForwardRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,uint48 deadline,bytes data)"))
+	structHash := crypto.Keccak256(
+		requestTypeHash,
+		common.LeftPadBytes(req.From.Bytes(), 32),
+		common.LeftPadBytes(req.To.Bytes(), 32),
+		common.LeftPadBytes(req.Value.Bytes(), 32),
+		common.LeftPadBytes(req.Gas.Bytes(), 32),
+		common.LeftPadBytes(req.Nonce.Bytes(), 32),
+		common.LeftPadBytes(new(big.Int).SetUint64(req.Deadline).Bytes(), 32),
+		crypto.Keccak256(req.Data),
+	)
+
+	expected := crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator, structHash)
+
+	digest, err := HashForwardRequest(domain, req)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, digest)
+
+	// Any change to the domain or the request changes the digest
+	otherChain := domain
+	otherChain.ChainID = big.NewInt(1)
+	otherDigest, err := HashForwardRequest(otherChain, req)
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, otherDigest)
+
+	bumped := req
+	bumped.Nonce = big.NewInt(1)
+	bumpedDigest, err := HashForwardRequest(domain, bumped)
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, bumpedDigest)
+
+	// Chain ID is mandatory
+	_, err = HashForwardRequest(EIP712Domain{Name: "LegacyChainForwarder", Version: "1"}, req)
+	assert.Error(t, err)
+}
+
+// Test VerifyForwardRequest with valid and invalid signatures
+func TestVerifyForwardRequest(t *testing.T) {
+	// Generate a test private key and address
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	from := crypto.PubkeyToAddress(privateKey.PublicKey)
+	domain := testDomain()
+	req := testForwardRequest(from)
+
+	// Create a valid signature the way eth_signTypedData_v4 does (v = 27/28)
+	digest, err := HashForwardRequest(domain, req)
+	assert.NoError(t, err)
+	validSignature, err := crypto.Sign(digest.Bytes(), privateKey)
+	assert.NoError(t, err)
+	validSignature[64] += 27
+
+	validSigHex := hexutil.Encode(validSignature)
+
+	// Personal-sign signature over the same digest must not verify as typed data
+	personalHash := crypto.Keccak256Hash([]byte("\x19Ethereum Signed Message:\n32"), digest.Bytes())
+	personalSignature, err := crypto.Sign(personalHash.Bytes(), privateKey)
+	assert.NoError(t, err)
+
+	// High-s copy of the valid signature (s' = N - s, flipped v)
+	malleated := make([]byte, 65)
+	copy(malleated, validSignature)
+	s := new(big.Int).SetBytes(validSignature[32:64])
+	copy(malleated[32:64], common.LeftPadBytes(new(big.Int).Sub(crypto.S256().Params().N, s).Bytes(), 32))
+	malleated[64] = 27 + (1 - (validSignature[64] - 27))
+
+	otherDomain := domain
+	otherDomain.VerifyingContract = common.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	otherTarget := req
+	otherTarget.To = common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	expired := req
+	expired.Deadline = req.Deadline - 1
+
+	impersonated := req
+	impersonated.From = common.HexToAddress("0x0000000000000000000000000000000000000003")
+
+	tests := []struct {
+		name      string
+		domain    EIP712Domain
+		req       ForwardRequest
+		signature string
+		wantValid bool
+		wantErr   bool
+	}{
+		{
+			name:      "Valid signature",
+			domain:    domain,
+			req:       req,
+			signature: validSigHex,
+			wantValid: true,
+			wantErr:   false,
+		},
+		{
+			name:      "Valid signature with 0/1 recovery id",
+			domain:    domain,
+			req:       req,
+			signature: hexutil.Encode(append(append([]byte{}, validSignature[:64]...), validSignature[64]-27)),
+			wantValid: true,
+			wantErr:   false,
+		},
+		{
+			name:      "Different verifying contract",
+			domain:    otherDomain,
+			req:       req,
+			signature: validSigHex,
+			wantValid: false,
+			wantErr:   false,
+		},
+		{
+			name:      "Different target",
+			domain:    domain,
+			req:       otherTarget,
+			signature: validSigHex,
+			wantValid: false,
+			wantErr:   false,
+		},
+		{
+			name:      "Different deadline",
+			domain:    domain,
+			req:       expired,
+			signature: validSigHex,
+			wantValid: false,
+			wantErr:   false,
+		},
+		{
+			name:      "Wrong signer",
+			domain:    domain,
+			req:       impersonated,
+			signature: validSigHex,
+			wantValid: false,
+			wantErr:   false,
+		},
+		{
+			name:      "Personal sign signature",
+			domain:    domain,
+			req:       req,
+			signature: hexutil.Encode(personalSignature),
+			wantValid: false,
+			wantErr:   false,
+		},
+		{
+			name:      "Malleated signature (high s)",
+			domain:    domain,
+			req:       req,
+			signature: hexutil.Encode(malleated),
+			wantValid: false,
+			wantErr:   true,
+		},
+		{
+			name:      "Invalid signature format (too short)",
+			domain:    domain,
+			req:       req,
+			signature: "0x1234",
+			wantValid: false,
+			wantErr:   true,
+		},
+		{
+			name:      "Invalid signature format (not hex)",
+			domain:    domain,
+			req:       req,
+			signature: "not-a-hex-string",
+			wantValid: false,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := VerifyForwardRequest(tt.domain, tt.req, tt.signature)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantValid, valid)
+			}
+		})
+	}
+}
+
+// Test RecoverTypedDataSigner function
+func TestRecoverTypedDataSigner(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	assert.NoError(t, err)
+
+	expectedAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	digest, err := HashForwardRequest(testDomain(), testForwardRequest(expectedAddress))
+	assert.NoError(t, err)
+
+	signature, err := crypto.Sign(digest.Bytes(), privateKey)
+	assert.NoError(t, err)
+
+	addr, err := RecoverTypedDataSigner(digest, hexutil.Encode(signature))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedAddress.Hex(), addr)
+
+	_, err = RecoverTypedDataSigner(digest, "0xinvalid")
+	assert.Error(t, err)
+}
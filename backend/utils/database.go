@@ -35,15 +35,37 @@ func InitDatabase(cfg *config.Config) (*gorm.DB, error) {
 	}
 
 	// Auto migrate models
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+
+	log.Println("✅ Database connected and migrated successfully")
+	return db, nil
+}
+
+// Migrate creates or updates the tables of all models
+func Migrate(db *gorm.DB) error {
 	if err := db.AutoMigrate(
 		&models.User{},
+		&models.Session{},
 		&models.Vault{},
 		&models.Heir{},
 		&models.Heartbeat{},
+		&models.IndexerCursor{},
+		&models.IndexedBlock{},
+		&models.ChainEvent{},
+		&models.VaultLedgerEntry{},
+		&models.VaultAuditLog{},
+		&models.HeartbeatReminder{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.WebhookDeadLetter{},
+		&models.Transaction{},
+		&models.TransactionReplacement{},
+		&models.MetaTransaction{},
+		&models.GasBudget{},
 	); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+		return fmt.Errorf("failed to migrate database: %w", err)
 	}
-
-	log.Println("✅ Database connected and migrated successfully")
-	return db, nil
+	return nil
 }
@@ -2,6 +2,7 @@
 pragma solidity ^0.8.20;
 
 import "forge-std/Script.sol";
+import "@openzeppelin/contracts/metatx/ERC2771Forwarder.sol";
 import "../src/VaultFactory.sol";
 
 /**
@@ -19,9 +20,13 @@ contract DeployVaultFactory is Script {
         // Start broadcasting transactions
         vm.startBroadcast(deployerPrivateKey);
 
+        // Deploy the ERC-2771 forwarder used by the backend relayer
+        ERC2771Forwarder forwarder = new ERC2771Forwarder("LegacyChainForwarder");
+
         // Deploy VaultFactory
-        VaultFactory factory = new VaultFactory();
+        VaultFactory factory = new VaultFactory(address(forwarder));
 
+        console.log("ERC2771Forwarder deployed at:", address(forwarder));
         console.log("VaultFactory deployed at:", address(factory));
         console.log("Deployer:", vm.addr(deployerPrivateKey));
         console.log("Implementation vault:", factory.vaultImplementation());
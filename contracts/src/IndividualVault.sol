@@ -4,6 +4,7 @@ pragma solidity ^0.8.20;
 import "@openzeppelin/contracts-upgradeable/utils/PausableUpgradeable.sol";
 import "@openzeppelin/contracts/utils/ReentrancyGuard.sol";
 import "@openzeppelin/contracts-upgradeable/proxy/utils/Initializable.sol";
+import "@openzeppelin/contracts-upgradeable/metatx/ERC2771ContextUpgradeable.sol";
 
 /**
  * @title IndividualVault
@@ -15,11 +16,13 @@ import "@openzeppelin/contracts-upgradeable/proxy/utils/Initializable.sol";
  * - Pausable (ADR-003): Emergency stop mechanism
  * - ReentrancyGuard: Prevents reentrancy attacks
  * - Grace Period: Allows owner to cancel unlock if still alive
+ * - ERC-2771: Owner and heirs can act through the trusted forwarder (gasless meta-transactions)
  * 
  * Architecture: Factory Pattern (ADR-001)
  */
 contract IndividualVault is
     Initializable,
+    ERC2771ContextUpgradeable,
     PausableUpgradeable,
     ReentrancyGuard
 {
@@ -67,14 +70,14 @@ contract IndividualVault is
     // ============ Modifiers ============
 
     modifier onlyOwner() {
-        require(msg.sender == config.owner, "IndividualVault: not owner");
+        require(_msgSender() == config.owner, "IndividualVault: not owner");
         _;
     }
 
     modifier onlyHeir() {
         bool isHeir = false;
         for (uint256 i = 0; i < config.heirs.length; i++) {
-            if (config.heirs[i] == msg.sender) {
+            if (config.heirs[i] == _msgSender()) {
                 isHeir = true;
                 break;
             }
@@ -83,8 +86,14 @@ contract IndividualVault is
         _;
     }
 
+    /**
+     * @param _trustedForwarder ERC-2771 forwarder allowed to relay calls for owner and heirs
+     * @dev The forwarder is immutable, so every clone shares the implementation's forwarder.
+     * Vaults cloned by a factory deployed before this change, or with address(0), cannot
+     * take meta-transactions and must be called directly.
+     */
     /// @custom:oz-upgrades-unsafe-allow constructor
-    constructor() {
+    constructor(address _trustedForwarder) ERC2771ContextUpgradeable(_trustedForwarder) {
         _disableInitializers();
     }
 
@@ -132,7 +141,7 @@ contract IndividualVault is
      * @param _commitment Hash of (owner address + nonce)
      * 
      * @dev Commit-Reveal pattern prevents front-running (ADR-002)
-     * Generate commitment: keccak256(abi.encodePacked(msg.sender, nonce))
+     * Generate commitment: keccak256(abi.encodePacked(_msgSender(), nonce))
      */
     function commitHeartbeat(bytes32 _commitment)
         external
@@ -155,7 +164,7 @@ contract IndividualVault is
         onlyOwner
         whenNotPaused
     {
-        bytes32 commitment = keccak256(abi.encodePacked(msg.sender, _nonce));
+        bytes32 commitment = keccak256(abi.encodePacked(_msgSender(), _nonce));
         require(usedCommitments[commitment], "IndividualVault: invalid commitment");
 
         // Update heartbeat
@@ -173,7 +182,7 @@ contract IndividualVault is
                 heirApprovals[config.heirs[i]] = false;
             }
 
-            emit UnlockCancelled(msg.sender, block.timestamp);
+            emit UnlockCancelled(_msgSender(), block.timestamp);
         }
 
         emit Heartbeat(block.timestamp, commitment);
@@ -210,12 +219,12 @@ contract IndividualVault is
         whenNotPaused
     {
         require(!config.isLocked, "IndividualVault: vault locked");
-        require(!heirApprovals[msg.sender], "IndividualVault: already approved");
+        require(!heirApprovals[_msgSender()], "IndividualVault: already approved");
 
-        heirApprovals[msg.sender] = true;
+        heirApprovals[_msgSender()] = true;
         config.approvalCount++;
 
-        emit InheritanceApproved(msg.sender);
+        emit InheritanceApproved(_msgSender());
     }
 
     /**
@@ -245,12 +254,12 @@ contract IndividualVault is
             block.timestamp >= config.unlockTime,
             "IndividualVault: grace period not ended"
         );
-        require(!heirClaimed[msg.sender], "IndividualVault: already claimed");
+        require(!heirClaimed[_msgSender()], "IndividualVault: already claimed");
 
         // Find heir index and calculate share
         uint256 heirIndex = type(uint256).max;
         for (uint256 i = 0; i < config.heirs.length; i++) {
-            if (config.heirs[i] == msg.sender) {
+            if (config.heirs[i] == _msgSender()) {
                 heirIndex = i;
                 break;
             }
@@ -258,7 +267,7 @@ contract IndividualVault is
         require(heirIndex != type(uint256).max, "IndividualVault: not heir");
 
         // Effects
-        heirClaimed[msg.sender] = true;
+        heirClaimed[_msgSender()] = true;
         
         // Snapshot balance on first claim after grace period ends
         if (config.totalBalanceAtUnlock == 0) {
@@ -269,10 +278,10 @@ contract IndividualVault is
         uint256 amount = (config.totalBalanceAtUnlock * config.heirShares[heirIndex]) / 10000;
 
         // Interactions (CEI pattern)
-        (bool success, ) = msg.sender.call{value: amount}("");
+        (bool success, ) = _msgSender().call{value: amount}("");
         require(success, "IndividualVault: transfer failed");
 
-        emit InheritanceClaimed(msg.sender, amount);
+        emit InheritanceClaimed(_msgSender(), amount);
     }
 
     // ============ Emergency Functions ============
@@ -310,10 +319,10 @@ contract IndividualVault is
         require(config.isLocked, "IndividualVault: cannot withdraw when unlocked");
         require(address(this).balance >= _amount, "IndividualVault: insufficient balance");
 
-        (bool success, ) = msg.sender.call{value: _amount}("");
+        (bool success, ) = _msgSender().call{value: _amount}("");
         require(success, "IndividualVault: transfer failed");
 
-        emit Withdrawn(msg.sender, _amount);
+        emit Withdrawn(_msgSender(), _amount);
     }
 
     /**
@@ -324,6 +333,35 @@ contract IndividualVault is
         emit Deposited(msg.sender, msg.value);
     }
 
+    // ============ ERC-2771 Context ============
+
+    function _msgSender()
+        internal
+        view
+        override(ContextUpgradeable, ERC2771ContextUpgradeable)
+        returns (address)
+    {
+        return ERC2771ContextUpgradeable._msgSender();
+    }
+
+    function _msgData()
+        internal
+        view
+        override(ContextUpgradeable, ERC2771ContextUpgradeable)
+        returns (bytes calldata)
+    {
+        return ERC2771ContextUpgradeable._msgData();
+    }
+
+    function _contextSuffixLength()
+        internal
+        view
+        override(ContextUpgradeable, ERC2771ContextUpgradeable)
+        returns (uint256)
+    {
+        return ERC2771ContextUpgradeable._contextSuffixLength();
+    }
+
     // ============ View Functions ============
 
     /**
@@ -37,10 +37,11 @@ contract VaultFactory is Ownable {
 
     /**
      * @notice Constructor deploys the implementation contract
+     * @param _trustedForwarder ERC-2771 forwarder trusted by every vault (address(0) disables meta-transactions)
      * @dev Implementation is deployed once and cloned for each vault
      */
-    constructor() Ownable(msg.sender) {
-        vaultImplementation = address(new IndividualVault());
+    constructor(address _trustedForwarder) Ownable(msg.sender) {
+        vaultImplementation = address(new IndividualVault(_trustedForwarder));
     }
 
     /**
@@ -67,6 +68,52 @@ contract VaultFactory is Ownable {
         uint256 _gracePeriod,
         uint256 _requiredApprovals
     ) external returns (address vaultAddress) {
+        return _createVault(
+            msg.sender,
+            _heirs,
+            _shares,
+            _heartbeatInterval,
+            _gracePeriod,
+            _requiredApprovals
+        );
+    }
+
+    /**
+     * @notice Creates a new vault on behalf of another owner
+     * @param _owner Address that will own the vault
+     * @return vaultAddress Address of the newly created vault
+     *
+     * @dev Lets the backend deploy vaults for users without them paying gas.
+     * Only the factory owner may call it, so vaults cannot be created in
+     * someone else's name. Same requirements as createVault.
+     */
+    function createVaultFor(
+        address _owner,
+        address[] memory _heirs,
+        uint256[] memory _shares,
+        uint256 _heartbeatInterval,
+        uint256 _gracePeriod,
+        uint256 _requiredApprovals
+    ) external onlyOwner returns (address vaultAddress) {
+        require(_owner != address(0), "VaultFactory: zero owner");
+        return _createVault(
+            _owner,
+            _heirs,
+            _shares,
+            _heartbeatInterval,
+            _gracePeriod,
+            _requiredApprovals
+        );
+    }
+
+    function _createVault(
+        address _owner,
+        address[] memory _heirs,
+        uint256[] memory _shares,
+        uint256 _heartbeatInterval,
+        uint256 _gracePeriod,
+        uint256 _requiredApprovals
+    ) internal returns (address vaultAddress) {
         // Input validation
         require(_heirs.length > 0, "VaultFactory: no heirs");
         require(_heirs.length == _shares.length, "VaultFactory: length mismatch");
@@ -99,7 +146,7 @@ contract VaultFactory is Ownable {
 
         // Initialize the vault
         IndividualVault(payable(vaultAddress)).initialize(
-            msg.sender,
+            _owner,
             _heirs,
             _shares,
             _heartbeatInterval,
@@ -108,13 +155,13 @@ contract VaultFactory is Ownable {
         );
 
         // Record ownership
-        ownerVaults[msg.sender].push(vaultAddress);
+        ownerVaults[_owner].push(vaultAddress);
         totalVaults++;
 
         emit VaultCreated(
             vaultAddress,
-            msg.sender,
-            ownerVaults[msg.sender].length - 1,
+            _owner,
+            ownerVaults[_owner].length - 1,
             block.timestamp
         );
 
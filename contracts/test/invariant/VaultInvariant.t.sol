@@ -17,7 +17,7 @@ contract VaultInvariantTest is StdInvariant, Test {
 
     function setUp() public {
         // Deploy factory
-        factory = new VaultFactory();
+        factory = new VaultFactory(address(0));
 
         // Deploy handler
         handler = new VaultHandler(factory);
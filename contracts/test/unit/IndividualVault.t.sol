@@ -39,7 +39,7 @@ contract IndividualVaultTest is Test {
 
     function setUp() public {
         // Deploy factory
-        factory = new VaultFactory();
+        factory = new VaultFactory(address(0));
 
         // Setup heirs and shares
         heirs.push(heir1);
@@ -131,6 +131,39 @@ contract IndividualVaultTest is Test {
         );
     }
 
+    function test_FactoryCreatesVaultFor() public {
+        vm.expectEmit(false, true, false, false);
+        emit VaultCreated(address(0), owner, 0, block.timestamp);
+
+        // The test contract deployed the factory, so it is the factory owner
+        address vaultAddr = factory.createVaultFor(
+            owner,
+            heirs,
+            shares,
+            HEARTBEAT_INTERVAL,
+            GRACE_PERIOD,
+            REQUIRED_APPROVALS
+        );
+
+        IndividualVault.VaultConfig memory config = IndividualVault(payable(vaultAddr)).getConfig();
+        assertEq(config.owner, owner, "Owner mismatch");
+        assertEq(factory.getOwnerVaults(owner)[0], vaultAddr, "Vault address mismatch");
+        assertEq(factory.getOwnerVaultCount(address(this)), 0, "Caller should not own the vault");
+    }
+
+    function test_RevertWhen_CreateVaultForNotFactoryOwner() public {
+        vm.prank(owner);
+        vm.expectRevert(abi.encodeWithSelector(Ownable.OwnableUnauthorizedAccount.selector, owner));
+        factory.createVaultFor(
+            owner,
+            heirs,
+            shares,
+            HEARTBEAT_INTERVAL,
+            GRACE_PERIOD,
+            REQUIRED_APPROVALS
+        );
+    }
+
     // ============ Initialization Tests ============
 
     function test_VaultInitialized() public {
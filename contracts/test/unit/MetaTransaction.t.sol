@@ -0,0 +1,309 @@
+// SPDX-License-Identifier: AGPL-3.0
+pragma solidity ^0.8.20;
+
+import "forge-std/Test.sol";
+import "@openzeppelin/contracts/metatx/ERC2771Forwarder.sol";
+import "@openzeppelin/contracts/utils/Errors.sol";
+import "../../src/VaultFactory.sol";
+import "../../src/IndividualVault.sol";
+
+/**
+ * @title MetaTransactionTest
+ * @notice Unit tests for vault calls relayed through the ERC-2771 forwarder
+ * @dev Checks that _msgSender() resolves to the signer only when the call
+ * comes from the forwarder the factory was deployed with
+ */
+contract MetaTransactionTest is Test {
+    ERC2771Forwarder public forwarder;
+    VaultFactory public factory;
+    IndividualVault public vault;
+
+    uint256 constant OWNER_KEY = 0xA11CE;
+    uint256 constant HEIR1_KEY = 0xB0B;
+    uint256 constant HEIR2_KEY = 0xCA401;
+    uint256 constant NON_HEIR_KEY = 0xD00D;
+
+    address public owner = vm.addr(OWNER_KEY);
+    address public heir1 = vm.addr(HEIR1_KEY);
+    address public heir2 = vm.addr(HEIR2_KEY);
+    address public nonHeir = vm.addr(NON_HEIR_KEY);
+    address public relayer = address(0xF1);
+
+    address[] public heirs;
+    uint256[] public shares;
+
+    uint256 constant HEARTBEAT_INTERVAL = 7 days;
+    uint256 constant GRACE_PERIOD = 30 days;
+    uint256 constant REQUIRED_APPROVALS = 2;
+    uint256 constant FORWARD_GAS = 300_000;
+
+    bytes32 constant FORWARD_REQUEST_TYPEHASH = keccak256(
+        "ForwardRequest(address from,address to,uint256 value,uint256 gas,uint256 nonce,uint48 deadline,bytes data)"
+    );
+
+    event Heartbeat(uint256 timestamp, bytes32 commitment);
+    event InheritanceApproved(address indexed heir);
+    event InheritanceClaimed(address indexed heir, uint256 amount);
+
+    function setUp() public {
+        // Deploy forwarder and a factory whose vaults trust it
+        forwarder = new ERC2771Forwarder("LegacyChainForwarder");
+        factory = new VaultFactory(address(forwarder));
+
+        heirs.push(heir1);
+        heirs.push(heir2);
+
+        shares.push(6000); // 60%
+        shares.push(4000); // 40%
+
+        vm.deal(owner, 100 ether);
+        vm.deal(relayer, 1 ether);
+    }
+
+    function _createVault(VaultFactory _factory) internal returns (IndividualVault) {
+        vm.prank(owner);
+        address vaultAddr = _factory.createVault(
+            heirs,
+            shares,
+            HEARTBEAT_INTERVAL,
+            GRACE_PERIOD,
+            REQUIRED_APPROVALS
+        );
+        return IndividualVault(payable(vaultAddr));
+    }
+
+    /// @dev Builds a forward request from the key's address and signs it with EIP-712
+    function _signRequest(uint256 _key, address _to, bytes memory _data)
+        internal
+        view
+        returns (ERC2771Forwarder.ForwardRequestData memory request)
+    {
+        address from = vm.addr(_key);
+        request = ERC2771Forwarder.ForwardRequestData({
+            from: from,
+            to: _to,
+            value: 0,
+            gas: FORWARD_GAS,
+            deadline: uint48(block.timestamp + 1 hours),
+            data: _data,
+            signature: ""
+        });
+        request.signature = _sign(_key, request, forwarder.nonces(from));
+    }
+
+    function _sign(uint256 _key, ERC2771Forwarder.ForwardRequestData memory _request, uint256 _nonce)
+        internal
+        view
+        returns (bytes memory)
+    {
+        bytes32 structHash = keccak256(abi.encode(
+            FORWARD_REQUEST_TYPEHASH,
+            _request.from,
+            _request.to,
+            _request.value,
+            _request.gas,
+            _nonce,
+            _request.deadline,
+            keccak256(_request.data)
+        ));
+        bytes32 digest = keccak256(abi.encodePacked("\x19\x01", _domainSeparator(), structHash));
+
+        (uint8 v, bytes32 r, bytes32 s) = vm.sign(_key, digest);
+        return abi.encodePacked(r, s, v);
+    }
+
+    function _domainSeparator() internal view returns (bytes32) {
+        (, string memory name, string memory version, uint256 chainId, address verifyingContract, , ) =
+            forwarder.eip712Domain();
+        return keccak256(abi.encode(
+            keccak256("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"),
+            keccak256(bytes(name)),
+            keccak256(bytes(version)),
+            chainId,
+            verifyingContract
+        ));
+    }
+
+    function _relay(ERC2771Forwarder.ForwardRequestData memory _request) internal {
+        vm.prank(relayer);
+        forwarder.execute(_request);
+    }
+
+    // ============ Trusted Forwarder Tests ============
+
+    function test_VaultTrustsFactoryForwarder() public {
+        vault = _createVault(factory);
+
+        assertEq(vault.trustedForwarder(), address(forwarder), "Forwarder mismatch");
+        assertTrue(vault.isTrustedForwarder(address(forwarder)), "Forwarder should be trusted");
+        assertFalse(vault.isTrustedForwarder(relayer), "Relayer should not be trusted");
+    }
+
+    function test_ForwardedRevealActsAsOwner() public {
+        vault = _createVault(factory);
+
+        bytes32 nonce = keccak256("secret");
+        bytes32 commitment = keccak256(abi.encodePacked(owner, nonce));
+
+        vm.prank(owner);
+        vault.commitHeartbeat(commitment);
+
+        vm.warp(block.timestamp + 1 days);
+
+        ERC2771Forwarder.ForwardRequestData memory request = _signRequest(
+            OWNER_KEY,
+            address(vault),
+            abi.encodeCall(IndividualVault.revealHeartbeat, (nonce))
+        );
+
+        // The commitment only matches if _msgSender() is the owner, not the forwarder
+        vm.expectEmit(false, false, false, true);
+        emit Heartbeat(block.timestamp, commitment);
+
+        _relay(request);
+
+        IndividualVault.VaultConfig memory config = vault.getConfig();
+        assertEq(config.lastHeartbeat, block.timestamp, "Heartbeat not updated");
+    }
+
+    function test_ForwardedPauseActsAsOwner() public {
+        vault = _createVault(factory);
+
+        _relay(_signRequest(OWNER_KEY, address(vault), abi.encodeCall(IndividualVault.pause, ())));
+
+        assertTrue(vault.paused(), "Vault should be paused");
+    }
+
+    function test_ForwardedApproveAndClaimActAsHeir() public {
+        vault = _createVault(factory);
+
+        vm.prank(owner);
+        (bool success, ) = address(vault).call{value: 10 ether}("");
+        require(success);
+
+        vm.warp(block.timestamp + HEARTBEAT_INTERVAL + 1);
+        vault.checkAndUnlock();
+
+        ERC2771Forwarder.ForwardRequestData memory approval =
+            _signRequest(HEIR1_KEY, address(vault), abi.encodeCall(IndividualVault.approveInheritance, ()));
+
+        vm.expectEmit(true, false, false, false);
+        emit InheritanceApproved(heir1);
+        _relay(approval);
+        _relay(_signRequest(HEIR2_KEY, address(vault), abi.encodeCall(IndividualVault.approveInheritance, ())));
+
+        assertTrue(vault.heirApprovals(heir1), "Heir1 approval not recorded");
+        assertTrue(vault.heirApprovals(heir2), "Heir2 approval not recorded");
+
+        vm.warp(block.timestamp + GRACE_PERIOD + 1);
+
+        uint256 relayerBalance = relayer.balance;
+        ERC2771Forwarder.ForwardRequestData memory claim =
+            _signRequest(HEIR1_KEY, address(vault), abi.encodeCall(IndividualVault.claimInheritance, ()));
+
+        vm.expectEmit(true, false, false, true);
+        emit InheritanceClaimed(heir1, 6 ether);
+        _relay(claim);
+
+        // The share goes to the signer, not the relayer or forwarder
+        assertEq(heir1.balance, 6 ether, "Heir1 should receive 60%");
+        assertEq(relayer.balance, relayerBalance, "Relayer should receive nothing");
+        assertEq(address(forwarder).balance, 0, "Forwarder should receive nothing");
+        assertTrue(vault.heirClaimed(heir1), "Claim not recorded for heir1");
+    }
+
+    function test_RevertWhen_ForwardedByNonOwner() public {
+        vault = _createVault(factory);
+
+        // A valid signature from someone who is not the owner still fails the vault's check
+        ERC2771Forwarder.ForwardRequestData memory request =
+            _signRequest(NON_HEIR_KEY, address(vault), abi.encodeCall(IndividualVault.pause, ()));
+
+        vm.prank(relayer);
+        vm.expectRevert(Errors.FailedCall.selector);
+        forwarder.execute(request);
+
+        assertFalse(vault.paused(), "Vault should not be paused");
+    }
+
+    function test_RevertWhen_ForwardedWithWrongSigner() public {
+        vault = _createVault(factory);
+
+        // Claims to come from the owner but is signed by the non-heir
+        ERC2771Forwarder.ForwardRequestData memory request =
+            _signRequest(OWNER_KEY, address(vault), abi.encodeCall(IndividualVault.pause, ()));
+        request.signature = _sign(NON_HEIR_KEY, request, forwarder.nonces(owner));
+
+        vm.prank(relayer);
+        vm.expectRevert(abi.encodeWithSelector(
+            ERC2771Forwarder.ERC2771ForwarderInvalidSigner.selector,
+            nonHeir,
+            owner
+        ));
+        forwarder.execute(request);
+    }
+
+    function test_RevertWhen_ForwardRequestReplayed() public {
+        vault = _createVault(factory);
+
+        ERC2771Forwarder.ForwardRequestData memory request =
+            _signRequest(OWNER_KEY, address(vault), abi.encodeCall(IndividualVault.pause, ()));
+        _relay(request);
+
+        // The forwarder nonce moved on, so the old signature no longer recovers the owner
+        vm.prank(relayer);
+        vm.expectRevert(ERC2771Forwarder.ERC2771ForwarderInvalidSigner.selector);
+        forwarder.execute(request);
+    }
+
+    function test_RevertWhen_ForwardRequestExpired() public {
+        vault = _createVault(factory);
+
+        ERC2771Forwarder.ForwardRequestData memory request =
+            _signRequest(OWNER_KEY, address(vault), abi.encodeCall(IndividualVault.pause, ()));
+
+        vm.warp(uint256(request.deadline) + 1);
+
+        vm.prank(relayer);
+        vm.expectRevert(abi.encodeWithSelector(
+            ERC2771Forwarder.ERC2771ForwarderExpiredRequest.selector,
+            request.deadline
+        ));
+        forwarder.execute(request);
+    }
+
+    function test_RevertWhen_SenderAppendedWithoutForwarder() public {
+        vault = _createVault(factory);
+
+        // Calldata suffixes are only trusted from the forwarder
+        vm.prank(nonHeir);
+        (bool success, bytes memory reason) = address(vault).call(
+            abi.encodePacked(abi.encodeCall(IndividualVault.pause, ()), owner)
+        );
+
+        assertFalse(success, "Appended sender should be ignored");
+        assertEq(reason, abi.encodeWithSignature("Error(string)", "IndividualVault: not owner"));
+        assertFalse(vault.paused(), "Vault should not be paused");
+    }
+
+    function test_RevertWhen_FactoryHasNoForwarder() public {
+        // Vaults from a factory deployed without a forwarder cannot take meta-transactions
+        VaultFactory legacyFactory = new VaultFactory(address(0));
+        vault = _createVault(legacyFactory);
+
+        assertFalse(vault.isTrustedForwarder(address(forwarder)), "Forwarder should not be trusted");
+
+        ERC2771Forwarder.ForwardRequestData memory request =
+            _signRequest(OWNER_KEY, address(vault), abi.encodeCall(IndividualVault.pause, ()));
+
+        vm.prank(relayer);
+        vm.expectRevert(abi.encodeWithSelector(
+            ERC2771Forwarder.ERC2771UntrustedTarget.selector,
+            address(vault),
+            address(forwarder)
+        ));
+        forwarder.execute(request);
+
+        assertFalse(vault.paused(), "Vault should not be paused");
+    }
+}